@@ -54,7 +54,7 @@ func NewAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 
 		// Generate a hash out of s3Conf.
 		confHash := fnv.New32a()
-		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey))
+		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.TLSServerName))
 		confSum := confHash.Sum32()
 
 		// Lookup previous cache by hash.
@@ -81,6 +81,9 @@ func NewAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 			if config.Insecure {
 				tlsConfig.InsecureSkipVerify = true
 			}
+			if config.TLSServerName != "" {
+				tlsConfig.ServerName = config.TLSServerName
+			}
 
 			var transport http.RoundTripper = &http.Transport{
 				Proxy: http.ProxyFromEnvironment,
@@ -16,7 +16,10 @@
 
 package cmd
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 /// Collection of standard errors
 
@@ -190,6 +193,171 @@ func (e UnexpectedExcessRead) Error() string {
 	return msg
 }
 
+// WebhookFailed - object was uploaded but the webhook notification failed.
+type WebhookFailed struct {
+	URL string
+	Err error
+}
+
+func (e WebhookFailed) Error() string {
+	return "Unable to notify webhook `" + e.URL + "`: " + e.Err.Error()
+}
+
+// UnknownSizeWithoutMultipart - streams of unknown size can only be
+// uploaded using multipart.
+type UnknownSizeWithoutMultipart struct {
+	Object string
+}
+
+func (e UnknownSizeWithoutMultipart) Error() string {
+	return "Object `" + e.Object + "` of unknown size cannot be uploaded with multipart disabled."
+}
+
+// InvalidConfig - a field of the client configuration is invalid.
+type InvalidConfig struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidConfig) Error() string {
+	return "Invalid `" + e.Field + "` in the client configuration, " + e.Reason + "."
+}
+
+// AWSProfileNotFound - profile is in neither the credentials nor the
+// config file of the AWS CLI.
+type AWSProfileNotFound struct {
+	Profile string
+}
+
+func (e AWSProfileNotFound) Error() string {
+	return "AWS profile `" + e.Profile + "` not found."
+}
+
+// InvalidStorageClass - storage class is not one of the known classes.
+type InvalidStorageClass struct {
+	StorageClass string
+	Valid        []string
+}
+
+func (e InvalidStorageClass) Error() string {
+	return "Invalid storage class `" + e.StorageClass + "`, valid storage classes are " + strings.Join(e.Valid, ", ") + "."
+}
+
+// ChecksumMismatch - downloaded content doesn't match the object ETag.
+type ChecksumMismatch struct {
+	Object   string
+	Expected string
+	Got      string
+}
+
+func (e ChecksumMismatch) Error() string {
+	return "Checksum mismatch for object `" + e.Object + "`, expected `" + e.Expected + "` but got `" + e.Got + "`."
+}
+
+// InconsistentObject - the parts of a multipart object don't add up to
+// its size, or one of them is missing.
+type InconsistentObject struct {
+	Object      string
+	Size        int64
+	Parts       int
+	PartsSize   int64
+	MissingPart int
+}
+
+func (e InconsistentObject) Error() string {
+	if e.MissingPart > 0 {
+		return fmt.Sprintf("Object `%s` is inconsistent, part %d of %d is missing.", e.Object, e.MissingPart, e.Parts)
+	}
+	return fmt.Sprintf("Object `%s` is inconsistent, its %d parts have %d bytes instead of %d.", e.Object, e.Parts, e.PartsSize, e.Size)
+}
+
+// IntegrityMismatch - the checksum of an object content isn't the
+// expected one.
+type IntegrityMismatch struct {
+	Object    string
+	Algorithm string
+	Expected  string
+	Got       string
+}
+
+func (e IntegrityMismatch) Error() string {
+	return fmt.Sprintf("Integrity check of object `%s` failed, expected %s `%s` but got `%s`.", e.Object, e.Algorithm, e.Expected, e.Got)
+}
+
+// BucketACLNotCanned - the ACL of a bucket grants permissions no canned
+// ACL does.
+type BucketACLNotCanned struct {
+	Bucket string
+}
+
+func (e BucketACLNotCanned) Error() string {
+	return "ACL of bucket `" + e.Bucket + "` is not a canned ACL."
+}
+
+// BucketRegionMismatch - bucket lives in another region than the one
+// requests were made for. Expected and Actual are empty when unknown.
+type BucketRegionMismatch struct {
+	Bucket   string
+	Expected string
+	Actual   string
+}
+
+func (e BucketRegionMismatch) Error() string {
+	msg := "Bucket `" + e.Bucket + "` is in another region"
+	if e.Actual != "" {
+		msg = "Bucket `" + e.Bucket + "` is in region `" + e.Actual + "`"
+	}
+	if e.Expected != "" {
+		msg += ", not `" + e.Expected + "`"
+	}
+	return msg + "."
+}
+
+// SelectExpressionTooLarge - select expression exceeds the size S3
+// accepts.
+type SelectExpressionTooLarge struct {
+	Size  int
+	Limit int
+}
+
+func (e SelectExpressionTooLarge) Error() string {
+	return fmt.Sprintf("Select expression is %d bytes long, longer than the %d bytes limit.", e.Size, e.Limit)
+}
+
+// ParquetInvalid - object is not a Parquet file, or its metadata
+// can't be decoded.
+type ParquetInvalid struct {
+	Object string
+	Reason string
+}
+
+func (e ParquetInvalid) Error() string {
+	return "Object `" + e.Object + "` is not a valid Parquet file: " + e.Reason + "."
+}
+
+// PreconditionFailed - a condition of a request on an object, such as
+// If-Match, doesn't hold. Condition lists the conditional headers of
+// the request, e.g. `If-Match: "etag"`.
+type PreconditionFailed struct {
+	Object    string
+	Condition string
+}
+
+func (e PreconditionFailed) Error() string {
+	return "Precondition `" + e.Condition + "` failed for object `" + e.Object + "`."
+}
+
+// ObjectNotModified - object wasn't sent as it wasn't modified
+// according to a condition of the request, such as If-None-Match.
+type ObjectNotModified struct {
+	Object    string
+	Condition string
+}
+
+func (e ObjectNotModified) Error() string {
+	return "Object `" + e.Object + "` not modified, `" + e.Condition + "` doesn't hold."
+}
+
 // SameFile - source and destination are same files.
 type SameFile struct {
 	Source, Destination string
@@ -408,7 +408,7 @@ func (f *fsClient) put(reader io.Reader, size int64, metadata map[string][]strin
 }
 
 // Put - create a new file with metadata.
-func (f *fsClient) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool) (int64, *probe.Error) {
+func (f *fsClient) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool, cannedACL string) (int64, *probe.Error) {
 	if metadata["mc-attrs"] != "" {
 		meta := make(map[string][]string)
 		meta["mc-attrs"] = append(meta["mc-attrs"], metadata["mc-attrs"])
@@ -434,7 +434,7 @@ func (f *fsClient) ShareUpload(startsWith bool, expires time.Duration, contentTy
 }
 
 // Copy - copy data from source to destination
-func (f *fsClient) Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool) *probe.Error {
+func (f *fsClient) Copy(ctx context.Context, source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool, cannedACL string) *probe.Error {
 	rc, e := os.Open(source)
 	if e != nil {
 		err := f.toClientError(e, source)
@@ -450,7 +450,7 @@ func (f *fsClient) Copy(source string, size int64, progress io.Reader, srcSSE, t
 }
 
 // Get returns reader and any additional metadata.
-func (f *fsClient) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+func (f *fsClient) Get(ctx context.Context, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
 	fileData, e := os.Open(f.PathURL.Path)
 	if e != nil {
 		err := f.toClientError(e, f.PathURL.Path)
@@ -504,7 +504,7 @@ func deleteFile(deletePath string) error {
 }
 
 // Remove - remove entry read from clientContent channel.
-func (f *fsClient) Remove(isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent) <-chan *probe.Error {
+func (f *fsClient) Remove(ctx context.Context, isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent) <-chan *probe.Error {
 	errorCh := make(chan *probe.Error)
 
 	// Goroutine reads from contentCh and removes the entry in content.
@@ -917,7 +917,7 @@ func (f *fsClient) listRecursiveInRoutine(contentCh chan *ClientContent, isMetad
 }
 
 // MakeBucket - create a new bucket.
-func (f *fsClient) MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error {
+func (f *fsClient) MakeBucket(ctx context.Context, region string, ignoreExisting, withLock bool) *probe.Error {
 	// TODO: ignoreExisting has no effect currently. In the future, we want
 	// to call os.Mkdir() when ignoredExisting is disabled and os.MkdirAll()
 	// otherwise.
@@ -930,7 +930,7 @@ func (f *fsClient) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 }
 
 // Set object lock configuration of bucket.
-func (f *fsClient) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
+func (f *fsClient) SetObjectLockConfig(ctx context.Context, mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
 	return probe.NewError(APINotImplemented{
 		API:     "SetObjectLockConfig",
 		APIType: "filesystem",
@@ -938,7 +938,7 @@ func (f *fsClient) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint
 }
 
 // Get object lock configuration of bucket.
-func (f *fsClient) GetObjectLockConfig() (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error) {
+func (f *fsClient) GetObjectLockConfig(ctx context.Context) (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error) {
 	return nil, nil, nil, probe.NewError(APINotImplemented{
 		API:     "GetObjectLockConfig",
 		APIType: "filesystem",
@@ -954,7 +954,7 @@ func (f *fsClient) GetAccessRules() (map[string]string, *probe.Error) {
 }
 
 // Set object retention for a given object.
-func (f *fsClient) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
+func (f *fsClient) PutObjectRetention(ctx context.Context, mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
 	return probe.NewError(APINotImplemented{
 		API:     "PutObjectRetention",
 		APIType: "filesystem",
@@ -962,7 +962,7 @@ func (f *fsClient) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate
 }
 
 // Set object legal hold for a given object.
-func (f *fsClient) PutObjectLegalHold(lhold *minio.LegalHoldStatus) *probe.Error {
+func (f *fsClient) PutObjectLegalHold(ctx context.Context, lhold *minio.LegalHoldStatus) *probe.Error {
 	return probe.NewError(APINotImplemented{
 		API:     "PutObjectLegalHold",
 		APIType: "filesystem",
@@ -1027,7 +1027,7 @@ func (f *fsClient) SetAccess(access string, isJSON bool) *probe.Error {
 }
 
 // Stat - get metadata from path.
-func (f *fsClient) Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (content *ClientContent, err *probe.Error) {
+func (f *fsClient) Stat(ctx context.Context, isIncomplete, isPreserve bool, sse encrypt.ServerSide) (content *ClientContent, err *probe.Error) {
 	st, err := f.fsStat(isIncomplete)
 	if err != nil {
 		return nil, err.Trace(f.PathURL.String())
@@ -1103,7 +1103,7 @@ func (f *fsClient) AddUserAgent(_, _ string) {
 }
 
 // Get Object Tags
-func (f *fsClient) GetObjectTagging() (tagging.Tagging, *probe.Error) {
+func (f *fsClient) GetObjectTagging(ctx context.Context) (tagging.Tagging, *probe.Error) {
 	return tagging.Tagging{}, probe.NewError(APINotImplemented{
 		API:     "GetObjectTagging",
 		APIType: "filesystem",
@@ -1111,7 +1111,7 @@ func (f *fsClient) GetObjectTagging() (tagging.Tagging, *probe.Error) {
 }
 
 // Set Object tags
-func (f *fsClient) SetObjectTagging(tagMap map[string]string) *probe.Error {
+func (f *fsClient) SetObjectTagging(ctx context.Context, tagMap map[string]string) *probe.Error {
 	return probe.NewError(APINotImplemented{
 		API:     "SetObjectTagging",
 		APIType: "filesystem",
@@ -1119,7 +1119,7 @@ func (f *fsClient) SetObjectTagging(tagMap map[string]string) *probe.Error {
 }
 
 // Delete object tags
-func (f *fsClient) DeleteObjectTagging() *probe.Error {
+func (f *fsClient) DeleteObjectTagging(ctx context.Context) *probe.Error {
 	return probe.NewError(APINotImplemented{
 		API:     "DeleteObjectTagging",
 		APIType: "filesystem",
@@ -0,0 +1,213 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+// Copies throttled with SlowDown are attempted up to batchCopyRetries
+// times, waiting batchCopyRetryUnit times the attempt in between.
+const (
+	batchCopyRetries   = 5
+	batchCopyRetryUnit = 500 * time.Millisecond
+)
+
+// CopyPair - server side copy of an object to another, on the host of
+// the client.
+type CopyPair struct {
+	SourceBucket string
+	SourceObject string
+	Bucket       string
+	Object       string
+	// SourceSSE is the SSE-C key of the source object, if encrypted
+	// with one, TargetSSE the encryption of the copy.
+	SourceSSE encrypt.ServerSide
+	TargetSSE encrypt.ServerSide
+	// Metadata replaces the metadata of the source object when not
+	// nil, the metadata of the source is copied otherwise.
+	Metadata map[string]string
+}
+
+// CopyResult - outcome of the copy of a pair by BatchCopy.
+type CopyResult struct {
+	Pair CopyPair
+	// Size is the size of the source object.
+	Size    int64
+	Skipped bool
+	Err     *probe.Error
+}
+
+// BatchCopyStats - outcome of a BatchCopy run.
+type BatchCopyStats struct {
+	Copied  int
+	Skipped int
+	Failed  int
+	// Bytes is the size of all copied objects.
+	Bytes int64
+}
+
+// BatchCopy - copy the objects of all pairs received on pairs until it
+// is closed, with server side copies made by workers goroutines. If
+// skipExisting is set, pairs whose target already has the size and
+// ETag of their source are skipped. Copies throttled with SlowDown are
+// retried. The result of every pair is sent on results, if not nil,
+// which is closed once done. Canceling ctx stops the copies, pairs
+// still sent afterwards are not read anymore.
+func (c *S3Client) BatchCopy(ctx context.Context, pairs <-chan CopyPair, workers int, skipExisting bool, results chan<- CopyResult) (BatchCopyStats, *probe.Error) {
+	var stats BatchCopyStats
+	var mutex sync.Mutex
+
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var pair CopyPair
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case pair, ok = <-pairs:
+					if !ok {
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					// Both were ready, stop anyway.
+					return
+				}
+				result := c.batchCopyObject(ctx, pair, skipExisting)
+				mutex.Lock()
+				switch {
+				case result.Err != nil:
+					stats.Failed++
+				case result.Skipped:
+					stats.Skipped++
+				default:
+					stats.Copied++
+					stats.Bytes += result.Size
+				}
+				mutex.Unlock()
+				if results != nil {
+					results <- result
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if results != nil {
+		close(results)
+	}
+
+	if ctx.Err() != nil {
+		return stats, probe.NewError(ctx.Err())
+	}
+	return stats, nil
+}
+
+// batchCopyObject - copy the object of a pair, unless skipExisting is
+// set and its target already matches it.
+func (c *S3Client) batchCopyObject(ctx context.Context, pair CopyPair, skipExisting bool) CopyResult {
+	result := CopyResult{Pair: pair}
+	if pair.SourceBucket == "" || pair.Bucket == "" {
+		result.Err = probe.NewError(BucketNameEmpty{})
+		return result
+	}
+	if pair.SourceObject == "" || pair.Object == "" {
+		result.Err = probe.NewError(ObjectNameEmpty{})
+		return result
+	}
+
+	srcSSE := c.readSSE(pair.SourceSSE)
+	opts := minio.StatObjectOptions{}
+	opts.ServerSideEncryption = srcSSE
+	source, e := c.api.StatObjectWithContext(ctx, pair.SourceBucket, pair.SourceObject, opts)
+	if e != nil {
+		result.Err = c.copyError(pair.SourceBucket, pair.SourceObject, nil, e).Trace(pair.SourceBucket, pair.SourceObject)
+		return result
+	}
+	result.Size = source.Size
+
+	if skipExisting {
+		target, e := c.api.StatObjectWithContext(ctx, pair.Bucket, pair.Object, minio.StatObjectOptions{})
+		if e == nil && target.Size == source.Size && strings.Trim(target.ETag, "\"") == strings.Trim(source.ETag, "\"") {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	var metadata map[string]string
+	if pair.Metadata != nil {
+		// The metadata of the source is replaced, keep the default
+		// storage class of the client as well.
+		metadata = canonicalizeMetadata(pair.Metadata)
+		if _, ok := metadata["X-Amz-Storage-Class"]; !ok && c.defaultStorageClass != "" {
+			metadata["X-Amz-Storage-Class"] = c.defaultStorageClass
+		}
+	}
+	dst, e := minio.NewDestinationInfo(pair.Bucket, pair.Object, c.writeSSE(pair.TargetSSE), metadata)
+	if e != nil {
+		result.Err = probe.NewError(e).Trace(pair.Bucket, pair.Object)
+		return result
+	}
+	src := minio.NewSourceInfo(pair.SourceBucket, pair.SourceObject, srcSSE)
+
+	// minio-go copy APIs don't accept a context. Sources in other
+	// buckets may be in other regions, minio-go looks them up.
+	regionBucket := pair.Bucket
+	if pair.SourceBucket != pair.Bucket {
+		regionBucket = ""
+	}
+	api, release := c.contextAPI(ctx, regionBucket)
+	defer release()
+	for attempt := 1; ; attempt++ {
+		if source.Size > maxSingleCopySize {
+			e = api.ComposeObject(dst, []minio.SourceInfo{src})
+		} else {
+			e = api.CopyObject(dst, src)
+		}
+		if e == nil || ctx.Err() != nil {
+			break
+		}
+		if minio.ToErrorResponse(e).Code != "SlowDown" || attempt == batchCopyRetries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * batchCopyRetryUnit):
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		result.Err = probe.NewError(ctx.Err()).Trace(pair.Bucket, pair.Object)
+	case e != nil:
+		result.Err = c.copyError(pair.Bucket, pair.Object, nil, e).Trace(pair.Bucket, pair.Object)
+	}
+	return result
+}
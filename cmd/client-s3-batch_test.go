@@ -0,0 +1,146 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+// batchCopyHandler serves server side copies between buckets, the
+// first copy to /dst/slow is throttled with SlowDown.
+type batchCopyHandler struct {
+	mutex      *sync.Mutex
+	objects    map[string]int64
+	directives map[string]string
+	throttled  *bool
+}
+
+func (h batchCopyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	switch r.Method {
+	case "HEAD":
+		size, ok := h.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "\"9af2f8218b150c351ad802c6f3d66abe\"")
+		w.WriteHeader(http.StatusOK)
+	case "PUT":
+		source := r.Header.Get("X-Amz-Copy-Source")
+		size, ok := h.objects["/"+source]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/dst/slow" && !*h.throttled {
+			*h.throttled = true
+			response := []byte("<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write(response)
+			return
+		}
+		h.objects[r.URL.Path] = size
+		h.directives[r.URL.Path] = r.Header.Get("X-Amz-Metadata-Directive")
+		response := []byte("<CopyObjectResult><LastModified>2015-05-21T18:24:21.097Z</LastModified><ETag>\"9af2f8218b150c351ad802c6f3d66abe\"</ETag></CopyObjectResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Test copying objects with a pool of workers.
+func (s *TestSuite) TestBatchCopy(c *C) {
+	var throttled bool
+	handler := batchCopyHandler{
+		mutex: &sync.Mutex{},
+		objects: map[string]int64{
+			"/src/a":    10,
+			"/src/b":    20,
+			"/src/slow": 30,
+			"/src/meta": 40,
+			"/dst/b":    20,
+		},
+		directives: make(map[string]string),
+		throttled:  &throttled,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := testConfig(server.URL, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	pairs := make(chan CopyPair)
+	go func() {
+		for _, name := range []string{"a", "b", "slow", "missing"} {
+			pairs <- CopyPair{SourceBucket: "src", SourceObject: name, Bucket: "dst", Object: name}
+		}
+		pairs <- CopyPair{SourceBucket: "src", SourceObject: "meta", Bucket: "dst", Object: "meta", Metadata: map[string]string{"x-amz-meta-owner": "ops"}}
+		close(pairs)
+	}()
+	results := make(chan CopyResult)
+	var outcomes []string
+	done := make(chan struct{})
+	go func() {
+		for result := range results {
+			outcome := result.Pair.Object + " copied"
+			switch {
+			case result.Err != nil:
+				outcome = result.Pair.Object + " failed"
+			case result.Skipped:
+				outcome = result.Pair.Object + " skipped"
+			}
+			outcomes = append(outcomes, outcome)
+		}
+		close(done)
+	}()
+
+	stats, err := s3c.BatchCopy(context.Background(), pairs, 3, true, results)
+	<-done
+	c.Assert(err, IsNil)
+	c.Assert(stats, DeepEquals, BatchCopyStats{Copied: 3, Skipped: 1, Failed: 1, Bytes: 80})
+	sort.Strings(outcomes)
+	c.Assert(outcomes, DeepEquals, []string{"a copied", "b skipped", "meta copied", "missing failed", "slow copied"})
+	c.Assert(throttled, Equals, true)
+	c.Assert(handler.directives["/dst/a"], Equals, "")
+	c.Assert(handler.directives["/dst/meta"], Equals, "REPLACE")
+
+	// Nothing is copied once canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pairs = make(chan CopyPair, 1)
+	pairs <- CopyPair{SourceBucket: "src", SourceObject: "a", Bucket: "dst", Object: "canceled"}
+	stats, err = s3c.BatchCopy(ctx, pairs, 2, false, nil)
+	c.Assert(err, NotNil)
+	c.Assert(stats, DeepEquals, BatchCopyStats{})
+	_, ok := handler.objects["/dst/canceled"]
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,341 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	"github.com/mitchellh/go-homedir"
+)
+
+// Credentials returned by a credential process or the ECS endpoint are
+// refreshed this long before they expire.
+const credentialsExpiryWindow = 5 * time.Minute
+
+// expiringProvider - credentials provider calling retrieve to get
+// credentials along with their expiration, if any, again whenever they
+// are about to expire.
+type expiringProvider struct {
+	retrieve   func() (credentials.Value, *time.Time, error)
+	signerType credentials.SignatureType
+
+	mutex      sync.Mutex
+	expiration time.Time
+}
+
+// newExpiringCredentials - credentials obtained with retrieve.
+func newExpiringCredentials(retrieve func() (credentials.Value, *time.Time, error), signerType credentials.SignatureType) *credentials.Credentials {
+	return credentials.New(&expiringProvider{retrieve: retrieve, signerType: signerType})
+}
+
+// Retrieve - get the credentials and record their expiration.
+func (p *expiringProvider) Retrieve() (credentials.Value, error) {
+	value, expiration, e := p.retrieve()
+	if e != nil {
+		return credentials.Value{}, e
+	}
+
+	p.mutex.Lock()
+	p.expiration = time.Time{}
+	if expiration != nil {
+		p.expiration = expiration.Add(-credentialsExpiryWindow)
+	}
+	p.mutex.Unlock()
+
+	value.SignerType = p.signerType
+	return value, nil
+}
+
+// IsExpired - credentials without expiration never expire.
+func (p *expiringProvider) IsExpired() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return !p.expiration.IsZero() && !time.Now().Before(p.expiration)
+}
+
+// processCredentials - output of a credential process, as documented
+// for the credential_process setting of AWS SDKs.
+type processCredentials struct {
+	Version         int
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      *time.Time
+}
+
+// newProcessCredentials - credentials obtained by running command with
+// the shell, again whenever they are about to expire.
+func newProcessCredentials(command string, signerType credentials.SignatureType) *credentials.Credentials {
+	return newExpiringCredentials(func() (credentials.Value, *time.Time, error) {
+		return runCredentialProcess(command)
+	}, signerType)
+}
+
+// runCredentialProcess - run command and parse the credentials it prints.
+func runCredentialProcess(command string) (credentials.Value, *time.Time, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if e := cmd.Run(); e != nil {
+		return credentials.Value{}, nil, fmt.Errorf("credential process failed: %v: %s", e, strings.TrimSpace(stderr.String()))
+	}
+
+	var creds processCredentials
+	if e := json.Unmarshal(stdout.Bytes(), &creds); e != nil {
+		return credentials.Value{}, nil, fmt.Errorf("unable to parse credential process output: %v", e)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return credentials.Value{}, nil, errors.New("credential process returned no AccessKeyId or SecretAccessKey")
+	}
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, creds.Expiration, nil
+}
+
+// ecsCredentialsEndpoint - endpoint of the ECS task role credentials,
+// relative URIs of the container environment are resolved against it.
+var ecsCredentialsEndpoint = "http://169.254.170.2"
+
+// ecsCredentials - credentials of the ECS task role.
+type ecsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+	Expiration      *time.Time
+}
+
+// ecsCredentialsURI - URI of the task role credentials from the
+// container environment, empty when not running in ECS.
+func ecsCredentialsURI() string {
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return ecsCredentialsEndpoint + uri
+	}
+	return os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+}
+
+// newECSCredentials - credentials of the ECS task role, fetched again
+// whenever they are about to expire.
+func newECSCredentials(signerType credentials.SignatureType) *credentials.Credentials {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return newExpiringCredentials(func() (credentials.Value, *time.Time, error) {
+		return fetchECSCredentials(client)
+	}, signerType)
+}
+
+// fetchECSCredentials - fetch the credentials from the ECS endpoint.
+func fetchECSCredentials(client *http.Client) (credentials.Value, *time.Time, error) {
+	uri := ecsCredentialsURI()
+	if uri == "" {
+		return credentials.Value{}, nil, errors.New("neither AWS_CONTAINER_CREDENTIALS_RELATIVE_URI nor AWS_CONTAINER_CREDENTIALS_FULL_URI is set")
+	}
+	req, e := http.NewRequest(http.MethodGet, uri, nil)
+	if e != nil {
+		return credentials.Value{}, nil, e
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, e := client.Do(req)
+	if e != nil {
+		return credentials.Value{}, nil, e
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return credentials.Value{}, nil, fmt.Errorf("unable to get ECS credentials from %s: %s", uri, resp.Status)
+	}
+
+	var creds ecsCredentials
+	if e = json.NewDecoder(resp.Body).Decode(&creds); e != nil {
+		return credentials.Value{}, nil, fmt.Errorf("unable to parse ECS credentials: %v", e)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return credentials.Value{}, nil, errors.New("ECS endpoint returned no AccessKeyId or SecretAccessKey")
+	}
+
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.Token,
+	}, creds.Expiration, nil
+}
+
+// parseAWSProfiles - settings of every section of an AWS credentials or
+// config file, nested settings such as those under `s3 =` keyed as
+// "s3.endpoint_url".
+func parseAWSProfiles(r io.Reader) (map[string]map[string]string, error) {
+	profiles := make(map[string]map[string]string)
+	var settings map[string]string
+	var parent string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			name := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if profiles[name] == nil {
+				profiles[name] = make(map[string]string)
+			}
+			settings, parent = profiles[name], ""
+			continue
+		}
+		i := strings.Index(trimmed, "=")
+		if settings == nil || i < 0 {
+			return nil, fmt.Errorf("unable to parse `%s`", trimmed)
+		}
+		key, value := strings.TrimSpace(trimmed[:i]), strings.TrimSpace(trimmed[i+1:])
+		if parent != "" && (line[0] == ' ' || line[0] == '\t') {
+			settings[parent+"."+key] = value
+			continue
+		}
+		parent = ""
+		if value == "" {
+			parent = key
+		}
+		settings[key] = value
+	}
+	return profiles, scanner.Err()
+}
+
+// readAWSProfile - settings of the section of an AWS credentials or
+// config file, nil if the file or the section doesn't exist.
+func readAWSProfile(filename, section string) (map[string]string, *probe.Error) {
+	f, e := os.Open(filename)
+	if os.IsNotExist(e) {
+		return nil, nil
+	}
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	defer f.Close()
+	profiles, e := parseAWSProfiles(f)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(filename)
+	}
+	return profiles[section], nil
+}
+
+// awsProfileConfig - configuration of the client of an AWS CLI profile,
+// see NewFromAWSProfile.
+func awsProfileConfig(profileName, configFile string) (*Config, *probe.Error) {
+	if profileName == "" {
+		profileName = os.Getenv("AWS_PROFILE")
+	}
+	if profileName == "" {
+		profileName = "default"
+	}
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if configFile == "" {
+		configFile = os.Getenv("AWS_CONFIG_FILE")
+	}
+	if credentialsFile == "" || configFile == "" {
+		homeDir, e := homedir.Dir()
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		if credentialsFile == "" {
+			credentialsFile = filepath.Join(homeDir, ".aws", "credentials")
+		}
+		if configFile == "" {
+			configFile = filepath.Join(homeDir, ".aws", "config")
+		}
+	}
+
+	// Profiles of the config file are named `profile name`, but the
+	// default one.
+	section := "profile " + profileName
+	if profileName == "default" {
+		section = profileName
+	}
+	settings, err := readAWSProfile(configFile, section)
+	if err != nil {
+		return nil, err.Trace(configFile)
+	}
+	creds, err := readAWSProfile(credentialsFile, profileName)
+	if err != nil {
+		return nil, err.Trace(credentialsFile)
+	}
+	if settings == nil && creds == nil {
+		return nil, probe.NewError(AWSProfileNotFound{Profile: profileName})
+	}
+	if settings == nil {
+		settings = make(map[string]string)
+	}
+	// Credentials of the credentials file come first.
+	if creds["aws_access_key_id"] != "" {
+		settings["aws_access_key_id"] = creds["aws_access_key_id"]
+		settings["aws_secret_access_key"] = creds["aws_secret_access_key"]
+	}
+
+	config := &Config{
+		AccessKey: settings["aws_access_key_id"],
+		SecretKey: settings["aws_secret_access_key"],
+		Signature: "S3v4",
+		Region:    settings["region"],
+		HostURL:   settings["s3.endpoint_url"],
+	}
+	if config.HostURL == "" {
+		config.HostURL = settings["endpoint_url"]
+	}
+	if config.HostURL == "" {
+		config.HostURL = "https://s3.amazonaws.com"
+		if config.Region != "" {
+			config.HostURL = "https://s3." + config.Region + ".amazonaws.com"
+		}
+	}
+	return config, nil
+}
+
+// NewFromAWSProfile - client of the profile profileName of the AWS CLI,
+// AWS_PROFILE or "default" if empty. Its aws_access_key_id and
+// aws_secret_access_key come from the credentials file,
+// AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials, or else from the
+// config file, configFile or if empty AWS_CONFIG_FILE or ~/.aws/config,
+// along with its region and endpoint_url. Profiles without endpoint_url
+// use the Amazon S3 endpoint of their region.
+func NewFromAWSProfile(profileName, configFile string) (Client, *probe.Error) {
+	config, err := awsProfileConfig(profileName, configFile)
+	if err != nil {
+		return nil, err.Trace(profileName)
+	}
+	return S3New(config)
+}
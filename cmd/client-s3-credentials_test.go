@@ -0,0 +1,341 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	. "gopkg.in/check.v1"
+)
+
+// Test getting credentials from an external process.
+func (s *TestSuite) TestProcessCredentials(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("credential process mock needs a POSIX shell")
+	}
+	dir, e := ioutil.TempDir("", "mc-credentials-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(dir)
+	credsFile := filepath.Join(dir, "creds.json")
+	runsFile := filepath.Join(dir, "runs")
+	command := "echo run >> '" + runsFile + "'; cat '" + credsFile + "'"
+	runs := func() int {
+		data, _ := ioutil.ReadFile(runsFile)
+		return strings.Count(string(data), "run")
+	}
+
+	testCases := []struct {
+		expiration string
+		runs       int
+	}{
+		// Never expires.
+		{"", 1},
+		// Expires within the refresh window.
+		{`,"Expiration":"` + time.Now().Add(2*time.Minute).UTC().Format(time.RFC3339) + `"`, 3},
+		// Expires later.
+		{`,"Expiration":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"`, 1},
+	}
+	for i, testCase := range testCases {
+		os.Remove(runsFile)
+		output := `{"Version":1,"AccessKeyId":"PROCESSACCESSKEY","SecretAccessKey":"process/secret","SessionToken":"token"` + testCase.expiration + `}`
+		c.Assert(ioutil.WriteFile(credsFile, []byte(output), 0600), IsNil)
+
+		creds := newProcessCredentials(command, credentials.SignatureV4)
+		for j := 0; j < 3; j++ {
+			value, e := creds.Get()
+			c.Assert(e, IsNil, Commentf("test %d", i+1))
+			c.Assert(value.AccessKeyID, Equals, "PROCESSACCESSKEY")
+			c.Assert(value.SecretAccessKey, Equals, "process/secret")
+			c.Assert(value.SessionToken, Equals, "token")
+			c.Assert(value.SignerType, Equals, credentials.SignatureV4)
+		}
+		c.Assert(runs(), Equals, testCase.runs, Commentf("test %d", i+1))
+	}
+
+	// Failing processes and invalid outputs.
+	for _, output := range []string{"not json", `{"AccessKeyId":"PROCESSACCESSKEY"}`} {
+		c.Assert(ioutil.WriteFile(credsFile, []byte(output), 0600), IsNil)
+		_, e = newProcessCredentials(command, credentials.SignatureV4).Get()
+		c.Assert(e, NotNil)
+	}
+	_, e = newProcessCredentials("exit 3", credentials.SignatureV4).Get()
+	c.Assert(e, NotNil)
+
+	// Requests are signed with the process credentials.
+	output := `{"Version":1,"AccessKeyId":"PROCESSACCESSKEY","SecretAccessKey":"process/secret"}`
+	c.Assert(ioutil.WriteFile(credsFile, []byte(output), 0600), IsNil)
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := new(Config)
+	conf.HostURL = server.URL + object.resource
+	conf.Signature = "S3v4"
+	conf.CredentialProcess = "exit 1"
+	_, err := S3New(conf)
+	c.Assert(err, NotNil)
+
+	conf.CredentialProcess = command
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(len(headers) > 0, Equals, true)
+	c.Assert(strings.Contains(headers[len(headers)-1].Get("Authorization"), "Credential=PROCESSACCESSKEY/"), Equals, true)
+}
+
+// ec2MetadataHandler serves the credentials of an instance role.
+type ec2MetadataHandler struct{}
+
+func (h ec2MetadataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+		w.Write([]byte("session-token"))
+	case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+		w.Write([]byte("instance-role"))
+	case r.URL.Path == "/latest/meta-data/iam/security-credentials/instance-role":
+		w.Write([]byte(`{"Code":"Success","LastUpdated":"` + UTCNow().Format(time.RFC3339) + `","Type":"AWS-HMAC",` +
+			`"AccessKeyId":"EC2ACCESSKEY","SecretAccessKey":"ec2/secret","Token":"ec2-token",` +
+			`"Expiration":"` + UTCNow().Add(6*time.Hour).Format(time.RFC3339) + `"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Test getting credentials from the EC2 instance metadata service.
+func (s *TestSuite) TestEC2MetadataCredentials(c *C) {
+	metadata := httptest.NewServer(ec2MetadataHandler{})
+	defer metadata.Close()
+	defer func(endpoint string) { ec2MetadataEndpoint = endpoint }(ec2MetadataEndpoint)
+	ec2MetadataEndpoint = metadata.URL
+
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	testCases := []struct {
+		accessKey, secretKey string
+		credential           string
+		token                string
+	}{
+		{"", "", "Credential=EC2ACCESSKEY/", "ec2-token"},
+		// Static keys are preferred.
+		{"WLGDGYAQYIGI833EV05A", "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF", "Credential=WLGDGYAQYIGI833EV05A/", ""},
+	}
+	for i, testCase := range testCases {
+		conf := new(Config)
+		conf.HostURL = server.URL + object.resource
+		conf.AccessKey = testCase.accessKey
+		conf.SecretKey = testCase.secretKey
+		conf.Signature = "S3v4"
+		conf.UseEC2Metadata = true
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+
+		headers = nil
+		_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "")
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(len(headers) > 0, Equals, true)
+		header := headers[len(headers)-1]
+		c.Assert(strings.Contains(header.Get("Authorization"), testCase.credential), Equals, true, Commentf("test %d", i+1))
+		c.Assert(header.Get("X-Amz-Security-Token"), Equals, testCase.token, Commentf("test %d", i+1))
+	}
+}
+
+// ecsHandler serves the credentials of an ECS task role, counting
+// the requests.
+type ecsHandler struct {
+	expiration time.Duration
+	requests   *int
+}
+
+func (h ecsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if auth := r.Header.Get("Authorization"); r.URL.Path != "/v2/credentials/task" || (auth != "" && auth != "Bearer task-token") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	*h.requests++
+	w.Write([]byte(`{"RoleArn":"arn:aws:iam::123456789012:role/task","AccessKeyId":"ECSACCESSKEY","SecretAccessKey":"ecs/secret",` +
+		`"Token":"ecs-token","Expiration":"` + UTCNow().Add(h.expiration).Format(time.RFC3339) + `"}`))
+}
+
+// Test getting credentials of the ECS task role.
+func (s *TestSuite) TestECSCredentials(c *C) {
+	var requests int
+	endpoint := httptest.NewServer(ecsHandler{expiration: 6 * time.Hour, requests: &requests})
+	defer endpoint.Close()
+	shortEndpoint := httptest.NewServer(ecsHandler{expiration: 2 * time.Minute, requests: &requests})
+	defer shortEndpoint.Close()
+	defer func(endpoint string) { ecsCredentialsEndpoint = endpoint }(ecsCredentialsEndpoint)
+	for _, env := range []string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+
+	testCases := []struct {
+		endpoint    string
+		relativeURI string
+		fullURI     string
+		token       string
+		requests    int
+	}{
+		{endpoint.URL, "/v2/credentials/task", "", "", 1},
+		{"", "", endpoint.URL + "/v2/credentials/task", "Bearer task-token", 1},
+		// Refreshed within the expiry window.
+		{shortEndpoint.URL, "/v2/credentials/task", "", "", 3},
+	}
+	for i, testCase := range testCases {
+		ecsCredentialsEndpoint = testCase.endpoint
+		os.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", testCase.relativeURI)
+		os.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", testCase.fullURI)
+		os.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", testCase.token)
+		requests = 0
+
+		creds := newECSCredentials(credentials.SignatureV4)
+		for j := 0; j < 3; j++ {
+			value, e := creds.Get()
+			c.Assert(e, IsNil, Commentf("test %d", i+1))
+			c.Assert(value.AccessKeyID, Equals, "ECSACCESSKEY")
+			c.Assert(value.SecretAccessKey, Equals, "ecs/secret")
+			c.Assert(value.SessionToken, Equals, "ecs-token")
+		}
+		c.Assert(requests, Equals, testCase.requests, Commentf("test %d", i+1))
+	}
+
+	// Wrong token.
+	os.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "Bearer wrong")
+	_, e := newECSCredentials(credentials.SignatureV4).Get()
+	c.Assert(e, NotNil)
+
+	// Requests are signed with the task role credentials.
+	os.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "Bearer task-token")
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := new(Config)
+	conf.HostURL = server.URL + object.resource
+	conf.Signature = "S3v4"
+	conf.UseECSCredentials = true
+	// Not running in ECS.
+	os.Unsetenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
+	_, err := S3New(conf)
+	c.Assert(err, NotNil)
+
+	os.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", endpoint.URL+"/v2/credentials/task")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(len(headers) > 0, Equals, true)
+	header := headers[len(headers)-1]
+	c.Assert(strings.Contains(header.Get("Authorization"), "Credential=ECSACCESSKEY/"), Equals, true)
+	c.Assert(header.Get("X-Amz-Security-Token"), Equals, "ecs-token")
+}
+
+// Test making the configuration of a client from AWS CLI profiles.
+func (s *TestSuite) TestAWSProfile(c *C) {
+	dir, e := ioutil.TempDir("", "mc-aws-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(dir)
+	credentialsFile := filepath.Join(dir, "credentials")
+	configFile := filepath.Join(dir, "config")
+	c.Assert(ioutil.WriteFile(credentialsFile, []byte(`[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = default/secret
+
+# Local MinIO.
+[minio]
+aws_access_key_id=minioadmin
+aws_secret_access_key=minio/secret
+`), 0600), IsNil)
+	c.Assert(ioutil.WriteFile(configFile, []byte(`[default]
+region = eu-west-3
+
+[profile minio]
+region = us-east-1
+endpoint_url = http://localhost:9000
+aws_access_key_id = ignored
+aws_secret_access_key = ignored
+
+[profile nested]
+aws_access_key_id = AKIANESTED
+aws_secret_access_key = nested/secret
+endpoint_url = https://other.example.com
+s3 =
+    addressing_style = path
+    endpoint_url = https://s3.example.com
+`), 0600), IsNil)
+	for _, env := range []string{"AWS_PROFILE", "AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+
+	testCases := []struct {
+		profile   string
+		accessKey string
+		secretKey string
+		region    string
+		hostURL   string
+	}{
+		{"", "AKIADEFAULT", "default/secret", "eu-west-3", "https://s3.eu-west-3.amazonaws.com"},
+		{"minio", "minioadmin", "minio/secret", "us-east-1", "http://localhost:9000"},
+		{"nested", "AKIANESTED", "nested/secret", "", "https://s3.example.com"},
+	}
+	for i, testCase := range testCases {
+		config, err := awsProfileConfig(testCase.profile, configFile)
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(config.AccessKey, Equals, testCase.accessKey, Commentf("test %d", i+1))
+		c.Assert(config.SecretKey, Equals, testCase.secretKey, Commentf("test %d", i+1))
+		c.Assert(config.Region, Equals, testCase.region, Commentf("test %d", i+1))
+		c.Assert(config.HostURL, Equals, testCase.hostURL, Commentf("test %d", i+1))
+	}
+
+	// AWS_PROFILE picks the profile.
+	os.Setenv("AWS_PROFILE", "minio")
+	client, err := NewFromAWSProfile("", configFile)
+	c.Assert(err, IsNil)
+	c.Assert(client.GetURL().String(), Equals, "http://localhost:9000")
+
+	_, err = NewFromAWSProfile("missing", configFile)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(AWSProfileNotFound)
+	c.Assert(ok, Equals, true)
+}
@@ -0,0 +1,306 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+)
+
+// defaultDiffChunkSize - size of the ranges Diff reads to compare the
+// content of objects.
+const defaultDiffChunkSize = 8 * 1024 * 1024
+
+// DiffKind - how the objects of a DiffRecord differ.
+type DiffKind string
+
+// Kinds of DiffRecord, objects of both listings are compared by size,
+// then ETag or content, then metadata.
+const (
+	DiffOnlyInFirst  DiffKind = "only-in-first"
+	DiffOnlyInSecond DiffKind = "only-in-second"
+	DiffSize         DiffKind = "size"
+	DiffETag         DiffKind = "etag"
+	DiffContent      DiffKind = "content"
+	DiffMetadata     DiffKind = "metadata"
+)
+
+// DiffOptions - options of Diff.
+type DiffOptions struct {
+	// Metadata compares the user metadata and content type of objects.
+	Metadata bool
+	// CompareContent compares objects of the same size whose ETags
+	// differ but aren't both MD5s, such as objects uploaded in parts,
+	// reading them in ranges of ChunkSize bytes, defaultDiffChunkSize
+	// if unset. Such objects are deemed equal otherwise.
+	CompareContent bool
+	ChunkSize      int64
+}
+
+// DiffObject - object of one of the listings of a DiffRecord.
+type DiffObject struct {
+	URL          string            `json:"url"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag,omitempty"`
+	Time         time.Time         `json:"lastModified"`
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+}
+
+// DiffRecord - object differing between the listings of Diff, or the
+// error which stopped it or the comparison of Key.
+type DiffRecord struct {
+	// Key is relative to the prefixes of the clients.
+	Key    string       `json:"key,omitempty"`
+	Kind   DiffKind     `json:"kind,omitempty"`
+	First  *DiffObject  `json:"first,omitempty"`
+	Second *DiffObject  `json:"second,omitempty"`
+	Error  *probe.Error `json:"error,omitempty"`
+}
+
+func newDiffObject(content *ClientContent) *DiffObject {
+	return &DiffObject{
+		URL:          content.URL.String(),
+		Size:         content.Size,
+		ETag:         content.ETag,
+		Time:         content.Time,
+		UserMetadata: content.UserMetadata,
+	}
+}
+
+// diffListing - recursive listing of the objects of one side of Diff.
+type diffListing struct {
+	client   *S3Client
+	bucket   string
+	prefix   string
+	metadata bool
+	objectCh <-chan minio.ObjectInfo
+	// Next object, nil once all are listed.
+	next *ClientContent
+}
+
+// newDiffListing - listing of the objects under the prefix of the
+// client whose keys, relative to it, start with shard and sort after
+// startAfter.
+func (c *S3Client) newDiffListing(ctx context.Context, shard, startAfter string, metadata bool) (*diffListing, *probe.Error) {
+	bucket, prefix := c.url2BucketAndObject()
+	if bucket == "" {
+		return nil, probe.NewError(BucketNameEmpty{})
+	}
+	if startAfter != "" {
+		startAfter = prefix + startAfter
+	}
+	isRecursive := true
+	l := &diffListing{
+		client:   c,
+		bucket:   bucket,
+		prefix:   prefix,
+		metadata: metadata,
+		objectCh: c.listObjectsAfter(bucket, prefix+shard, isRecursive, ctx.Done(), metadata, startAfter),
+	}
+	return l, l.advance()
+}
+
+// advance - list the next object.
+func (l *diffListing) advance() *probe.Error {
+	info, ok := <-l.objectCh
+	if !ok {
+		l.next = nil
+		return nil
+	}
+	if info.Err != nil {
+		l.next = nil
+		return probe.NewError(regionError(l.bucket, info.Err)).Trace(l.bucket, l.prefix)
+	}
+	l.next = l.client.objectInfo2ClientContent(l.bucket, info, l.metadata)
+	return nil
+}
+
+func (l *diffListing) key() string {
+	return strings.TrimPrefix(l.next.Key, l.prefix)
+}
+
+// Diff - compare the objects under the prefix of the client with those
+// under the prefix of other, walking both listings in key order, and
+// send a record for every object which is missing from either or
+// differs. A record with only an Error is sent last if a listing
+// fails, or ctx is canceled.
+func (c *S3Client) Diff(ctx context.Context, other *S3Client, opts DiffOptions) <-chan DiffRecord {
+	diffCh := make(chan DiffRecord)
+	go func() {
+		defer close(diffCh)
+		err := c.diffWalk(ctx, other, opts, "", "", func(record DiffRecord) bool {
+			if record.Kind != "" || record.Error != nil {
+				diffCh <- record
+			}
+			return true
+		})
+		if err != nil {
+			diffCh <- DiffRecord{Error: err}
+		}
+	}()
+	return diffCh
+}
+
+// diffWalk - compare the objects of the client with those of other as
+// Diff does, only for keys starting with shard and sorting after
+// startAfter, calling send with the record of every key in key order,
+// whose Kind is empty if the objects don't differ. The walk stops
+// early, without error, once send returns false.
+func (c *S3Client) diffWalk(ctx context.Context, other *S3Client, opts DiffOptions, shard, startAfter string, send func(DiffRecord) bool) *probe.Error {
+	// Stop both listings however the walk ends.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultDiffChunkSize
+	}
+	first, err := c.newDiffListing(ctx, shard, startAfter, opts.Metadata)
+	if err != nil {
+		return err.Trace(c.targetURL.String())
+	}
+	second, err := other.newDiffListing(ctx, shard, startAfter, opts.Metadata)
+	if err != nil {
+		return err.Trace(other.targetURL.String())
+	}
+
+	for err == nil && (first.next != nil || second.next != nil) {
+		var record DiffRecord
+		switch {
+		case second.next == nil || first.next != nil && first.key() < second.key():
+			record = DiffRecord{Key: first.key(), Kind: DiffOnlyInFirst, First: newDiffObject(first.next)}
+			err = first.advance()
+		case first.next == nil || second.key() < first.key():
+			record = DiffRecord{Key: second.key(), Kind: DiffOnlyInSecond, Second: newDiffObject(second.next)}
+			err = second.advance()
+		default:
+			kind, cerr := c.diffObjects(ctx, other, first.next, second.next, opts)
+			if cerr != nil {
+				record = DiffRecord{Key: first.key(), Error: cerr}
+			} else {
+				record = DiffRecord{Key: first.key(), Kind: kind, First: newDiffObject(first.next), Second: newDiffObject(second.next)}
+			}
+			if err = first.advance(); err == nil {
+				err = second.advance()
+			}
+		}
+		if !send(record) {
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		// Listings stop without error once canceled.
+		return probe.NewError(ctx.Err())
+	}
+	return err
+}
+
+// diffMD5ETag - whether the ETag of an object is the MD5 of its
+// content, see S3Client.md5ETag.
+func diffMD5ETag(content *ClientContent) bool {
+	switch {
+	case len(content.ETag) != md5.Size*2 || strings.Contains(content.ETag, "-"):
+		return false
+	case content.Encryption == "aws:kms" || content.Encryption == "SSE-C":
+		return false
+	}
+	return true
+}
+
+// diffObjects - how two objects of the same key differ, empty if they
+// don't.
+func (c *S3Client) diffObjects(ctx context.Context, other *S3Client, first, second *ClientContent, opts DiffOptions) (DiffKind, *probe.Error) {
+	if first.Size != second.Size {
+		return DiffSize, nil
+	}
+	if first.ETag != second.ETag {
+		if diffMD5ETag(first) && diffMD5ETag(second) {
+			return DiffETag, nil
+		}
+		if opts.CompareContent {
+			same, err := c.sameContent(ctx, other, first, second, opts.ChunkSize)
+			if err != nil {
+				return "", err.Trace(first.URL.String(), second.URL.String())
+			}
+			if !same {
+				return DiffContent, nil
+			}
+		}
+	}
+	if opts.Metadata {
+		if err := c.LoadMetadata(ctx, first); err != nil {
+			return "", err.Trace(first.URL.String())
+		}
+		if err := other.LoadMetadata(ctx, second); err != nil {
+			return "", err.Trace(second.URL.String())
+		}
+		if !metadataEqual(first.UserMetadata, second.UserMetadata) ||
+			first.Metadata["Content-Type"] != second.Metadata["Content-Type"] {
+			return DiffMetadata, nil
+		}
+	}
+	return "", nil
+}
+
+// sameContent - compare the content of two objects of the same size,
+// range by range, stopping at the first which differs.
+func (c *S3Client) sameContent(ctx context.Context, other *S3Client, first, second *ClientContent, chunkSize int64) (bool, *probe.Error) {
+	for start := int64(0); start < first.Size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= first.Size {
+			end = first.Size - 1
+		}
+		firstData, err := c.readRange(ctx, first, start, end)
+		if err != nil {
+			return false, err.Trace()
+		}
+		secondData, err := other.readRange(ctx, second, start, end)
+		if err != nil {
+			return false, err.Trace()
+		}
+		if !bytes.Equal(firstData, secondData) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readRange - read the bytes from start to end, included, of a listed
+// object.
+func (c *S3Client) readRange(ctx context.Context, content *ClientContent, start, end int64) ([]byte, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	opts := minio.GetObjectOptions{}
+	opts.ServerSideEncryption = c.readSSE(nil)
+	if e := opts.SetRange(start, end); e != nil {
+		return nil, probe.NewError(e)
+	}
+	reader, e := c.api.GetObjectWithContext(ctx, bucket, content.Key, opts)
+	if e != nil {
+		return nil, probe.NewError(regionError(bucket, e)).Trace(bucket, content.Key)
+	}
+	defer reader.Close()
+	data := make([]byte, end-start+1)
+	if _, e = io.ReadFull(reader, data); e != nil {
+		return nil, probe.NewError(regionError(bucket, e)).Trace(bucket, content.Key)
+	}
+	return data, nil
+}
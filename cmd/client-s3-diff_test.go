@@ -0,0 +1,200 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// diffObject - object served by diffHandler.
+type diffObject struct {
+	data []byte
+	// Uploaded in parts, its ETag isn't the MD5 of its content.
+	multipart bool
+	owner     string
+}
+
+func (o diffObject) etag() string {
+	sum := md5.Sum(o.data)
+	if o.multipart {
+		return hex.EncodeToString(sum[:]) + "-2"
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+// diffHandler serves objects of a bucket, their listings with metadata,
+// which start after start-after, and ranged reads.
+type diffHandler struct {
+	objects map[string]diffObject
+	// Ranges read, e.g. "a/multi bytes=0-3".
+	ranges *[]string
+}
+
+func (h diffHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	modified := time.Date(2020, 5, 21, 18, 24, 21, 0, time.UTC)
+	if r.URL.Path == "/bucket/" && r.Method == "GET" {
+		var keys []string
+		for key := range h.objects {
+			if strings.HasPrefix(key, query.Get("prefix")) && key > query.Get("start-after") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		response := "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+		for _, key := range keys {
+			object := h.objects[key]
+			var userMetadata string
+			if query.Get("metadata") == "true" && object.owner != "" {
+				userMetadata = "<UserMetadata><X-Amz-Meta-Owner>" + object.owner + "</X-Amz-Meta-Owner></UserMetadata>"
+			}
+			response += "<Contents><ETag>\"" + object.etag() + "\"</ETag><Key>" + key + "</Key><LastModified>" +
+				modified.Format(time.RFC3339) + "</LastModified><Size>" + strconv.Itoa(len(object.data)) +
+				"</Size><StorageClass>STANDARD</StorageClass>" + userMetadata + "</Contents>"
+		}
+		response += "<IsTruncated>false</IsTruncated><KeyCount>" + strconv.Itoa(len(keys)) + "</KeyCount><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix>" +
+			query.Get("prefix") + "</Prefix></ListBucketResult>"
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write([]byte(response))
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	object, ok := h.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == "GET" && h.ranges != nil {
+		*h.ranges = append(*h.ranges, key+" "+r.Header.Get("Range"))
+	}
+	w.Header().Set("ETag", "\""+object.etag()+"\"")
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if object.owner != "" {
+		w.Header().Set("X-Amz-Meta-Owner", object.owner)
+	}
+	http.ServeContent(w, r, key, modified, bytes.NewReader(object.data))
+}
+
+// Test comparing objects by size, ETag, content and metadata.
+func (s *TestSuite) TestDiff(c *C) {
+	var ranges []string
+	server := httptest.NewServer(diffHandler{objects: map[string]diffObject{
+		"a/bigger.txt":    {data: []byte("hello")},
+		"b/bigger.txt":    {data: []byte("hello, world")},
+		"a/changed.txt":   {data: []byte("hello")},
+		"b/changed.txt":   {data: []byte("jello")},
+		"a/meta":          {data: []byte("hello"), owner: "me"},
+		"b/meta":          {data: []byte("hello"), owner: "you"},
+		"a/multi":         {data: []byte("0123456789"), multipart: true},
+		"b/multi":         {data: []byte("0123456789")},
+		"a/multi-changed": {data: []byte("0123456789"), multipart: true},
+		"b/multi-changed": {data: []byte("012345678x"), multipart: true, owner: "me"},
+		"a/only-a":        {data: []byte("a")},
+		"b/only-b":        {data: []byte("b")},
+		"a/same.txt":      {data: []byte("same")},
+		"b/same.txt":      {data: []byte("same")},
+	}, ranges: &ranges})
+	defer server.Close()
+
+	first := newTestS3Client(c, server.URL+"/bucket/a/", "S3v4")
+	second := newTestS3Client(c, server.URL+"/bucket/b/", "S3v4")
+
+	testCases := []struct {
+		opts    DiffOptions
+		records []string
+	}{
+		{
+			DiffOptions{},
+			[]string{"bigger.txt size", "changed.txt etag", "only-a only-in-first", "only-b only-in-second"},
+		},
+		{
+			DiffOptions{Metadata: true},
+			[]string{"bigger.txt size", "changed.txt etag", "meta metadata", "multi-changed metadata", "only-a only-in-first", "only-b only-in-second"},
+		},
+		// Content differs before metadata.
+		{
+			DiffOptions{Metadata: true, CompareContent: true, ChunkSize: 4},
+			[]string{"bigger.txt size", "changed.txt etag", "meta metadata", "multi-changed content", "only-a only-in-first", "only-b only-in-second"},
+		},
+	}
+	for i, testCase := range testCases {
+		ranges = nil
+		var records []string
+		for record := range first.Diff(context.Background(), second, testCase.opts) {
+			c.Assert(record.Error, IsNil, Commentf("test %d", i+1))
+			records = append(records, record.Key+" "+string(record.Kind))
+			switch record.Kind {
+			case DiffOnlyInFirst:
+				c.Assert(record.First, NotNil)
+				c.Assert(record.Second, IsNil)
+			case DiffOnlyInSecond:
+				c.Assert(record.First, IsNil)
+				c.Assert(record.Second, NotNil)
+			default:
+				c.Assert(record.First.URL, Equals, server.URL+"/bucket/a/"+record.Key)
+				c.Assert(record.Second.URL, Equals, server.URL+"/bucket/b/"+record.Key)
+			}
+		}
+		c.Assert(records, DeepEquals, testCase.records, Commentf("test %d", i+1))
+	}
+
+	// Objects are read range by range until they differ.
+	c.Assert(ranges, DeepEquals, []string{
+		"a/multi bytes=0-3", "b/multi bytes=0-3",
+		"a/multi bytes=4-7", "b/multi bytes=4-7",
+		"a/multi bytes=8-9", "b/multi bytes=8-9",
+		"a/multi-changed bytes=0-3", "b/multi-changed bytes=0-3",
+		"a/multi-changed bytes=4-7", "b/multi-changed bytes=4-7",
+		"a/multi-changed bytes=8-9", "b/multi-changed bytes=8-9",
+	})
+
+	// Records are suitable for JSON.
+	for record := range first.Diff(context.Background(), second, DiffOptions{}) {
+		data, e := json.Marshal(record)
+		c.Assert(e, IsNil)
+		if record.Key == "only-a" {
+			c.Assert(strings.Contains(string(data), `"kind":"only-in-first"`), Equals, true)
+		}
+	}
+
+	// Listing errors are sent last.
+	missing := newTestS3Client(c, server.URL+"/missing/", "S3v4")
+	var records []DiffRecord
+	for record := range first.Diff(context.Background(), missing, DiffOptions{}) {
+		records = append(records, record)
+	}
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Error, NotNil)
+}
@@ -0,0 +1,219 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/wildcard"
+)
+
+// listPattern - include or exclude pattern of a listing, a glob or a
+// regular expression.
+type listPattern struct {
+	glob string
+	re   *regexp.Regexp
+	// Whether matching keys all start with prefix, true for globs and
+	// regular expressions anchored at the start.
+	anchored bool
+	prefix   string
+}
+
+// newListPatterns - parse globs and regular expressions.
+func newListPatterns(globs, exprs []string) ([]listPattern, *probe.Error) {
+	var patterns []listPattern
+	for _, glob := range globs {
+		prefix := glob
+		if i := strings.IndexAny(glob, "*?"); i >= 0 {
+			prefix = glob[:i]
+		}
+		patterns = append(patterns, listPattern{glob: glob, anchored: true, prefix: prefix})
+	}
+	for _, expr := range exprs {
+		re, e := regexp.Compile(expr)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(expr)
+		}
+		prefix, anchored := regexpPrefix(expr)
+		patterns = append(patterns, listPattern{re: re, anchored: anchored, prefix: prefix})
+	}
+	return patterns, nil
+}
+
+// regexpPrefix - literal prefix of the keys a regular expression
+// anchored at the start matches. Unlike regexp.LiteralPrefix, it tells
+// whether the expression is anchored.
+func regexpPrefix(expr string) (prefix string, anchored bool) {
+	re, e := syntax.Parse(expr, syntax.Perl)
+	if e != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) == 0 || re.Sub[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+	var runes []rune
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		runes = append(runes, sub.Rune...)
+	}
+	return string(runes), true
+}
+
+func (p listPattern) match(key string) bool {
+	if p.re != nil {
+		return p.re.MatchString(key)
+	}
+	return wildcard.Match(p.glob, key)
+}
+
+// mayMatchUnder - whether keys under the directory dir may match.
+func (p listPattern) mayMatchUnder(dir string) bool {
+	return !p.anchored || strings.HasPrefix(dir, p.prefix) || strings.HasPrefix(p.prefix, dir)
+}
+
+// matchesAllUnder - whether all keys under the directory dir match,
+// known for globs ending with * only.
+func (p listPattern) matchesAllUnder(dir string) bool {
+	return p.re == nil && strings.HasSuffix(p.glob, "*") && wildcard.Match(p.glob, dir)
+}
+
+// listFilter - include and exclude patterns of a listing, matched
+// against keys relative to base, the listed prefix, and bounds of the
+// time and size of the listed entries.
+type listFilter struct {
+	base    string
+	include []listPattern
+	exclude []listPattern
+	// Zero when unbounded.
+	after, before    time.Time
+	minSize, maxSize int64
+}
+
+// newListFilter - filter of the listing of the prefix base with the
+// options of opts, durations being relative to now, nil without any.
+func newListFilter(opts ListOptions, base string, now time.Time) (*listFilter, *probe.Error) {
+	include, err := newListPatterns(opts.IncludeGlobs, opts.IncludeRegexps)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	exclude, err := newListPatterns(opts.ExcludeGlobs, opts.ExcludeRegexps)
+	if err != nil {
+		return nil, err.Trace()
+	}
+	if opts.NewerThan < 0 || opts.OlderThan < 0 || opts.MinSize < 0 || opts.MaxSize < 0 {
+		return nil, errInvalidArgument().Trace(opts.NewerThan.String(), opts.OlderThan.String(),
+			strconv.FormatInt(opts.MinSize, 10), strconv.FormatInt(opts.MaxSize, 10))
+	}
+	f := &listFilter{
+		base:    base,
+		include: include,
+		exclude: exclude,
+		after:   opts.ModifiedAfter,
+		before:  opts.ModifiedBefore,
+		minSize: opts.MinSize,
+		maxSize: opts.MaxSize,
+	}
+	if opts.NewerThan > 0 {
+		if after := now.Add(-opts.NewerThan); after.After(f.after) {
+			f.after = after
+		}
+	}
+	if opts.OlderThan > 0 {
+		if before := now.Add(-opts.OlderThan); f.before.IsZero() || before.Before(f.before) {
+			f.before = before
+		}
+	}
+	if len(include) == 0 && len(exclude) == 0 && f.after.IsZero() && f.before.IsZero() && f.minSize == 0 && f.maxSize == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// descend - whether keys under the directory key may be listed, the
+// directory and its content are skipped otherwise.
+func (f *listFilter) descend(key string) bool {
+	if f == nil {
+		return true
+	}
+	dir := strings.TrimPrefix(key, f.base)
+	for _, p := range f.exclude {
+		if p.matchesAllUnder(dir) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.mayMatchUnder(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// keep - whether content is listed. Errors and entries without a key,
+// buckets and the listed directory itself, always are. Directories
+// are when keys under them may be, whatever their time and size.
+func (f *listFilter) keep(content *ClientContent) bool {
+	if f == nil || content.Err != nil || content.Key == "" {
+		return true
+	}
+	key := strings.TrimPrefix(content.Key, f.base)
+	for _, p := range f.exclude {
+		if p.match(key) {
+			return false
+		}
+	}
+	if content.Type.IsDir() {
+		return f.descend(content.Key)
+	}
+	switch {
+	case !f.after.IsZero() && content.Time.Before(f.after):
+		return false
+	case !f.before.IsZero() && !content.Time.Before(f.before):
+		return false
+	case content.Size < f.minSize:
+		return false
+	case f.maxSize > 0 && content.Size > f.maxSize:
+		return false
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// send - send content on contentCh if it is kept.
+func (f *listFilter) send(contentCh chan<- *ClientContent, content *ClientContent) {
+	if f.keep(content) {
+		contentCh <- content
+	}
+}
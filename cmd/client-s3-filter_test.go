@@ -0,0 +1,291 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestRegexpPrefix(c *C) {
+	testCases := []struct {
+		expr     string
+		prefix   string
+		anchored bool
+	}{
+		{"abc", "", false},
+		{"^abc", "abc", true},
+		{"^abc.*x$", "abc", true},
+		{"^a/b/.*\\.txt$", "a/b/", true},
+		{"^(?i)abc", "", true},
+		{"^.*abc", "", true},
+		{"x|^abc", "", false},
+	}
+	for i, testCase := range testCases {
+		prefix, anchored := regexpPrefix(testCase.expr)
+		c.Assert(prefix, Equals, testCase.prefix, Commentf("test %d", i+1))
+		c.Assert(anchored, Equals, testCase.anchored, Commentf("test %d", i+1))
+	}
+}
+
+// Test filtering listings with globs and regular expressions, matched
+// against keys relative to the listed directory.
+func (s *TestSuite) TestListFilter(c *C) {
+	var maxKeys []string
+	keys := []string{
+		"docs/a.txt",
+		"photos/2019/x.jpg",
+		"photos/2020/y.jpg",
+		"photos/2020/z.txt",
+		"photos/raw/r.dng",
+		"readme.txt",
+	}
+	server := httptest.NewServer(statListHandler{keys: keys, maxKeys: &maxKeys})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/photos/", "S3v4")
+
+	testCases := []struct {
+		opts ListOptions
+		keys []string
+	}{
+		{ListOptions{Recursive: true}, keys[1:5]},
+		{ListOptions{Recursive: true, IncludeGlobs: []string{"2020/*"}}, []string{"photos/2020/y.jpg", "photos/2020/z.txt"}},
+		// Globs match whole keys, separators included.
+		{ListOptions{Recursive: true, IncludeGlobs: []string{"*.jpg", "raw/*"}}, []string{"photos/2019/x.jpg", "photos/2020/y.jpg", "photos/raw/r.dng"}},
+		{ListOptions{Recursive: true, IncludeGlobs: []string{"x.jpg"}}, nil},
+		{ListOptions{Recursive: true, ExcludeGlobs: []string{"*.jpg"}}, []string{"photos/2020/z.txt", "photos/raw/r.dng"}},
+		// Regular expressions match anywhere unless anchored.
+		{ListOptions{Recursive: true, IncludeRegexps: []string{"y|z"}}, []string{"photos/2020/y.jpg", "photos/2020/z.txt"}},
+		{ListOptions{Recursive: true, IncludeRegexps: []string{"^2"}, ExcludeRegexps: []string{"txt$"}}, []string{"photos/2019/x.jpg", "photos/2020/y.jpg"}},
+		// Directories are listed when they may hold matching keys.
+		{ListOptions{IncludeGlobs: []string{"2020/*"}}, []string{"photos/2020/"}},
+		{ListOptions{IncludeRegexps: []string{"\\.txt$"}}, []string{"photos/2019/", "photos/2020/", "photos/raw/"}},
+		{ListOptions{ExcludeGlobs: []string{"raw/*"}}, []string{"photos/2019/", "photos/2020/"}},
+	}
+	for i, testCase := range testCases {
+		var listed []string
+		for content := range s3c.ListWithOptions(testCase.opts) {
+			c.Assert(content.Err, IsNil, Commentf("test %d", i+1))
+			listed = append(listed, content.Key)
+		}
+		c.Assert(listed, DeepEquals, testCase.keys, Commentf("test %d", i+1))
+	}
+
+	for content := range s3c.ListWithOptions(ListOptions{Recursive: true, IncludeRegexps: []string{"("}}) {
+		c.Assert(content.Err, NotNil)
+	}
+}
+
+// Test directories which can't hold listed keys aren't listed at all.
+func (s *TestSuite) TestListFilterPruning(c *C) {
+	var maxKeys []string
+	keys := []string{
+		"photos/2019/x.jpg",
+		"photos/2020/jan/y.jpg",
+		"photos/2020/z.txt",
+		"photos/raw/r.dng",
+	}
+
+	testCases := []struct {
+		opts     ListOptions
+		keys     []string
+		prefixes []string
+	}{
+		{
+			ListOptions{IncludeGlobs: []string{"2020/*.txt"}},
+			[]string{"photos/2020/", "photos/2020/z.txt", "photos/2020/jan/"},
+			[]string{"photos/", "photos/2020/", "photos/2020/jan/"},
+		},
+		{
+			ListOptions{IncludeRegexps: []string{"^2019/"}},
+			[]string{"photos/2019/", "photos/2019/x.jpg"},
+			[]string{"photos/", "photos/2019/"},
+		},
+		{
+			ListOptions{ExcludeGlobs: []string{"2020/*", "raw/*"}},
+			[]string{"photos/2019/", "photos/2019/x.jpg"},
+			[]string{"photos/", "photos/2019/"},
+		},
+		// Unanchored expressions may match under any directory.
+		{
+			ListOptions{IncludeRegexps: []string{"\\.dng$"}},
+			[]string{"photos/2019/", "photos/2020/", "photos/2020/jan/", "photos/raw/", "photos/raw/r.dng"},
+			[]string{"photos/", "photos/2019/", "photos/2020/", "photos/2020/jan/", "photos/raw/"},
+		},
+	}
+	for i, testCase := range testCases {
+		var prefixes []string
+		server := httptest.NewServer(statListHandler{keys: keys, maxKeys: &maxKeys, prefixes: &prefixes})
+		s3c := newTestS3Client(c, server.URL+"/bucket/photos/", "S3v4")
+
+		opts := testCase.opts
+		opts.Recursive = true
+		opts.ShowDir = DirFirst
+		var listed []string
+		for content := range s3c.ListWithOptions(opts) {
+			c.Assert(content.Err, IsNil, Commentf("test %d", i+1))
+			if content.Key != "" {
+				listed = append(listed, content.Key)
+			}
+		}
+		server.Close()
+		c.Assert(listed, DeepEquals, testCase.keys, Commentf("test %d", i+1))
+
+		// Stat lists the directory first.
+		c.Assert(prefixes, DeepEquals, append([]string{"photos/"}, testCase.prefixes...), Commentf("test %d", i+1))
+	}
+}
+
+// agedObject - key, modification time and size of an object listed by
+// agedListHandler, also listed as an upload of a single part.
+type agedObject struct {
+	key      string
+	modified time.Time
+	size     int64
+}
+
+// agedListHandler serves delimited listings of objects and their
+// uploads with the times and sizes of objects.
+type agedListHandler struct {
+	objects []agedObject
+}
+
+func (h agedListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	var response string
+	switch {
+	case r.Method == "GET" && location:
+		response = "<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>"
+	case r.Method == "GET" && uploads:
+		response = "<ListMultipartUploadsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><KeyMarker/><UploadIdMarker/><NextKeyMarker/><NextUploadIdMarker/><MaxUploads>1000</MaxUploads><IsTruncated>false</IsTruncated>"
+		for _, object := range h.objects {
+			if strings.HasPrefix(object.key, prefix) {
+				response += "<Upload><Key>" + object.key + "</Key><UploadId>" + object.key + "</UploadId><Initiated>" +
+					object.modified.UTC().Format(time.RFC3339Nano) + "</Initiated></Upload>"
+			}
+		}
+		response += "<Prefix>" + prefix + "</Prefix></ListMultipartUploadsResult>"
+	case r.Method == "GET" && query.Get("uploadId") != "":
+		var size int64
+		for _, object := range h.objects {
+			if object.key == query.Get("uploadId") {
+				size = object.size
+			}
+		}
+		response = "<ListPartsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>" + query.Get("uploadId") + "</Key><UploadId>" + query.Get("uploadId") + "</UploadId>" +
+			"<Part><PartNumber>1</PartNumber><LastModified>2015-05-21T18:24:21.097Z</LastModified><ETag>\"9af2f8218b150c351ad802c6f3d66abe\"</ETag><Size>" + strconv.FormatInt(size, 10) + "</Size></Part>" +
+			"<PartNumberMarker>0</PartNumberMarker><NextPartNumberMarker>1</NextPartNumberMarker><MaxParts>1000</MaxParts><IsTruncated>false</IsTruncated></ListPartsResult>"
+	case r.Method == "GET" && r.URL.Path == "/bucket/":
+		var prefixes []string
+		response = "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+		for _, object := range h.objects {
+			if !strings.HasPrefix(object.key, prefix) {
+				continue
+			}
+			if i := strings.Index(object.key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+				commonPrefix := object.key[:len(prefix)+i+len(delimiter)]
+				if len(prefixes) == 0 || prefixes[len(prefixes)-1] != commonPrefix {
+					prefixes = append(prefixes, commonPrefix)
+				}
+				continue
+			}
+			response += "<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>" + object.key + "</Key><LastModified>" +
+				object.modified.UTC().Format(time.RFC3339Nano) + "</LastModified><Size>" + strconv.FormatInt(object.size, 10) +
+				"</Size><StorageClass>STANDARD</StorageClass></Contents>"
+		}
+		for _, commonPrefix := range prefixes {
+			response += "<CommonPrefixes><Prefix>" + commonPrefix + "</Prefix></CommonPrefixes>"
+		}
+		response += "<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix>" + prefix + "</Prefix></ListBucketResult>"
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test filtering listings of objects and uploads by time and size.
+func (s *TestSuite) TestListFilterTimeSize(c *C) {
+	now := time.Now().Truncate(time.Second)
+	objects := []agedObject{
+		{"logs/2020/a.log", now.Add(-90 * 24 * time.Hour), 10},
+		{"logs/2020/b.log", now.Add(-10 * 24 * time.Hour), 2000},
+		{"logs/c.log", now.Add(-2 * time.Hour), 500},
+		{"logs/d.tmp", now.Add(-time.Minute), 0},
+	}
+	server := httptest.NewServer(agedListHandler{objects: objects})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/logs/", "S3v4")
+
+	testCases := []struct {
+		opts ListOptions
+		keys []string
+	}{
+		{ListOptions{}, []string{"logs/2020/a.log", "logs/2020/b.log", "logs/c.log", "logs/d.tmp"}},
+		{ListOptions{NewerThan: 24 * time.Hour}, []string{"logs/c.log", "logs/d.tmp"}},
+		{ListOptions{OlderThan: 24 * time.Hour}, []string{"logs/2020/a.log", "logs/2020/b.log"}},
+		{ListOptions{NewerThan: 30 * 24 * time.Hour, OlderThan: time.Hour}, []string{"logs/2020/b.log", "logs/c.log"}},
+		{ListOptions{ModifiedAfter: now.Add(-2 * time.Hour)}, []string{"logs/c.log", "logs/d.tmp"}},
+		{ListOptions{ModifiedBefore: now.Add(-2 * time.Hour)}, []string{"logs/2020/a.log", "logs/2020/b.log"}},
+		// The narrowest of absolute and relative bounds applies.
+		{ListOptions{ModifiedAfter: now.Add(-24 * time.Hour), NewerThan: 30 * 24 * time.Hour}, []string{"logs/c.log", "logs/d.tmp"}},
+		{ListOptions{MinSize: 1}, []string{"logs/2020/a.log", "logs/2020/b.log", "logs/c.log"}},
+		{ListOptions{MaxSize: 500}, []string{"logs/2020/a.log", "logs/c.log", "logs/d.tmp"}},
+		{ListOptions{MinSize: 100, MaxSize: 1000}, []string{"logs/c.log"}},
+		// Time and size filters compose with patterns.
+		{ListOptions{IncludeGlobs: []string{"*.log"}, MinSize: 100}, []string{"logs/2020/b.log", "logs/c.log"}},
+		{ListOptions{ExcludeGlobs: []string{"2020/*"}, OlderThan: time.Hour}, []string{"logs/c.log"}},
+	}
+	for _, incomplete := range []bool{false, true} {
+		for i, testCase := range testCases {
+			opts := testCase.opts
+			opts.Recursive = true
+			opts.Incomplete = incomplete
+			var listed []string
+			for content := range s3c.ListWithOptions(opts) {
+				c.Assert(content.Err, IsNil, Commentf("test %d incomplete %t", i+1, incomplete))
+				listed = append(listed, content.Key)
+			}
+			c.Assert(listed, DeepEquals, testCase.keys, Commentf("test %d incomplete %t", i+1, incomplete))
+		}
+	}
+
+	// Directories are listed whatever their time and size.
+	var listed []string
+	for content := range s3c.ListWithOptions(ListOptions{NewerThan: time.Hour}) {
+		c.Assert(content.Err, IsNil)
+		listed = append(listed, content.Key)
+	}
+	c.Assert(listed, DeepEquals, []string{"logs/d.tmp", "logs/2020/"})
+
+	for _, opts := range []ListOptions{{OlderThan: -time.Hour}, {MinSize: -1}} {
+		for content := range s3c.ListWithOptions(opts) {
+			c.Assert(content.Err, NotNil)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/wildcard"
+)
+
+// FindOptions - predicates of Find, which matching objects satisfy all
+// of, and the action run on matches.
+type FindOptions struct {
+	// Patterns, times and sizes of the listing, see ListOptions.
+	// Recursive, ShowDir and Incomplete are ignored, Find lists all
+	// objects under the prefix of the client.
+	ListOptions
+	// Name is a glob matched against the last element of keys as
+	// path.Match does, malformed ones matching nothing. Path is a glob
+	// matched against whole keys, in which * and ? match separators
+	// too, and Regex a regular expression matched against whole keys.
+	Name  string
+	Path  string
+	Regex string
+	// Match, if not nil, is called last with objects matching all
+	// the other predicates.
+	Match func(content *ClientContent) bool
+	// Action, if not nil, is called with every match by Workers
+	// goroutines, before the match is sent.
+	Action  func(ctx context.Context, content *ClientContent) *probe.Error
+	Workers int
+}
+
+// findMatcher - evaluates the predicates of FindOptions, the cheapest
+// first.
+type findMatcher struct {
+	filter    *listFilter
+	name      string
+	path      string
+	re        *regexp.Regexp
+	match     func(content *ClientContent) bool
+	separator string
+}
+
+func (m findMatcher) matches(content *ClientContent) bool {
+	if !m.filter.keep(content) {
+		return false
+	}
+	if m.name != "" {
+		name := content.Key[strings.LastIndex(content.Key, m.separator)+1:]
+		if matched, _ := path.Match(m.name, name); !matched {
+			return false
+		}
+	}
+	if m.path != "" && !wildcard.Match(m.path, content.Key) {
+		return false
+	}
+	if m.re != nil && !m.re.MatchString(content.Key) {
+		return false
+	}
+	return m.match == nil || m.match(content)
+}
+
+// newFindMatcher - matcher of the predicates of opts, for a listing of
+// the prefix base.
+func (c *S3Client) newFindMatcher(opts FindOptions, base string) (findMatcher, *probe.Error) {
+	m := findMatcher{
+		name:      opts.Name,
+		path:      opts.Path,
+		match:     opts.Match,
+		separator: string(c.targetURL.Separator),
+	}
+	if opts.Regex != "" {
+		re, e := regexp.Compile(opts.Regex)
+		if e != nil {
+			return m, probe.NewError(e).Trace(opts.Regex)
+		}
+		m.re = re
+	}
+	filter, err := newListFilter(opts.ListOptions, base, UTCNow())
+	if err != nil {
+		return m, err.Trace()
+	}
+	m.filter = filter
+	return m, nil
+}
+
+// Find - list the objects under the prefix of the client matching all
+// the predicates of opts, running opts.Action on each, if set, with
+// opts.Workers goroutines. Matches are sent once their action is done,
+// with their Err set if it failed, so they may be received out of key
+// order. Canceling ctx stops the listing and the actions not started
+// yet, the context error is sent last.
+func (c *S3Client) Find(ctx context.Context, opts FindOptions) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		bucket, object := c.url2BucketAndObject()
+		if bucket == "" {
+			contentCh <- &ClientContent{Err: probe.NewError(BucketNameEmpty{})}
+			return
+		}
+		if opts.Incomplete {
+			contentCh <- &ClientContent{Err: errInvalidArgument().Trace("incomplete uploads can't be found")}
+			return
+		}
+		// Patterns of the listing match keys relative to the listed
+		// directory.
+		base := object[:strings.LastIndex(object, string(c.targetURL.Separator))+1]
+		m, err := c.newFindMatcher(opts, base)
+		if err != nil {
+			contentCh <- &ClientContent{Err: err.Trace(c.targetURL.String())}
+			return
+		}
+
+		matchCh := make(chan *ClientContent)
+		var wg sync.WaitGroup
+		workers := opts.Workers
+		if workers <= 0 {
+			workers = 1
+		}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for content := range matchCh {
+					if opts.Action != nil && ctx.Err() == nil {
+						if err := opts.Action(ctx, content); err != nil {
+							content.Err = err.Trace(content.URL.String())
+						}
+					}
+					if ctx.Err() != nil && content.Err == nil {
+						// Not acted upon.
+						continue
+					}
+					contentCh <- content
+				}
+			}()
+		}
+
+		isRecursive := true
+	walk:
+		for info := range c.listObjectsAfter(bucket, object, isRecursive, ctx.Done(), opts.Metadata, opts.StartAfter) {
+			if info.Err != nil {
+				contentCh <- &ClientContent{Err: probe.NewError(regionError(bucket, info.Err)).Trace(bucket, object)}
+				break
+			}
+			content := c.objectInfo2ClientContent(bucket, info, opts.Metadata)
+			if content.Type.IsDir() || !m.matches(content) {
+				continue
+			}
+			select {
+			case matchCh <- content:
+			case <-ctx.Done():
+				break walk
+			}
+		}
+		close(matchCh)
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			contentCh <- &ClientContent{Err: probe.NewError(ctx.Err())}
+		}
+	}()
+	return contentCh
+}
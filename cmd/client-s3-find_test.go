@@ -0,0 +1,142 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+func findTestObjects() []agedObject {
+	now := time.Now()
+	return []agedObject{
+		{"logs/2020/a.log", now.Add(-90 * 24 * time.Hour), 10},
+		{"logs/2020/b.log", now.Add(-10 * 24 * time.Hour), 2000},
+		{"logs/c.log", now.Add(-2 * time.Hour), 500},
+		{"logs/d.tmp", now.Add(-time.Minute), 0},
+		{"other/e.log", now, 1},
+	}
+}
+
+// Test finding objects with predicates on their key, time and size.
+func (s *TestSuite) TestFind(c *C) {
+	server := httptest.NewServer(agedListHandler{objects: findTestObjects()})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/logs/", "S3v4")
+
+	testCases := []struct {
+		opts FindOptions
+		keys []string
+	}{
+		{FindOptions{}, []string{"logs/2020/a.log", "logs/2020/b.log", "logs/c.log", "logs/d.tmp"}},
+		{FindOptions{Name: "*.log"}, []string{"logs/2020/a.log", "logs/2020/b.log", "logs/c.log"}},
+		// Name globs don't match separators, unlike path globs.
+		{FindOptions{Name: "2020*"}, nil},
+		{FindOptions{Path: "logs/2020*"}, []string{"logs/2020/a.log", "logs/2020/b.log"}},
+		{FindOptions{Regex: "[cd]\\."}, []string{"logs/c.log", "logs/d.tmp"}},
+		{FindOptions{Name: "*.log", ListOptions: ListOptions{NewerThan: 30 * 24 * time.Hour}}, []string{"logs/2020/b.log", "logs/c.log"}},
+		{FindOptions{Path: "*.log", ListOptions: ListOptions{ExcludeGlobs: []string{"2020/*"}}}, []string{"logs/c.log"}},
+		{FindOptions{ListOptions: ListOptions{MinSize: 100}, Match: func(content *ClientContent) bool {
+			return content.Size < 1000
+		}}, []string{"logs/c.log"}},
+	}
+	for i, testCase := range testCases {
+		var keys []string
+		for content := range s3c.Find(context.Background(), testCase.opts) {
+			c.Assert(content.Err, IsNil, Commentf("test %d", i+1))
+			keys = append(keys, content.Key)
+		}
+		c.Assert(keys, DeepEquals, testCase.keys, Commentf("test %d", i+1))
+	}
+
+	for _, opts := range []FindOptions{{Regex: "("}, {ListOptions: ListOptions{Incomplete: true}}} {
+		for content := range s3c.Find(context.Background(), opts) {
+			c.Assert(content.Err, NotNil)
+		}
+	}
+}
+
+// Test Find runs its action on every match, reporting the failures.
+func (s *TestSuite) TestFindAction(c *C) {
+	server := httptest.NewServer(agedListHandler{objects: findTestObjects()})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/logs/", "S3v4")
+
+	var mutex sync.Mutex
+	var acted []string
+	opts := FindOptions{
+		Name:    "*.log",
+		Workers: 3,
+		Action: func(ctx context.Context, content *ClientContent) *probe.Error {
+			mutex.Lock()
+			acted = append(acted, content.Key)
+			mutex.Unlock()
+			if content.Key == "logs/c.log" {
+				return probe.NewError(errors.New("action failed"))
+			}
+			return nil
+		},
+	}
+	var keys, failed []string
+	for content := range s3c.Find(context.Background(), opts) {
+		keys = append(keys, content.Key)
+		if content.Err != nil {
+			failed = append(failed, content.Key)
+		}
+	}
+	sort.Strings(keys)
+	sort.Strings(acted)
+	c.Assert(keys, DeepEquals, []string{"logs/2020/a.log", "logs/2020/b.log", "logs/c.log"})
+	c.Assert(acted, DeepEquals, keys)
+	c.Assert(failed, DeepEquals, []string{"logs/c.log"})
+}
+
+// Test canceling Find stops running actions.
+func (s *TestSuite) TestFindCancel(c *C) {
+	server := httptest.NewServer(agedListHandler{objects: findTestObjects()})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/logs/", "S3v4")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var acted int
+	opts := FindOptions{
+		Action: func(ctx context.Context, content *ClientContent) *probe.Error {
+			acted++
+			cancel()
+			return nil
+		},
+	}
+	var last *ClientContent
+	for content := range s3c.Find(ctx, opts) {
+		last = content
+	}
+	c.Assert(acted, Equals, 1)
+	c.Assert(last, NotNil)
+	c.Assert(last.Err, NotNil)
+	c.Assert(last.Err.ToGoError(), Equals, context.Canceled)
+}
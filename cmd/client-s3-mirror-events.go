@@ -0,0 +1,296 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+)
+
+// defaultMirrorEventsDebounce - time MirrorEvents waits for the events
+// of a key to settle before mirroring it.
+const defaultMirrorEventsDebounce = 500 * time.Millisecond
+
+// MirrorEventsOptions - options of MirrorEvents.
+type MirrorEventsOptions struct {
+	// Debounce is the time without new events a key waits before it
+	// is mirrored, defaultMirrorEventsDebounce if unset. Only the last
+	// event received for a key in the meantime is mirrored.
+	Debounce time.Duration
+	// Workers is the number of keys mirrored at once, 1 if unset.
+	Workers int
+}
+
+// mirrorEventKey - state of a key of MirrorEvents with pending events.
+type mirrorEventKey struct {
+	event EventInfo
+	// gen tells the expiry of the current debounce timer from those of
+	// timers reset since.
+	gen   int
+	timer *time.Timer
+	// ready is set once the key waited long enough, it is mirrored as
+	// soon as no worker is busy with it.
+	ready bool
+}
+
+// mirrorEventOp - latest event of a key handed to a worker.
+type mirrorEventOp struct {
+	key   string
+	event EventInfo
+}
+
+// mirrorEventFire - expiry of the debounce timer of a key.
+type mirrorEventFire struct {
+	key string
+	gen int
+}
+
+// eventKey - key of the object of an event relative to the prefix of
+// the client, false if the object isn't under it. Watch builds the
+// paths of events in path style, whatever the style of the client.
+func (c *S3Client) eventKey(event EventInfo) (string, bool) {
+	bucket, prefix := c.url2BucketAndObject()
+	separator := string(c.targetURL.Separator)
+	tokens := splitStr(strings.TrimPrefix(newClientURL(event.Path).Path, separator), separator, 2)
+	eventBucket, object := tokens[0], tokens[1]
+	if eventBucket != bucket || object == "" || !strings.HasPrefix(object, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(object, prefix), true
+}
+
+// MirrorEvents - mirror the objects created and removed under the
+// prefix of the client, as reported by the events received on events,
+// such as those of Watch, under the prefix of target until events is
+// closed, or ctx canceled. Rapid events of a same key are coalesced,
+// the key being mirrored once its events settled, with the last of
+// them, by at most opts.Workers workers, never two at once for a key.
+// Created objects are read from the client and uploaded to target,
+// unless superseded by the time they are: gone, or with another
+// version ID or ETag than the event when it has them. Removed objects
+// are deleted from target unless they exist again. Errors are sent on
+// the returned channel, closed once all events are mirrored, which
+// must be drained.
+func (c *S3Client) MirrorEvents(ctx context.Context, events <-chan EventInfo, target *S3Client, opts MirrorEventsOptions) <-chan *probe.Error {
+	errorCh := make(chan *probe.Error)
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = defaultMirrorEventsDebounce
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// Stop the timers and workers however the dispatch ends.
+	ctx, cancel := context.WithCancel(ctx)
+	workCh := make(chan mirrorEventOp)
+	doneCh := make(chan string)
+	fireCh := make(chan mirrorEventFire)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for op := range workCh {
+				if err := c.mirrorEvent(ctx, target, op); err != nil {
+					errorCh <- err.Trace(op.event.Path)
+				}
+				select {
+				case doneCh <- op.key:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(errorCh)
+		defer wg.Wait()
+		defer close(workCh)
+		defer cancel()
+
+		keys := make(map[string]*mirrorEventKey)
+		busy := make(map[string]bool)
+		// Keys ready and not busy, in the order they got ready.
+		var queue []string
+		for events != nil || len(keys) > 0 || len(busy) > 0 {
+			var sendCh chan mirrorEventOp
+			var next mirrorEventOp
+			if len(queue) > 0 {
+				sendCh = workCh
+				next = mirrorEventOp{key: queue[0], event: keys[queue[0]].event}
+			}
+			select {
+			case <-ctx.Done():
+				for _, k := range keys {
+					if k.timer != nil {
+						k.timer.Stop()
+					}
+				}
+				errorCh <- probe.NewError(ctx.Err())
+				return
+			case event, ok := <-events:
+				if !ok {
+					// Mirror all pending keys without waiting.
+					events = nil
+					for key, k := range keys {
+						if !k.ready {
+							k.timer.Stop()
+							k.ready = true
+							if !busy[key] {
+								queue = append(queue, key)
+							}
+						}
+					}
+					continue
+				}
+				if event.Type != EventCreate && event.Type != EventCreateCopy &&
+					event.Type != EventCreatePutRetention && event.Type != EventRemove {
+					continue
+				}
+				key, ok := c.eventKey(event)
+				if !ok {
+					continue
+				}
+				k, ok := keys[key]
+				if !ok {
+					k = &mirrorEventKey{}
+					keys[key] = k
+				}
+				k.event = event
+				if k.ready {
+					// Already waited, mirror the last event when due.
+					continue
+				}
+				if k.timer != nil {
+					k.timer.Stop()
+				}
+				k.gen++
+				fire := mirrorEventFire{key: key, gen: k.gen}
+				k.timer = time.AfterFunc(debounce, func() {
+					select {
+					case fireCh <- fire:
+					case <-ctx.Done():
+					}
+				})
+			case fire := <-fireCh:
+				k, ok := keys[fire.key]
+				if !ok || k.ready || k.gen != fire.gen {
+					// Reset or flushed since.
+					continue
+				}
+				k.ready = true
+				if !busy[fire.key] {
+					queue = append(queue, fire.key)
+				}
+			case sendCh <- next:
+				queue = queue[1:]
+				delete(keys, next.key)
+				busy[next.key] = true
+			case key := <-doneCh:
+				delete(busy, key)
+				if k, ok := keys[key]; ok && k.ready {
+					queue = append(queue, key)
+				}
+			}
+		}
+	}()
+	return errorCh
+}
+
+// mirrorEvent - mirror the object of the key of op to target, unless
+// superseded.
+func (c *S3Client) mirrorEvent(ctx context.Context, target *S3Client, op mirrorEventOp) *probe.Error {
+	bucket, prefix := c.url2BucketAndObject()
+	object := prefix + op.key
+	targetBucket, targetPrefix := target.url2BucketAndObject()
+	if targetBucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	targetObject := targetPrefix + op.key
+
+	statOpts := minio.StatObjectOptions{}
+	statOpts.ServerSideEncryption = c.readSSE(nil)
+	info, e := c.api.StatObjectWithContext(ctx, bucket, object, statOpts)
+	missing := e != nil && minio.ToErrorResponse(e).Code == "NoSuchKey"
+	if e != nil && !missing {
+		return probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+	}
+
+	if op.event.Type == EventRemove {
+		if !missing {
+			// Created again since, mirrored by its own event.
+			return nil
+		}
+		api, release := target.contextAPI(ctx, targetBucket)
+		e = api.RemoveObject(targetBucket, targetObject)
+		release()
+		if e != nil {
+			if minio.ToErrorResponse(e).Code == "NoSuchKey" {
+				return nil
+			}
+			return probe.NewError(regionError(targetBucket, e)).Trace(targetBucket, targetObject)
+		}
+		return nil
+	}
+
+	etag := strings.Trim(info.ETag, "\"")
+	versionID := info.Metadata.Get("X-Amz-Version-Id")
+	switch {
+	case missing:
+		return nil
+	case op.event.VersionID != "" && versionID != "" && versionID != op.event.VersionID:
+		return nil
+	case op.event.ETag != "" && etag != strings.Trim(op.event.ETag, "\""):
+		return nil
+	}
+
+	getOpts := minio.GetObjectOptions{}
+	getOpts.ServerSideEncryption = c.readSSE(nil)
+	// Don't copy a newer object than the one checked.
+	if e = getOpts.SetMatchETag(etag); e != nil {
+		return probe.NewError(e)
+	}
+	reader, e := c.api.GetObjectWithContext(ctx, bucket, object, getOpts)
+	if e != nil {
+		return probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+	}
+	defer reader.Close()
+
+	putOpts := minio.PutObjectOptions{
+		UserMetadata:         userMetadata(info.Metadata),
+		ContentType:          info.ContentType,
+		ServerSideEncryption: target.writeSSE(nil),
+		StorageClass:         target.defaultStorageClass,
+	}
+	if _, e = target.api.PutObjectWithContext(ctx, targetBucket, targetObject, reader, info.Size, putOpts); e != nil {
+		switch minio.ToErrorResponse(e).Code {
+		case "PreconditionFailed", "NoSuchKey":
+			// Superseded while read.
+			return nil
+		}
+		return probe.NewError(regionError(targetBucket, e)).Trace(targetBucket, targetObject)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// storedObject - object of storeHandler.
+type storedObject struct {
+	data      []byte
+	versionID string
+	header    http.Header
+}
+
+// storeHandler stores objects in memory by path, and counts the
+// uploads of every path.
+type storeHandler struct {
+	mutex   *sync.Mutex
+	objects map[string]storedObject
+	puts    map[string]int
+}
+
+func (h storeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	object, ok := h.objects[r.URL.Path]
+	sum := md5.Sum(object.data)
+	etag := hex.EncodeToString(sum[:])
+	switch r.Method {
+	case "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		header := http.Header{}
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") || k == "Content-Type" {
+				header[k] = v
+			}
+		}
+		h.objects[r.URL.Path] = storedObject{data: data, header: header}
+		h.puts[r.URL.Path]++
+		sum = md5.Sum(data)
+		w.Header().Set("ETag", "\""+hex.EncodeToString(sum[:])+"\"")
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		delete(h.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	case "HEAD", "GET":
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && strings.Trim(match, "\"") != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		for k, v := range object.header {
+			w.Header()[k] = v
+		}
+		if object.versionID != "" {
+			w.Header().Set("X-Amz-Version-Id", object.versionID)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "\""+etag+"\"")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == "GET" {
+			w.Write(object.data)
+		}
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// Test mirroring events, skipping those superseded.
+func (s *TestSuite) TestMirrorEvents(c *C) {
+	owner := http.Header{"X-Amz-Meta-Owner": []string{"me"}, "Content-Type": []string{"text/plain"}}
+	handler := storeHandler{mutex: &sync.Mutex{}, puts: map[string]int{}, objects: map[string]storedObject{
+		"/bucket/a/1": {data: []byte("one"), versionID: "v1", header: owner},
+		"/bucket/a/2": {data: []byte("two")},
+		"/bucket/a/3": {data: []byte("three"), versionID: "v3"},
+		"/bucket/a/4": {data: []byte("four")},
+		"/bucket/b/4": {data: []byte("four")},
+		"/bucket/b/5": {data: []byte("five")},
+		"/bucket/c/7": {data: []byte("seven")},
+	}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	source := newTestS3Client(c, server.URL+"/bucket/a/", "S3v4")
+	target := newTestS3Client(c, server.URL+"/bucket/b/", "S3v4")
+
+	event := func(key string, eventType EventType, versionID string) EventInfo {
+		return EventInfo{Path: server.URL + "/bucket/" + key, Type: eventType, VersionID: versionID}
+	}
+	events := make(chan EventInfo)
+	errorCh := source.MirrorEvents(context.Background(), events, target, MirrorEventsOptions{Debounce: 50 * time.Millisecond, Workers: 2})
+	var errs []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errorCh {
+			errs = append(errs, err.ToGoError().Error())
+		}
+	}()
+
+	events <- event("a/1", EventCreate, "v1")
+	for i := 0; i < 3; i++ {
+		events <- event("a/2", EventCreate, "")
+	}
+	// Superseded by another version.
+	events <- event("a/3", EventCreate, "v2")
+	// Created again, or never there.
+	events <- event("a/4", EventRemove, "")
+	events <- event("a/6", EventCreate, "")
+	events <- event("a/5", EventRemove, "")
+	// Ignored.
+	events <- event("c/7", EventCreate, "")
+	events <- event("a/1", EventAccessedRead, "")
+	// Let the first keys settle before the others.
+	time.Sleep(200 * time.Millisecond)
+	events <- event("a/2", EventCreateCopy, "")
+	close(events)
+	<-done
+
+	c.Assert(errs, HasLen, 0)
+	c.Assert(handler.puts, DeepEquals, map[string]int{"/bucket/b/1": 1, "/bucket/b/2": 2})
+	c.Assert(string(handler.objects["/bucket/b/1"].data), Equals, "one")
+	c.Assert(handler.objects["/bucket/b/1"].header.Get("X-Amz-Meta-Owner"), Equals, "me")
+	c.Assert(handler.objects["/bucket/b/1"].header.Get("Content-Type"), Equals, "text/plain")
+	c.Assert(string(handler.objects["/bucket/b/2"].data), Equals, "two")
+	_, ok := handler.objects["/bucket/b/3"]
+	c.Assert(ok, Equals, false)
+	_, ok = handler.objects["/bucket/b/4"]
+	c.Assert(ok, Equals, true)
+	_, ok = handler.objects["/bucket/b/5"]
+	c.Assert(ok, Equals, false)
+
+	// Canceling stops mirroring with the error of the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	errorCh = source.MirrorEvents(ctx, make(chan EventInfo), target, MirrorEventsOptions{})
+	cancel()
+	err := <-errorCh
+	c.Assert(err, NotNil)
+	c.Assert(err.ToGoError(), Equals, context.Canceled)
+	_, ok = <-errorCh
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,513 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+)
+
+// mirrorManifestFile - name of the file, at the root of a local mirror,
+// recording the ETag of every object downloaded by MirrorToLocal.
+const mirrorManifestFile = ".mcmirror"
+
+// MirrorStats - outcome of a MirrorToLocal or MirrorFromLocal run.
+type MirrorStats struct {
+	Downloaded int
+	Uploaded   int
+	Skipped    int
+	Deleted    int
+	Failed     int
+}
+
+// mirrorManifest - object path relative to the mirror root to ETag.
+type mirrorManifest map[string]string
+
+// loadMirrorManifest - read the manifest of a local mirror, a missing
+// manifest is an empty one.
+func loadMirrorManifest(localDir string) (mirrorManifest, *probe.Error) {
+	manifest := make(mirrorManifest)
+	data, e := ioutil.ReadFile(filepath.Join(localDir, mirrorManifestFile))
+	if os.IsNotExist(e) {
+		return manifest, nil
+	}
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	if e = json.Unmarshal(data, &manifest); e != nil {
+		return nil, probe.NewError(e)
+	}
+	return manifest, nil
+}
+
+// save - atomically replace the manifest of a local mirror.
+func (m mirrorManifest) save(localDir string) *probe.Error {
+	data, e := json.MarshalIndent(m, "", " ")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	tmp, e := ioutil.TempFile(localDir, mirrorManifestFile)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if _, e = tmp.Write(data); e != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return probe.NewError(e)
+	}
+	if e = tmp.Close(); e != nil {
+		os.Remove(tmp.Name())
+		return probe.NewError(e)
+	}
+	if e = os.Rename(tmp.Name(), filepath.Join(localDir, mirrorManifestFile)); e != nil {
+		os.Remove(tmp.Name())
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// MirrorToLocal - download all objects under the current prefix to
+// localDir, the prefix being a directory as with MirrorFromLocal.
+// Objects whose ETag matches the one recorded in the local manifest
+// are skipped, if remove is set local files which don't exist anymore
+// under the prefix are removed. Objects are downloaded by workers
+// goroutines. The returned channel holds all errors met and is closed.
+func (c *S3Client) MirrorToLocal(ctx context.Context, localDir string, remove bool, workers int) (MirrorStats, <-chan *probe.Error) {
+	var stats MirrorStats
+	var errs []*probe.Error
+	var mutex sync.Mutex
+
+	done := func() (MirrorStats, <-chan *probe.Error) {
+		errCh := make(chan *probe.Error, len(errs))
+		for _, err := range errs {
+			errCh <- err
+		}
+		close(errCh)
+		return stats, errCh
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+	bucket, prefix := c.url2BucketAndObject()
+	if bucket == "" {
+		errs = append(errs, probe.NewError(BucketNameEmpty{}))
+		return done()
+	}
+	// Neither "dir2/object" nor "dir" itself are under "dir".
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if e := os.MkdirAll(localDir, 0777); e != nil {
+		errs = append(errs, probe.NewError(e).Trace(localDir))
+		return done()
+	}
+	manifest, err := loadMirrorManifest(localDir)
+	if err != nil {
+		errs = append(errs, err.Trace(localDir))
+		return done()
+	}
+
+	// Objects found under the prefix, relative to it.
+	found := make(map[string]bool)
+	objectCh := make(chan minio.ObjectInfo)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objectCh {
+				name := mirrorRelativePath(prefix, object.Key)
+				err := c.mirrorObject(ctx, bucket, object.Key, filepath.Join(localDir, name))
+				mutex.Lock()
+				if err != nil {
+					stats.Failed++
+					errs = append(errs, err.Trace(object.Key))
+				} else {
+					stats.Downloaded++
+					manifest[name] = object.ETag
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	var listFailed bool
+	for object := range c.listObjectWrapper(bucket, prefix, true, ctx.Done(), false) {
+		if object.Err != nil {
+			mutex.Lock()
+			errs = append(errs, probe.NewError(object.Err).Trace(bucket, prefix))
+			mutex.Unlock()
+			listFailed = true
+			break
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			// Directory marker.
+			continue
+		}
+		name := mirrorRelativePath(prefix, object.Key)
+		if name == "" || name == mirrorManifestFile || !isMirrorPathSafe(name) {
+			mutex.Lock()
+			stats.Failed++
+			errs = append(errs, errInvalidArgument().Trace(object.Key))
+			mutex.Unlock()
+			continue
+		}
+		found[name] = true
+
+		mutex.Lock()
+		etag, ok := manifest[name]
+		mutex.Unlock()
+		if ok && etag == object.ETag {
+			if _, e := os.Stat(filepath.Join(localDir, name)); e == nil {
+				mutex.Lock()
+				stats.Skipped++
+				mutex.Unlock()
+				continue
+			}
+		}
+		objectCh <- object
+	}
+	close(objectCh)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		errs = append(errs, probe.NewError(ctx.Err()))
+	}
+
+	// Never delete anything based on a partial listing.
+	if remove && !listFailed && ctx.Err() == nil {
+		walkErr := filepath.Walk(localDir, func(path string, info os.FileInfo, e error) error {
+			if e != nil {
+				return e
+			}
+			if info.IsDir() {
+				return nil
+			}
+			rel, e := filepath.Rel(localDir, path)
+			if e != nil {
+				return e
+			}
+			name := filepath.ToSlash(rel)
+			if name == mirrorManifestFile || found[name] {
+				return nil
+			}
+			if e = os.Remove(path); e != nil {
+				stats.Failed++
+				errs = append(errs, probe.NewError(e).Trace(path))
+				return nil
+			}
+			stats.Deleted++
+			delete(manifest, name)
+			return nil
+		})
+		if walkErr != nil {
+			errs = append(errs, probe.NewError(walkErr).Trace(localDir))
+		}
+	}
+
+	if err := manifest.save(localDir); err != nil {
+		errs = append(errs, err.Trace(localDir))
+	}
+	return done()
+}
+
+// mirrorObject - download an object to path through a temporary file,
+// so that an interrupted download never leaves a partial file behind.
+func (c *S3Client) mirrorObject(ctx context.Context, bucket, object, path string) *probe.Error {
+	if e := os.MkdirAll(filepath.Dir(path), 0777); e != nil {
+		return probe.NewError(e)
+	}
+	reader, err := c.get(ctx, bucket, object, object, nil)
+	if err != nil {
+		return err.Trace(bucket, object)
+	}
+	defer reader.Close()
+
+	tmp, e := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".part")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if _, e = io.Copy(tmp, reader); e != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return probe.NewError(e)
+	}
+	if e = tmp.Close(); e != nil {
+		os.Remove(tmp.Name())
+		return probe.NewError(e)
+	}
+	if e = os.Rename(tmp.Name(), path); e != nil {
+		os.Remove(tmp.Name())
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// mirrorUpload - a local file to upload by MirrorFromLocal.
+type mirrorUpload struct {
+	name   string
+	path   string
+	size   int64
+	remote *minio.ObjectInfo
+}
+
+// MirrorFromLocal - upload all files of localDir under the current
+// prefix. Files whose MD5 matches the ETag of the existing object are
+// skipped, files matching one of the exclude patterns are ignored. If
+// remove is set objects which don't exist locally anymore are removed.
+// Files are uploaded by workers goroutines. The returned channel holds
+// all errors met and is closed.
+func (c *S3Client) MirrorFromLocal(ctx context.Context, localDir string, remove bool, workers int, exclude []string) (MirrorStats, <-chan *probe.Error) {
+	var stats MirrorStats
+	var errs []*probe.Error
+	var mutex sync.Mutex
+
+	done := func() (MirrorStats, <-chan *probe.Error) {
+		errCh := make(chan *probe.Error, len(errs))
+		for _, err := range errs {
+			errCh <- err
+		}
+		close(errCh)
+		return stats, errCh
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+	bucket, prefix := c.url2BucketAndObject()
+	if bucket == "" {
+		errs = append(errs, probe.NewError(BucketNameEmpty{}))
+		return done()
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	// Objects existing under the prefix, relative to it.
+	remote := make(map[string]minio.ObjectInfo)
+	for object := range c.listObjectWrapper(bucket, prefix, true, ctx.Done(), false) {
+		if object.Err != nil {
+			errs = append(errs, probe.NewError(object.Err).Trace(bucket, prefix))
+			return done()
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		object.ETag = strings.Trim(object.ETag, "\"")
+		remote[strings.TrimPrefix(object.Key, prefix)] = object
+	}
+
+	uploadCh := make(chan mirrorUpload)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for upload := range uploadCh {
+				uploaded, err := c.mirrorFile(ctx, bucket, prefix+upload.name, upload)
+				mutex.Lock()
+				switch {
+				case err != nil:
+					stats.Failed++
+					errs = append(errs, err.Trace(upload.path))
+				case uploaded:
+					stats.Uploaded++
+				default:
+					stats.Skipped++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	// Local files, relative to localDir.
+	local := make(map[string]bool)
+	walkErr := filepath.Walk(localDir, func(path string, info os.FileInfo, e error) error {
+		if e != nil {
+			return e
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rel, e := filepath.Rel(localDir, path)
+		if e != nil {
+			return e
+		}
+		name := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if name != "." && matchExcludeOptions(exclude, name+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || name == mirrorManifestFile || matchExcludeOptions(exclude, name) {
+			return nil
+		}
+		local[name] = true
+		upload := mirrorUpload{name: name, path: path, size: info.Size()}
+		if object, ok := remote[name]; ok {
+			upload.remote = &object
+		}
+		uploadCh <- upload
+		return nil
+	})
+	close(uploadCh)
+	wg.Wait()
+	if walkErr != nil {
+		errs = append(errs, probe.NewError(walkErr).Trace(localDir))
+	}
+
+	// Never remove anything based on a partial walk.
+	if remove && walkErr == nil {
+		api, release := c.contextAPI(ctx, bucket)
+		defer release()
+		for name, object := range remote {
+			if ctx.Err() != nil {
+				errs = append(errs, probe.NewError(ctx.Err()))
+				break
+			}
+			if local[name] || matchExcludeOptions(exclude, name) {
+				continue
+			}
+			if e := api.RemoveObject(bucket, object.Key); e != nil {
+				stats.Failed++
+				errs = append(errs, probe.NewError(e).Trace(bucket, object.Key))
+				continue
+			}
+			stats.Deleted++
+		}
+	}
+	return done()
+}
+
+// mirrorFile - upload a local file unless its content matches the ETag
+// of the existing object, returns whether the file was uploaded.
+func (c *S3Client) mirrorFile(ctx context.Context, bucket, object string, upload mirrorUpload) (bool, *probe.Error) {
+	file, e := os.Open(upload.path)
+	if e != nil {
+		return false, probe.NewError(e)
+	}
+	defer file.Close()
+
+	if upload.remote != nil && upload.remote.Size == upload.size {
+		etag, e := localETag(file, upload.size, upload.remote.ETag)
+		if e != nil {
+			return false, probe.NewError(e)
+		}
+		if etag == upload.remote.ETag {
+			return false, nil
+		}
+		if _, e = file.Seek(0, io.SeekStart); e != nil {
+			return false, probe.NewError(e)
+		}
+	}
+
+	// Upload as Put does, with the default encryption, storage class
+	// and content type guessing of the client.
+	if _, err := c.putObject(ctx, bucket, object, file, upload.size, nil, nil, nil, false, false, ""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// putMinPartSize - minimum size of the parts of uploads of minio-go.
+const putMinPartSize = 128 * 1024 * 1024
+
+// putPartSize - part size picked by minio-go for an upload of size
+// bytes, the size divided by the maximum number of parts rounded up to
+// a multiple of the minimum part size.
+func putPartSize(size int64) int64 {
+	const maxParts = 10000
+	partSize := (size/maxParts + putMinPartSize - 1) / putMinPartSize * putMinPartSize
+	if partSize == 0 {
+		partSize = putMinPartSize
+	}
+	return partSize
+}
+
+// localETag - compute the ETag S3 would return for the content of r,
+// as a plain MD5 or, when etag is a multipart ETag, as the MD5 of the
+// MD5 of each part assuming the part size Put uses for size bytes.
+// The result doesn't match objects encrypted with SSE-C or SSE-KMS.
+func localETag(r io.Reader, size int64, etag string) (string, error) {
+	i := strings.LastIndex(etag, "-")
+	if i < 0 {
+		h := md5.New()
+		if _, e := io.Copy(h, r); e != nil {
+			return "", e
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	partSize := putPartSize(size)
+
+	var sums []byte
+	var parts int
+	for {
+		h := md5.New()
+		n, e := io.CopyN(h, r, partSize)
+		if n > 0 {
+			sums = append(sums, h.Sum(nil)...)
+			parts++
+		}
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return "", e
+		}
+	}
+	sum := md5.Sum(sums)
+	return hex.EncodeToString(sum[:]) + "-" + strconv.Itoa(parts), nil
+}
+
+// mirrorRelativePath - path of an object relative to the mirrored
+// prefix, a directory ending with "/" unless empty. Objects outside of
+// the prefix have no such path.
+func mirrorRelativePath(prefix, object string) string {
+	if !strings.HasPrefix(object, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(object, prefix)
+}
+
+// isMirrorPathSafe - reject object names which would be written outside
+// of the mirror root.
+func isMirrorPathSafe(name string) bool {
+	if strings.HasPrefix(name, "/") {
+		return false
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,308 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+// bucketObjectsHandler serves a bucket listing and its objects, which
+// can be uploaded and removed, recording every object request.
+type bucketObjectsHandler struct {
+	objects map[string]string
+
+	mutex    *sync.Mutex
+	requests *[]string
+}
+
+func objectETag(data string) string {
+	sum := md5.Sum([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h bucketObjectsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if r.Method == "GET" && r.URL.Path == "/bucket/" {
+		var keys []string
+		for key := range h.objects {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		response := "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+		for _, key := range keys {
+			response += "<Contents><ETag>" + objectETag(h.objects[key]) + "</ETag><Key>" + key +
+				"</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>" + strconv.Itoa(len(h.objects[key])) +
+				"</Size><StorageClass>STANDARD</StorageClass></Contents>"
+		}
+		response += "<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>"
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write([]byte(response))
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	*h.requests = append(*h.requests, r.Method+" "+key)
+	switch r.Method {
+	case "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		h.objects[key] = string(data)
+		w.Header().Set("ETag", objectETag(string(data)))
+		w.WriteHeader(http.StatusOK)
+		return
+	case "DELETE":
+		delete(h.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	data, ok := h.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+	w.Header().Set("ETag", objectETag(data))
+	w.Write([]byte(data))
+}
+
+// Test mirroring a bucket to a local directory only downloads changes.
+func (s *TestSuite) TestMirrorToLocal(c *C) {
+	var requests []string
+	handler := bucketObjectsHandler{
+		objects: map[string]string{
+			"unchanged":  "unchanged data",
+			"changed":    "new data",
+			"dir/object": "object in a directory",
+		},
+		mutex:    &sync.Mutex{},
+		requests: &requests,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	localDir, e := ioutil.TempDir("", "mc-mirror-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(localDir)
+
+	// Seed the local mirror.
+	seed := map[string]string{
+		"unchanged": "unchanged data",
+		"changed":   "old data",
+		"stale":     "removed from the bucket",
+	}
+	for name, data := range seed {
+		c.Assert(ioutil.WriteFile(filepath.Join(localDir, name), []byte(data), 0644), IsNil)
+	}
+	manifest, e := json.Marshal(map[string]string{
+		"unchanged": objectETag("unchanged data"),
+		"changed":   objectETag("old data"),
+		"stale":     objectETag("removed from the bucket"),
+	})
+	c.Assert(e, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(localDir, mirrorManifestFile), manifest, 0644), IsNil)
+
+	stats, errCh := s3c.MirrorToLocal(context.Background(), localDir, true, 2)
+	for err := range errCh {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(stats, DeepEquals, MirrorStats{Downloaded: 2, Skipped: 1, Deleted: 1})
+	sort.Strings(requests)
+	c.Assert(requests, DeepEquals, []string{"GET changed", "GET dir/object"})
+
+	for name, data := range handler.objects {
+		got, e := ioutil.ReadFile(filepath.Join(localDir, filepath.FromSlash(name)))
+		c.Assert(e, IsNil)
+		c.Assert(string(got), Equals, data)
+	}
+	_, e = os.Stat(filepath.Join(localDir, "stale"))
+	c.Assert(os.IsNotExist(e), Equals, true)
+
+	// Nothing changed since the last run.
+	requests = nil
+	stats, errCh = s3c.MirrorToLocal(context.Background(), localDir, true, 2)
+	for err := range errCh {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(stats, DeepEquals, MirrorStats{Skipped: 3})
+	c.Assert(requests, IsNil)
+
+	// A prefix is a directory, neither its siblings nor an object
+	// named after it are under it.
+	handler.objects["photos"] = "not a directory"
+	handler.objects["photos/a"] = "photo"
+	handler.objects["photos2/b"] = "another photo"
+	conf.HostURL = server.URL + "/bucket/photos"
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	photosDir := filepath.Join(localDir, "photos")
+	requests = nil
+	stats, errCh = clnt.(*S3Client).MirrorToLocal(context.Background(), photosDir, false, 2)
+	for err := range errCh {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(stats, DeepEquals, MirrorStats{Downloaded: 1})
+	c.Assert(requests, DeepEquals, []string{"GET photos/a"})
+	got, e := ioutil.ReadFile(filepath.Join(photosDir, "a"))
+	c.Assert(e, IsNil)
+	c.Assert(string(got), Equals, "photo")
+}
+
+// Test mirroring a local directory to a bucket only uploads changes.
+func (s *TestSuite) TestMirrorFromLocal(c *C) {
+	var requests []string
+	handler := bucketObjectsHandler{
+		objects: map[string]string{
+			"unchanged": "unchanged data",
+			"changed":   "old data",
+			"stale":     "removed locally",
+			"tmp/kept":  "excluded from the mirror",
+		},
+		mutex:    &sync.Mutex{},
+		requests: &requests,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/", "S3v2")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	localDir, e := ioutil.TempDir("", "mc-mirror-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(localDir)
+
+	files := map[string]string{
+		"unchanged":  "unchanged data",
+		"changed":    "new data",
+		"dir/object": "object in a directory",
+		"tmp/local":  "excluded from the mirror",
+		"notes.swp":  "excluded from the mirror",
+	}
+	for name, data := range files {
+		path := filepath.Join(localDir, filepath.FromSlash(name))
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(data), 0644), IsNil)
+	}
+	exclude := []string{"tmp/*", "*.swp"}
+
+	stats, errCh := s3c.MirrorFromLocal(context.Background(), localDir, true, 2, exclude)
+	for err := range errCh {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(stats, DeepEquals, MirrorStats{Uploaded: 2, Skipped: 1, Deleted: 1})
+	sort.Strings(requests)
+	c.Assert(requests, DeepEquals, []string{"DELETE stale", "PUT changed", "PUT dir/object"})
+	c.Assert(handler.objects, DeepEquals, map[string]string{
+		"unchanged":  "unchanged data",
+		"changed":    "new data",
+		"dir/object": "object in a directory",
+		"tmp/kept":   "excluded from the mirror",
+	})
+
+	// Nothing changed since the last run.
+	requests = nil
+	stats, errCh = s3c.MirrorFromLocal(context.Background(), localDir, true, 2, exclude)
+	for err := range errCh {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(stats, DeepEquals, MirrorStats{Skipped: 3})
+	c.Assert(requests, IsNil)
+}
+
+// Test mirrored files are uploaded with the defaults of the client.
+func (s *TestSuite) TestMirrorFromLocalDefaults(c *C) {
+	var requests []string
+	handler := bucketObjectsHandler{
+		objects:  map[string]string{},
+		mutex:    &sync.Mutex{},
+		requests: &requests,
+	}
+	var mutex sync.Mutex
+	headers := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			mutex.Lock()
+			headers[strings.TrimPrefix(r.URL.Path, "/bucket/")] = r.Header
+			mutex.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/", "S3v2")
+	conf.DefaultStorageClass = "REDUCED_REDUNDANCY"
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	localDir, e := ioutil.TempDir("", "mc-mirror-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(localDir)
+	c.Assert(ioutil.WriteFile(filepath.Join(localDir, "photo.jpg"), []byte("photo"), 0644), IsNil)
+
+	stats, errCh := clnt.(*S3Client).MirrorFromLocal(context.Background(), localDir, false, 1, nil)
+	for err := range errCh {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(stats, DeepEquals, MirrorStats{Uploaded: 1})
+	c.Assert(headers["photo.jpg"].Get("X-Amz-Storage-Class"), Equals, "REDUCED_REDUNDANCY")
+	c.Assert(headers["photo.jpg"].Get("Content-Type"), Equals, "image/jpeg")
+}
+
+// Test computing multipart ETags of local files.
+func (s *TestSuite) TestLocalETag(c *C) {
+	data := "Hello, World"
+	etag, e := localETag(strings.NewReader(data), int64(len(data)), objectETag(data))
+	c.Assert(e, IsNil)
+	c.Assert(etag, Equals, objectETag(data))
+
+	sum := md5.Sum([]byte(data))
+	etag, e = localETag(strings.NewReader(data), int64(len(data)), "anything-1")
+	c.Assert(e, IsNil)
+	c.Assert(etag, Equals, objectETag(string(sum[:]))+"-1")
+}
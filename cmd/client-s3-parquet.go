@@ -0,0 +1,506 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+)
+
+// A Parquet file ends with its Thrift encoded metadata, the length of
+// the metadata as a little endian uint32 and the magic.
+const (
+	parquetMagic      = "PAR1"
+	parquetFooterSize = 8
+	// Metadata larger than this isn't fetched.
+	maxParquetMetadataSize = 64 * 1024 * 1024
+)
+
+// ParquetSchema - schema of a Parquet object, read from its footer.
+type ParquetSchema struct {
+	NumRows int64
+	Columns []ParquetColumn
+}
+
+// ParquetColumn - leaf column of a Parquet schema. Name is the path of
+// the column, with the names of its parent groups separated by dots.
+// Type is the physical type, e.g. INT64 or BYTE_ARRAY, LogicalType how
+// it is interpreted, e.g. UTF8 or TIMESTAMP_MILLIS, if annotated.
+type ParquetColumn struct {
+	Name        string
+	Type        string
+	LogicalType string
+	Repetition  string
+}
+
+// Names of the Type, FieldRepetitionType and ConvertedType enums of
+// the Parquet format.
+var (
+	parquetTypes       = []string{"BOOLEAN", "INT32", "INT64", "INT96", "FLOAT", "DOUBLE", "BYTE_ARRAY", "FIXED_LEN_BYTE_ARRAY"}
+	parquetRepetitions = []string{"REQUIRED", "OPTIONAL", "REPEATED"}
+	parquetConverted   = []string{"UTF8", "MAP", "MAP_KEY_VALUE", "LIST", "ENUM", "DECIMAL", "DATE", "TIME_MILLIS", "TIME_MICROS",
+		"TIMESTAMP_MILLIS", "TIMESTAMP_MICROS", "UINT_8", "UINT_16", "UINT_32", "UINT_64", "INT_8", "INT_16", "INT_32", "INT_64",
+		"JSON", "BSON", "INTERVAL"}
+)
+
+// parquetLogicalTypes - names of the fields of the LogicalType union,
+// used by writers which don't set a converted type anymore.
+var parquetLogicalTypes = map[int16]string{
+	1: "STRING", 2: "MAP", 3: "LIST", 4: "ENUM", 5: "DECIMAL", 6: "DATE", 7: "TIME",
+	8: "TIMESTAMP", 10: "INTEGER", 11: "NULL", 12: "JSON", 13: "BSON", 14: "UUID",
+}
+
+// InspectParquetSchema - columns of the Parquet object, read from its
+// footer with range requests instead of running a Select query.
+func (c *S3Client) InspectParquetSchema(ctx context.Context) (ParquetSchema, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return ParquetSchema{}, probe.NewError(BucketNameEmpty{})
+	}
+	if object == "" {
+		return ParquetSchema{}, probe.NewError(ObjectNameEmpty{})
+	}
+
+	footer, err := c.getObjectSuffix(ctx, bucket, object, parquetFooterSize)
+	if err != nil {
+		return ParquetSchema{}, err.Trace(bucket, object)
+	}
+	if len(footer) != parquetFooterSize || string(footer[4:]) != parquetMagic {
+		return ParquetSchema{}, probe.NewError(ParquetInvalid{Object: object, Reason: "no " + parquetMagic + " footer"})
+	}
+	length := int64(binary.LittleEndian.Uint32(footer))
+	if length > maxParquetMetadataSize {
+		return ParquetSchema{}, probe.NewError(ParquetInvalid{
+			Object: object,
+			Reason: "metadata of " + strconv.FormatInt(length, 10) + " bytes is too large",
+		})
+	}
+
+	data, err := c.getObjectSuffix(ctx, bucket, object, length+parquetFooterSize)
+	if err != nil {
+		return ParquetSchema{}, err.Trace(bucket, object)
+	}
+	if int64(len(data)) != length+parquetFooterSize {
+		return ParquetSchema{}, probe.NewError(ParquetInvalid{Object: object, Reason: "object is shorter than its metadata"})
+	}
+	schema, e := parseParquetMetadata(data[:length])
+	if e != nil {
+		return ParquetSchema{}, probe.NewError(ParquetInvalid{Object: object, Reason: e.Error()})
+	}
+	return schema, nil
+}
+
+// getObjectSuffix - last size bytes of the object, all of it if it is
+// smaller.
+func (c *S3Client) getObjectSuffix(ctx context.Context, bucket, object string, size int64) ([]byte, *probe.Error) {
+	header := http.Header{"Range": []string{"bytes=-" + strconv.FormatInt(size, 10)}}
+	if sse := c.readSSE(nil); sse != nil {
+		sse.Marshal(header)
+	}
+	resp, e := c.executeRequest(ctx, http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		object: object,
+		header: header,
+	})
+	if e != nil {
+		switch minio.ToErrorResponse(e).Code {
+		case "NoSuchBucket":
+			return nil, probe.NewError(BucketDoesNotExist{Bucket: bucket})
+		case "NoSuchKey":
+			return nil, probe.NewError(ObjectMissing{})
+		case "AccessDenied":
+			return nil, probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
+		}
+		return nil, probe.NewError(regionError(bucket, e))
+	}
+	defer resp.Body.Close()
+	data, e := ioutil.ReadAll(resp.Body)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	return data, nil
+}
+
+// parquetSchemaElement - fields of a SchemaElement used to build the
+// columns of a schema.
+type parquetSchemaElement struct {
+	name        string
+	typ         int32
+	hasType     bool
+	repetition  int32
+	numChildren int32
+	converted   int32
+	hasConvert  bool
+	logical     string
+}
+
+// parseParquetMetadata - schema of the Thrift compact encoded
+// FileMetaData of a Parquet file.
+func parseParquetMetadata(data []byte) (ParquetSchema, error) {
+	r := &thriftCompactReader{data: data}
+	var schema ParquetSchema
+	var elements []parquetSchemaElement
+	var lastID int16
+	for {
+		id, typ, e := r.fieldHeader(&lastID)
+		if e != nil {
+			return ParquetSchema{}, e
+		}
+		if typ == thriftStop {
+			break
+		}
+		switch {
+		case id == 2 && typ == thriftList:
+			size, elemType, e := r.listHeader()
+			if e != nil {
+				return ParquetSchema{}, e
+			}
+			if elemType != thriftStruct {
+				return ParquetSchema{}, errors.New("schema is not a list of elements")
+			}
+			for i := 0; i < size; i++ {
+				element, e := r.schemaElement()
+				if e != nil {
+					return ParquetSchema{}, e
+				}
+				elements = append(elements, element)
+			}
+		case id == 3 && typ == thriftI64:
+			if schema.NumRows, e = r.varint64(); e != nil {
+				return ParquetSchema{}, e
+			}
+		default:
+			if e = r.skip(typ, 0); e != nil {
+				return ParquetSchema{}, e
+			}
+		}
+	}
+	if len(elements) == 0 {
+		return ParquetSchema{}, errors.New("metadata has no schema")
+	}
+
+	// Elements are the depth first walk of the schema tree, the first
+	// one being the root.
+	var walk func(i int, prefix string, depth int) (int, error)
+	walk = func(i int, prefix string, depth int) (int, error) {
+		if i >= len(elements) {
+			return 0, errors.New("schema has less elements than declared")
+		}
+		if depth > thriftMaxDepth {
+			return 0, errors.New("schema is nested too deep")
+		}
+		element := elements[i]
+		name := prefix + element.name
+		if element.numChildren <= 0 {
+			schema.Columns = append(schema.Columns, element.column(name))
+			return i + 1, nil
+		}
+		next := i + 1
+		for child := int32(0); child < element.numChildren; child++ {
+			var e error
+			if next, e = walk(next, name+".", depth+1); e != nil {
+				return 0, e
+			}
+		}
+		return next, nil
+	}
+	next := 1
+	for child := int32(0); child < elements[0].numChildren; child++ {
+		var e error
+		if next, e = walk(next, "", 1); e != nil {
+			return ParquetSchema{}, e
+		}
+	}
+	return schema, nil
+}
+
+// column - leaf column of the element, named name.
+func (el parquetSchemaElement) column(name string) ParquetColumn {
+	column := ParquetColumn{Name: name, LogicalType: el.logical}
+	if el.hasType {
+		column.Type = parquetEnumName(parquetTypes, el.typ)
+	}
+	column.Repetition = parquetEnumName(parquetRepetitions, el.repetition)
+	if el.hasConvert {
+		column.LogicalType = parquetEnumName(parquetConverted, el.converted)
+	}
+	return column
+}
+
+// parquetEnumName - name of the value of an enum, the number itself if
+// unknown.
+func parquetEnumName(names []string, value int32) string {
+	if value >= 0 && int(value) < len(names) {
+		return names[value]
+	}
+	return strconv.Itoa(int(value))
+}
+
+// schemaElement - read a SchemaElement struct.
+func (r *thriftCompactReader) schemaElement() (parquetSchemaElement, error) {
+	var element parquetSchemaElement
+	var lastID int16
+	for {
+		id, typ, e := r.fieldHeader(&lastID)
+		if e != nil {
+			return element, e
+		}
+		if typ == thriftStop {
+			return element, nil
+		}
+		switch {
+		case id == 1 && typ == thriftI32:
+			element.typ, e = r.varint32()
+			element.hasType = true
+		case id == 3 && typ == thriftI32:
+			element.repetition, e = r.varint32()
+		case id == 4 && typ == thriftBinary:
+			var name []byte
+			name, e = r.binary()
+			element.name = string(name)
+		case id == 5 && typ == thriftI32:
+			element.numChildren, e = r.varint32()
+		case id == 6 && typ == thriftI32:
+			element.converted, e = r.varint32()
+			element.hasConvert = true
+		case id == 10 && typ == thriftStruct:
+			element.logical, e = r.logicalType()
+		default:
+			e = r.skip(typ, 0)
+		}
+		if e != nil {
+			return element, e
+		}
+	}
+}
+
+// logicalType - read a LogicalType union, returns the name of its set
+// field.
+func (r *thriftCompactReader) logicalType() (string, error) {
+	var name string
+	var lastID int16
+	for {
+		id, typ, e := r.fieldHeader(&lastID)
+		if e != nil {
+			return "", e
+		}
+		if typ == thriftStop {
+			return name, nil
+		}
+		if n, ok := parquetLogicalTypes[id]; ok {
+			name = n
+		}
+		if e = r.skip(typ, 0); e != nil {
+			return "", e
+		}
+	}
+}
+
+// Types of the Thrift compact protocol.
+const (
+	thriftStop     = 0
+	thriftTrue     = 1
+	thriftFalse    = 2
+	thriftByte     = 3
+	thriftI16      = 4
+	thriftI32      = 5
+	thriftI64      = 6
+	thriftDouble   = 7
+	thriftBinary   = 8
+	thriftList     = 9
+	thriftSet      = 10
+	thriftMap      = 11
+	thriftStruct   = 12
+	thriftMaxDepth = 64
+)
+
+var errThriftTruncated = errors.New("metadata is truncated")
+
+// thriftCompactReader - minimal reader of the Thrift compact protocol,
+// enough to decode the metadata of Parquet files.
+type thriftCompactReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftCompactReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errThriftTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// uvarint - read an unsigned LEB128 varint.
+func (r *thriftCompactReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errThriftTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+// varint64 - read a zigzag encoded integer.
+func (r *thriftCompactReader) varint64() (int64, error) {
+	v, e := r.uvarint()
+	if e != nil {
+		return 0, e
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+func (r *thriftCompactReader) varint32() (int32, error) {
+	v, e := r.varint64()
+	return int32(v), e
+}
+
+func (r *thriftCompactReader) binary() ([]byte, error) {
+	length, e := r.uvarint()
+	if e != nil {
+		return nil, e
+	}
+	if length > uint64(len(r.data)-r.pos) {
+		return nil, errThriftTruncated
+	}
+	b := r.data[r.pos : r.pos+int(length)]
+	r.pos += int(length)
+	return b, nil
+}
+
+// fieldHeader - read the header of the next field of a struct, whose
+// previous field ID is lastID.
+func (r *thriftCompactReader) fieldHeader(lastID *int16) (int16, byte, error) {
+	b, e := r.readByte()
+	if e != nil {
+		return 0, 0, e
+	}
+	typ := b & 0x0f
+	if typ == thriftStop {
+		return 0, thriftStop, nil
+	}
+	if delta := int16(b >> 4); delta != 0 {
+		*lastID += delta
+	} else {
+		id, e := r.varint64()
+		if e != nil {
+			return 0, 0, e
+		}
+		*lastID = int16(id)
+	}
+	return *lastID, typ, nil
+}
+
+// listHeader - read the header of a list or set.
+func (r *thriftCompactReader) listHeader() (int, byte, error) {
+	b, e := r.readByte()
+	if e != nil {
+		return 0, 0, e
+	}
+	size := uint64(b >> 4)
+	if size == 0x0f {
+		if size, e = r.uvarint(); e != nil {
+			return 0, 0, e
+		}
+	}
+	// Every element takes at least a byte.
+	if size > uint64(len(r.data)-r.pos) {
+		return 0, 0, errThriftTruncated
+	}
+	return int(size), b & 0x0f, nil
+}
+
+// skip - skip a value of type typ.
+func (r *thriftCompactReader) skip(typ byte, depth int) error {
+	if depth > thriftMaxDepth {
+		return errors.New("metadata is nested too deep")
+	}
+	var e error
+	switch typ {
+	case thriftTrue, thriftFalse:
+		// Values of boolean fields are part of their header.
+	case thriftByte:
+		_, e = r.readByte()
+	case thriftI16, thriftI32, thriftI64:
+		_, e = r.uvarint()
+	case thriftDouble:
+		if len(r.data)-r.pos < 8 {
+			return errThriftTruncated
+		}
+		r.pos += 8
+	case thriftBinary:
+		_, e = r.binary()
+	case thriftList, thriftSet:
+		var size int
+		var elemType byte
+		if size, elemType, e = r.listHeader(); e != nil {
+			return e
+		}
+		for i := 0; i < size && e == nil; i++ {
+			e = r.skipElement(elemType, depth+1)
+		}
+	case thriftMap:
+		var size uint64
+		if size, e = r.uvarint(); e != nil || size == 0 {
+			return e
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return errThriftTruncated
+		}
+		var types byte
+		if types, e = r.readByte(); e != nil {
+			return e
+		}
+		for i := uint64(0); i < size && e == nil; i++ {
+			if e = r.skipElement(types>>4, depth+1); e == nil {
+				e = r.skipElement(types&0x0f, depth+1)
+			}
+		}
+	case thriftStruct:
+		var lastID int16
+		for {
+			var fieldType byte
+			if _, fieldType, e = r.fieldHeader(&lastID); e != nil || fieldType == thriftStop {
+				return e
+			}
+			if e = r.skip(fieldType, depth+1); e != nil {
+				return e
+			}
+		}
+	default:
+		return errors.New("metadata has unknown type " + strconv.Itoa(int(typ)))
+	}
+	return e
+}
+
+// skipElement - skip an element of a list, set or map, booleans of
+// which take a byte each.
+func (r *thriftCompactReader) skipElement(typ byte, depth int) error {
+	if typ == thriftTrue || typ == thriftFalse {
+		_, e := r.readByte()
+		return e
+	}
+	return r.skip(typ, depth)
+}
@@ -0,0 +1,223 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+// thriftCompactWriter - encodes the few Thrift compact types used by
+// the Parquet metadata of tests.
+type thriftCompactWriter struct {
+	data   []byte
+	lastID []int16
+}
+
+func (w *thriftCompactWriter) field(id int16, typ byte) {
+	last := &w.lastID[len(w.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.data = append(w.data, byte(delta)<<4|typ)
+	} else {
+		w.data = append(w.data, typ)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftCompactWriter) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.data = append(w.data, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func (w *thriftCompactWriter) varint(v int64) {
+	w.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftCompactWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftCompactWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftCompactWriter) str(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.uvarint(uint64(len(v)))
+	w.data = append(w.data, v...)
+}
+
+func (w *thriftCompactWriter) list(id int16, elemType byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.data = append(w.data, byte(size)<<4|elemType)
+	} else {
+		w.data = append(w.data, 0xf0|elemType)
+		w.uvarint(uint64(size))
+	}
+}
+
+func (w *thriftCompactWriter) beginStruct() {
+	w.lastID = append(w.lastID, 0)
+}
+
+func (w *thriftCompactWriter) endStruct() {
+	w.data = append(w.data, thriftStop)
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+// testParquetMetadata - FileMetaData of a file of 42 rows with the
+// schema:
+//
+//	message schema {
+//	  required int64 id;
+//	  optional binary name (UTF8);
+//	  optional group address {
+//	    optional binary city (STRING);
+//	    optional int32 zip;
+//	  }
+//	}
+func testParquetMetadata() []byte {
+	w := &thriftCompactWriter{}
+	w.beginStruct()
+	w.i32(1, 1)
+	w.list(2, thriftStruct, 6)
+	elements := []func(){
+		func() { w.str(4, "schema"); w.i32(5, 3) },
+		func() { w.i32(1, 2); w.i32(3, 0); w.str(4, "id") },
+		func() { w.i32(1, 6); w.i32(3, 1); w.str(4, "name"); w.i32(6, 0) },
+		func() { w.i32(3, 1); w.str(4, "address"); w.i32(5, 2) },
+		func() {
+			w.i32(1, 6)
+			w.i32(3, 1)
+			w.str(4, "city")
+			// logicalType, a LogicalType union set to STRING.
+			w.field(10, thriftStruct)
+			w.beginStruct()
+			w.field(1, thriftStruct)
+			w.beginStruct()
+			w.endStruct()
+			w.endStruct()
+		},
+		func() { w.i32(1, 1); w.i32(3, 1); w.str(4, "zip") },
+	}
+	for _, element := range elements {
+		w.beginStruct()
+		element()
+		w.endStruct()
+	}
+	w.i64(3, 42)
+	// row_groups, skipped.
+	w.list(4, thriftStruct, 1)
+	w.beginStruct()
+	w.i64(2, 1024)
+	w.i64(3, 42)
+	w.endStruct()
+	w.str(6, "parquet-mr version 1.10.1")
+	w.endStruct()
+	return w.data
+}
+
+// parquetFile - Parquet file of the metadata, without column chunks.
+func parquetFile(metadata []byte, length uint32) []byte {
+	file := []byte(parquetMagic)
+	file = append(file, metadata...)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], length)
+	file = append(file, buf[:]...)
+	return append(file, parquetMagic...)
+}
+
+// suffixRangeHandler serves objects, honoring suffix byte ranges.
+type suffixRangeHandler struct {
+	objects map[string][]byte
+}
+
+func (h suffixRangeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, ok := h.objects[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>"))
+		return
+	}
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); strings.HasPrefix(rng, "bytes=-") {
+		n, e := strconv.Atoi(strings.TrimPrefix(rng, "bytes=-"))
+		if e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if n < len(data) {
+			data = data[len(data)-n:]
+		}
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Test reading the schema of Parquet objects from their footer.
+func (s *TestSuite) TestInspectParquetSchema(c *C) {
+	metadata := testParquetMetadata()
+	server := httptest.NewServer(suffixRangeHandler{objects: map[string][]byte{
+		"/bucket/data.parquet": parquetFile(metadata, uint32(len(metadata))),
+		"/bucket/data.csv":     []byte("id,name\n1,a\n"),
+		"/bucket/short":        parquetFile(nil, 1000),
+	}})
+	defer server.Close()
+
+	newClient := func(object string) *S3Client {
+		conf := testConfig(server.URL+"/bucket/"+object, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		return clnt.(*S3Client)
+	}
+
+	schema, err := newClient("data.parquet").InspectParquetSchema(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(schema, DeepEquals, ParquetSchema{
+		NumRows: 42,
+		Columns: []ParquetColumn{
+			{Name: "id", Type: "INT64", Repetition: "REQUIRED"},
+			{Name: "name", Type: "BYTE_ARRAY", LogicalType: "UTF8", Repetition: "OPTIONAL"},
+			{Name: "address.city", Type: "BYTE_ARRAY", LogicalType: "STRING", Repetition: "OPTIONAL"},
+			{Name: "address.zip", Type: "INT32", Repetition: "OPTIONAL"},
+		},
+	})
+
+	for _, object := range []string{"data.csv", "short"} {
+		_, err = newClient(object).InspectParquetSchema(context.Background())
+		c.Assert(err, NotNil, Commentf("object %s", object))
+		_, ok := err.ToGoError().(ParquetInvalid)
+		c.Assert(ok, Equals, true, Commentf("object %s", object))
+	}
+
+	_, err = newClient("missing").InspectParquetSchema(context.Background())
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ObjectMissing)
+	c.Assert(ok, Equals, true)
+}
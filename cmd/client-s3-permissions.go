@@ -0,0 +1,173 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+)
+
+// errPermissionUndetermined - returned by permission probes which can't
+// tell whether an action is allowed without side effects.
+var errPermissionUndetermined = errors.New("permission undetermined")
+
+// permissionProbes - dry-run requests telling whether an action is
+// allowed on bucket, key is a key that doesn't exist under the client
+// URL. A nil error means the action is allowed.
+var permissionProbes = map[string]func(c *S3Client, bucket, object, key string) error{
+	"s3:ListBucket": func(c *S3Client, bucket, object, key string) error {
+		_, e := c.hasKeys(c.api, bucket, object, "")
+		return e
+	},
+	"s3:GetObject": func(c *S3Client, bucket, object, key string) error {
+		// A missing key is reported as such only when reading is
+		// allowed, without s3:ListBucket S3 denies it either way.
+		named := object != "" && !strings.HasSuffix(object, string(c.targetURL.Separator))
+		if named {
+			key = object
+		}
+		_, e := c.api.StatObject(bucket, key, minio.StatObjectOptions{})
+		if e != nil && minio.ToErrorResponse(e).Code == "NoSuchKey" {
+			return nil
+		}
+		if e != nil && !named && isAccessDenied(e) {
+			if _, le := c.hasKeys(c.api, bucket, object, ""); le != nil && isAccessDenied(le) {
+				return errPermissionUndetermined
+			}
+		}
+		return e
+	},
+	"s3:PutObject": func(c *S3Client, bucket, object, key string) error {
+		// Starting an upload needs the same permission as a put
+		// but doesn't leave an object behind once aborted.
+		core := minio.Core{Client: c.api}
+		uploadID, e := core.NewMultipartUpload(bucket, key, minio.PutObjectOptions{})
+		if e != nil {
+			return e
+		}
+		if e = core.AbortMultipartUpload(bucket, key, uploadID); e != nil {
+			return fmt.Errorf("upload %s of %s was left behind: %v", uploadID, key, e)
+		}
+		return nil
+	},
+	"s3:DeleteObject": func(c *S3Client, bucket, object, key string) error {
+		// Removing a missing key succeeds when allowed, but records
+		// a delete marker for it unless versioning was never enabled.
+		versioned, e := c.bucketVersioned(bucket)
+		if e != nil {
+			if isAccessDenied(e) {
+				return errPermissionUndetermined
+			}
+			return e
+		}
+		if versioned {
+			return errPermissionUndetermined
+		}
+		return c.api.RemoveObject(bucket, key)
+	},
+	"s3:GetBucketPolicy": func(c *S3Client, bucket, object, key string) error {
+		_, e := c.api.GetBucketPolicy(bucket)
+		return e
+	},
+}
+
+// isAccessDenied - tells whether e is an S3 access denied error.
+func isAccessDenied(e error) bool {
+	errResponse := minio.ToErrorResponse(e)
+	return errResponse.Code == "AccessDenied" || errResponse.StatusCode == http.StatusForbidden
+}
+
+// bucketVersioned - tells whether versioning is enabled or suspended
+// on bucket, i.e. whether it was ever enabled.
+func (c *S3Client) bucketVersioned(bucket string) (bool, error) {
+	resp, e := c.executeRequest(context.Background(), http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"versioning": []string{""}},
+	})
+	if e != nil {
+		return false, e
+	}
+	defer resp.Body.Close()
+	var config struct {
+		Status string `xml:"Status"`
+	}
+	if e = xml.NewDecoder(resp.Body).Decode(&config); e != nil {
+		return false, e
+	}
+	return config.Status != "", nil
+}
+
+// CheckBucketPermissions - tells which of the actions the credentials
+// are allowed to perform on the bucket, with dry-run requests under
+// the client URL. Supported actions are s3:ListBucket, s3:GetObject,
+// s3:PutObject, s3:DeleteObject and s3:GetBucketPolicy. Actions which
+// can't be checked without side effects are missing from the result:
+// s3:GetObject when neither s3:ListBucket is allowed nor an object is
+// named by the URL, and s3:DeleteObject when the bucket is or was
+// versioned, or its versioning can't be read.
+func (c *S3Client) CheckBucketPermissions(actions []string) (map[string]bool, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return nil, probe.NewError(BucketNameEmpty{})
+	}
+	for _, action := range actions {
+		if _, ok := permissionProbes[action]; !ok {
+			return nil, errInvalidArgument().Trace("unsupported action " + action)
+		}
+	}
+
+	prefix := object
+	if i := strings.LastIndex(prefix, string(c.targetURL.Separator)); i >= 0 {
+		prefix = prefix[:i+1]
+	} else {
+		prefix = ""
+	}
+	key := prefix + ".mc-permission-check-" + newRandomID(16)
+
+	allowed := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		e := permissionProbes[action](c, bucket, object, key)
+		if e == nil {
+			allowed[action] = true
+			continue
+		}
+		if e == errPermissionUndetermined {
+			continue
+		}
+		errResponse := minio.ToErrorResponse(e)
+		switch {
+		case isAccessDenied(e):
+			allowed[action] = false
+		case errResponse.Code == "NoSuchBucketPolicy":
+			// Reading the policy was allowed, there is none.
+			allowed[action] = true
+		case errResponse.Code == "NoSuchBucket":
+			return nil, probe.NewError(BucketDoesNotExist{Bucket: bucket})
+		default:
+			return nil, probe.NewError(regionError(bucket, e)).Trace(action)
+		}
+	}
+	return allowed, nil
+}
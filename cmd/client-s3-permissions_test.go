@@ -0,0 +1,144 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+// permissionsHandler denies requests of the given methods and serves
+// an empty bucket otherwise, versioned if asked to.
+type permissionsHandler struct {
+	denied    map[string]bool
+	versioned bool
+}
+
+func (h permissionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	_, policy := query["policy"]
+	_, versioning := query["versioning"]
+	if r.Method == "GET" && location {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	var response string
+	status := http.StatusOK
+	switch {
+	case h.denied[r.Method]:
+		if r.Method != "HEAD" {
+			response = "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>"
+		}
+		status = http.StatusForbidden
+	case r.Method == "GET" && policy:
+		response = "<Error><Code>NoSuchBucketPolicy</Code><Message>The bucket policy does not exist</Message></Error>"
+		status = http.StatusNotFound
+	case r.Method == "GET" && versioning:
+		response = "<VersioningConfiguration xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"></VersioningConfiguration>"
+		if h.versioned {
+			response = "<VersioningConfiguration xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Status>Suspended</Status></VersioningConfiguration>"
+		}
+	case r.Method == "GET" && r.URL.Path == "/bucket/":
+		response = "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"><IsTruncated>false</IsTruncated><MaxKeys>1</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>"
+	case r.Method == "HEAD":
+		status = http.StatusNotFound
+	case r.Method == "POST" && uploads:
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		response = "<InitiateMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>" + key + "</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>"
+	case r.Method == "DELETE":
+		status = http.StatusNoContent
+	default:
+		status = http.StatusBadRequest
+	}
+	if response != "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(response))
+}
+
+// Test checking permissions with dry-run requests.
+func (s *TestSuite) TestCheckBucketPermissions(c *C) {
+	actions := []string{"s3:ListBucket", "s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:GetBucketPolicy"}
+	testCases := []struct {
+		denied    map[string]bool
+		versioned bool
+		allowed   map[string]bool
+	}{
+		{
+			map[string]bool{},
+			false,
+			map[string]bool{"s3:ListBucket": true, "s3:GetObject": true, "s3:PutObject": true, "s3:DeleteObject": true, "s3:GetBucketPolicy": true},
+		},
+		{
+			map[string]bool{"POST": true, "DELETE": true},
+			false,
+			map[string]bool{"s3:ListBucket": true, "s3:GetObject": true, "s3:PutObject": false, "s3:DeleteObject": false, "s3:GetBucketPolicy": true},
+		},
+		{
+			map[string]bool{"HEAD": true},
+			false,
+			map[string]bool{"s3:ListBucket": true, "s3:GetObject": false, "s3:PutObject": true, "s3:DeleteObject": true, "s3:GetBucketPolicy": true},
+		},
+		// Removing a key of a versioned bucket would leave a delete marker.
+		{
+			map[string]bool{},
+			true,
+			map[string]bool{"s3:ListBucket": true, "s3:GetObject": true, "s3:PutObject": true, "s3:GetBucketPolicy": true},
+		},
+		// Without s3:ListBucket a missing key is denied either way.
+		{
+			map[string]bool{"GET": true, "HEAD": true},
+			false,
+			map[string]bool{"s3:ListBucket": false, "s3:PutObject": true, "s3:GetBucketPolicy": false},
+		},
+	}
+	for i, testCase := range testCases {
+		server := httptest.NewServer(permissionsHandler{denied: testCase.denied, versioned: testCase.versioned})
+
+		conf := testConfig(server.URL+"/bucket/dir/", "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		s3c := clnt.(*S3Client)
+
+		allowed, err := s3c.CheckBucketPermissions(actions)
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(allowed, DeepEquals, testCase.allowed, Commentf("test %d", i+1))
+
+		_, err = s3c.CheckBucketPermissions([]string{"s3:GetObject", "iam:CreateUser"})
+		c.Assert(err, NotNil)
+		server.Close()
+	}
+
+	// Uploads which can't be aborted are reported.
+	server := httptest.NewServer(permissionsHandler{denied: map[string]bool{"DELETE": true}})
+	defer server.Close()
+	conf := testConfig(server.URL+"/bucket/dir/", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.(*S3Client).CheckBucketPermissions([]string{"s3:PutObject"})
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.ToGoError().Error(), "upload upload of dir/.mc-permission-check-"), Equals, true, Commentf("%s", err))
+}
@@ -0,0 +1,254 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// defaultPlanCheckpointEvery - operations applied between two
+// checkpoints of Plan.
+const defaultPlanCheckpointEvery = 1000
+
+// PlanAction - what a PlanOperation does to the target.
+type PlanAction string
+
+// Actions of PlanOperation.
+const (
+	PlanCopy   PlanAction = "copy"
+	PlanDelete PlanAction = "delete"
+	PlanSkip   PlanAction = "skip"
+)
+
+// PlanOptions - options of Plan.
+type PlanOptions struct {
+	// Comparison of the objects of the source and the target, objects
+	// which differ are copied.
+	DiffOptions
+	// Remove plans deleting the objects of the target missing from the
+	// source, which are skipped otherwise.
+	Remove bool
+	// Shards are disjoint key prefixes, relative to the prefix of the
+	// source, planned one after another and checkpointed separately.
+	// Objects outside of them are left out. The whole prefix is one
+	// shard if unset.
+	Shards []string
+	// Checkpoint, if not nil, is read when the plan starts, which
+	// resumes from the last checkpoint it holds. Checkpoints are
+	// appended to it as JSON lines every CheckpointEvery operations,
+	// defaultPlanCheckpointEvery if unset, and whenever a shard or the
+	// plan ends.
+	Checkpoint      io.ReadWriter
+	CheckpointEvery int
+}
+
+// PlanOperation - operation bringing a key of the target in line with
+// the source.
+type PlanOperation struct {
+	// Key is relative to the prefixes of the clients.
+	Key    string     `json:"key"`
+	Action PlanAction `json:"action"`
+	// Reason is how the objects differ, empty for skipped objects
+	// present in both.
+	Reason DiffKind    `json:"reason,omitempty"`
+	Source *DiffObject `json:"source,omitempty"`
+	Target *DiffObject `json:"target,omitempty"`
+}
+
+// PlanShard - progress of a shard of Plan.
+type PlanShard struct {
+	// LastKey is the key of the last operation applied.
+	LastKey string `json:"lastKey,omitempty"`
+	Done    bool   `json:"done,omitempty"`
+}
+
+// PlanCheckpoint - progress of Plan, counting the operations applied
+// and the bytes of the objects copied.
+type PlanCheckpoint struct {
+	Shards  map[string]PlanShard `json:"shards"`
+	Copies  int64                `json:"copies"`
+	Deletes int64                `json:"deletes"`
+	Skips   int64                `json:"skips"`
+	Bytes   int64                `json:"bytes"`
+}
+
+// count - add an operation applied to the checkpoint.
+func (p *PlanCheckpoint) count(op PlanOperation) {
+	switch op.Action {
+	case PlanCopy:
+		p.Copies++
+		p.Bytes += op.Source.Size
+	case PlanDelete:
+		p.Deletes++
+	case PlanSkip:
+		p.Skips++
+	}
+}
+
+// write - append the checkpoint to w as a line of JSON.
+func (p *PlanCheckpoint) write(w io.Writer) *probe.Error {
+	data, e := json.Marshal(p)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if _, e = w.Write(append(data, '\n')); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// readPlanCheckpoint - the last checkpoint appended to r, nil if there
+// is none.
+func readPlanCheckpoint(r io.Reader) (*PlanCheckpoint, *probe.Error) {
+	var last *PlanCheckpoint
+	decoder := json.NewDecoder(r)
+	for {
+		checkpoint := &PlanCheckpoint{}
+		e := decoder.Decode(checkpoint)
+		switch {
+		case e == io.EOF || e == io.ErrUnexpectedEOF:
+			// A checkpoint cut short by a crash is ignored.
+			return last, nil
+		case e != nil:
+			return nil, probe.NewError(e)
+		}
+		if checkpoint.Shards == nil {
+			checkpoint.Shards = make(map[string]PlanShard)
+		}
+		last = checkpoint
+	}
+}
+
+// newPlanOperation - operation of the record of a key.
+func newPlanOperation(record DiffRecord, remove bool) PlanOperation {
+	op := PlanOperation{Key: record.Key, Reason: record.Kind, Source: record.First, Target: record.Second}
+	switch {
+	case record.Kind == "":
+		op.Action = PlanSkip
+	case record.Kind == DiffOnlyInSecond && remove:
+		op.Action = PlanDelete
+	case record.Kind == DiffOnlyInSecond:
+		op.Action = PlanSkip
+	default:
+		op.Action = PlanCopy
+	}
+	return op
+}
+
+// checkPlanShards - fail if a shard is a prefix of another.
+func checkPlanShards(shards []string) *probe.Error {
+	for i, shard := range shards {
+		for j, other := range shards {
+			if i != j && strings.HasPrefix(other, shard) {
+				return errInvalidArgument().Trace(shard, other)
+			}
+		}
+	}
+	return nil
+}
+
+// Plan - walk the objects under the prefix of the client, the source,
+// and those under the prefix of target as Diff does, shard by shard,
+// calling apply in key order with the operation copying, deleting or
+// skipping every key. Executing operations is up to apply, one is
+// counted as applied once apply returns nil. The plan stops at the
+// first error of apply, a listing or a comparison, or once ctx is
+// canceled, after checkpointing the operations applied so far, and
+// resumes after the last of them the next time it runs with the same
+// checkpoint. The returned checkpoint counts the operations of resumed
+// runs too.
+func (c *S3Client) Plan(ctx context.Context, target *S3Client, opts PlanOptions, apply func(op PlanOperation) *probe.Error) (PlanCheckpoint, *probe.Error) {
+	checkpoint := PlanCheckpoint{Shards: make(map[string]PlanShard)}
+	shards := opts.Shards
+	if len(shards) == 0 {
+		shards = []string{""}
+	}
+	if err := checkPlanShards(shards); err != nil {
+		return checkpoint, err.Trace()
+	}
+	if opts.Checkpoint != nil {
+		resumed, err := readPlanCheckpoint(opts.Checkpoint)
+		if err != nil {
+			return checkpoint, err.Trace()
+		}
+		if resumed != nil {
+			checkpoint = *resumed
+		}
+	}
+	every := opts.CheckpointEvery
+	if every <= 0 {
+		every = defaultPlanCheckpointEvery
+	}
+	applied := 0
+	save := func() *probe.Error {
+		applied = 0
+		if opts.Checkpoint == nil {
+			return nil
+		}
+		return checkpoint.write(opts.Checkpoint).Trace()
+	}
+
+	for _, shard := range shards {
+		state := checkpoint.Shards[shard]
+		if state.Done {
+			continue
+		}
+		var stopErr *probe.Error
+		err := c.diffWalk(ctx, target, opts.DiffOptions, shard, state.LastKey, func(record DiffRecord) bool {
+			switch {
+			case ctx.Err() != nil:
+				stopErr = probe.NewError(ctx.Err())
+				return false
+			case record.Error != nil:
+				stopErr = record.Error
+				return false
+			}
+			op := newPlanOperation(record, opts.Remove)
+			if stopErr = apply(op); stopErr != nil {
+				stopErr = stopErr.Trace(record.Key)
+				return false
+			}
+			checkpoint.count(op)
+			state.LastKey = record.Key
+			checkpoint.Shards[shard] = state
+			if applied++; applied >= every {
+				stopErr = save()
+			}
+			return stopErr == nil
+		})
+		if err == nil {
+			err = stopErr
+		}
+		if err != nil {
+			// Keep the progress made, the error of the walk matters
+			// more than one saving it.
+			save()
+			return checkpoint, err.Trace(c.targetURL.String(), target.targetURL.String())
+		}
+		state.Done = true
+		checkpoint.Shards[shard] = state
+		if err = save(); err != nil {
+			return checkpoint, err.Trace()
+		}
+	}
+	return checkpoint, nil
+}
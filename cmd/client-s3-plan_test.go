@@ -0,0 +1,107 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+// Test planning a mirror, and resuming it from its checkpoint.
+func (s *TestSuite) TestPlan(c *C) {
+	server := httptest.NewServer(diffHandler{objects: map[string]diffObject{
+		"a/x/1": {data: []byte("one")},
+		"b/x/1": {data: []byte("one")},
+		"a/x/2": {data: []byte("two")},
+		"a/x/3": {data: []byte("three")},
+		"b/x/3": {data: []byte("thr33")},
+		"b/x/4": {data: []byte("four")},
+		"a/y/5": {data: []byte("five")},
+		"b/y/5": {data: []byte("five")},
+		"a/y/6": {data: []byte("six!")},
+		"a/z/7": {data: []byte("seven")},
+	}})
+	defer server.Close()
+
+	source := newTestS3Client(c, server.URL+"/bucket/a/", "S3v4")
+	target := newTestS3Client(c, server.URL+"/bucket/b/", "S3v4")
+
+	plan := func(opts PlanOptions, failAt string) ([]string, PlanCheckpoint, *probe.Error) {
+		var ops []string
+		checkpoint, err := source.Plan(context.Background(), target, opts, func(op PlanOperation) *probe.Error {
+			if op.Key == failAt {
+				return probe.NewError(errors.New("copy failed"))
+			}
+			ops = append(ops, op.Key+" "+string(op.Action)+" "+string(op.Reason))
+			return nil
+		})
+		return ops, checkpoint, err
+	}
+
+	ops, checkpoint, err := plan(PlanOptions{}, "")
+	c.Assert(err, IsNil)
+	c.Assert(ops, DeepEquals, []string{
+		"x/1 skip ", "x/2 copy only-in-first", "x/3 copy etag", "x/4 skip only-in-second",
+		"y/5 skip ", "y/6 copy only-in-first", "z/7 copy only-in-first",
+	})
+	c.Assert(checkpoint.Copies, Equals, int64(4))
+	c.Assert(checkpoint.Skips, Equals, int64(3))
+	c.Assert(checkpoint.Bytes, Equals, int64(17))
+
+	shards := []string{"x/", "y/"}
+	ops, checkpoint, err = plan(PlanOptions{Remove: true, Shards: shards}, "")
+	c.Assert(err, IsNil)
+	c.Assert(ops, DeepEquals, []string{
+		"x/1 skip ", "x/2 copy only-in-first", "x/3 copy etag", "x/4 delete only-in-second",
+		"y/5 skip ", "y/6 copy only-in-first",
+	})
+	c.Assert(checkpoint, DeepEquals, PlanCheckpoint{
+		Shards: map[string]PlanShard{"x/": {LastKey: "x/4", Done: true}, "y/": {LastKey: "y/6", Done: true}},
+		Copies: 3, Deletes: 1, Skips: 2, Bytes: 12,
+	})
+
+	// A failed operation stops the plan, which resumes with it.
+	var buf bytes.Buffer
+	opts := PlanOptions{Remove: true, Shards: shards, Checkpoint: &buf, CheckpointEvery: 2}
+	ops, checkpoint, err = plan(opts, "x/4")
+	c.Assert(err, NotNil)
+	c.Assert(ops, HasLen, 3)
+	c.Assert(strings.Count(buf.String(), "\n"), Equals, 2)
+	c.Assert(checkpoint.Shards, DeepEquals, map[string]PlanShard{"x/": {LastKey: "x/3"}})
+
+	ops, checkpoint, err = plan(opts, "")
+	c.Assert(err, IsNil)
+	c.Assert(ops, DeepEquals, []string{"x/4 delete only-in-second", "y/5 skip ", "y/6 copy only-in-first"})
+	c.Assert(checkpoint.Copies, Equals, int64(3))
+	c.Assert(checkpoint.Deletes, Equals, int64(1))
+	c.Assert(checkpoint.Skips, Equals, int64(2))
+	c.Assert(checkpoint.Bytes, Equals, int64(12))
+
+	// Nothing is left once all shards are done.
+	ops, _, err = plan(opts, "")
+	c.Assert(err, IsNil)
+	c.Assert(ops, HasLen, 0)
+
+	_, _, err = plan(PlanOptions{Shards: []string{"x/", "x/1"}}, "")
+	c.Assert(err, NotNil)
+}
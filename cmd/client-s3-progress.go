@@ -0,0 +1,98 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// TransferProgress - receives the progress of Copy and Put calls: the
+// bytes transferred so far out of total, and the part of a multipart
+// transfer they reached, 0 for transfers made at once. Total is -1
+// for uploads of unknown size. Calls may come from several goroutines,
+// one at a time.
+type TransferProgress interface {
+	Progress(done, total int64, part int)
+}
+
+// TransferProgressFunc - function receiving progress as a
+// TransferProgress.
+type TransferProgressFunc func(done, total int64, part int)
+
+// Progress - call f.
+func (f TransferProgressFunc) Progress(done, total int64, part int) {
+	f(done, total, part)
+}
+
+// transferProgressKey - context key of the TransferProgress set by
+// WithTransferProgress.
+type transferProgressKey struct{}
+
+// WithTransferProgress - returns a context making Copy and Put calls
+// report their progress to p, along with their progress reader. The
+// total is reported first, taken from a Stat of the source of copies,
+// then every part copied as it completes. Server side copies of
+// objects larger than a part, which are otherwise made at once, are
+// made part by part so that their progress keeps moving.
+func WithTransferProgress(ctx context.Context, p TransferProgress) context.Context {
+	return context.WithValue(ctx, transferProgressKey{}, p)
+}
+
+// transferProgress - the TransferProgress set on ctx, if any.
+func transferProgress(ctx context.Context) TransferProgress {
+	p, _ := ctx.Value(transferProgressKey{}).(TransferProgress)
+	return p
+}
+
+// progressReader - progress reader of an upload reporting the bytes
+// read from it to a TransferProgress as well as to the progress reader
+// it wraps, if any. minio-go reads it from the goroutines uploading
+// parts.
+type progressReader struct {
+	mutex    sync.Mutex
+	progress io.Reader
+	p        TransferProgress
+	total    int64
+	partSize int64
+	done     int64
+}
+
+// newProgressReader - progress reader of an upload of total bytes in
+// parts of partSize bytes, 0 if uploaded at once, reporting the total
+// to p right away.
+func newProgressReader(progress io.Reader, p TransferProgress, total, partSize int64) *progressReader {
+	r := &progressReader{progress: progress, p: p, total: total, partSize: partSize}
+	p.Progress(0, total, 0)
+	return r
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.progress != nil {
+		r.progress.Read(b)
+	}
+	r.done += int64(len(b))
+	part := 0
+	if r.partSize > 0 && r.done > 0 {
+		part = int((r.done-1)/r.partSize) + 1
+	}
+	r.p.Progress(r.done, r.total, part)
+	return len(b), nil
+}
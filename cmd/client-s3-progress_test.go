@@ -0,0 +1,71 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+// Test the progress of Put and Copy, reported from the first byte.
+func (s *TestSuite) TestTransferProgress(c *C) {
+	var ticks []string
+	ctx := WithTransferProgress(context.Background(), TransferProgressFunc(func(done, total int64, part int) {
+		ticks = append(ticks, strconv.FormatInt(done, 10)+"/"+strconv.FormatInt(total, 10)+" "+strconv.Itoa(part))
+	}))
+
+	var stored []byte
+	object := memoryObjectHandler{resource: "/bucket/object", data: &stored}
+	server := httptest.NewServer(object)
+	defer server.Close()
+	s3c := newTestS3Client(c, server.URL+object.resource, "S3v4")
+
+	data := bytes.Repeat([]byte("a"), 1000)
+	progress := &bytes.Buffer{}
+	progress.Write(data)
+	_, err := s3c.Put(ctx, bytes.NewReader(data), int64(len(data)), nil, progress, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(ticks[0], Equals, "0/1000 0")
+	c.Assert(ticks[len(ticks)-1], Equals, "1000/1000 0")
+	// The progress reader of the call still gets the bytes.
+	c.Assert(progress.Len(), Equals, 0)
+
+	// A copy smaller than 5GiB is made part by part.
+	size := int64(copyPartSize + copyPartSize/2)
+	var ranges []string
+	var completed bool
+	server = httptest.NewServer(composeHandler{
+		sizes:     map[string]int64{"/bucket/source": size},
+		ranges:    &ranges,
+		completed: &completed,
+	})
+	defer server.Close()
+	s3c = newTestS3Client(c, server.URL+"/bucket/target", "S3v4")
+
+	ticks = nil
+	err = s3c.Copy(ctx, "/bucket/source", size, nil, nil, nil, map[string]string{}, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(completed, Equals, true)
+	c.Assert(ranges, HasLen, 2)
+	first := strconv.FormatInt(size-size/2, 10)
+	total := strconv.FormatInt(size, 10)
+	c.Assert(ticks, DeepEquals, []string{"0/" + total + " 0", first + "/" + total + " 1", total + "/" + total + " 2"})
+}
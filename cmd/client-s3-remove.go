@@ -0,0 +1,192 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+)
+
+// maxDeleteObjects - most objects a DeleteObjects request removes.
+const maxDeleteObjects = 1000
+
+// removeObject - object removed by removeObjects, only the version
+// versionID of it if set.
+type removeObject struct {
+	key       string
+	versionID string
+}
+
+// deleteObjectsRequest - body of a DeleteObjects request.
+type deleteObjectsRequest struct {
+	XMLName xml.Name             `xml:"Delete"`
+	Quiet   bool                 `xml:"Quiet"`
+	Objects []deleteObjectsEntry `xml:"Object"`
+}
+
+type deleteObjectsEntry struct {
+	Key       string `xml:"Key"`
+	VersionID string `xml:"VersionId,omitempty"`
+}
+
+// deleteObjectsResult - response to a DeleteObjects request, listing
+// the objects removed and the objects which failed to be removed.
+type deleteObjectsResult struct {
+	XMLName xml.Name               `xml:"DeleteResult"`
+	Deleted []deleteObjectsDeleted `xml:"Deleted"`
+	Errors  []deleteObjectsError   `xml:"Error"`
+}
+
+type deleteObjectsDeleted struct {
+	Key                   string `xml:"Key"`
+	VersionID             string `xml:"VersionId"`
+	DeleteMarker          bool   `xml:"DeleteMarker"`
+	DeleteMarkerVersionID string `xml:"DeleteMarkerVersionId"`
+}
+
+type deleteObjectsError struct {
+	Key       string `xml:"Key"`
+	VersionID string `xml:"VersionId"`
+	Code      string `xml:"Code"`
+	Message   string `xml:"Message"`
+}
+
+// RemoveStatus - outcome of the removal of an object, or of a version
+// of it, as reported by the server. Err is set when it failed, with an
+// empty Key when a whole bucket failed to be removed.
+type RemoveStatus struct {
+	Key string
+	// VersionID - version removed for good, empty when the removal
+	// created a delete marker instead.
+	VersionID string
+	// DeleteMarker - set when a delete marker was created, or when
+	// the version removed was one, DeleteMarkerVersionID being the
+	// version of the marker.
+	DeleteMarker          bool
+	DeleteMarkerVersionID string
+	Err                   *probe.Error
+}
+
+// RemoveWithStatus - remove the objects of contentCh as Remove does,
+// sending the status of every one of them on the returned channel,
+// closed once all are removed. Unlike Remove, permanent removals of
+// versions can be told apart from delete markers created.
+func (c *S3Client) RemoveWithStatus(ctx context.Context, isBypass bool, contentCh <-chan *ClientContent) <-chan RemoveStatus {
+	statusCh := make(chan RemoveStatus)
+	go func() {
+		defer close(statusCh)
+		c.remove(ctx, false, false, isBypass, contentCh, func(status RemoveStatus) {
+			statusCh <- status
+		})
+	}()
+	return statusCh
+}
+
+// removeObjects - remove the objects received on objectsCh from bucket
+// like minio-go RemoveObjects, with DeleteObjects requests of up to
+// maxDeleteObjects objects, sending the status of every object on the
+// returned channel, closed once objectsCh is. Unlike minio-go,
+// versions of objects can be removed, and successes are reported.
+func (c *S3Client) removeObjects(ctx context.Context, bucket string, objectsCh <-chan removeObject, bypass bool) <-chan RemoveStatus {
+	removeStatusCh := make(chan RemoveStatus)
+	go func() {
+		defer close(removeStatusCh)
+		for last := false; !last; {
+			// Gather a batch, the last one is smaller.
+			var batch []removeObject
+			for object := range objectsCh {
+				batch = append(batch, object)
+				if len(batch) == maxDeleteObjects {
+					break
+				}
+			}
+			if len(batch) == 0 {
+				return
+			}
+			last = len(batch) < maxDeleteObjects
+
+			result, e := c.deleteObjects(ctx, bucket, batch, bypass)
+			if e != nil {
+				for _, object := range batch {
+					removeStatusCh <- RemoveStatus{Key: object.key, VersionID: object.versionID, Err: probe.NewError(e)}
+				}
+				continue
+			}
+			for _, deleted := range result.Deleted {
+				removeStatusCh <- RemoveStatus{
+					Key:                   deleted.Key,
+					VersionID:             deleted.VersionID,
+					DeleteMarker:          deleted.DeleteMarker,
+					DeleteMarkerVersionID: deleted.DeleteMarkerVersionID,
+				}
+			}
+			for _, failed := range result.Errors {
+				removeStatusCh <- RemoveStatus{
+					Key:       failed.Key,
+					VersionID: failed.VersionID,
+					Err: probe.NewError(minio.ErrorResponse{
+						Code:       failed.Code,
+						Message:    failed.Message,
+						BucketName: bucket,
+						Key:        failed.Key,
+					}),
+				}
+			}
+		}
+	}()
+	return removeStatusCh
+}
+
+// deleteObjects - send a DeleteObjects request removing objects from
+// bucket.
+func (c *S3Client) deleteObjects(ctx context.Context, bucket string, objects []removeObject, bypass bool) (*deleteObjectsResult, error) {
+	request := deleteObjectsRequest{}
+	for _, object := range objects {
+		request.Objects = append(request.Objects, deleteObjectsEntry{Key: object.key, VersionID: object.versionID})
+	}
+	body, e := xml.Marshal(request)
+	if e != nil {
+		return nil, e
+	}
+	sum := md5.Sum(body)
+	header := http.Header{"Content-Md5": []string{base64.StdEncoding.EncodeToString(sum[:])}}
+	if bypass {
+		header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	}
+	resp, e := c.executeRequest(ctx, http.MethodPost, s3RequestMetadata{
+		bucket:  bucket,
+		query:   url.Values{"delete": []string{""}},
+		header:  header,
+		content: body,
+	})
+	if e != nil {
+		return nil, e
+	}
+	defer resp.Body.Close()
+	result := &deleteObjectsResult{}
+	if e = xml.NewDecoder(resp.Body).Decode(result); e != nil {
+		return nil, e
+	}
+	return result, nil
+}
@@ -0,0 +1,244 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	. "gopkg.in/check.v1"
+)
+
+// versionedHandler serves a versioned bucket: uploads add versions,
+// deletes without version ID add delete markers, and versions are
+// listed newest first as versionsHandler does.
+type versionedHandler struct {
+	mutex    *sync.Mutex
+	versions *[]testVersion
+	ids      *int
+}
+
+func newVersionedHandler() versionedHandler {
+	return versionedHandler{mutex: &sync.Mutex{}, versions: &[]testVersion{}, ids: new(int)}
+}
+
+// add - add a version of key, the latest, returning its ID.
+func (h versionedHandler) add(key string, deleteMarker bool, size int64) string {
+	*h.ids++
+	id := "v" + strconv.Itoa(*h.ids)
+	for i := range *h.versions {
+		if (*h.versions)[i].key == key {
+			(*h.versions)[i].isLatest = false
+		}
+	}
+	*h.versions = append([]testVersion{{key, id, true, deleteMarker, time.Now().UTC(), size}}, *h.versions...)
+	return id
+}
+
+// remove - remove a version of key, false if there is none.
+func (h versionedHandler) remove(key, id string) (deleteMarker, ok bool) {
+	for i, v := range *h.versions {
+		if v.key != key || v.versionID != id {
+			continue
+		}
+		*h.versions = append((*h.versions)[:i], (*h.versions)[i+1:]...)
+		if v.isLatest {
+			for j := range *h.versions {
+				if (*h.versions)[j].key == key {
+					(*h.versions)[j].isLatest = true
+					break
+				}
+			}
+		}
+		return v.deleteMarker, true
+	}
+	return false, false
+}
+
+func (h versionedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	query := r.URL.Query()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	_, multiDelete := query["delete"]
+	switch {
+	case r.Method == "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Amz-Version-Id", h.add(key, false, int64(len(data))))
+		w.Header().Set("ETag", "\"9af2f8218b150c351ad802c6f3d66abe\"")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "DELETE":
+		id := query.Get("versionId")
+		deleteMarker := true
+		if id == "" {
+			id = h.add(key, true, 0)
+		} else {
+			// Only removing a delete marker is reported as one.
+			deleteMarker, _ = h.remove(key, id)
+		}
+		w.Header().Set("X-Amz-Version-Id", id)
+		if deleteMarker {
+			w.Header().Set("X-Amz-Delete-Marker", "true")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "POST" && multiDelete:
+		var request deleteObjectsRequest
+		if e := xml.NewDecoder(r.Body).Decode(&request); e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := "<DeleteResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\">"
+		for _, object := range request.Objects {
+			entry := "<Key>" + object.Key + "</Key>"
+			switch {
+			case object.VersionID == "":
+				entry += "<DeleteMarker>true</DeleteMarker><DeleteMarkerVersionId>" + h.add(object.Key, true, 0) + "</DeleteMarkerVersionId>"
+			default:
+				deleteMarker, ok := h.remove(object.Key, object.VersionID)
+				entry += "<VersionId>" + object.VersionID + "</VersionId>"
+				if !ok {
+					response += "<Error>" + entry + "<Code>NoSuchVersion</Code><Message>The specified version does not exist.</Message></Error>"
+					continue
+				}
+				if deleteMarker {
+					entry += "<DeleteMarker>true</DeleteMarker><DeleteMarkerVersionId>" + object.VersionID + "</DeleteMarkerVersionId>"
+				}
+			}
+			if !request.Quiet {
+				response += "<Deleted>" + entry + "</Deleted>"
+			}
+		}
+		response += "</DeleteResult>"
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write([]byte(response))
+	default:
+		versionsHandler{versions: *h.versions}.ServeHTTP(w, r)
+	}
+}
+
+// Test removing versions of objects, and objects.
+func (s *TestSuite) TestRemoveVersions(c *C) {
+	handler := newVersionedHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, key := range []string{"object", "object", "object", "other"} {
+		s3c := newTestS3Client(c, server.URL+"/bucket/"+key, "S3v4")
+		_, err := s3c.Put(context.Background(), bytes.NewReader([]byte("data")), 4, nil, nil, nil, false, false, "")
+		c.Assert(err, IsNil)
+	}
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+	remove := func(contents ...*ClientContent) []*probe.Error {
+		contentCh := make(chan *ClientContent, len(contents))
+		for _, content := range contents {
+			contentCh <- content
+		}
+		close(contentCh)
+		var errs []*probe.Error
+		for err := range s3c.Remove(context.Background(), false, false, false, contentCh) {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+	content := func(key, versionID string) *ClientContent {
+		return &ClientContent{URL: *newClientURL(server.URL + "/bucket/" + key), VersionID: versionID}
+	}
+
+	errs := remove(content("object", "v1"), content("object", "v3"), content("other", ""))
+	c.Assert(errs, HasLen, 0)
+
+	history := func(key string) []string {
+		versions, err := s3c.ObjectHistory(context.Background(), key)
+		c.Assert(err, IsNil)
+		var ids []string
+		for _, v := range versions {
+			id := v.VersionID
+			if v.DeleteMarker {
+				id += " marker"
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	c.Assert(history("object"), DeepEquals, []string{"v2"})
+	c.Assert(history("other"), DeepEquals, []string{"v5 marker", "v4"})
+
+	errs = remove(content("object", "v1"))
+	c.Assert(errs, HasLen, 1)
+	c.Assert(minio.ToErrorResponse(errs[0].ToGoError()).Code, Equals, "NoSuchVersion")
+	c.Assert(history("object"), DeepEquals, []string{"v2"})
+}
+
+// Test the statuses of removals, telling apart versions removed for
+// good from delete markers created.
+func (s *TestSuite) TestRemoveStatus(c *C) {
+	handler := newVersionedHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, key := range []string{"object", "object", "other"} {
+		s3c := newTestS3Client(c, server.URL+"/bucket/"+key, "S3v4")
+		_, err := s3c.Put(context.Background(), bytes.NewReader([]byte("data")), 4, nil, nil, nil, false, false, "")
+		c.Assert(err, IsNil)
+	}
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+	remove := func(contents ...*ClientContent) []RemoveStatus {
+		contentCh := make(chan *ClientContent, len(contents))
+		for _, content := range contents {
+			contentCh <- content
+		}
+		close(contentCh)
+		var statuses []RemoveStatus
+		for status := range s3c.RemoveWithStatus(context.Background(), false, contentCh) {
+			statuses = append(statuses, status)
+		}
+		return statuses
+	}
+	content := func(key, versionID string) *ClientContent {
+		return &ClientContent{URL: *newClientURL(server.URL + "/bucket/" + key), VersionID: versionID}
+	}
+
+	statuses := remove(content("object", "v1"), content("other", ""))
+	c.Assert(statuses, DeepEquals, []RemoveStatus{
+		{Key: "object", VersionID: "v1"},
+		{Key: "other", DeleteMarker: true, DeleteMarkerVersionID: "v4"},
+	})
+
+	statuses = remove(content("other", "v4"), content("object", "v1"))
+	c.Assert(statuses, HasLen, 2)
+	c.Assert(statuses[0], DeepEquals, RemoveStatus{Key: "other", VersionID: "v4", DeleteMarker: true, DeleteMarkerVersionID: "v4"})
+	c.Assert(statuses[1].Key, Equals, "object")
+	c.Assert(statuses[1].VersionID, Equals, "v1")
+	c.Assert(statuses[1].Err, NotNil)
+	c.Assert(minio.ToErrorResponse(statuses[1].Err.ToGoError()).Code, Equals, "NoSuchVersion")
+}
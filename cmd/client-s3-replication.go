@@ -0,0 +1,227 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+)
+
+// Replication rule statuses.
+const (
+	replicationEnabled  = "Enabled"
+	replicationDisabled = "Disabled"
+)
+
+// ReplicationConfig - common fields of a bucket replication
+// configuration. A configuration without rules is not configured.
+type ReplicationConfig struct {
+	// Role is the ARN of the role replicating objects.
+	Role  string
+	Rules []ReplicationRule
+}
+
+// ReplicationRule - replicates objects under Prefix to the
+// destination bucket, given as an ARN.
+type ReplicationRule struct {
+	ID     string
+	Status string
+	// Priority orders overlapping rules, the position of the rule
+	// is used when 0.
+	Priority                int
+	Prefix                  string
+	DestinationBucket       string
+	StorageClass            string
+	DeleteMarkerReplication bool
+}
+
+// replicationConfiguration - ReplicationConfiguration XML document.
+type replicationConfiguration struct {
+	XMLName xml.Name          `xml:"ReplicationConfiguration"`
+	Xmlns   string            `xml:"xmlns,attr,omitempty"`
+	Role    string            `xml:"Role"`
+	Rules   []replicationRule `xml:"Rule"`
+}
+
+type replicationRule struct {
+	ID       string `xml:"ID,omitempty"`
+	Status   string `xml:"Status"`
+	Priority int    `xml:"Priority,omitempty"`
+	// Prefix of rules in the first version of the schema.
+	Prefix                  string                 `xml:"Prefix,omitempty"`
+	Filter                  *replicationFilter     `xml:"Filter"`
+	DeleteMarkerReplication *replicationStatus     `xml:"DeleteMarkerReplication"`
+	Destination             replicationDestination `xml:"Destination"`
+}
+
+type replicationDestination struct {
+	Bucket       string `xml:"Bucket"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+type replicationFilter struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type replicationStatus struct {
+	Status string `xml:"Status"`
+}
+
+// GetReplication - replication configuration of the bucket, without
+// rules if replication is not configured.
+func (c *S3Client) GetReplication() (ReplicationConfig, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return ReplicationConfig{}, probe.NewError(BucketNameEmpty{})
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"replication": []string{""}},
+	})
+	if e != nil {
+		if minio.ToErrorResponse(e).Code == "ReplicationConfigurationNotFoundError" {
+			return ReplicationConfig{}, nil
+		}
+		return ReplicationConfig{}, c.bucketConfigError("Replication", bucket, e)
+	}
+	defer resp.Body.Close()
+
+	var wire replicationConfiguration
+	if e = xml.NewDecoder(resp.Body).Decode(&wire); e != nil {
+		return ReplicationConfig{}, probe.NewError(e)
+	}
+	config := ReplicationConfig{Role: wire.Role}
+	for _, r := range wire.Rules {
+		rule := ReplicationRule{
+			ID:                r.ID,
+			Status:            r.Status,
+			Priority:          r.Priority,
+			Prefix:            r.Prefix,
+			DestinationBucket: r.Destination.Bucket,
+			StorageClass:      r.Destination.StorageClass,
+		}
+		if r.Filter != nil {
+			rule.Prefix = r.Filter.Prefix
+		}
+		if r.DeleteMarkerReplication != nil {
+			rule.DeleteMarkerReplication = r.DeleteMarkerReplication.Status == replicationEnabled
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	return config, nil
+}
+
+// SetReplication - replace the replication configuration of the bucket.
+func (c *S3Client) SetReplication(config ReplicationConfig) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if len(config.Rules) == 0 {
+		return errInvalidArgument().Trace("replication needs at least one rule, use RemoveReplication to disable it")
+	}
+
+	wire := replicationConfiguration{
+		Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/",
+		Role:  config.Role,
+	}
+	for i, rule := range config.Rules {
+		if rule.Status != replicationEnabled && rule.Status != replicationDisabled {
+			return errInvalidArgument().Trace("invalid replication rule status", rule.Status)
+		}
+		if rule.DestinationBucket == "" {
+			return errInvalidArgument().Trace("replication rule without destination bucket")
+		}
+		r := replicationRule{
+			ID:       rule.ID,
+			Status:   rule.Status,
+			Priority: rule.Priority,
+		}
+		if r.Priority == 0 {
+			r.Priority = i + 1
+		}
+		r.Filter = &replicationFilter{Prefix: rule.Prefix}
+		r.DeleteMarkerReplication = &replicationStatus{Status: replicationDisabled}
+		if rule.DeleteMarkerReplication {
+			r.DeleteMarkerReplication.Status = replicationEnabled
+		}
+		r.Destination.Bucket = rule.DestinationBucket
+		r.Destination.StorageClass = rule.StorageClass
+		wire.Rules = append(wire.Rules, r)
+	}
+	configBytes, e := xml.Marshal(wire)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	sum := md5.Sum(configBytes)
+	resp, e := c.executeRequest(context.Background(), http.MethodPut, s3RequestMetadata{
+		bucket:  bucket,
+		query:   url.Values{"replication": []string{""}},
+		header:  http.Header{"Content-Md5": []string{base64.StdEncoding.EncodeToString(sum[:])}},
+		content: configBytes,
+	})
+	if e != nil {
+		return c.bucketConfigError("Replication", bucket, e)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RemoveReplication - remove the replication configuration of the
+// bucket, succeeds if there is none.
+func (c *S3Client) RemoveReplication() *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodDelete, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"replication": []string{""}},
+	})
+	if e != nil {
+		if minio.ToErrorResponse(e).Code == "ReplicationConfigurationNotFoundError" {
+			return nil
+		}
+		return c.bucketConfigError("Replication", bucket, e)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// bucketConfigError - typed error of a failed request on a bucket
+// configuration of the api.
+func (c *S3Client) bucketConfigError(api, bucket string, e error) *probe.Error {
+	switch minio.ToErrorResponse(e).Code {
+	case "NoSuchBucket":
+		return probe.NewError(BucketDoesNotExist{Bucket: bucket})
+	case "AccessDenied":
+		return probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
+	case "NotImplemented":
+		return probe.NewError(APINotImplemented{
+			API:     api,
+			APIType: c.targetURL.Scheme + "://" + c.targetURL.Host,
+		})
+	}
+	return probe.NewError(regionError(bucket, e))
+}
@@ -0,0 +1,131 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+// replicationHandler stores the replication configuration of a bucket.
+type replicationHandler struct {
+	config *string
+}
+
+func (h replicationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if _, ok := query["replication"]; !ok || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	notFound := func() {
+		response := []byte("<Error><Code>ReplicationConfigurationNotFoundError</Code><Message>The replication configuration was not found</Message></Error>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(response)
+	}
+	switch r.Method {
+	case "GET":
+		if *h.config == "" {
+			notFound()
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(*h.config)))
+		w.Write([]byte(*h.config))
+	case "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		sum := md5.Sum(data)
+		if r.Header.Get("Content-Md5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*h.config = string(data)
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		if *h.config == "" {
+			notFound()
+			return
+		}
+		*h.config = ""
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Test managing the replication configuration of a bucket.
+func (s *TestSuite) TestReplication(c *C) {
+	var config string
+	server := httptest.NewServer(replicationHandler{config: &config})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	// Not configured.
+	replication, err := s3c.GetReplication()
+	c.Assert(err, IsNil)
+	c.Assert(replication.Rules, HasLen, 0)
+	c.Assert(s3c.RemoveReplication(), IsNil)
+
+	// Invalid configurations.
+	c.Assert(s3c.SetReplication(ReplicationConfig{Role: "arn:aws:iam::123456789012:role/replication"}), NotNil)
+	c.Assert(s3c.SetReplication(ReplicationConfig{Rules: []ReplicationRule{{Status: "On", DestinationBucket: "arn:aws:s3:::dr"}}}), NotNil)
+	c.Assert(s3c.SetReplication(ReplicationConfig{Rules: []ReplicationRule{{Status: "Enabled"}}}), NotNil)
+	c.Assert(config, Equals, "")
+
+	expected := ReplicationConfig{
+		Role: "arn:aws:iam::123456789012:role/replication",
+		Rules: []ReplicationRule{
+			{ID: "logs", Status: "Enabled", Priority: 1, Prefix: "logs/", DestinationBucket: "arn:aws:s3:::dr", StorageClass: "STANDARD_IA"},
+			{ID: "all", Status: "Disabled", Priority: 2, DestinationBucket: "arn:aws:s3:::dr", DeleteMarkerReplication: true},
+		},
+	}
+	c.Assert(s3c.SetReplication(expected), IsNil)
+	replication, err = s3c.GetReplication()
+	c.Assert(err, IsNil)
+	c.Assert(replication, DeepEquals, expected)
+
+	c.Assert(s3c.RemoveReplication(), IsNil)
+	replication, err = s3c.GetReplication()
+	c.Assert(err, IsNil)
+	c.Assert(replication.Rules, HasLen, 0)
+
+	// Rules of the first version of the schema.
+	config = "<ReplicationConfiguration><Role>arn:aws:iam::123456789012:role/replication</Role>" +
+		"<Rule><ID>v1</ID><Status>Enabled</Status><Prefix>docs/</Prefix><Destination><Bucket>arn:aws:s3:::dr</Bucket></Destination></Rule>" +
+		"</ReplicationConfiguration>"
+	replication, err = s3c.GetReplication()
+	c.Assert(err, IsNil)
+	c.Assert(replication.Rules, DeepEquals, []ReplicationRule{{ID: "v1", Status: "Enabled", Prefix: "docs/", DestinationBucket: "arn:aws:s3:::dr"}})
+}
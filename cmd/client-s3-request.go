@@ -0,0 +1,684 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	"github.com/minio/minio-go/v6/pkg/s3signer"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// headerTransport injects additional headers on requests targeting
+// specific buckets before handing them over to the wrapped transport.
+type headerTransport struct {
+	transport http.RoundTripper
+
+	// Credentials used to sign again requests on which
+	// x-amz-* headers were injected.
+	creds        *credentials.Credentials
+	virtualStyle bool
+
+	// Headers of requests per bucket, never modified.
+	bucketHeaders map[string]http.Header
+}
+
+// newHeaderTransport - wraps transport with a header injecting
+// transport, sending bucketHeaders on the requests of their bucket.
+func newHeaderTransport(transport http.RoundTripper, creds *credentials.Credentials, virtualStyle bool, bucketHeaders map[string]http.Header) *headerTransport {
+	return &headerTransport{
+		transport:     transport,
+		creds:         creds,
+		virtualStyle:  virtualStyle,
+		bucketHeaders: bucketHeaders,
+	}
+}
+
+// headersFor - returns the headers configured for the bucket a request
+// is targeting, for both path style and virtual host style requests.
+func (t *headerTransport) headersFor(u *url.URL) http.Header {
+	if len(t.bucketHeaders) == 0 {
+		return nil
+	}
+	pathBucket := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)[0]
+	for bucket, h := range t.bucketHeaders {
+		if bucket == pathBucket || strings.HasPrefix(u.Host, bucket+".") {
+			return h
+		}
+	}
+	return nil
+}
+
+// requestHeadersKey - context key of the raw headers set by withRequestHeaders.
+type requestHeadersKey struct{}
+
+// withRequestHeaders - returns a context which sends h on every request
+// made with it, overriding headers set by minio-go. This is an escape
+// hatch for headers minio-go doesn't know about.
+func withRequestHeaders(ctx context.Context, h http.Header) context.Context {
+	if prev := requestHeaders(ctx); len(prev) > 0 {
+		merged := prev.Clone()
+		for k, v := range h {
+			merged[k] = v
+		}
+		h = merged
+	}
+	return context.WithValue(ctx, requestHeadersKey{}, h)
+}
+
+// requestHeaders - returns the raw headers set on ctx, if any.
+func requestHeaders(ctx context.Context) http.Header {
+	h, _ := ctx.Value(requestHeadersKey{}).(http.Header)
+	return h
+}
+
+type userAgentSuffixKey struct{}
+
+// WithUserAgentSuffix - returns a context appending suffix to the
+// User-Agent of the requests made with it, after the app info of the
+// client, e.g. to tell the commands apart in access logs.
+func WithUserAgentSuffix(ctx context.Context, suffix string) context.Context {
+	if prev := userAgentSuffix(ctx); prev != "" {
+		suffix = prev + " " + suffix
+	}
+	return context.WithValue(ctx, userAgentSuffixKey{}, suffix)
+}
+
+// userAgentSuffix - returns the User-Agent suffix set on ctx, if any.
+func userAgentSuffix(ctx context.Context) string {
+	suffix, _ := ctx.Value(userAgentSuffixKey{}).(string)
+	return suffix
+}
+
+// objectHeadersKey - context key of the headers set by withObjectHeaders.
+type objectHeadersKey struct{}
+
+// withObjectHeaders - returns a context which sends h on the request
+// creating an object only: a single part upload, a copy or the
+// initiation of a multipart upload, not on its parts. This is for
+// headers minio-go rejects as metadata, such as Expires.
+func withObjectHeaders(ctx context.Context, h http.Header) context.Context {
+	if prev := objectHeaders(ctx); len(prev) > 0 {
+		merged := prev.Clone()
+		for k, v := range h {
+			merged[k] = v
+		}
+		h = merged
+	}
+	return context.WithValue(ctx, objectHeadersKey{}, h)
+}
+
+// objectHeaders - returns the object headers set on ctx, if any.
+func objectHeaders(ctx context.Context) http.Header {
+	h, _ := ctx.Value(objectHeadersKey{}).(http.Header)
+	return h
+}
+
+// createsObject - tells whether req creates an object, as opposed to
+// uploading or completing the parts of a multipart upload.
+func createsObject(req *http.Request) bool {
+	query := req.URL.Query()
+	switch req.Method {
+	case http.MethodPut:
+		// Parts have a partNumber and an uploadId.
+		return len(query) == 0
+	case http.MethodPost:
+		_, uploads := query["uploads"]
+		return uploads
+	}
+	return false
+}
+
+// RoundTrip - add configured headers and execute the request.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.headersFor(req.URL)
+	rh := requestHeaders(req.Context())
+	var oh http.Header
+	if createsObject(req) {
+		oh = objectHeaders(req.Context())
+	}
+	uaSuffix := userAgentSuffix(req.Context())
+	if len(h) == 0 && len(rh) == 0 && len(oh) == 0 && uaSuffix == "" {
+		return t.transport.RoundTrip(req)
+	}
+	// Never modify the original request, work on a copy.
+	r := req.Clone(req.Context())
+	if uaSuffix != "" {
+		// Not signed, unlike x-amz-* headers.
+		r.Header.Set("User-Agent", strings.TrimSpace(r.Header.Get("User-Agent")+" "+uaSuffix))
+	}
+	var amzHeaders bool
+	for _, headers := range []http.Header{h, rh, oh} {
+		for k, v := range headers {
+			if strings.Join(r.Header[k], ",") == strings.Join(v, ",") {
+				// Already sent, and signed, as is.
+				continue
+			}
+			r.Header[k] = v
+			amzHeaders = amzHeaders || strings.HasPrefix(strings.ToLower(k), "x-amz-")
+		}
+	}
+	if amzHeaders {
+		// x-amz-* headers are part of the signature.
+		r = t.resign(r)
+	}
+	return t.transport.RoundTrip(r)
+}
+
+// contextTransport - sends the requests of a pooled minio client with
+// the context of the call using it, for the minio-go APIs which don't
+// accept a context.
+type contextTransport struct {
+	transport http.RoundTripper
+
+	mutex sync.Mutex
+	ctx   context.Context
+}
+
+// RoundTrip - execute the request with the context of the call, if any.
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mutex.Lock()
+	ctx := t.ctx
+	t.mutex.Unlock()
+	if ctx == nil {
+		return t.transport.RoundTrip(req)
+	}
+	return t.transport.RoundTrip(req.WithContext(ctx))
+}
+
+// setContext - send the next requests with ctx, none when nil.
+func (t *contextTransport) setContext(ctx context.Context) {
+	t.mutex.Lock()
+	t.ctx = ctx
+	t.mutex.Unlock()
+}
+
+// contextClient - minio client of a contextClientPool.
+type contextClient struct {
+	api       *minio.Client
+	transport *contextTransport
+}
+
+// Idle clients kept per region by a contextClientPool.
+const maxIdleContextClients = 16
+
+// contextClientPool - idle minio clients sending their requests with
+// the context of a call, shared by the clients of a host and reused
+// across calls, per region.
+type contextClientPool struct {
+	mutex sync.Mutex
+	idle  map[string][]*contextClient
+}
+
+// get - an idle client of region, nil if there is none.
+func (p *contextClientPool) get(region string) *contextClient {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	clients := p.idle[region]
+	if len(clients) == 0 {
+		return nil
+	}
+	client := clients[len(clients)-1]
+	p.idle[region] = clients[:len(clients)-1]
+	return client
+}
+
+// put - make client of region idle again, once its call is over.
+func (p *contextClientPool) put(region string, client *contextClient) {
+	client.transport.setContext(nil)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.idle == nil {
+		p.idle = make(map[string][]*contextClient)
+	}
+	if len(p.idle[region]) < maxIdleContextClients {
+		p.idle[region] = append(p.idle[region], client)
+	}
+}
+
+// contextAPI - minio client sending the requests of a single call with
+// ctx, so that minio-go APIs without context support can be canceled,
+// carry the headers of withRequestHeaders and record their IDs. The
+// client is taken from the pool of the host and must be given back with
+// release once the call is over, requests still made with it after that
+// aren't sent with ctx. All its requests must target bucket, whose
+// region is looked up once per host by minio-go. With an empty bucket
+// minio-go looks up the regions itself, e.g. for calls on several
+// buckets or to make one.
+func (c *S3Client) contextAPI(ctx context.Context, bucket string) (api *minio.Client, release func()) {
+	if ctx.Done() == nil && len(requestHeaders(ctx)) == 0 && contextRequestIDs(ctx) == nil && userAgentSuffix(ctx) == "" {
+		// Nothing to cancel, send nor record.
+		return c.api, func() {}
+	}
+	var region string
+	if bucket != "" {
+		// Cached by c.api, a failed lookup is left to the pooled client.
+		region, _ = c.api.GetBucketLocation(bucket)
+	}
+	client := c.contextClients.get(region)
+	if client == nil {
+		transport := &contextTransport{transport: c.transport}
+		api, e := c.newAPI(transport, region)
+		if e != nil {
+			// Only invalid endpoints fail, c.api was built with the same.
+			return c.api, func() {}
+		}
+		client = &contextClient{api: api, transport: transport}
+	}
+	client.transport.setContext(ctx)
+	return client.api, func() {
+		c.contextClients.put(region, client)
+	}
+}
+
+// resign - sign again a request signed by minio-go, the region is taken
+// from the original signature. Anonymous and presigned requests are
+// returned as is.
+func (t *headerTransport) resign(req *http.Request) *http.Request {
+	auth := req.Header.Get("Authorization")
+	if t.creds == nil || auth == "" {
+		return req
+	}
+	value, e := t.creds.Get()
+	if e != nil {
+		return req
+	}
+	if strings.HasPrefix(auth, "AWS4-HMAC-SHA256") {
+		// Credential=<access-key>/<date>/<region>/s3/aws4_request
+		location := "us-east-1"
+		if i := strings.Index(auth, "Credential="); i >= 0 {
+			if scope := strings.Split(auth[i:], "/"); len(scope) > 2 {
+				location = scope[2]
+			}
+		}
+		if req.Header.Get("X-Amz-Content-Sha256") == streamingPayload {
+			return resignStreaming(req, value, location)
+		}
+		return s3signer.SignV4(*req, value.AccessKeyID, value.SecretAccessKey, value.SessionToken, location)
+	}
+	return s3signer.SignV2(*req, value.AccessKeyID, value.SecretAccessKey, t.virtualStyle)
+}
+
+// X-Amz-Content-Sha256 value of requests with streaming signatures,
+// which minio-go uses for single part uploads over plain HTTP.
+const streamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+// resignStreaming - sign again a request with a streaming signature,
+// whose chunks are signed along with the headers. The chunks of minio-go
+// are decoded and signed again with the new seed signature.
+func resignStreaming(req *http.Request, value credentials.Value, location string) *http.Request {
+	dataLen, e := strconv.ParseInt(req.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+	if e != nil || req.Body == nil {
+		return req
+	}
+	req.Body = &awsChunkedReader{reader: bufio.NewReader(req.Body), closer: req.Body}
+	req.GetBody = nil
+	return s3signer.StreamingSignV4(req, value.AccessKeyID, value.SecretAccessKey, value.SessionToken, location, dataLen, time.Now().UTC())
+}
+
+// awsChunkedReader - reads the payload of an aws-chunked body, made of
+// chunks such as "<hex-size>;chunk-signature=<signature>\r\n<data>\r\n"
+// ending with an empty chunk, without the chunk signatures.
+type awsChunkedReader struct {
+	reader  *bufio.Reader
+	closer  io.Closer
+	left    int64 // data left in the current chunk
+	started bool
+	done    bool
+}
+
+// Read - read the data of the chunks.
+func (r *awsChunkedReader) Read(p []byte) (int, error) {
+	for r.left == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if e := r.nextChunk(); e != nil {
+			return 0, e
+		}
+	}
+	if int64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	n, e := r.reader.Read(p)
+	r.left -= int64(n)
+	if e == io.EOF {
+		e = io.ErrUnexpectedEOF
+	}
+	return n, e
+}
+
+// nextChunk - read the header of the next chunk.
+func (r *awsChunkedReader) nextChunk() error {
+	if r.started {
+		// CRLF ending the data of the previous chunk.
+		if _, e := r.reader.Discard(2); e != nil {
+			return io.ErrUnexpectedEOF
+		}
+	}
+	r.started = true
+	line, e := r.reader.ReadString('\n')
+	if e != nil {
+		return io.ErrUnexpectedEOF
+	}
+	size := strings.TrimSpace(line)
+	if i := strings.IndexByte(size, ';'); i >= 0 {
+		size = size[:i]
+	}
+	n, e := strconv.ParseInt(size, 16, 64)
+	if e != nil || n < 0 {
+		return errors.New("malformed aws-chunked chunk header " + strconv.Quote(line))
+	}
+	r.left = n
+	r.done = n == 0
+	return nil
+}
+
+// Close - close the underlying body.
+func (r *awsChunkedReader) Close() error {
+	return r.closer.Close()
+}
+
+// unsignedPayload - X-Amz-Content-Sha256 value of requests whose
+// payload is not part of the signature.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// RequestIDCapture - transport recording the x-amz-request-id and
+// x-amz-id-2 headers of responses, needed to report issues to AWS.
+// The IDs of the last response are kept for the whole client, those
+// of the responses to the requests of a call are recorded on the
+// RequestIDs of its context, see WithRequestIDs.
+type RequestIDCapture struct {
+	Transport http.RoundTripper
+
+	last atomic.Value // requestIDs
+}
+
+// requestIDs - IDs of a response.
+type requestIDs struct {
+	requestID string
+	hostID    string
+}
+
+// RequestIDs - IDs of the last response to the requests made with a
+// context returned by WithRequestIDs.
+type RequestIDs struct {
+	mutex sync.Mutex
+	ids   requestIDs
+}
+
+// RequestID - x-amz-request-id of the last response.
+func (r *RequestIDs) RequestID() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.ids.requestID
+}
+
+// HostID - x-amz-id-2 of the last response.
+func (r *RequestIDs) HostID() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.ids.hostID
+}
+
+// requestIDsKey - context key of the RequestIDs set by WithRequestIDs.
+type requestIDsKey struct{}
+
+// WithRequestIDs - returns a context recording the IDs of the responses
+// to the requests made with it on the returned RequestIDs, e.g. to
+// report the failure of a call to AWS.
+func WithRequestIDs(ctx context.Context) (context.Context, *RequestIDs) {
+	ids := &RequestIDs{}
+	return context.WithValue(ctx, requestIDsKey{}, ids), ids
+}
+
+// contextRequestIDs - returns the RequestIDs set on ctx, if any.
+func contextRequestIDs(ctx context.Context) *RequestIDs {
+	ids, _ := ctx.Value(requestIDsKey{}).(*RequestIDs)
+	return ids
+}
+
+// RoundTrip - execute the request and record the IDs of the response.
+func (t *RequestIDCapture) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, e := t.Transport.RoundTrip(req)
+	if e != nil {
+		return resp, e
+	}
+	ids := requestIDs{
+		requestID: resp.Header.Get("X-Amz-Request-Id"),
+		hostID:    resp.Header.Get("X-Amz-Id-2"),
+	}
+	if ids.requestID == "" && ids.hostID == "" {
+		return resp, nil
+	}
+	t.last.Store(ids)
+	if r := contextRequestIDs(req.Context()); r != nil {
+		r.mutex.Lock()
+		r.ids = ids
+		r.mutex.Unlock()
+	}
+	return resp, nil
+}
+
+// LastRequestID - x-amz-request-id of the last response.
+func (t *RequestIDCapture) LastRequestID() string {
+	ids, _ := t.last.Load().(requestIDs)
+	return ids.requestID
+}
+
+// LastHostID - x-amz-id-2 of the last response.
+func (t *RequestIDCapture) LastHostID() string {
+	ids, _ := t.last.Load().(requestIDs)
+	return ids.hostID
+}
+
+// s3RequestMetadata - raw S3 request description, used for
+// S3 APIs which are not provided by minio-go.
+type s3RequestMetadata struct {
+	bucket  string
+	object  string
+	query   url.Values
+	header  http.Header
+	content []byte
+}
+
+// executeRequest - sign and send a raw S3 request using the same
+// credentials and transport as the minio client. A non 2xx response
+// is returned as minio.ErrorResponse.
+func (c *S3Client) executeRequest(ctx context.Context, method string, metadata s3RequestMetadata) (*http.Response, error) {
+	req, e := c.newRequest(ctx, method, metadata)
+	if e != nil {
+		return nil, e
+	}
+	resp, e := (&http.Client{Transport: c.transport}).Do(req)
+	if e != nil {
+		return nil, e
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	errResp := minio.ErrorResponse{}
+	if body, e := ioutil.ReadAll(resp.Body); e == nil && len(body) > 0 {
+		// Ignore decoding errors, we fill in the details below.
+		xml.Unmarshal(body, &errResp)
+	}
+	errResp.StatusCode = resp.StatusCode
+	if errResp.Code == "" {
+		errResp.Code = strings.Replace(http.StatusText(resp.StatusCode), " ", "", -1)
+	}
+	if errResp.Message == "" {
+		errResp.Message = http.StatusText(resp.StatusCode)
+	}
+	if errResp.BucketName == "" {
+		errResp.BucketName = metadata.bucket
+	}
+	if errResp.Key == "" {
+		errResp.Key = metadata.object
+	}
+	if errResp.RequestID == "" {
+		errResp.RequestID = resp.Header.Get("X-Amz-Request-Id")
+	}
+	if errResp.HostID == "" {
+		errResp.HostID = resp.Header.Get("X-Amz-Id-2")
+	}
+	if errResp.Region == "" {
+		errResp.Region = resp.Header.Get("X-Amz-Bucket-Region")
+	}
+	return nil, errResp
+}
+
+// newRequest - build and sign a raw S3 request.
+func (c *S3Client) newRequest(ctx context.Context, method string, metadata s3RequestMetadata) (*http.Request, error) {
+	endpoint := c.api.EndpointURL()
+	host := endpoint.Host
+	urlStr := endpoint.Scheme + "://"
+	switch {
+	case metadata.bucket == "":
+		urlStr += host + "/"
+	case c.virtualStyle:
+		urlStr += metadata.bucket + "." + host + "/" + s3utils.EncodePath(metadata.object)
+	default:
+		urlStr += host + "/" + metadata.bucket + "/" + s3utils.EncodePath(metadata.object)
+	}
+	if len(metadata.query) > 0 {
+		urlStr += "?" + s3utils.QueryEncode(metadata.query)
+	}
+
+	req, e := http.NewRequest(method, urlStr, bytes.NewReader(metadata.content))
+	if e != nil {
+		return nil, e
+	}
+	req = req.WithContext(ctx)
+	// Headers of the bucket are signed along with the others.
+	for k, v := range c.transport.bucketHeaders[metadata.bucket] {
+		req.Header[k] = v
+	}
+	for k, v := range metadata.header {
+		req.Header[k] = v
+	}
+	req.ContentLength = int64(len(metadata.content))
+	sum := sha256.Sum256(metadata.content)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+
+	return c.sign(req, metadata.bucket, c.virtualStyle)
+}
+
+// sign - sign req with the client credentials, using the region of
+// bucket for signature V4, looked up once per client. Anonymous
+// requests are returned as is.
+func (c *S3Client) sign(req *http.Request, bucket string, virtualStyle bool) (*http.Request, error) {
+	value, e := c.creds.Get()
+	if e != nil {
+		return nil, e
+	}
+	switch {
+	case value.SignerType.IsAnonymous():
+	case value.SignerType.IsV2():
+		req = s3signer.SignV2(*req, value.AccessKeyID, value.SecretAccessKey, virtualStyle)
+	default:
+		location := "us-east-1"
+		if bucket != "" {
+			if l, err := c.bucketRegion(bucket); err == nil && l != "" {
+				location = l
+			}
+		}
+		req = s3signer.SignV4(*req, value.AccessKeyID, value.SecretAccessKey, value.SessionToken, location)
+	}
+	return req, nil
+}
+
+// requestBucket - bucket targeted by a request to the client endpoint,
+// for both path style and virtual host style requests.
+func (c *S3Client) requestBucket(u *url.URL) (bucket string, virtualStyle bool) {
+	host := c.api.EndpointURL().Host
+	if u.Host != host && strings.HasSuffix(u.Host, "."+host) {
+		return strings.TrimSuffix(u.Host, "."+host), true
+	}
+	return strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)[0], false
+}
+
+// SignRequest - sign req with the client credentials without sending
+// it, e.g. to relay it. For signature V4 the region of the bucket is
+// looked up the first time one of its requests is signed. The payload
+// is left unsigned unless req already carries X-Amz-Content-Sha256.
+// req itself is not modified.
+func (c *S3Client) SignRequest(req *http.Request) (*http.Request, *probe.Error) {
+	if req == nil || req.URL == nil {
+		return nil, errInvalidArgument().Trace("nil request")
+	}
+	r := req.Clone(req.Context())
+	if r.Header.Get("X-Amz-Content-Sha256") == "" {
+		r.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	}
+	bucket, virtualStyle := c.requestBucket(r.URL)
+	signed, e := c.sign(r, bucket, virtualStyle)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(req.URL.String())
+	}
+	return signed, nil
+}
+
+// maxSignedHeadersExpiry - S3 rejects requests signed in their headers
+// more than 15 minutes after their X-Amz-Date.
+const maxSignedHeadersExpiry = 15 * time.Minute
+
+// SignedHeaders - headers, including Host, signing a virtual host style
+// request for object which expires after expires. The expiry is signed
+// as X-Amz-Expires and can't exceed the 15 minutes S3 accepts such a
+// signature for, use Presign for longer validities.
+func (c *S3Client) SignedHeaders(method, bucket, object string, expires time.Duration) (http.Header, *probe.Error) {
+	if bucket == "" {
+		return nil, probe.NewError(BucketNameEmpty{})
+	}
+	if expires < time.Second || expires > maxSignedHeadersExpiry {
+		return nil, errInvalidArgument().Trace(expires.String())
+	}
+	endpoint := c.api.EndpointURL()
+	urlStr := endpoint.Scheme + "://" + bucket + "." + endpoint.Host + "/" + s3utils.EncodePath(object)
+	req, e := http.NewRequest(strings.ToUpper(method), urlStr, nil)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	req.Header.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	signed, err := c.SignRequest(req)
+	if err != nil {
+		return nil, err.Trace(bucket, object)
+	}
+	header := signed.Header.Clone()
+	header.Set("Host", signed.URL.Host)
+	return header, nil
+}
@@ -0,0 +1,391 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+const (
+	// maxSelectMessageSize - longest message of the event stream of
+	// select results.
+	maxSelectMessageSize = 16 * 1024 * 1024
+	// maxSelectRecordSize - longest record S3 Select returns.
+	maxSelectRecordSize = 1024 * 1024
+)
+
+// SelectStats - bytes of the object scanned, processed once
+// decompressed, and returned by a select so far, from its Progress and
+// Stats events.
+type SelectStats struct {
+	BytesScanned   int64
+	BytesProcessed int64
+	BytesReturned  int64
+}
+
+// SelectRecord - decoded record of the results of SelectRecords, a
+// progress or stats event of the query, or the error which ended it.
+type SelectRecord struct {
+	// JSON is set to records of JSON outputs, CSV to those of CSV and
+	// raw outputs.
+	JSON map[string]interface{}
+	CSV  []string
+	// Progress is set to progress events, sent while the object is
+	// scanned, and Stats to the stats event, sent last.
+	Progress *SelectStats
+	Stats    *SelectStats
+	Err      *probe.Error
+}
+
+// selectEventStream - reads the payload of the Records events of
+// select results, an event stream, calling onStats with the stats of
+// its Progress and Stats events.
+type selectEventStream struct {
+	body    io.Reader
+	onStats func(eventType string, stats SelectStats)
+	payload []byte
+	err     error
+}
+
+func (s *selectEventStream) Read(p []byte) (int, error) {
+	for len(s.payload) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.payload)
+	s.payload = s.payload[n:]
+	return n, nil
+}
+
+// next - read the next message of the stream, io.EOF once it ends.
+func (s *selectEventStream) next() error {
+	var prelude [12]byte
+	if _, e := io.ReadFull(s.body, prelude[:]); e != nil {
+		if e == io.EOF {
+			// Results end with an End event.
+			e = io.ErrUnexpectedEOF
+		}
+		return e
+	}
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:]) {
+		return errors.New("select results are corrupted, prelude checksum mismatch")
+	}
+	if totalLen < 16 || totalLen > maxSelectMessageSize || headersLen > totalLen-16 {
+		return fmt.Errorf("select results are corrupted, message of %d bytes", totalLen)
+	}
+	message := make([]byte, totalLen)
+	copy(message, prelude[:])
+	if _, e := io.ReadFull(s.body, message[len(prelude):]); e != nil {
+		if e == io.EOF {
+			e = io.ErrUnexpectedEOF
+		}
+		return e
+	}
+	end := totalLen - 4
+	if crc32.ChecksumIEEE(message[:end]) != binary.BigEndian.Uint32(message[end:]) {
+		return errors.New("select results are corrupted, message checksum mismatch")
+	}
+	headers, e := parseSelectEventHeaders(message[len(prelude) : uint32(len(prelude))+headersLen])
+	if e != nil {
+		return e
+	}
+	payload := message[uint32(len(prelude))+headersLen : end]
+
+	if headers["message-type"] == "error" {
+		return minio.ErrorResponse{Code: headers["error-code"], Message: headers["error-message"]}
+	}
+	switch eventType := headers["event-type"]; eventType {
+	case "Records":
+		s.payload = payload
+	case "Progress", "Stats":
+		var stats SelectStats
+		if e = xml.Unmarshal(payload, &stats); e != nil {
+			return e
+		}
+		if s.onStats != nil {
+			s.onStats(eventType, stats)
+		}
+	case "End":
+		return io.EOF
+	}
+	// Cont events only keep the connection alive.
+	return nil
+}
+
+// parseSelectEventHeaders - string headers of an event stream message,
+// without their leading colon.
+func parseSelectEventHeaders(data []byte) (map[string]string, error) {
+	errCorrupted := errors.New("select results are corrupted, invalid message headers")
+	headers := make(map[string]string)
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 2+nameLen {
+			return nil, errCorrupted
+		}
+		name := strings.TrimPrefix(string(data[1:1+nameLen]), ":")
+		valueType := data[1+nameLen]
+		data = data[2+nameLen:]
+		// Length of the values of each type, strings and byte
+		// arrays have theirs first.
+		var valueLen int
+		switch valueType {
+		case 0, 1:
+		case 2:
+			valueLen = 1
+		case 3:
+			valueLen = 2
+		case 4:
+			valueLen = 4
+		case 5, 8:
+			valueLen = 8
+		case 9:
+			valueLen = 16
+		case 6, 7:
+			if len(data) < 2 {
+				return nil, errCorrupted
+			}
+			valueLen = int(binary.BigEndian.Uint16(data))
+			data = data[2:]
+		default:
+			return nil, errCorrupted
+		}
+		if len(data) < valueLen {
+			return nil, errCorrupted
+		}
+		if valueType == 7 {
+			headers[name] = string(data[:valueLen])
+		}
+		data = data[valueLen:]
+	}
+	return headers, nil
+}
+
+// newSelectRecordDecoder - decoder of the records of the output
+// serialization o of selOpts read from r, which returns io.EOF after
+// the last one. Only outputs encoding/json and encoding/csv can parse
+// are supported, raw outputs are split on their delimiters.
+func newSelectRecordDecoder(r io.Reader, selOpts SelectObjectOpts, o minio.SelectObjectOutputSerialization) (func() (SelectRecord, error), *probe.Error) {
+	if o.JSON != nil {
+		if strings.TrimSpace(o.JSON.RecordDelimiter) != "" {
+			return nil, errInvalidArgument().Trace("JSON records must be delimited by white space", o.JSON.RecordDelimiter)
+		}
+		decoder := json.NewDecoder(r)
+		return func() (SelectRecord, error) {
+			var record map[string]interface{}
+			if e := decoder.Decode(&record); e != nil {
+				return SelectRecord{}, e
+			}
+			return SelectRecord{JSON: record}, nil
+		}, nil
+	}
+
+	recordDelimiter, fieldDelimiter := o.CSV.RecordDelimiter, o.CSV.FieldDelimiter
+	_, csvOutput := selOpts.OutputSerOpts["csv"]
+	if _, ok := selOpts.OutputSerOpts["raw"]; ok && !csvOutput {
+		// Raw outputs aren't quoted.
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxSelectRecordSize)
+		scanner.Split(splitSelectRecords([]byte(recordDelimiter)))
+		return func() (SelectRecord, error) {
+			if !scanner.Scan() {
+				if e := scanner.Err(); e != nil {
+					return SelectRecord{}, e
+				}
+				return SelectRecord{}, io.EOF
+			}
+			return SelectRecord{CSV: strings.Split(scanner.Text(), fieldDelimiter)}, nil
+		}, nil
+	}
+
+	switch {
+	case recordDelimiter != "\n" && recordDelimiter != "\r\n":
+		return nil, errInvalidArgument().Trace("CSV records must be delimited by new lines", recordDelimiter)
+	case utf8.RuneCountInString(fieldDelimiter) != 1 || strings.ContainsAny(fieldDelimiter, "\"\r\n"):
+		return nil, errInvalidArgument().Trace("CSV fields must be delimited by a single character", fieldDelimiter)
+	case o.CSV.QuoteCharacter != "" && o.CSV.QuoteCharacter != "\"",
+		o.CSV.QuoteEscapeCharacter != "" && o.CSV.QuoteEscapeCharacter != "\"":
+		return nil, errInvalidArgument().Trace("CSV fields must be quoted with double quotes", o.CSV.QuoteCharacter, o.CSV.QuoteEscapeCharacter)
+	}
+	reader := csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(fieldDelimiter)
+	reader.FieldsPerRecord = -1
+	return func() (SelectRecord, error) {
+		record, e := reader.Read()
+		if e != nil {
+			return SelectRecord{}, e
+		}
+		return SelectRecord{CSV: record}, nil
+	}, nil
+}
+
+// splitSelectRecords - split function of records ending with
+// delimiter, but maybe the last one.
+func splitSelectRecords(delimiter []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, delimiter); i >= 0 {
+			return i + len(delimiter), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// SelectRecords - select object content like Select, sending the
+// decoded records of the results, along with the progress and stats
+// events of the query. Records of JSON outputs, whose delimiter must be
+// white space, are decoded as encoding/json does. Those of CSV outputs
+// are split into fields, CSV ones only if they are delimited by new
+// lines and quoted with double quotes. The error which ends the query,
+// such as the cancellation of ctx, is sent last.
+func (c *S3Client) SelectRecords(ctx context.Context, expression string, sse encrypt.ServerSide, selOpts SelectObjectOpts) <-chan SelectRecord {
+	recordCh := make(chan SelectRecord)
+	go func() {
+		defer close(recordCh)
+		send := func(record SelectRecord) bool {
+			select {
+			case recordCh <- record:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		resp, output, err := c.selectEvents(ctx, expression, sse, selOpts, true)
+		if err != nil {
+			send(SelectRecord{Err: err})
+			return
+		}
+		defer resp.Body.Close()
+		bucket, object := c.url2BucketAndObject()
+
+		var stats *SelectStats
+		canceled := false
+		stream := &selectEventStream{body: resp.Body, onStats: func(eventType string, s SelectStats) {
+			if eventType == "Stats" {
+				// Sent once all records are.
+				stats = &s
+				return
+			}
+			canceled = canceled || !send(SelectRecord{Progress: &s})
+		}}
+		decode, err := newSelectRecordDecoder(stream, selOpts, output)
+		if err != nil {
+			send(SelectRecord{Err: err.Trace(bucket, object)})
+			return
+		}
+		for !canceled {
+			record, e := decode()
+			if e == io.EOF {
+				break
+			}
+			if e != nil {
+				if ctx.Err() != nil {
+					e = ctx.Err()
+				}
+				send(SelectRecord{Err: probe.NewError(e).Trace(bucket, object)})
+				return
+			}
+			canceled = !send(record)
+		}
+		if canceled {
+			return
+		}
+		if stats != nil {
+			send(SelectRecord{Stats: stats})
+		}
+	}()
+	return recordCh
+}
+
+// selectEvents - send the query of SelectWithStats and SelectRecords,
+// asking for Progress events if progress is set, and return the
+// response along with the serialization of its records. Passthrough
+// outputs, which don't use S3 Select, are rejected.
+func (c *S3Client) selectEvents(ctx context.Context, expression string, sse encrypt.ServerSide, selOpts SelectObjectOpts, progress bool) (*http.Response, minio.SelectObjectOutputSerialization, *probe.Error) {
+	if err := validateSelectExpression(expression); err != nil {
+		return nil, minio.SelectObjectOutputSerialization{}, err.Trace(c.targetURL.String())
+	}
+	if _, ok := selOpts.OutputSerOpts["passthrough"]; ok {
+		return nil, minio.SelectObjectOutputSerialization{}, errInvalidArgument().Trace("passthrough output doesn't use S3 Select")
+	}
+	bucket, object := c.url2BucketAndObject()
+	opts := minio.SelectObjectOptions{
+		Expression:           expression,
+		ExpressionType:       minio.QueryExpressionTypeSQL,
+		ServerSideEncryption: c.readSSE(sse),
+	}
+	opts.InputSerialization = selectObjectInputOpts(selOpts, object)
+	opts.OutputSerialization = selectObjectOutputOpts(selOpts, opts.InputSerialization)
+	opts.RequestProgress.Enabled = progress
+
+	resp, err := c.selectObjectContent(ctx, bucket, object, opts)
+	if err != nil {
+		return nil, minio.SelectObjectOutputSerialization{}, err.Trace(bucket, object)
+	}
+	return resp, opts.OutputSerialization, nil
+}
+
+// selectObjectContent - send the SelectObjectContent request of opts,
+// whose response is the event stream of the results. minio-go doesn't
+// report the Progress events of the stream.
+func (c *S3Client) selectObjectContent(ctx context.Context, bucket, object string, opts minio.SelectObjectOptions) (*http.Response, *probe.Error) {
+	body, e := xml.Marshal(opts)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	sum := md5.Sum(body)
+	header := opts.Header()
+	header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	query := url.Values{"select": []string{""}, "select-type": []string{"2"}}
+	resp, e := c.executeRequest(ctx, http.MethodPost, s3RequestMetadata{
+		bucket:  bucket,
+		object:  object,
+		query:   query,
+		header:  header,
+		content: body,
+	})
+	if e != nil {
+		return nil, probe.NewError(regionError(bucket, e))
+	}
+	return resp, nil
+}
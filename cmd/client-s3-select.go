@@ -0,0 +1,414 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+	"github.com/minio/minio/pkg/mimedb"
+)
+
+// maxSelectExpressionSize - longest SQL expression S3 accepts.
+const maxSelectExpressionSize = 256 * 1024
+
+// SelectFromReader - select object content with the SQL expression
+// read from r, e.g. a .sql file.
+func (c *S3Client) SelectFromReader(r io.Reader, sse encrypt.ServerSide, selOpts SelectObjectOpts) (io.ReadCloser, *probe.Error) {
+	expression, err := readSelectExpression(r)
+	if err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	return c.Select(expression, sse, selOpts)
+}
+
+// readSelectExpression - reads a SQL expression, stripping UTF-8 byte
+// order marks and line comments.
+func readSelectExpression(r io.Reader) (string, *probe.Error) {
+	// Comments may make the source longer than the expression, but
+	// not unboundedly.
+	data, e := ioutil.ReadAll(io.LimitReader(r, 16*maxSelectExpressionSize+1))
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	if len(data) > 16*maxSelectExpressionSize {
+		return "", probe.NewError(SelectExpressionTooLarge{Size: len(data), Limit: maxSelectExpressionSize})
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	expression := stripSQLComments(string(data))
+	if err := validateSelectExpression(expression); err != nil {
+		return "", err.Trace()
+	}
+	return expression, nil
+}
+
+// stripSQLComments - removes '--' comments up to the end of their
+// line, outside of quoted strings and identifiers.
+func stripSQLComments(sql string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case quote != 0:
+			// A doubled quote is an escaped one and toggles the
+			// quoting twice.
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			if i == len(sql) {
+				return strings.TrimSpace(b.String())
+			}
+		}
+		b.WriteByte(sql[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// validateSelectExpression - rejects expressions S3 would refuse.
+func validateSelectExpression(expression string) *probe.Error {
+	if strings.TrimSpace(expression) == "" {
+		return errInvalidArgument().Trace("select expression cannot be empty")
+	}
+	if len(expression) > maxSelectExpressionSize {
+		return probe.NewError(SelectExpressionTooLarge{Size: len(expression), Limit: maxSelectExpressionSize})
+	}
+	return nil
+}
+
+// selectAllRegexp - matches SELECT * FROM S3Object, with an optional
+// alias.
+var selectAllRegexp = regexp.MustCompile(`(?i)^\s*select\s+\*\s+from\s+s3object(\s+(as\s+)?\w+)?\s*;?\s*$`)
+
+// selectsWholeObject - tells whether expression selects the whole
+// object content, unfiltered.
+func selectsWholeObject(expression string) bool {
+	return selectAllRegexp.MatchString(stripSQLComments(expression))
+}
+
+// SelectWithLimit - select object content, delivering at most limit
+// records, all of them when limit is 0. Delivered data is also copied
+// to w when it is not nil, e.g. to stream results to a file.
+func (c *S3Client) SelectWithLimit(expression string, sse encrypt.ServerSide, selOpts SelectObjectOpts, limit int64, w io.Writer) (*SelectReader, *probe.Error) {
+	if limit < 0 {
+		return nil, errInvalidArgument().Trace("record limit cannot be negative")
+	}
+	reader, err := c.Select(expression, sse, selOpts)
+	if err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	_, object := c.url2BucketAndObject()
+	delimiter := selectRecordDelimiter(selOpts, selectObjectInputOpts(selOpts, object))
+	return newSelectReader(reader, delimiter, limit, w), nil
+}
+
+// CompressionStats - compression of the data scanned by a select, from
+// the Stats event ending its results.
+type CompressionStats struct {
+	BytesOnDisk             int64
+	BytesAfterDecompression int64
+	// BytesAfterDecompression over BytesOnDisk, 0 when nothing was
+	// scanned.
+	CompressionRatio float64
+}
+
+// newCompressionStats - compression stats of a select Stats event.
+func newCompressionStats(stats minio.StatsMessage) CompressionStats {
+	compression := CompressionStats{
+		BytesOnDisk:             stats.BytesScanned,
+		BytesAfterDecompression: stats.BytesProcessed,
+	}
+	if stats.BytesScanned > 0 {
+		compression.CompressionRatio = float64(stats.BytesProcessed) / float64(stats.BytesScanned)
+	}
+	return compression
+}
+
+// SelectWithProgress - select object content like Select, calling
+// progress with the compression stats of the query once its results
+// are read until the end. progress is not called when the results are
+// closed before, nor for passthrough outputs which don't use S3 Select.
+func (c *S3Client) SelectWithProgress(expression string, sse encrypt.ServerSide, selOpts SelectObjectOpts, progress func(CompressionStats)) (io.ReadCloser, *probe.Error) {
+	reader, err := c.Select(expression, sse, selOpts)
+	if err != nil {
+		return nil, err
+	}
+	results, ok := reader.(*minio.SelectResults)
+	if !ok || progress == nil {
+		return reader, nil
+	}
+	return &selectStatsReader{results: results, progress: progress}, nil
+}
+
+// selectStatsReader - reads select results, reporting their stats at
+// the end.
+type selectStatsReader struct {
+	results  *minio.SelectResults
+	progress func(CompressionStats)
+	reported bool
+}
+
+func (r *selectStatsReader) Read(p []byte) (int, error) {
+	n, e := r.results.Read(p)
+	if e == io.EOF && !r.reported {
+		// The Stats event precedes the end of the results.
+		r.reported = true
+		if stats := r.results.Stats(); stats != nil {
+			r.progress(newCompressionStats(*stats))
+		}
+	}
+	return n, e
+}
+
+// Close - closes the select stream.
+func (r *selectStatsReader) Close() error {
+	return r.results.Close()
+}
+
+// SelectWithStats - select object content like Select, calling onStats
+// from the reads of the results with the bytes scanned, processed and
+// returned so far, as the Progress events of the query report them,
+// then with final set once its Stats event, which precedes the end of
+// the results, reports their totals. Passthrough outputs, which don't
+// use S3 Select, have no stats.
+func (c *S3Client) SelectWithStats(ctx context.Context, expression string, sse encrypt.ServerSide, selOpts SelectObjectOpts, onStats func(stats SelectStats, final bool)) (io.ReadCloser, *probe.Error) {
+	resp, _, err := c.selectEvents(ctx, expression, sse, selOpts, onStats != nil)
+	if err != nil {
+		return nil, err
+	}
+	stream := &selectEventStream{body: resp.Body}
+	if onStats != nil {
+		stream.onStats = func(eventType string, stats SelectStats) {
+			onStats(stats, eventType == "Stats")
+		}
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{stream, resp.Body}, nil
+}
+
+// selectRecordDelimiter - record delimiter of the output serialization
+// selectObjectOutputOpts picks for the same options.
+func selectRecordDelimiter(selOpts SelectObjectOpts, i minio.SelectObjectInputSerialization) string {
+	if _, ok := selOpts.OutputSerOpts["json"]; ok {
+		if recDelim, ok := selOpts.OutputSerOpts["json"][recordDelimiterType]; ok {
+			return recDelim
+		}
+		return "\n"
+	}
+	if _, ok := selOpts.OutputSerOpts["csv"]; ok {
+		if recDelim, ok := selOpts.OutputSerOpts["csv"][recordDelimiterType]; ok {
+			return recDelim
+		}
+		return defaultRecordDelimiter
+	}
+	if _, ok := selOpts.OutputSerOpts["raw"]; ok {
+		if recDelim, ok := selOpts.OutputSerOpts["raw"][recordDelimiterType]; ok {
+			return recDelim
+		}
+		return defaultRecordDelimiter
+	}
+	if i.JSON != nil {
+		return "\n"
+	}
+	return defaultRecordDelimiter
+}
+
+// SelectReader - reads select results up to a record limit, counting
+// delivered records and bytes.
+type SelectReader struct {
+	reader    io.ReadCloser
+	writer    io.Writer
+	delimiter []byte
+	limit     int64
+
+	// number of delimiter bytes matched at the end of the last read.
+	matched int
+	// data was delivered after the last record delimiter.
+	pending bool
+	done    bool
+	closed  bool
+
+	records int64
+	bytes   int64
+}
+
+func newSelectReader(reader io.ReadCloser, delimiter string, limit int64, w io.Writer) *SelectReader {
+	if delimiter == "" {
+		delimiter = defaultRecordDelimiter
+	}
+	return &SelectReader{
+		reader:    reader,
+		writer:    w,
+		delimiter: []byte(delimiter),
+		limit:     limit,
+	}
+}
+
+// Records - number of records delivered so far, a trailing record
+// without delimiter is counted once the stream ends.
+func (r *SelectReader) Records() int64 {
+	return r.records
+}
+
+// Bytes - number of bytes delivered so far.
+func (r *SelectReader) Bytes() int64 {
+	return r.bytes
+}
+
+func (r *SelectReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, io.EOF
+	}
+	n, e := r.reader.Read(p)
+	for i := 0; i < n; i++ {
+		r.pending = true
+		switch {
+		case p[i] == r.delimiter[r.matched]:
+			r.matched++
+		case p[i] == r.delimiter[0]:
+			r.matched = 1
+		default:
+			r.matched = 0
+		}
+		if r.matched < len(r.delimiter) {
+			continue
+		}
+		r.matched = 0
+		r.pending = false
+		r.records++
+		if r.limit > 0 && r.records >= r.limit {
+			// Limit reached, stop the query instead of reading
+			// the remaining results.
+			n = i + 1
+			r.done = true
+			e = r.Close()
+			if e == nil {
+				e = io.EOF
+			}
+			break
+		}
+	}
+	if e == io.EOF {
+		r.done = true
+		if r.pending {
+			r.pending = false
+			r.records++
+		}
+	}
+	r.bytes += int64(n)
+	if n > 0 && r.writer != nil {
+		if _, we := r.writer.Write(p[:n]); we != nil {
+			return n, we
+		}
+	}
+	return n, e
+}
+
+// Close - closes the select stream.
+func (r *SelectReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.reader.Close()
+}
+
+// selectFormats - formats of the objects S3 Select can query.
+var selectFormats = []string{"csv", "json", "parquet", "orc"}
+
+// selectObjectFormat - format of an object guessed from the extension
+// of its name, compression extensions aside, or else from its content
+// type, empty if S3 Select can't query it.
+func selectObjectFormat(name, contentType string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(trimCompressionFileExts(name)), "."))
+	for _, format := range selectFormats {
+		if ext == format {
+			return format
+		}
+	}
+	if contentType == "" {
+		contentType = mimedb.TypeByExtension(filepath.Ext(name))
+	}
+	for _, format := range selectFormats {
+		if strings.Contains(contentType, format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// ListSelectableObjects - list recursively the objects under prefix,
+// relative to the current prefix, which S3 Select can query, with
+// their SelectFormat set. Listing stops at the first error, sent on
+// the error channel. Both channels are closed once done.
+func (c *S3Client) ListSelectableObjects(ctx context.Context, prefix string) (<-chan *ClientContent, <-chan *probe.Error) {
+	contentCh := make(chan *ClientContent)
+	// Buffered so that the listing ends even if the error is read
+	// after all contents.
+	errCh := make(chan *probe.Error, 1)
+	go func() {
+		defer close(contentCh)
+		defer close(errCh)
+		bucket, object := c.url2BucketAndObject()
+		if bucket == "" {
+			errCh <- probe.NewError(BucketNameEmpty{})
+			return
+		}
+		prefix = object + prefix
+		for entry := range c.listObjectWrapper(bucket, prefix, true, ctx.Done(), false) {
+			if entry.Err != nil {
+				errCh <- probe.NewError(regionError(bucket, entry.Err)).Trace(bucket, prefix)
+				return
+			}
+			if strings.HasSuffix(entry.Key, "/") {
+				// Directory marker.
+				continue
+			}
+			format := selectObjectFormat(entry.Key, entry.ContentType)
+			if format == "" {
+				continue
+			}
+			entry.ETag = strings.Trim(entry.ETag, "\"")
+			content := c.objectInfo2ClientContent(bucket, entry, false)
+			content.SelectFormat = format
+			select {
+			case contentCh <- content:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			errCh <- probe.NewError(ctx.Err())
+		}
+	}()
+	return contentCh, errCh
+}
@@ -0,0 +1,496 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+	. "gopkg.in/check.v1"
+)
+
+// closeRecorder records whether the stream was closed.
+type closeRecorder struct {
+	*strings.Reader
+	closed *bool
+}
+
+func (r closeRecorder) Close() error {
+	*r.closed = true
+	return nil
+}
+
+// Test limiting and teeing select results.
+func (s *TestSuite) TestSelectReader(c *C) {
+	testCases := []struct {
+		data      string
+		delimiter string
+		limit     int64
+		output    string
+		records   int64
+		closed    bool
+	}{
+		{"a,1\nb,2\nc,3\n", "\n", 0, "a,1\nb,2\nc,3\n", 3, false},
+		{"a,1\nb,2\nc,3", "\n", 0, "a,1\nb,2\nc,3", 3, false},
+		{"a,1\nb,2\nc,3\n", "\n", 2, "a,1\nb,2\n", 2, true},
+		{"a,1\r\nb,2\r\nc,3\r\n", "\r\n", 1, "a,1\r\n", 1, true},
+		{"a,\r1\r\nb,2\r\n", "\r\n", 0, "a,\r1\r\nb,2\r\n", 2, false},
+		{"{\"a\":1}|{\"a\":2}|{\"a\":3}|", "|", 2, "{\"a\":1}|{\"a\":2}|", 2, true},
+		{"", "\n", 5, "", 0, false},
+	}
+	for i, testCase := range testCases {
+		var closed bool
+		var tee bytes.Buffer
+		source := closeRecorder{Reader: strings.NewReader(testCase.data), closed: &closed}
+		reader := newSelectReader(source, testCase.delimiter, testCase.limit, &tee)
+		// Tiny reads to split delimiters across reads.
+		var got []byte
+		buf := make([]byte, 3)
+		for {
+			n, e := reader.Read(buf)
+			got = append(got, buf[:n]...)
+			if e != nil {
+				break
+			}
+		}
+		c.Assert(string(got), Equals, testCase.output, Commentf("test %d", i+1))
+		c.Assert(tee.String(), Equals, testCase.output, Commentf("test %d", i+1))
+		c.Assert(reader.Records(), Equals, testCase.records, Commentf("test %d", i+1))
+		c.Assert(reader.Bytes(), Equals, int64(len(testCase.output)), Commentf("test %d", i+1))
+		c.Assert(closed, Equals, testCase.closed, Commentf("test %d", i+1))
+		c.Assert(reader.Close(), IsNil)
+		c.Assert(closed, Equals, true)
+	}
+
+	// Output record delimiter follows the output serialization.
+	c.Assert(selectRecordDelimiter(SelectObjectOpts{}, minio.SelectObjectInputSerialization{}), Equals, defaultRecordDelimiter)
+	c.Assert(selectRecordDelimiter(SelectObjectOpts{}, minio.SelectObjectInputSerialization{JSON: &minio.JSONInputOptions{}}), Equals, "\n")
+	opts := SelectObjectOpts{OutputSerOpts: map[string]map[string]string{"csv": {recordDelimiterType: "\r\n"}}}
+	c.Assert(selectRecordDelimiter(opts, minio.SelectObjectInputSerialization{}), Equals, "\r\n")
+
+	data, e := ioutil.ReadAll(newSelectReader(closeRecorder{Reader: strings.NewReader("x\ny\n"), closed: new(bool)}, "", 1, nil))
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "x\n")
+}
+
+// Test reading select expressions from files.
+func (s *TestSuite) TestReadSelectExpression(c *C) {
+	testCases := []struct {
+		source     string
+		expression string
+		tooLarge   bool
+		invalid    bool
+	}{
+		{"SELECT * FROM s3object", "SELECT * FROM s3object", false, false},
+		{"\xef\xbb\xbfSELECT * FROM s3object\n", "SELECT * FROM s3object", false, false},
+		{"-- all rows\nSELECT *\nFROM s3object -- everything\n", "SELECT *\nFROM s3object", false, false},
+		{"SELECT * FROM s3object s WHERE s.name = 'a--b' -- comment", "SELECT * FROM s3object s WHERE s.name = 'a--b'", false, false},
+		{"SELECT \"x--y\" FROM s3object", "SELECT \"x--y\" FROM s3object", false, false},
+		{"SELECT * FROM s3object s WHERE s.name = 'it''s' -- comment", "SELECT * FROM s3object s WHERE s.name = 'it''s'", false, false},
+		{"-- nothing\n  \n", "", false, true},
+		{"\xef\xbb\xbf", "", false, true},
+		{"SELECT '" + strings.Repeat("x", maxSelectExpressionSize) + "' FROM s3object", "", true, false},
+		{strings.Repeat("-- padding\n", 2*maxSelectExpressionSize) + "SELECT * FROM s3object", "", true, false},
+	}
+	for i, testCase := range testCases {
+		expression, err := readSelectExpression(strings.NewReader(testCase.source))
+		switch {
+		case testCase.tooLarge:
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			c.Assert(errors.As(err.ToGoError(), &SelectExpressionTooLarge{}), Equals, true, Commentf("test %d", i+1))
+		case testCase.invalid:
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+		default:
+			c.Assert(err, IsNil, Commentf("test %d", i+1))
+			c.Assert(expression, Equals, testCase.expression, Commentf("test %d", i+1))
+		}
+	}
+
+	// Empty expressions are rejected without any request.
+	conf := testConfig("http://localhost:1/bucket/object.csv", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.Select(" ", nil, SelectObjectOpts{})
+	c.Assert(err, NotNil)
+}
+
+// selectRequestHandler records the body of select requests, which
+// fail, and serves a stored CSV object.
+type selectRequestHandler struct {
+	body *string
+}
+
+func (h selectRequestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.URL.Path != "/bucket/data.csv" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "POST":
+		data, _ := ioutil.ReadAll(r.Body)
+		*h.body = string(data)
+		w.WriteHeader(http.StatusNotImplemented)
+	case "GET":
+		data := []byte("name,city\n\"Doe, John\",Ottawa\n")
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "\"9af2f8218b150c351ad802c6f3d66abe\"")
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Test unquoted raw and passthrough select outputs.
+func (s *TestSuite) TestSelectOutputRawAndPassthrough(c *C) {
+	var body string
+	server := httptest.NewServer(selectRequestHandler{body: &body})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/data.csv", "S3v2")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	// Raw output disables quoting.
+	raw := SelectObjectOpts{OutputSerOpts: map[string]map[string]string{"raw": {fieldDelimiterType: "|"}}}
+	_, err = clnt.Select("SELECT * FROM S3Object", nil, raw)
+	c.Assert(err, NotNil)
+	c.Assert(body, Matches, "(?s).*<OutputSerialization><CSV>.*</CSV></OutputSerialization>.*")
+	c.Assert(strings.Contains(body, "<FieldDelimiter>|</FieldDelimiter>"), Equals, true)
+	c.Assert(strings.Contains(body, "<QuoteCharacter></QuoteCharacter>"), Equals, true)
+	c.Assert(strings.Contains(body, "<QuoteFields>ASNEEDED</QuoteFields>"), Equals, true)
+	c.Assert(selectRecordDelimiter(raw, minio.SelectObjectInputSerialization{}), Equals, defaultRecordDelimiter)
+
+	// Quotes are only set for CSV output when asked for.
+	body = ""
+	_, err = clnt.Select("SELECT * FROM S3Object", nil, SelectObjectOpts{OutputSerOpts: map[string]map[string]string{"csv": {}}})
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(body, "<QuoteCharacter>"), Equals, false)
+
+	// Passthrough returns the object as stored, without select.
+	passthrough := SelectObjectOpts{OutputSerOpts: map[string]map[string]string{"passthrough": {}}}
+	for _, expression := range []string{"SELECT * FROM S3Object", "select *\nfrom s3object s;", "-- all\nSELECT * FROM S3Object"} {
+		body = ""
+		reader, err := clnt.Select(expression, nil, passthrough)
+		c.Assert(err, IsNil, Commentf("%q", expression))
+		data, e := ioutil.ReadAll(reader)
+		reader.Close()
+		c.Assert(e, IsNil)
+		c.Assert(string(data), Equals, "name,city\n\"Doe, John\",Ottawa\n")
+		c.Assert(body, Equals, "")
+	}
+	// Filters can't be applied without select.
+	_, err = clnt.Select("SELECT name FROM S3Object WHERE city = 'Ottawa'", nil, passthrough)
+	c.Assert(err, NotNil)
+	c.Assert(body, Equals, "")
+}
+
+// Test listing the objects S3 Select can query.
+func (s *TestSuite) TestListSelectableObjects(c *C) {
+	var requests []string
+	handler := bucketObjectsHandler{
+		objects:  make(map[string]string),
+		mutex:    &sync.Mutex{},
+		requests: &requests,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	newClient := func(path string) *S3Client {
+		conf := testConfig(server.URL+"/bucket/"+path, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		return clnt.(*S3Client)
+	}
+
+	for _, name := range []string{
+		"data/cities.csv", "data/events.json", "data/events.JSON", "data/logs.csv.gz",
+		"data/archive.csv.tar.gz", "data/table.parquet", "data/table.orc",
+		"data/readme.txt", "data/photo.png", "data/backup.tar.gz", "data/noext",
+		"other/cities.csv",
+	} {
+		data := "a,b\n1,2\n"
+		_, err := newClient(name).Put(context.Background(), strings.NewReader(data), int64(len(data)), nil, nil, nil, false, false, "")
+		c.Assert(err, IsNil)
+	}
+
+	contentCh, errCh := newClient("").ListSelectableObjects(context.Background(), "data/")
+	formats := make(map[string]string)
+	for content := range contentCh {
+		formats[content.Key] = content.SelectFormat
+	}
+	c.Assert(<-errCh, IsNil)
+	c.Assert(formats, DeepEquals, map[string]string{
+		"data/cities.csv":         "csv",
+		"data/events.json":        "json",
+		"data/events.JSON":        "json",
+		"data/logs.csv.gz":        "csv",
+		"data/archive.csv.tar.gz": "csv",
+		"data/table.parquet":      "parquet",
+		"data/table.orc":          "orc",
+	})
+
+	// The prefix is relative to the one of the client.
+	contentCh, errCh = newClient("other/").ListSelectableObjects(context.Background(), "")
+	var keys []string
+	for content := range contentCh {
+		keys = append(keys, content.Key)
+	}
+	c.Assert(<-errCh, IsNil)
+	c.Assert(keys, DeepEquals, []string{"other/cities.csv"})
+}
+
+// Test guessing the format of queryable objects.
+func (s *TestSuite) TestSelectObjectFormat(c *C) {
+	testCases := []struct {
+		name        string
+		contentType string
+		format      string
+	}{
+		{"a.csv", "", "csv"},
+		{"a.json.bz2", "", "json"},
+		{"a.parquet.zst", "", "parquet"},
+		{"a", "text/csv", "csv"},
+		{"a.data", "application/json", "json"},
+		{"a.gz", "application/gzip", ""},
+		{"a.txt", "", ""},
+	}
+	for i, testCase := range testCases {
+		c.Assert(selectObjectFormat(testCase.name, testCase.contentType), Equals, testCase.format, Commentf("test %d", i+1))
+	}
+}
+
+// selectEventMessage - event stream message of select results.
+func selectEventMessage(eventType, contentType string, payload []byte) []byte {
+	return selectStreamMessage([][2]string{
+		{":message-type", "event"},
+		{":event-type", eventType},
+		{":content-type", contentType},
+	}, payload)
+}
+
+// selectStreamMessage - event stream message with string headers.
+func selectStreamMessage(stringHeaders [][2]string, payload []byte) []byte {
+	var headers bytes.Buffer
+	for _, header := range stringHeaders {
+		headers.WriteByte(byte(len(header[0])))
+		headers.WriteString(header[0])
+		// String value.
+		headers.WriteByte(7)
+		binary.Write(&headers, binary.BigEndian, uint16(len(header[1])))
+		headers.WriteString(header[1])
+	}
+	var message bytes.Buffer
+	binary.Write(&message, binary.BigEndian, uint32(16+headers.Len()+len(payload)))
+	binary.Write(&message, binary.BigEndian, uint32(headers.Len()))
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	message.Write(headers.Bytes())
+	message.Write(payload)
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	return message.Bytes()
+}
+
+// Test reporting the compression stats of select results.
+func (s *TestSuite) TestSelectWithProgress(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.Write(response)
+			return
+		}
+		if r.Method != "POST" || r.URL.Path != "/bucket/data.csv.gz" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var stream []byte
+		stream = append(stream, selectEventMessage("Records", "application/octet-stream", []byte("Doe,Ottawa\n"))...)
+		stream = append(stream, selectEventMessage("Stats", "text/xml",
+			[]byte("<Stats><BytesScanned>100</BytesScanned><BytesProcessed>400</BytesProcessed><BytesReturned>11</BytesReturned></Stats>"))...)
+		stream = append(stream, selectEventMessage("End", "", nil)...)
+		w.Write(stream)
+	}))
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/data.csv.gz", "S3v4")
+	var stats []CompressionStats
+	reader, err := s3c.SelectWithProgress("SELECT * FROM S3Object", nil, SelectObjectOpts{}, func(compression CompressionStats) {
+		stats = append(stats, compression)
+	})
+	c.Assert(err, IsNil)
+	data, e := ioutil.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(string(data), Equals, "Doe,Ottawa\n")
+	c.Assert(stats, DeepEquals, []CompressionStats{{BytesOnDisk: 100, BytesAfterDecompression: 400, CompressionRatio: 4}})
+
+	c.Assert(newCompressionStats(minio.StatsMessage{}), DeepEquals, CompressionStats{})
+}
+
+// selectStreamHandler serves the select results of objects, recording
+// the body of the last request.
+type selectStreamHandler struct {
+	streams map[string][][]byte
+	body    *string
+}
+
+func (h selectStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	stream, ok := h.streams[strings.TrimPrefix(r.URL.Path, "/bucket/")]
+	if r.Method != "POST" || !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	data, _ := ioutil.ReadAll(r.Body)
+	*h.body = string(data)
+	w.Write(bytes.Join(stream, nil))
+}
+
+// Test decoding the records of select results split across events.
+func (s *TestSuite) TestSelectRecords(c *C) {
+	records := func(payloads ...string) [][]byte {
+		var stream [][]byte
+		for _, payload := range payloads {
+			stream = append(stream, selectEventMessage("Records", "application/octet-stream", []byte(payload)))
+		}
+		return stream
+	}
+	progress := selectEventMessage("Progress", "text/xml",
+		[]byte("<Progress><BytesScanned>50</BytesScanned><BytesProcessed>200</BytesProcessed><BytesReturned>10</BytesReturned></Progress>"))
+	stats := selectEventMessage("Stats", "text/xml",
+		[]byte("<Stats><BytesScanned>100</BytesScanned><BytesProcessed>400</BytesProcessed><BytesReturned>30</BytesReturned></Stats>"))
+	end := selectEventMessage("End", "", nil)
+	cont := selectEventMessage("Cont", "", nil)
+	failure := selectStreamMessage([][2]string{
+		{":message-type", "error"},
+		{":error-code", "CSVParsingError"},
+		{":error-message", "bad CSV"},
+	}, nil)
+
+	var body string
+	server := httptest.NewServer(selectStreamHandler{streams: map[string][][]byte{
+		"data.csv": append(append(records("name,city\n\"Doe, John\",Otta"), progress, cont),
+			append(records("wa\n"), stats, end)...),
+		"data.json": append(records("{\"name\":\"Doe\",\"age\":40}\n{\"na", "me\":\"Roe\"}\n"), stats, end),
+		"raw.csv":   append(records("a|b;c|", "d"), stats, end),
+		"bad.csv":   append(records("a,b\n"), failure),
+		"short.csv": records("a,b\n"),
+	}, body: &body})
+	defer server.Close()
+
+	testCases := []struct {
+		object  string
+		selOpts SelectObjectOpts
+		records []string
+		err     bool
+	}{
+		{"data.csv", SelectObjectOpts{}, []string{"[name city]", "progress 50", "[Doe, John Ottawa]", "stats 100"}, false},
+		{"data.json", SelectObjectOpts{}, []string{"map[age:40 name:Doe]", "map[name:Roe]", "stats 100"}, false},
+		{"raw.csv", SelectObjectOpts{OutputSerOpts: map[string]map[string]string{"raw": {fieldDelimiterType: "|", recordDelimiterType: ";"}}},
+			[]string{"[a b]", "[c d]", "stats 100"}, false},
+		// Errors of the query and streams without end are reported.
+		{"bad.csv", SelectObjectOpts{}, []string{"[a b]"}, true},
+		{"short.csv", SelectObjectOpts{}, []string{"[a b]"}, true},
+		// JSON records must be delimited by white space.
+		{"data.json", SelectObjectOpts{OutputSerOpts: map[string]map[string]string{"json": {recordDelimiterType: ","}}}, nil, true},
+	}
+	for i, testCase := range testCases {
+		s3c := newTestS3Client(c, server.URL+"/bucket/"+testCase.object, "S3v4")
+		var got []string
+		var err *probe.Error
+		for record := range s3c.SelectRecords(context.Background(), "SELECT * FROM S3Object", nil, testCase.selOpts) {
+			switch {
+			case record.Err != nil:
+				err = record.Err
+			case record.Progress != nil:
+				got = append(got, "progress "+strconv.FormatInt(record.Progress.BytesScanned, 10))
+			case record.Stats != nil:
+				got = append(got, "stats "+strconv.FormatInt(record.Stats.BytesScanned, 10))
+			case record.JSON != nil:
+				got = append(got, fmt.Sprint(record.JSON))
+			default:
+				got = append(got, fmt.Sprint(record.CSV))
+			}
+		}
+		c.Assert(got, DeepEquals, testCase.records, Commentf("test %d", i+1))
+		c.Assert(err != nil, Equals, testCase.err, Commentf("test %d", i+1))
+		if testCase.object == "bad.csv" {
+			c.Assert(minio.ToErrorResponse(err.ToGoError()).Code, Equals, "CSVParsingError")
+		}
+	}
+	c.Assert(strings.Contains(body, "<RequestProgress><Enabled>true</Enabled></RequestProgress>"), Equals, true)
+}
+
+// Test reading select results along with their progress and stats.
+func (s *TestSuite) TestSelectWithStats(c *C) {
+	progress := selectEventMessage("Progress", "text/xml",
+		[]byte("<Progress><BytesScanned>50</BytesScanned><BytesProcessed>200</BytesProcessed><BytesReturned>10</BytesReturned></Progress>"))
+	stats := selectEventMessage("Stats", "text/xml",
+		[]byte("<Stats><BytesScanned>100</BytesScanned><BytesProcessed>400</BytesProcessed><BytesReturned>20</BytesReturned></Stats>"))
+	var body string
+	server := httptest.NewServer(selectStreamHandler{streams: map[string][][]byte{
+		"data.csv": {
+			selectEventMessage("Records", "application/octet-stream", []byte("Doe,Ottawa\n")),
+			progress,
+			selectEventMessage("Records", "application/octet-stream", []byte("Roe,Paris\n")),
+			stats,
+			selectEventMessage("End", "", nil),
+		},
+	}, body: &body})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/data.csv", "S3v4")
+	var got []string
+	reader, err := s3c.SelectWithStats(context.Background(), "SELECT * FROM S3Object", nil, SelectObjectOpts{}, func(stats SelectStats, final bool) {
+		got = append(got, fmt.Sprint(stats.BytesScanned, stats.BytesProcessed, stats.BytesReturned, final))
+	})
+	c.Assert(err, IsNil)
+	data, e := ioutil.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(string(data), Equals, "Doe,Ottawa\nRoe,Paris\n")
+	c.Assert(got, DeepEquals, []string{"50 200 10 false", "100 400 20 true"})
+	c.Assert(strings.Contains(body, "<RequestProgress><Enabled>true</Enabled></RequestProgress>"), Equals, true)
+
+	_, err = s3c.SelectWithStats(context.Background(), "SELECT * FROM S3Object", nil, SelectObjectOpts{
+		OutputSerOpts: map[string]map[string]string{"passthrough": {}},
+	}, nil)
+	c.Assert(err, NotNil)
+}
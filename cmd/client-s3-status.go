@@ -0,0 +1,82 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+)
+
+// ServiceStatus - health of an endpoint.
+type ServiceStatus struct {
+	// Reachable is true when the endpoint answered, even with an
+	// error such as AccessDenied.
+	Reachable bool
+	Latency   time.Duration
+	// ServerVersion is the Server header of the response.
+	ServerVersion string
+	// RegionHint is the x-amz-bucket-region header of an error
+	// response, if any.
+	RegionHint string
+}
+
+// GetServiceStatus - checks the endpoint is up with a ListBuckets call,
+// measuring its round-trip latency.
+func (c *S3Client) GetServiceStatus(ctx context.Context) (ServiceStatus, *probe.Error) {
+	req, e := c.newRequest(ctx, http.MethodGet, s3RequestMetadata{})
+	if e != nil {
+		return ServiceStatus{}, probe.NewError(e)
+	}
+	start := time.Now()
+	resp, e := (&http.Client{Transport: c.transport}).Do(req)
+	latency := time.Since(start)
+	if e != nil {
+		return ServiceStatus{Latency: latency}, probe.NewError(e).Trace(c.targetURL.String())
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	status := ServiceStatus{
+		Reachable:     true,
+		Latency:       latency,
+		ServerVersion: resp.Header.Get("Server"),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		status.RegionHint = resp.Header.Get("X-Amz-Bucket-Region")
+	}
+	return status, nil
+}
+
+// PingObject - measures the latency of a HEAD request on object.
+func (c *S3Client) PingObject(ctx context.Context, bucket, object string) (time.Duration, *probe.Error) {
+	if bucket == "" {
+		return 0, probe.NewError(BucketNameEmpty{})
+	}
+	start := time.Now()
+	_, e := c.api.StatObjectWithContext(ctx, bucket, object, minio.StatObjectOptions{})
+	latency := time.Since(start)
+	if e != nil {
+		return latency, probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+	}
+	return latency, nil
+}
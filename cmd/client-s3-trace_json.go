@@ -0,0 +1,144 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jsonTrace - trace of a request and its response, written as one JSON
+// object per line by jsonTraceTransport.
+type jsonTrace struct {
+	Time           time.Time   `json:"time"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Status         int         `json:"status,omitempty"`
+	Error          string      `json:"error,omitempty"`
+	DurationNanos  int64       `json:"durationNanos"`
+	RequestHeader  http.Header `json:"requestHeader"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	RequestBytes   int64       `json:"requestBytes"`
+	ResponseBytes  int64       `json:"responseBytes"`
+}
+
+// Headers whose values are secrets, redacted in JSON traces.
+var jsonTraceSecretHeaders = []string{
+	"Authorization",
+	"X-Amz-Security-Token",
+	"X-Amz-Server-Side-Encryption-Customer-Key",
+	"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key",
+}
+
+// jsonTraceTransport - traces requests to a writer as JSON, once their
+// response body is read or closed, so that the durations and byte
+// counts cover the whole transfer.
+type jsonTraceTransport struct {
+	transport http.RoundTripper
+
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// newJSONTraceTransport - transport tracing the requests of transport
+// to w, one JSON object per line.
+func newJSONTraceTransport(transport http.RoundTripper, w io.Writer) *jsonTraceTransport {
+	return &jsonTraceTransport{transport: transport, encoder: json.NewEncoder(w)}
+}
+
+// RoundTrip - execute the request, tracing it with its response.
+func (t *jsonTraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &jsonTrace{
+		Time:          time.Now().UTC(),
+		Method:        req.Method,
+		URL:           redactPresignedURL(req.URL),
+		RequestHeader: redactTraceHeader(req.Header),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		counted := *req
+		counted.Body = &countingReadCloser{ReadCloser: req.Body, count: &trace.RequestBytes}
+		req = &counted
+	}
+	resp, e := t.transport.RoundTrip(req)
+	if e != nil {
+		trace.Error = e.Error()
+		t.write(trace)
+		return nil, e
+	}
+	trace.Status = resp.StatusCode
+	trace.ResponseHeader = redactTraceHeader(resp.Header)
+	resp.Body = &countingReadCloser{
+		ReadCloser: resp.Body,
+		count:      &trace.ResponseBytes,
+		done: func() {
+			trace.DurationNanos = time.Since(trace.Time).Nanoseconds()
+			t.write(trace)
+		},
+	}
+	return resp, nil
+}
+
+func (t *jsonTraceTransport) write(trace *jsonTrace) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// Tracing must not fail requests.
+	t.encoder.Encode(trace)
+}
+
+// redactTraceHeader - copy of header with the secret values redacted.
+func redactTraceHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range jsonTraceSecretHeaders {
+		if _, ok := redacted[name]; ok {
+			redacted.Set(name, "**REDACTED**")
+		}
+	}
+	return redacted
+}
+
+// countingReadCloser - counts the bytes read, calling done once on EOF
+// or Close.
+type countingReadCloser struct {
+	io.ReadCloser
+	count *int64
+	done  func()
+	once  sync.Once
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, e := r.ReadCloser.Read(p)
+	*r.count += int64(n)
+	if e == io.EOF {
+		r.finish()
+	}
+	return n, e
+}
+
+func (r *countingReadCloser) Close() error {
+	e := r.ReadCloser.Close()
+	r.finish()
+	return e
+}
+
+func (r *countingReadCloser) finish() {
+	if r.done != nil {
+		r.once.Do(r.done)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+// Transformer - transforms an object stream on the fly, e.g. to
+// decompress it. Closing the returned reader closes r. Errors are
+// returned by Read on the returned reader.
+type Transformer func(r io.ReadCloser) io.ReadCloser
+
+// GetAndTransform - get object and apply transformers in order
+// on the object stream before returning it.
+func (c *S3Client) GetAndTransform(sse encrypt.ServerSide, transformers ...Transformer) (io.ReadCloser, *probe.Error) {
+	reader, err := c.Get(context.Background(), sse)
+	if err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	for _, transform := range transformers {
+		reader = transform(reader)
+	}
+	return reader, nil
+}
+
+// transformedReader - reads transformed data, closing the source stream.
+type transformedReader struct {
+	io.Reader
+	close func() error
+}
+
+func (r transformedReader) Close() error {
+	return r.close()
+}
+
+// errReader - fails every read, used when a transformer can't start.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// GzipDecompressor - transformer decompressing a gzip stream.
+func GzipDecompressor() Transformer {
+	return func(r io.ReadCloser) io.ReadCloser {
+		zr, e := gzip.NewReader(r)
+		if e != nil {
+			return transformedReader{Reader: errReader{e}, close: r.Close}
+		}
+		return transformedReader{Reader: zr, close: func() error {
+			zr.Close()
+			return r.Close()
+		}}
+	}
+}
+
+// Bzip2Decompressor - transformer decompressing a bzip2 stream.
+func Bzip2Decompressor() Transformer {
+	return func(r io.ReadCloser) io.ReadCloser {
+		return transformedReader{Reader: bzip2.NewReader(r), close: r.Close}
+	}
+}
+
+var (
+	errInvalidCiphertext = errors.New("ciphertext is not a multiple of the AES block size")
+	errInvalidPadding    = errors.New("invalid PKCS#7 padding")
+)
+
+// AES256CBCDecryptor - transformer decrypting an AES-256-CBC stream
+// with PKCS#7 padding, the IV is read from the first block of the
+// stream. key must be 32 bytes long.
+func AES256CBCDecryptor(key []byte) (Transformer, *probe.Error) {
+	if len(key) != 32 {
+		return nil, errInvalidArgument().Trace("AES-256 key must be 32 bytes long")
+	}
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	return func(r io.ReadCloser) io.ReadCloser {
+		return transformedReader{
+			Reader: &cbcDecryptReader{src: r, block: block, buf: make([]byte, 32*1024)},
+			close:  r.Close,
+		}
+	}, nil
+}
+
+// cbcDecryptReader - decrypts a CBC stream, holding back the last
+// block until EOF to strip its padding.
+type cbcDecryptReader struct {
+	src   io.Reader
+	block cipher.Block
+	mode  cipher.BlockMode
+	buf   []byte
+	in    []byte
+	out   []byte
+	err   error
+}
+
+func (r *cbcDecryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill - read from source and decrypt all complete blocks but the last.
+func (r *cbcDecryptReader) fill() {
+	n, e := r.src.Read(r.buf)
+	r.in = append(r.in, r.buf[:n]...)
+	if r.mode == nil && len(r.in) >= aes.BlockSize {
+		r.mode = cipher.NewCBCDecrypter(r.block, r.in[:aes.BlockSize])
+		r.in = r.in[aes.BlockSize:]
+	}
+	switch {
+	case e == io.EOF:
+		r.err = r.final()
+	case e != nil:
+		r.err = e
+	case r.mode != nil && len(r.in) > aes.BlockSize:
+		n := (len(r.in) - 1) / aes.BlockSize * aes.BlockSize
+		r.out = make([]byte, n)
+		r.mode.CryptBlocks(r.out, r.in[:n])
+		r.in = r.in[n:]
+	}
+}
+
+// final - decrypt the remaining blocks and strip the padding.
+func (r *cbcDecryptReader) final() error {
+	if r.mode == nil || len(r.in) == 0 || len(r.in)%aes.BlockSize != 0 {
+		return errInvalidCiphertext
+	}
+	out := make([]byte, len(r.in))
+	r.mode.CryptBlocks(out, r.in)
+	r.in = nil
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return errInvalidPadding
+	}
+	for _, b := range out[len(out)-pad:] {
+		if int(b) != pad {
+			return errInvalidPadding
+		}
+	}
+	r.out = out[:len(out)-pad]
+	return io.EOF
+}
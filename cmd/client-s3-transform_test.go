@@ -0,0 +1,154 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+// memoryObjectHandler stores a single uploaded object in memory,
+// multipart uploads are expected to send their parts in order.
+type memoryObjectHandler struct {
+	resource string
+	data     *[]byte
+}
+
+func (h memoryObjectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.URL.Path != h.resource {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, uploads := r.URL.Query()["uploads"]
+	_, uploadID := r.URL.Query()["uploadId"]
+	switch r.Method {
+	case "POST":
+		var response []byte
+		switch {
+		case uploads:
+			*h.data = nil
+			response = []byte("<InitiateMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>")
+		case uploadID:
+			response = []byte("<CompleteMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>object</Key><ETag>\"3858f62230ac3c915f300c664312c11f-1\"</ETag></CompleteMultipartUploadResult>")
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	case "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if uploadID {
+			*h.data = append(*h.data, data...)
+		} else {
+			*h.data = data
+		}
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+	case "GET":
+		w.Header().Set("Content-Length", strconv.Itoa(len(*h.data)))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.Write(*h.data)
+	}
+}
+
+// Test transforming a gzipped object while downloading it.
+func (s *TestSuite) TestGetAndTransformGzip(c *C) {
+	var stored []byte
+	handler := memoryObjectHandler{resource: "/bucket/object.gz", data: &stored}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+handler.resource, "S3v2")
+
+	data := bytes.Repeat([]byte("Hello, World\n"), 1024)
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, e := zw.Write(data)
+	c.Assert(e, IsNil)
+	c.Assert(zw.Close(), IsNil)
+
+	_, err := s3c.Put(context.Background(), bytes.NewReader(gzipped.Bytes()), int64(gzipped.Len()), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(stored, DeepEquals, gzipped.Bytes())
+
+	reader, err := s3c.GetAndTransform(nil, GzipDecompressor())
+	c.Assert(err, IsNil)
+	got, e := ioutil.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(got, DeepEquals, data)
+
+	// Not a gzip stream.
+	stored = data
+	reader, err = s3c.GetAndTransform(nil, GzipDecompressor())
+	c.Assert(err, IsNil)
+	_, e = ioutil.ReadAll(reader)
+	c.Assert(e, NotNil)
+	reader.Close()
+}
+
+// Test AES-256-CBC decryption transformer.
+func (s *TestSuite) TestAES256CBCDecryptor(c *C) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	iv := bytes.Repeat([]byte{0x24}, aes.BlockSize)
+
+	_, err := AES256CBCDecryptor(key[:16])
+	c.Assert(err, NotNil)
+
+	block, e := aes.NewCipher(key)
+	c.Assert(e, IsNil)
+	for _, size := range []int{0, 1, 15, 16, 17, 100000} {
+		plaintext := bytes.Repeat([]byte{'a'}, size)
+		pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+		padded := append(plaintext, bytes.Repeat([]byte{byte(pad)}, pad)...)
+		ciphertext := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+		decrypt, err := AES256CBCDecryptor(key)
+		c.Assert(err, IsNil)
+		reader := decrypt(ioutil.NopCloser(bytes.NewReader(append(iv, ciphertext...))))
+		got, e := ioutil.ReadAll(reader)
+		c.Assert(e, IsNil, Commentf("size %d", size))
+		c.Assert(got, DeepEquals, plaintext, Commentf("size %d", size))
+
+		// Truncated ciphertext.
+		reader = decrypt(ioutil.NopCloser(bytes.NewReader(append(iv, ciphertext[:len(ciphertext)-1]...))))
+		_, e = ioutil.ReadAll(reader)
+		c.Assert(e, NotNil, Commentf("size %d", size))
+	}
+}
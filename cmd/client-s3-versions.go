@@ -0,0 +1,187 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+)
+
+// ObjectVersion - a version of an object, or a delete marker.
+type ObjectVersion struct {
+	VersionID    string
+	IsLatest     bool
+	DeleteMarker bool
+	Time         time.Time
+	// Size, ETag and StorageClass are empty for delete markers.
+	Size         int64
+	ETag         string
+	StorageClass string
+	// Metadata and UserMetadata of the latest version, nil for the
+	// others and for delete markers.
+	Metadata     map[string]string
+	UserMetadata map[string]string
+}
+
+// listVersionsResult - page of a ListObjectVersions listing.
+type listVersionsResult struct {
+	XMLName             xml.Name            `xml:"ListVersionsResult"`
+	IsTruncated         bool                `xml:"IsTruncated"`
+	NextKeyMarker       string              `xml:"NextKeyMarker"`
+	NextVersionIDMarker string              `xml:"NextVersionIdMarker"`
+	Versions            []listVersionsEntry `xml:"Version"`
+	DeleteMarkers       []listVersionsEntry `xml:"DeleteMarker"`
+}
+
+type listVersionsEntry struct {
+	Key          string    `xml:"Key"`
+	VersionID    string    `xml:"VersionId"`
+	IsLatest     bool      `xml:"IsLatest"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+// listKeyVersions - versions and delete markers of key, in the order
+// of the listing. minio-go doesn't list versions.
+func (c *S3Client) listKeyVersions(ctx context.Context, bucket, key string) ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	keyMarker, versionIDMarker := "", ""
+	for {
+		query := url.Values{"versions": []string{""}, "prefix": []string{key}}
+		if keyMarker != "" {
+			query.Set("key-marker", keyMarker)
+			query.Set("version-id-marker", versionIDMarker)
+		}
+		resp, e := c.executeRequest(ctx, http.MethodGet, s3RequestMetadata{bucket: bucket, query: query})
+		if e != nil {
+			return nil, e
+		}
+		var result listVersionsResult
+		e = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if e != nil {
+			return nil, e
+		}
+		for _, entry := range result.Versions {
+			if entry.Key == key {
+				versions = append(versions, ObjectVersion{
+					VersionID:    entry.VersionID,
+					IsLatest:     entry.IsLatest,
+					Time:         entry.LastModified,
+					Size:         entry.Size,
+					ETag:         strings.Trim(entry.ETag, "\""),
+					StorageClass: entry.StorageClass,
+				})
+			}
+		}
+		for _, entry := range result.DeleteMarkers {
+			if entry.Key == key {
+				versions = append(versions, ObjectVersion{
+					VersionID:    entry.VersionID,
+					IsLatest:     entry.IsLatest,
+					DeleteMarker: true,
+					Time:         entry.LastModified,
+				})
+			}
+		}
+		// Keys sort after their prefix, those after key have no
+		// versions of it.
+		if !result.IsTruncated || result.NextKeyMarker > key {
+			return versions, nil
+		}
+		keyMarker, versionIDMarker = result.NextKeyMarker, result.NextVersionIDMarker
+	}
+}
+
+// ObjectHistory - versions and delete markers of the object key of the
+// bucket, the object of the client URL if empty, the latest first and
+// then newest first. The latest version, unless a delete marker, has
+// the metadata of the object. Unversioned objects have a single
+// version, whose ID is "null".
+func (c *S3Client) ObjectHistory(ctx context.Context, key string) ([]ObjectVersion, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return nil, probe.NewError(BucketNameEmpty{})
+	}
+	if key == "" {
+		key = object
+	}
+	if key == "" {
+		return nil, probe.NewError(ObjectNameEmpty{})
+	}
+
+	versions, e := c.listKeyVersions(ctx, bucket, key)
+	if e != nil {
+		return nil, probe.NewError(regionError(bucket, e)).Trace(bucket, key)
+	}
+	if len(versions) == 0 {
+		return nil, probe.NewError(ObjectMissing{})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].IsLatest != versions[j].IsLatest {
+			return versions[i].IsLatest
+		}
+		return versions[i].Time.After(versions[j].Time)
+	})
+
+	if latest := &versions[0]; latest.IsLatest && !latest.DeleteMarker {
+		content, err := c.getObjectStat(ctx, bucket, key, minio.StatObjectOptions{})
+		if err != nil {
+			return nil, err.Trace(bucket, key)
+		}
+		latest.Metadata = content.Metadata
+		latest.UserMetadata = content.UserMetadata
+	}
+	return versions, nil
+}
+
+// CreateDeleteMarker - remove the object of the client URL without
+// version ID, which in versioned buckets hides it behind a new delete
+// marker, keeping its versions, and return the version ID of the
+// marker. The ID is empty if the bucket isn't versioned, the object
+// being removed for good then.
+func (c *S3Client) CreateDeleteMarker(ctx context.Context) (string, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return "", probe.NewError(BucketNameEmpty{})
+	}
+	if object == "" {
+		return "", probe.NewError(ObjectNameEmpty{})
+	}
+	resp, e := c.executeRequest(ctx, http.MethodDelete, s3RequestMetadata{
+		bucket: bucket,
+		object: object,
+	})
+	if e != nil {
+		return "", c.removeObjectError(bucket, object, nil, e)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Amz-Delete-Marker") != "true" {
+		return "", nil
+	}
+	return resp.Header.Get("X-Amz-Version-Id"), nil
+}
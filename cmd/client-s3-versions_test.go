@@ -0,0 +1,187 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// testVersion - version listed by versionsHandler.
+type testVersion struct {
+	key          string
+	versionID    string
+	isLatest     bool
+	deleteMarker bool
+	modified     time.Time
+	size         int64
+}
+
+// versionsHandler lists versions two at a time, in the order of
+// versions, and serves HEAD on the latest version of keys.
+type versionsHandler struct {
+	versions []testVersion
+}
+
+func (h versionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method == "HEAD" {
+		for _, v := range h.versions {
+			if "/bucket/"+v.key == r.URL.Path && v.isLatest && !v.deleteMarker {
+				w.Header().Set("Content-Length", strconv.FormatInt(v.size, 10))
+				w.Header().Set("Last-Modified", v.modified.UTC().Format(http.TimeFormat))
+				w.Header().Set("ETag", "\"9af2f8218b150c351ad802c6f3d66abe\"")
+				w.Header().Set("X-Amz-Version-Id", v.versionID)
+				w.Header().Set("X-Amz-Meta-Owner", "me")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if _, ok := query["versions"]; !ok || r.Method != "GET" || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	var matching []testVersion
+	for _, v := range h.versions {
+		if strings.HasPrefix(v.key, query.Get("prefix")) {
+			matching = append(matching, v)
+		}
+	}
+	start := 0
+	if marker := query.Get("key-marker"); marker != "" {
+		for i, v := range matching {
+			if v.key == marker && v.versionID == query.Get("version-id-marker") {
+				start = i + 1
+			}
+		}
+	}
+	end := start + 2
+	if end > len(matching) {
+		end = len(matching)
+	}
+	response := "<ListVersionsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><Prefix>" + query.Get("prefix") + "</Prefix>"
+	for _, v := range matching[start:end] {
+		entry := "<Key>" + v.key + "</Key><VersionId>" + v.versionID + "</VersionId><IsLatest>" + strconv.FormatBool(v.isLatest) +
+			"</IsLatest><LastModified>" + v.modified.UTC().Format(time.RFC3339Nano) + "</LastModified>"
+		if v.deleteMarker {
+			response += "<DeleteMarker>" + entry + "</DeleteMarker>"
+		} else {
+			response += "<Version>" + entry + "<ETag>\"9af2f8218b150c351ad802c6f3d66abe\"</ETag><Size>" + strconv.FormatInt(v.size, 10) +
+				"</Size><StorageClass>STANDARD</StorageClass></Version>"
+		}
+	}
+	if end < len(matching) {
+		response += "<IsTruncated>true</IsTruncated><NextKeyMarker>" + matching[end-1].key + "</NextKeyMarker><NextVersionIdMarker>" +
+			matching[end-1].versionID + "</NextVersionIdMarker>"
+	} else {
+		response += "<IsTruncated>false</IsTruncated>"
+	}
+	response += "</ListVersionsResult>"
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test the history of an object lists its versions and delete markers
+// only, the latest with its metadata.
+func (s *TestSuite) TestObjectHistory(c *C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	server := httptest.NewServer(versionsHandler{versions: []testVersion{
+		{"object", "v4", true, false, now, 40},
+		{"object", "v3", false, true, now.Add(-time.Hour), 0},
+		{"object", "v2", false, false, now.Add(-2 * time.Hour), 20},
+		{"object", "v1", false, false, now.Add(-3 * time.Hour), 10},
+		{"object2", "w1", true, false, now, 5},
+		{"removed", "x2", true, true, now, 0},
+		{"removed", "x1", false, false, now.Add(-time.Hour), 5},
+	}})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/object", "S3v4")
+
+	versions, err := s3c.ObjectHistory(context.Background(), "")
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 4)
+	var ids []string
+	for _, v := range versions {
+		ids = append(ids, v.VersionID)
+	}
+	c.Assert(ids, DeepEquals, []string{"v4", "v3", "v2", "v1"})
+	c.Assert(versions[0].IsLatest, Equals, true)
+	c.Assert(versions[0].Size, Equals, int64(40))
+	c.Assert(versions[0].ETag, Equals, "9af2f8218b150c351ad802c6f3d66abe")
+	c.Assert(versions[0].UserMetadata["X-Amz-Meta-Owner"], Equals, "me")
+	c.Assert(versions[1].DeleteMarker, Equals, true)
+	c.Assert(versions[1].Time.Equal(now.Add(-time.Hour)), Equals, true)
+	c.Assert(versions[2].Metadata, IsNil)
+	c.Assert(versions[3].Size, Equals, int64(10))
+
+	// Objects whose latest version is a delete marker have a history.
+	versions, err = s3c.ObjectHistory(context.Background(), "removed")
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 2)
+	c.Assert(versions[0].DeleteMarker, Equals, true)
+	c.Assert(versions[0].Metadata, IsNil)
+	c.Assert(versions[1].VersionID, Equals, "x1")
+
+	_, err = s3c.ObjectHistory(context.Background(), "missing")
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ObjectMissing)
+	c.Assert(ok, Equals, true)
+}
+
+// Test hiding an object behind a delete marker.
+func (s *TestSuite) TestCreateDeleteMarker(c *C) {
+	handler := newVersionedHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/object", "S3v4")
+	_, err := s3c.Put(context.Background(), bytes.NewReader([]byte("data")), 4, nil, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+
+	id, err := s3c.CreateDeleteMarker(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, "v2")
+
+	versions, err := s3c.ObjectHistory(context.Background(), "")
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 2)
+	c.Assert(versions[0].VersionID, Equals, id)
+	c.Assert(versions[0].DeleteMarker, Equals, true)
+	c.Assert(versions[0].IsLatest, Equals, true)
+	c.Assert(versions[1].VersionID, Equals, "v1")
+	c.Assert(versions[1].DeleteMarker, Equals, false)
+
+	_, err = newTestS3Client(c, server.URL+"/bucket/", "S3v4").CreateDeleteMarker(context.Background())
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,148 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6"
+)
+
+// BucketWebsite - static website hosting configuration of a bucket,
+// empty if website hosting is not configured.
+type BucketWebsite struct {
+	// IndexDocument is the suffix appended to requests for a
+	// directory, e.g. index.html.
+	IndexDocument string
+	// ErrorDocument is the key of the object returned on 4XX errors.
+	ErrorDocument string
+}
+
+// websiteConfiguration - WebsiteConfiguration XML document.
+type websiteConfiguration struct {
+	XMLName       xml.Name              `xml:"WebsiteConfiguration"`
+	Xmlns         string                `xml:"xmlns,attr,omitempty"`
+	IndexDocument *websiteIndexDocument `xml:"IndexDocument"`
+	ErrorDocument *websiteErrorDocument `xml:"ErrorDocument"`
+}
+
+type websiteIndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type websiteErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+// GetBucketWebsite - website configuration of the bucket.
+func (c *S3Client) GetBucketWebsite() (BucketWebsite, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return BucketWebsite{}, probe.NewError(BucketNameEmpty{})
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"website": []string{""}},
+	})
+	if e != nil {
+		if minio.ToErrorResponse(e).Code == "NoSuchWebsiteConfiguration" {
+			return BucketWebsite{}, nil
+		}
+		return BucketWebsite{}, c.bucketConfigError("Website", bucket, e)
+	}
+	defer resp.Body.Close()
+
+	var wire websiteConfiguration
+	if e = xml.NewDecoder(resp.Body).Decode(&wire); e != nil {
+		return BucketWebsite{}, probe.NewError(e)
+	}
+	var website BucketWebsite
+	if wire.IndexDocument != nil {
+		website.IndexDocument = wire.IndexDocument.Suffix
+	}
+	if wire.ErrorDocument != nil {
+		website.ErrorDocument = wire.ErrorDocument.Key
+	}
+	return website, nil
+}
+
+// SetBucketWebsite - serve the bucket as a static website, with
+// indexDoc for directories and the optional errorDoc on errors.
+func (c *S3Client) SetBucketWebsite(indexDoc, errorDoc string) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if indexDoc == "" {
+		return errInvalidArgument().Trace("website index document is empty")
+	}
+	if strings.Contains(indexDoc, "/") {
+		return errInvalidArgument().Trace("website index document can't contain a slash", indexDoc)
+	}
+
+	wire := websiteConfiguration{
+		Xmlns:         "http://s3.amazonaws.com/doc/2006-03-01/",
+		IndexDocument: &websiteIndexDocument{Suffix: indexDoc},
+	}
+	if errorDoc != "" {
+		wire.ErrorDocument = &websiteErrorDocument{Key: errorDoc}
+	}
+	configBytes, e := xml.Marshal(wire)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	sum := md5.Sum(configBytes)
+	resp, e := c.executeRequest(context.Background(), http.MethodPut, s3RequestMetadata{
+		bucket:  bucket,
+		query:   url.Values{"website": []string{""}},
+		header:  http.Header{"Content-Md5": []string{base64.StdEncoding.EncodeToString(sum[:])}},
+		content: configBytes,
+	})
+	if e != nil {
+		return c.bucketConfigError("Website", bucket, e)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteBucketWebsite - stop serving the bucket as a static website,
+// succeeds if it isn't.
+func (c *S3Client) DeleteBucketWebsite() *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodDelete, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"website": []string{""}},
+	})
+	if e != nil {
+		if minio.ToErrorResponse(e).Code == "NoSuchWebsiteConfiguration" {
+			return nil
+		}
+		return c.bucketConfigError("Website", bucket, e)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+// websiteHandler stores the website configuration of a bucket, or
+// doesn't implement it.
+type websiteHandler struct {
+	config         *string
+	notImplemented bool
+}
+
+func (h websiteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if _, ok := query["website"]; !ok || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeError := func(status int, code string) {
+		response := []byte("<Error><Code>" + code + "</Code><Message>" + code + "</Message></Error>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.WriteHeader(status)
+		w.Write(response)
+	}
+	if h.notImplemented {
+		writeError(http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		if *h.config == "" {
+			writeError(http.StatusNotFound, "NoSuchWebsiteConfiguration")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(*h.config)))
+		w.Write([]byte(*h.config))
+	case "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		*h.config = string(data)
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		*h.config = ""
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Test managing the website configuration of a bucket.
+func (s *TestSuite) TestBucketWebsite(c *C) {
+	var config string
+	handler := websiteHandler{config: &config}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	// Not configured.
+	website, err := s3c.GetBucketWebsite()
+	c.Assert(err, IsNil)
+	c.Assert(website, Equals, BucketWebsite{})
+
+	// Invalid index documents.
+	c.Assert(s3c.SetBucketWebsite("", "error.html"), NotNil)
+	c.Assert(s3c.SetBucketWebsite("docs/index.html", ""), NotNil)
+	c.Assert(config, Equals, "")
+
+	c.Assert(s3c.SetBucketWebsite("index.html", "error.html"), IsNil)
+	website, err = s3c.GetBucketWebsite()
+	c.Assert(err, IsNil)
+	c.Assert(website, Equals, BucketWebsite{IndexDocument: "index.html", ErrorDocument: "error.html"})
+
+	c.Assert(s3c.SetBucketWebsite("index.htm", ""), IsNil)
+	website, err = s3c.GetBucketWebsite()
+	c.Assert(err, IsNil)
+	c.Assert(website, Equals, BucketWebsite{IndexDocument: "index.htm"})
+
+	c.Assert(s3c.DeleteBucketWebsite(), IsNil)
+	website, err = s3c.GetBucketWebsite()
+	c.Assert(err, IsNil)
+	c.Assert(website, Equals, BucketWebsite{})
+
+	// Backends without website support.
+	notImplemented := httptest.NewServer(websiteHandler{config: &config, notImplemented: true})
+	defer notImplemented.Close()
+	conf.HostURL = notImplemented.URL + "/bucket"
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.(*S3Client).GetBucketWebsite()
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(APINotImplemented)
+	c.Assert(ok, Equals, true)
+}
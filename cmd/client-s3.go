@@ -18,13 +18,21 @@ package cmd
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"fmt"
+	"hash"
 	"hash/fnv"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -32,9 +40,13 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/minio/mc/pkg/httptracer"
 	"github.com/minio/mc/pkg/probe"
@@ -44,6 +56,7 @@ import (
 	"github.com/minio/minio-go/v6/pkg/policy"
 	"github.com/minio/minio-go/v6/pkg/s3utils"
 	"github.com/minio/minio/pkg/bucket/object/tagging"
+	"github.com/minio/minio/pkg/console"
 	"github.com/minio/minio/pkg/mimedb"
 )
 
@@ -52,7 +65,57 @@ type S3Client struct {
 	sync.Mutex
 	targetURL    *ClientURL
 	api          *minio.Client
+	creds        *credentials.Credentials
+	transport    *headerTransport
 	virtualStyle bool
+	defaultSSE   encrypt.ServerSide
+	// storage class of uploads not setting any.
+	defaultStorageClass string
+	// Part size used to upload streams of unknown size.
+	streamPartSize int64
+	// Parts uploaded in parallel by Put.
+	multipartThreads int
+	// Header of the expiry date of uploads, see PutExpiring.
+	objectExpiryHeader string
+	// Bucket lookup of requests, probed when auto, see
+	// probeBucketLookup.
+	bucketLookup minio.BucketLookupType
+	// Regions of buckets, see bucketRegion.
+	regionsMutex sync.Mutex
+	regions      map[string]string
+	requestIDs   *RequestIDCapture
+	// Minio client, transport and pool of context clients shared with
+	// the other clients of the host, see setBucketHeader.
+	sharedAPI            *minio.Client
+	sharedTransport      *headerTransport
+	sharedContextClients *contextClientPool
+	newAPI               func(http.RoundTripper, string) (*minio.Client, error)
+	// Clients of contextAPI, made over transport.
+	contextClients *contextClientPool
+	// Trace presigned URLs, requests are traced by the transport.
+	debug bool
+	// List objects with V1 listings, see listV1.
+	forceListV1 bool
+	// Timeout of Put and Get, see withRequestTimeout.
+	requestTimeout time.Duration
+}
+
+// ec2MetadataEndpoint - endpoint of the EC2 instance metadata service,
+// the default one when empty.
+var ec2MetadataEndpoint = ""
+
+// s3ClientCache holds the minio client along with the credentials
+// and transport it was initialized with, cached per host and keys.
+type s3ClientCache struct {
+	api        *minio.Client
+	creds      *credentials.Credentials
+	transport  *headerTransport
+	requestIDs *RequestIDCapture
+	// Clients of contextAPI, shared by the clients of the host.
+	contextClients *contextClientPool
+	// newAPI returns a minio client like api, using transport and
+	// region, Config.Region or looked up by minio-go when empty.
+	newAPI func(transport http.RoundTripper, region string) (*minio.Client, error)
 }
 
 const (
@@ -79,17 +142,53 @@ const (
 	AmzObjectLockRetainUntilDate = "X-Amz-Object-Lock-Retain-Until-Date"
 	// AmzObjectLockLegalHold sets object lock legal hold
 	AmzObjectLockLegalHold = "X-Amz-Object-Lock-Legal-Hold"
+
+	// amzACL sets the canned ACL of an object
+	amzACL = "X-Amz-Acl"
+
+	// amzWebsiteRedirectLocation redirects website requests for an object
+	amzWebsiteRedirectLocation = "X-Amz-Website-Redirect-Location"
 )
 
+// Canned ACLs which can be applied to objects at write time.
+var validCannedACLs = []string{
+	"private",
+	"public-read",
+	"public-read-write",
+	"authenticated-read",
+	"aws-exec-read",
+	"bucket-owner-read",
+	"bucket-owner-full-control",
+}
+
+// isValidCannedACL - validate canned ACL, empty means no ACL.
+func isValidCannedACL(acl string) bool {
+	if acl == "" {
+		return true
+	}
+	for _, validACL := range validCannedACLs {
+		if acl == validACL {
+			return true
+		}
+	}
+	return false
+}
+
 var timeSentinel = time.Unix(0, 0).UTC()
 
 // newFactory encloses New function with client cache.
 func newFactory() func(config *Config) (Client, *probe.Error) {
-	clientCache := make(map[uint32]*minio.Client)
+	clientCache := make(map[uint32]*s3ClientCache)
+	// Bucket lookup found by probing every host, see probeBucketLookup.
+	bucketLookups := make(map[string]minio.BucketLookupType)
 	var mutex sync.Mutex
 
 	// Return New function.
 	return func(config *Config) (Client, *probe.Error) {
+		if err := config.Validate(); err != nil {
+			return nil, err.Trace(config.HostURL)
+		}
+
 		// Creates a parsed URL.
 		targetURL := newClientURL(config.HostURL)
 		// By default enable HTTPs.
@@ -103,6 +202,38 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 		// Save the target URL.
 		s3Clnt.targetURL = targetURL
 
+		// Save the default encryption of the alias.
+		defaultSSE, err := newDefaultSSE(config)
+		if err != nil {
+			return nil, err.Trace(config.HostURL)
+		}
+		s3Clnt.defaultSSE = defaultSSE
+
+		defaultStorageClass, err := normalizeStorageClass(config.DefaultStorageClass)
+		if err != nil {
+			return nil, err.Trace(config.HostURL)
+		}
+		s3Clnt.defaultStorageClass = defaultStorageClass
+
+		s3Clnt.streamPartSize = defaultStreamPartSize
+		if config.StreamPartSize != 0 {
+			s3Clnt.streamPartSize = config.StreamPartSize
+		}
+
+		s3Clnt.multipartThreads = defaultMultipartThreadsNum
+		if config.MultipartThreads != 0 {
+			s3Clnt.multipartThreads = config.MultipartThreads
+		}
+
+		s3Clnt.objectExpiryHeader = http.CanonicalHeaderKey(config.ObjectExpiryHeader)
+		s3Clnt.forceListV1 = config.ForceListV1
+		s3Clnt.requestTimeout = config.RequestTimeout
+
+		proxy, err := newProxyFunc(config.ProxyByHost)
+		if err != nil {
+			return nil, err.Trace(config.HostURL)
+		}
+
 		// Save if target supports virtual host style.
 		hostName := targetURL.Host
 		s3Clnt.virtualStyle = isVirtualHostStyle(hostName, config.Lookup)
@@ -114,44 +245,134 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 				hostName = googleHostName
 			}
 		}
+		// Probe whether buckets of other hosts than Amazon and Google
+		// are reachable as subdomains, once per host. The lookup of
+		// minio clients is fixed when they are made, so this can't
+		// wait for the first request.
+		s3Clnt.bucketLookup = config.Lookup
+		hostOnly := hostName
+		if h, _, e := net.SplitHostPort(hostName); e == nil {
+			hostOnly = h
+		}
+		if bucket, _ := s3Clnt.url2BucketAndObject(); bucket != "" && config.Lookup == minio.BucketLookupAuto &&
+			!s3Clnt.virtualStyle && !isVirtualHostStyle(hostName, minio.BucketLookupAuto) &&
+			config.UnixSocket == "" && net.ParseIP(hostOnly) == nil {
+			if !isDNSCompatibleBucket(bucket, useTLS) {
+				// Not a valid subdomain, or one certificates of
+				// the host don't cover.
+				s3Clnt.bucketLookup = minio.BucketLookupPath
+			} else {
+				probeKey := targetURL.Scheme + "://" + hostName
+				mutex.Lock()
+				lookup, found := bucketLookups[probeKey]
+				mutex.Unlock()
+				if !found {
+					var conclusive bool
+					lookup, conclusive = probeBucketLookup(newBucketLookupProbeClient(config), proxy, targetURL.Scheme, hostName, bucket)
+					if conclusive {
+						mutex.Lock()
+						bucketLookups[probeKey] = lookup
+						mutex.Unlock()
+					}
+				}
+				s3Clnt.bucketLookup = lookup
+			}
+		}
+
 		// Generate a hash out of s3Conf.
+		// Strings are quoted so that no two configs hash the same input.
 		confHash := fnv.New32a()
-		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey))
+		fmt.Fprintf(confHash, "%q %q %q %q %q %q %q ", hostName, config.AccessKey, config.SecretKey,
+			config.TLSServerName, config.UnixSocket, config.CredentialProcess, config.Region)
+		fmt.Fprintf(confHash, "%t %t %t ", config.UseEC2Metadata, config.UseECSCredentials, config.Debug)
+		fmt.Fprintf(confHash, "%d %d %v ", config.TLSMinVersion, config.TLSMaxVersion, config.TLSCipherSuites)
+		// Writers of traces are told apart by identity.
+		fmt.Fprintf(confHash, "%T %p ", config.TraceJSON, config.TraceJSON)
+		fmt.Fprintf(confHash, "%d", s3Clnt.bucketLookup)
+		var proxyHosts []string
+		for host, proxyURL := range config.ProxyByHost {
+			proxyHosts = append(proxyHosts, fmt.Sprintf("%q=%q", host, proxyURL))
+		}
+		sort.Strings(proxyHosts)
+		fmt.Fprintf(confHash, " %s", strings.Join(proxyHosts, ","))
 		confSum := confHash.Sum32()
 
-		// Lookup previous cache by hash.
+		// Lookup previous cache by hash. The lock is released before
+		// the connections of new clients are warmed up.
 		mutex.Lock()
-		defer mutex.Unlock()
-		var api *minio.Client
+		var cached *s3ClientCache
 		var found bool
-		if api, found = clientCache[confSum]; !found {
-			// if Signature version '4' use NewV4 directly.
-			creds := credentials.NewStaticV4(config.AccessKey, config.SecretKey, "")
-			// if Signature version '2' use NewV2 directly.
+		if cached, found = clientCache[confSum]; !found {
+			// Signature version '4' unless '2' is asked for.
+			signerType := credentials.SignatureV4
 			if strings.ToUpper(config.Signature) == "S3V2" {
-				creds = credentials.NewStaticV2(config.AccessKey, config.SecretKey, "")
+				signerType = credentials.SignatureV2
+			}
+			creds := credentials.NewStatic(config.AccessKey, config.SecretKey, "", signerType)
+			if config.AccessKey == "" && config.SecretKey == "" && config.UseEC2Metadata {
+				creds = credentials.NewIAM(ec2MetadataEndpoint)
+				signerType = credentials.SignatureV4
+			}
+			var expiringCreds *credentials.Credentials
+			if config.AccessKey == "" && config.SecretKey == "" && config.UseECSCredentials {
+				if ecsCredentialsURI() == "" {
+					mutex.Unlock()
+					return nil, errInvalidArgument().Trace("ECS credentials need AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or AWS_CONTAINER_CREDENTIALS_FULL_URI")
+				}
+				expiringCreds = newECSCredentials(signerType)
+			}
+			if config.CredentialProcess != "" {
+				expiringCreds = newProcessCredentials(config.CredentialProcess, signerType)
+			}
+			if expiringCreds != nil {
+				// Fail early when the credentials can't be obtained.
+				if _, e := expiringCreds.Get(); e != nil {
+					mutex.Unlock()
+					return nil, probe.NewError(e).Trace(config.HostURL)
+				}
+				creds = expiringCreds
 			}
 			// Not found. Instantiate a new MinIO
-			var e error
-
 			options := minio.Options{
 				Creds:        creds,
 				Secure:       useTLS,
-				Region:       "",
-				BucketLookup: config.Lookup,
+				Region:       config.Region,
+				BucketLookup: s3Clnt.bucketLookup,
 			}
-
-			api, e = minio.NewWithOptions(hostName, &options)
-			if e != nil {
-				return nil, probe.NewError(e)
+			newAPI := func(transport http.RoundTripper, region string) (*minio.Client, error) {
+				opts := options
+				if region != "" {
+					opts.Region = region
+				}
+				api, e := minio.NewWithOptions(hostName, &opts)
+				if e != nil {
+					return nil, e
+				}
+				api.SetCustomTransport(transport)
+				// If Amazon Accelerated URL is requested enable it.
+				if isS3AcceleratedEndpoint {
+					api.SetS3TransferAccelerate(amazonHostNameAccelerated)
+				}
+				api.SetAppInfo(config.AppName, config.AppVersion)
+				return api, nil
 			}
 
+			dialer := &net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 15 * time.Second,
+			}
+			dialContext := dialer.DialContext
+			if config.UnixSocket != "" {
+				// Requests keep the host of the URL, only the
+				// connections go to the socket, bypassing proxies.
+				proxy = nil
+				dialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", config.UnixSocket)
+				}
+			}
 			tr := &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				DialContext: (&net.Dialer{
-					Timeout:   10 * time.Second,
-					KeepAlive: 15 * time.Second,
-				}).DialContext,
+				Proxy:                 proxy,
+				DialContext:           dialContext,
 				MaxIdleConnsPerHost:   256,
 				IdleConnTimeout:       90 * time.Second,
 				TLSHandshakeTimeout:   10 * time.Second,
@@ -167,15 +388,13 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 
 			if useTLS {
 				// Keep TLS config.
-				tlsConfig := &tls.Config{
-					RootCAs: globalRootCAs,
-					// Can't use SSLv3 because of POODLE and BEAST
-					// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
-					// Can't use TLSv1.1 because of RC4 cipher usage
-					MinVersion: tls.VersionTLS12,
+				tlsConfig := newTLSConfig(config)
+				if config.UnixSocket != "" {
+					// Local servers have certificates for localhost.
+					tlsConfig.ServerName = "localhost"
 				}
-				if config.Insecure {
-					tlsConfig.InsecureSkipVerify = true
+				if config.TLSServerName != "" {
+					tlsConfig.ServerName = config.TLSServerName
 				}
 				tr.TLSClientConfig = tlsConfig
 
@@ -191,55 +410,230 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 
 			var transport http.RoundTripper = tr
 			if config.Debug {
-				if strings.EqualFold(config.Signature, "S3v4") {
-					transport = httptracer.GetNewTraceTransport(newTraceV4(), transport)
-				} else if strings.EqualFold(config.Signature, "S3v2") {
+				// Console traces follow the signature the
+				// credentials sign with.
+				if config.TraceJSON != nil {
+					transport = newJSONTraceTransport(transport, config.TraceJSON)
+				} else if signerType.IsV2() {
 					transport = httptracer.GetNewTraceTransport(newTraceV2(), transport)
+				} else {
+					transport = httptracer.GetNewTraceTransport(newTraceV4(), transport)
 				}
 			}
 
-			// Set the new transport.
-			api.SetCustomTransport(transport)
+			// Record the request IDs of responses.
+			requestIDs := &RequestIDCapture{Transport: transport}
 
-			// If Amazon Accelerated URL is requested enable it.
-			if isS3AcceleratedEndpoint {
-				api.SetS3TransferAccelerate(amazonHostNameAccelerated)
-			}
+			// Wrap the transport to allow injecting headers on every request.
+			hdrTransport := newHeaderTransport(requestIDs, creds, s3Clnt.virtualStyle, nil)
 
-			// Set app info.
-			api.SetAppInfo(config.AppName, config.AppVersion)
+			api, e := newAPI(hdrTransport, "")
+			if e != nil {
+				mutex.Unlock()
+				return nil, probe.NewError(e)
+			}
 
 			// Cache the new MinIO Client with hash of config as key.
-			clientCache[confSum] = api
+			cached = &s3ClientCache{
+				api:            api,
+				creds:          creds,
+				transport:      hdrTransport,
+				requestIDs:     requestIDs,
+				contextClients: &contextClientPool{},
+				newAPI:         newAPI,
+			}
+			clientCache[confSum] = cached
 		}
 
 		// Store the new api object.
-		s3Clnt.api = api
+		s3Clnt.api = cached.api
+		s3Clnt.creds = cached.creds
+		s3Clnt.transport = cached.transport
+		s3Clnt.requestIDs = cached.requestIDs
+		s3Clnt.sharedAPI = cached.api
+		s3Clnt.sharedTransport = cached.transport
+		s3Clnt.sharedContextClients = cached.contextClients
+		s3Clnt.contextClients = cached.contextClients
+		s3Clnt.newAPI = cached.newAPI
+		s3Clnt.debug = config.Debug
+		mutex.Unlock()
+
+		if config.WarmupOnCreate && !found {
+			// Connections of the shared transport stay warm for
+			// the next clients. Failures are only reported, the
+			// client may still work once the host is reachable.
+			ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+			errorIf(s3Clnt.Warmup(ctx, s3Clnt.multipartThreads), "Unable to warm up connections to `%s`.", hostName)
+			cancel()
+		}
 
 		return s3Clnt, nil
 	}
 }
 
+// warmupTimeout - bounds the warmup of clients made with
+// Config.WarmupOnCreate.
+const warmupTimeout = 10 * time.Second
+
+// Warmup - open connections to the host ahead of latency sensitive
+// calls, making that many ListBuckets requests in parallel so that the
+// connections stay idle in the pool afterwards. Error responses are
+// fine as the connections were made, only failed connections are
+// reported. Buckets reached as subdomains have their own connections.
+func (c *S3Client) Warmup(ctx context.Context, connections int) *probe.Error {
+	if connections < 1 {
+		return errInvalidArgument().Trace(strconv.Itoa(connections))
+	}
+	api, release := c.contextAPI(ctx, "")
+	defer release()
+	errCh := make(chan error, connections)
+	for i := 0; i < connections; i++ {
+		go func() {
+			_, e := api.ListBuckets()
+			errCh <- e
+		}()
+	}
+	var err *probe.Error
+	for i := 0; i < connections; i++ {
+		e := <-errCh
+		if e != nil && !errors.As(e, &minio.ErrorResponse{}) && err == nil {
+			err = probe.NewError(e).Trace(c.targetURL.String())
+		}
+	}
+	return err
+}
+
+// Default encryption algorithms which can be set on Config.
+const (
+	defaultSSES3  = "SSE-S3"
+	defaultSSEKMS = "SSE-KMS"
+	defaultSSEC   = "SSE-C"
+)
+
+// newDefaultSSE - returns the default encryption configured for an alias.
+func newDefaultSSE(config *Config) (encrypt.ServerSide, *probe.Error) {
+	switch strings.ToUpper(config.DefaultSSE) {
+	case "":
+		return nil, nil
+	case defaultSSES3:
+		return encrypt.NewSSE(), nil
+	case defaultSSEKMS:
+		if config.DefaultSSEKMSKeyID == "" {
+			return nil, errInvalidArgument().Trace("SSE-KMS requires a key id")
+		}
+		sse, e := encrypt.NewSSEKMS(config.DefaultSSEKMSKeyID, nil)
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		return sse, nil
+	case defaultSSEC:
+		sse, e := encrypt.NewSSEC([]byte(config.DefaultSSECKey))
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		return sse, nil
+	}
+	return nil, errInvalidArgument().Trace(config.DefaultSSE)
+}
+
+// newProxyFunc - returns the proxy function of the transport, using the
+// proxy configured for the request host, or for the parent domain of
+// virtual host style requests, before the proxy from the environment.
+func newProxyFunc(proxyByHost map[string]string) (func(*http.Request) (*url.URL, error), *probe.Error) {
+	if len(proxyByHost) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxies := make(map[string]*url.URL, len(proxyByHost))
+	for host, proxyURL := range proxyByHost {
+		if proxyURL == "" {
+			// Connect directly.
+			proxies[strings.ToLower(host)] = nil
+			continue
+		}
+		u, e := url.Parse(proxyURL)
+		if e != nil || u.Scheme == "" || u.Host == "" {
+			return nil, errInvalidArgument().Trace(host, proxyURL)
+		}
+		proxies[strings.ToLower(host)] = u
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		host, hostname := strings.ToLower(req.URL.Host), strings.ToLower(req.URL.Hostname())
+		if u, ok := proxies[host]; ok {
+			return u, nil
+		}
+		if u, ok := proxies[hostname]; ok {
+			return u, nil
+		}
+		for h, u := range proxies {
+			if strings.HasSuffix(hostname, "."+h) || strings.HasSuffix(host, "."+h) {
+				return u, nil
+			}
+		}
+		return http.ProxyFromEnvironment(req)
+	}, nil
+}
+
+// writeSSE - returns sse, or the default encryption when nil.
+func (c *S3Client) writeSSE(sse encrypt.ServerSide) encrypt.ServerSide {
+	if sse == nil {
+		return c.defaultSSE
+	}
+	return sse
+}
+
+// readSSE - returns sse, or the default SSE-C key when nil. Reads
+// must not send SSE-S3 or SSE-KMS headers, only SSE-C keys.
+func (c *S3Client) readSSE(sse encrypt.ServerSide) encrypt.ServerSide {
+	if sse == nil && c.defaultSSE != nil && c.defaultSSE.Type() == encrypt.SSEC {
+		return c.defaultSSE
+	}
+	return sse
+}
+
 // S3New returns an initialized S3Client structure. If debug is enabled,
 // it also enables an internal trace transport.
 var S3New = newFactory()
 
+// LastRequestID - x-amz-request-id of the last response received by
+// the client, whichever call made the request. Use WithRequestIDs for
+// the IDs of a call when the client is shared.
+func (c *S3Client) LastRequestID() string {
+	if c.requestIDs == nil {
+		return ""
+	}
+	return c.requestIDs.LastRequestID()
+}
+
+// LastHostID - x-amz-id-2 of the last response received by the client,
+// see LastRequestID.
+func (c *S3Client) LastHostID() string {
+	if c.requestIDs == nil {
+		return ""
+	}
+	return c.requestIDs.LastHostID()
+}
+
 // GetURL get url.
 func (c *S3Client) GetURL() ClientURL {
 	return *c.targetURL
 }
 
 // AddNotificationConfig - Add bucket notification
-func (c *S3Client) AddNotificationConfig(arn string, events []string, prefix, suffix string, ignoreExisting bool) *probe.Error {
+func (c *S3Client) AddNotificationConfig(ctx context.Context, arn string, events []string, prefix, suffix string, ignoreExisting bool) *probe.Error {
 	bucket, _ := c.url2BucketAndObject()
-	// Validate total fields in ARN.
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
 	fields := strings.Split(arn, ":")
-	if len(fields) != 6 {
-		return errInvalidArgument()
+	if err := c.checkNotificationARN(api, bucket, arn); err != nil {
+		return err
+	}
+	eventTypes, err := notificationEventTypes(events)
+	if err != nil {
+		return err
 	}
 
 	// Get any enabled notification.
-	mb, e := c.api.GetBucketNotification(bucket)
+	mb, e := api.GetBucketNotification(bucket)
 	if e != nil {
 		return probe.NewError(e)
 	}
@@ -248,18 +642,7 @@ func (c *S3Client) AddNotificationConfig(arn string, events []string, prefix, su
 	nc := minio.NewNotificationConfig(accountArn)
 
 	// Configure events
-	for _, event := range events {
-		switch event {
-		case "put":
-			nc.AddEvents(minio.ObjectCreatedAll)
-		case "delete":
-			nc.AddEvents(minio.ObjectRemovedAll)
-		case "get":
-			nc.AddEvents(minio.ObjectAccessedAll)
-		default:
-			return errInvalidArgument().Trace(events...)
-		}
-	}
+	nc.AddEvents(eventTypes...)
 	if prefix != "" {
 		nc.AddFilterPrefix(prefix)
 	}
@@ -285,7 +668,7 @@ func (c *S3Client) AddNotificationConfig(arn string, events []string, prefix, su
 	}
 
 	// Set the new bucket configuration
-	if err := c.api.SetBucketNotification(bucket, mb); err != nil {
+	if err := api.SetBucketNotification(bucket, mb); err != nil {
 		if ignoreExisting && strings.Contains(err.Error(), "An object key name filtering rule defined with overlapping prefixes, overlapping suffixes, or overlapping combinations of prefixes and suffixes for the same event types") {
 			return nil
 		}
@@ -294,18 +677,167 @@ func (c *S3Client) AddNotificationConfig(arn string, events []string, prefix, su
 	return nil
 }
 
+// checkNotificationARN - validate a notification target ARN of bucket.
+func (c *S3Client) checkNotificationARN(api *minio.Client, bucket, arn string) *probe.Error {
+	if err := validateNotificationARN(arn); err != nil {
+		return err
+	}
+
+	// AWS rejects notification targets outside of the bucket region,
+	// MinIO ARNs are region-less so the check only applies to AWS.
+	fields := strings.Split(arn, ":")
+	if isAmazon(c.targetURL.Host) && strings.HasPrefix(fields[1], "aws") {
+		region, e := api.GetBucketLocation(bucket)
+		if e != nil {
+			return probe.NewError(e)
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+		if fields[3] != region {
+			return errInvalidARN(arn, "target region `"+fields[3]+"` does not match bucket region `"+region+"`")
+		}
+	}
+	return nil
+}
+
+// notificationEventTypes - event types of the put, delete and get
+// event names.
+func notificationEventTypes(events []string) ([]minio.NotificationEventType, *probe.Error) {
+	var eventTypes []minio.NotificationEventType
+	for _, event := range events {
+		switch event {
+		case "put":
+			eventTypes = append(eventTypes, minio.ObjectCreatedAll)
+		case "delete":
+			eventTypes = append(eventTypes, minio.ObjectRemovedAll)
+		case "get":
+			eventTypes = append(eventTypes, minio.ObjectAccessedAll)
+		default:
+			return nil, errInvalidArgument().Trace(events...)
+		}
+	}
+	return eventTypes, nil
+}
+
+// NotificationConflict - existing notification config a new one would
+// overlap with, on Events.
+type NotificationConflict struct {
+	Config NotificationConfig
+	Events []string
+}
+
+// CheckNotificationConfig - dry run of AddNotificationConfig, checking
+// the ARN and events and returning the existing configs the new one
+// would overlap with, without changing the bucket notification. Like
+// S3, configs overlap when they share event types and their prefixes
+// and suffixes overlap, whatever their targets.
+func (c *S3Client) CheckNotificationConfig(ctx context.Context, arn string, events []string, prefix, suffix string) ([]NotificationConflict, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	api, release := c.contextAPI(ctx, bucket)
+	err := c.checkNotificationARN(api, bucket, arn)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	eventTypes, err := notificationEventTypes(events)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := c.ListNotificationConfigs(ctx, "")
+	if err != nil {
+		return nil, err.Trace(bucket)
+	}
+	var conflicts []NotificationConflict
+	for _, config := range configs {
+		if !strings.HasPrefix(config.Prefix, prefix) && !strings.HasPrefix(prefix, config.Prefix) {
+			continue
+		}
+		if !strings.HasSuffix(config.Suffix, suffix) && !strings.HasSuffix(suffix, config.Suffix) {
+			continue
+		}
+		var overlapping []string
+		for _, event := range config.Events {
+			for _, eventType := range eventTypes {
+				if notificationEventsOverlap(event, string(eventType)) {
+					overlapping = append(overlapping, event)
+					break
+				}
+			}
+		}
+		if len(overlapping) > 0 {
+			conflicts = append(conflicts, NotificationConflict{Config: config, Events: overlapping})
+		}
+	}
+	return conflicts, nil
+}
+
+// notificationEventsOverlap - whether two event names match common
+// events, such as s3:ObjectCreated:* and s3:ObjectCreated:Put.
+func notificationEventsOverlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if strings.HasSuffix(a, "*") && strings.HasPrefix(b, strings.TrimSuffix(a, "*")) {
+		return true
+	}
+	return strings.HasSuffix(b, "*") && strings.HasPrefix(a, strings.TrimSuffix(b, "*"))
+}
+
+// Partitions allowed in notification target ARNs.
+var validARNPartitions = []string{"aws", "aws-cn", "aws-us-gov", "minio"}
+
+// validateNotificationARN - validate the shape of a notification target ARN
+// of the form arn:partition:service:region:account-id:resource.
+func validateNotificationARN(arn string) *probe.Error {
+	fields := strings.Split(arn, ":")
+	if len(fields) != 6 {
+		return errInvalidARN(arn, "expected 6 fields separated by `:`")
+	}
+	if fields[0] != "arn" {
+		return errInvalidARN(arn, "should start with `arn`")
+	}
+	validPartition := false
+	for _, partition := range validARNPartitions {
+		if fields[1] == partition {
+			validPartition = true
+			break
+		}
+	}
+	if !validPartition {
+		return errInvalidARN(arn, "unknown partition `"+fields[1]+"`, valid options are `["+strings.Join(validARNPartitions, ", ")+"]`")
+	}
+	switch fields[2] {
+	case "sns", "sqs", "lambda":
+	default:
+		return errInvalidARN(arn, "unknown service `"+fields[2]+"`, valid options are `[sns, sqs, lambda]`")
+	}
+	if fields[1] != "minio" && fields[3] == "" {
+		return errInvalidARN(arn, "region cannot be empty")
+	}
+	if fields[4] == "" {
+		return errInvalidARN(arn, "account ID cannot be empty")
+	}
+	if fields[5] == "" {
+		return errInvalidARN(arn, "resource cannot be empty")
+	}
+	return nil
+}
+
 // RemoveNotificationConfig - Remove bucket notification
-func (c *S3Client) RemoveNotificationConfig(arn string, event string, prefix string, suffix string) *probe.Error {
+func (c *S3Client) RemoveNotificationConfig(ctx context.Context, arn string, event string, prefix string, suffix string) *probe.Error {
 	bucket, _ := c.url2BucketAndObject()
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
 	// Remove all notification configs if arn is empty
 	if arn == "" {
-		if err := c.api.RemoveAllBucketNotification(bucket); err != nil {
+		if err := api.RemoveAllBucketNotification(bucket); err != nil {
 			return probe.NewError(err)
 		}
 		return nil
 	}
 
-	mb, e := c.api.GetBucketNotification(bucket)
+	mb, e := api.GetBucketNotification(bucket)
 	if e != nil {
 		return probe.NewError(e)
 	}
@@ -365,26 +897,43 @@ func (c *S3Client) RemoveNotificationConfig(arn string, event string, prefix str
 	}
 
 	// Set the new bucket configuration
-	if e := c.api.SetBucketNotification(bucket, mb); e != nil {
+	if e := api.SetBucketNotification(bucket, mb); e != nil {
 		return probe.NewError(e)
 	}
 	return nil
 }
 
+// Notification config types.
+const (
+	notificationTypeTopic  = "topic"
+	notificationTypeQueue  = "queue"
+	notificationTypeLambda = "lambda"
+)
+
+// NotificationFilterRule notification key filter rule
+type NotificationFilterRule struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 // NotificationConfig notification config
 type NotificationConfig struct {
-	ID     string   `json:"id"`
-	Arn    string   `json:"arn"`
-	Events []string `json:"events"`
-	Prefix string   `json:"prefix"`
-	Suffix string   `json:"suffix"`
+	ID          string                   `json:"id"`
+	Arn         string                   `json:"arn"`
+	Type        string                   `json:"type"`
+	Events      []string                 `json:"events"`
+	Prefix      string                   `json:"prefix"`
+	Suffix      string                   `json:"suffix"`
+	FilterRules []NotificationFilterRule `json:"filterRules,omitempty"`
 }
 
 // ListNotificationConfigs - List notification configs
-func (c *S3Client) ListNotificationConfigs(arn string) ([]NotificationConfig, *probe.Error) {
+func (c *S3Client) ListNotificationConfigs(ctx context.Context, arn string) ([]NotificationConfig, *probe.Error) {
 	var configs []NotificationConfig
 	bucket, _ := c.url2BucketAndObject()
-	mb, e := c.api.GetBucketNotification(bucket)
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
+	mb, e := api.GetBucketNotification(bucket)
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
@@ -398,7 +947,7 @@ func (c *S3Client) ListNotificationConfigs(arn string) ([]NotificationConfig, *p
 		return result
 	}
 
-	getFilters := func(config minio.NotificationConfig) (prefix, suffix string) {
+	getFilters := func(config minio.NotificationConfig) (prefix, suffix string, rules []NotificationFilterRule) {
 		if config.Filter == nil {
 			return
 		}
@@ -409,58 +958,85 @@ func (c *S3Client) ListNotificationConfigs(arn string) ([]NotificationConfig, *p
 			if strings.ToLower(filter.Name) == "suffix" {
 				suffix = filter.Value
 			}
-
+			rules = append(rules, NotificationFilterRule{Name: filter.Name, Value: filter.Value})
 		}
-		return prefix, suffix
+		return prefix, suffix, rules
 	}
 
 	for _, config := range mb.TopicConfigs {
 		if arn != "" && config.Topic != arn {
 			continue
 		}
-		prefix, suffix := getFilters(config.NotificationConfig)
+		prefix, suffix, rules := getFilters(config.NotificationConfig)
 		configs = append(configs, NotificationConfig{ID: config.ID,
-			Arn:    config.Topic,
-			Events: prettyEventNames(config.Events),
-			Prefix: prefix,
-			Suffix: suffix})
+			Arn:         config.Topic,
+			Type:        notificationTypeTopic,
+			Events:      prettyEventNames(config.Events),
+			Prefix:      prefix,
+			Suffix:      suffix,
+			FilterRules: rules})
 	}
 
 	for _, config := range mb.QueueConfigs {
 		if arn != "" && config.Queue != arn {
 			continue
 		}
-		prefix, suffix := getFilters(config.NotificationConfig)
+		prefix, suffix, rules := getFilters(config.NotificationConfig)
 		configs = append(configs, NotificationConfig{ID: config.ID,
-			Arn:    config.Queue,
-			Events: prettyEventNames(config.Events),
-			Prefix: prefix,
-			Suffix: suffix})
+			Arn:         config.Queue,
+			Type:        notificationTypeQueue,
+			Events:      prettyEventNames(config.Events),
+			Prefix:      prefix,
+			Suffix:      suffix,
+			FilterRules: rules})
 	}
 
 	for _, config := range mb.LambdaConfigs {
 		if arn != "" && config.Lambda != arn {
 			continue
 		}
-		prefix, suffix := getFilters(config.NotificationConfig)
+		prefix, suffix, rules := getFilters(config.NotificationConfig)
 		configs = append(configs, NotificationConfig{ID: config.ID,
-			Arn:    config.Lambda,
-			Events: prettyEventNames(config.Events),
-			Prefix: prefix,
-			Suffix: suffix})
+			Arn:         config.Lambda,
+			Type:        notificationTypeLambda,
+			Events:      prettyEventNames(config.Events),
+			Prefix:      prefix,
+			Suffix:      suffix,
+			FilterRules: rules})
 	}
 
+	sortNotificationConfigs(configs)
 	return configs, nil
 }
 
+// sortNotificationConfigs - sort notification configs by ARN then by ID
+// so that the listing is stable across calls.
+func sortNotificationConfigs(configs []NotificationConfig) {
+	sort.SliceStable(configs, func(i, j int) bool {
+		if configs[i].Arn != configs[j].Arn {
+			return configs[i].Arn < configs[j].Arn
+		}
+		return configs[i].ID < configs[j].ID
+	})
+}
+
 // Supported content types
 var supportedContentTypes = []string{
 	"csv",
 	"json",
 	"gzip",
 	"bzip2",
+	"zstd",
 }
 
+// selectCompressionZSTD - Zstandard compression of the input of select
+// requests, not known to minio-go.
+const selectCompressionZSTD minio.SelectCompressionType = "ZSTD"
+
+// Extensions of compressed files, the type is guessed from the content
+// type of the others.
+var compressionFileExts = []string{".gz", ".bz", ".bz2", ".zst"}
+
 // set the SelectObjectOutputSerialization struct using options passed in by client. If unspecified,
 // default S3 API specified defaults
 func selectObjectOutputOpts(selOpts SelectObjectOpts, i minio.SelectObjectInputSerialization) minio.SelectObjectOutputSerialization {
@@ -497,6 +1073,23 @@ func selectObjectOutputOpts(selOpts SelectObjectOpts, i minio.SelectObjectInputS
 		}
 		o.CSV = &ocsv
 	}
+	// Raw CSV output is never quoted, for parsers which don't
+	// understand quotes.
+	if _, ok := selOpts.OutputSerOpts["raw"]; ok && o.CSV == nil {
+		ocsv := minio.CSVOutputOptions{}
+		if recDelim, isOK = selOpts.OutputSerOpts["raw"][recordDelimiterType]; !isOK {
+			recDelim = defaultRecordDelimiter
+		}
+		ocsv.SetRecordDelimiter(recDelim)
+		if fldDelim, isOK = selOpts.OutputSerOpts["raw"][fieldDelimiterType]; !isOK {
+			fldDelim = defaultFieldDelimiter
+		}
+		ocsv.SetFieldDelimiter(fldDelim)
+		ocsv.SetQuoteCharacter("")
+		ocsv.SetQuoteEscapeCharacter("")
+		ocsv.SetQuoteFields(minio.CSVQuoteFieldsAsNeeded)
+		o.CSV = &ocsv
+	}
 	// default to CSV output if options left unspecified
 	if o.CSV == nil && o.JSON == nil {
 		if i.JSON != nil {
@@ -513,8 +1106,15 @@ func selectObjectOutputOpts(selOpts SelectObjectOpts, i minio.SelectObjectInputS
 	return o
 }
 
+// trimCompressionFileExts - name without its compression extension,
+// and without the .tar extension of compressed archives.
 func trimCompressionFileExts(name string) string {
-	return strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".bz"), ".bz2")
+	for _, ext := range compressionFileExts {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(strings.TrimSuffix(name, ext), ".tar")
+		}
+	}
+	return name
 }
 
 // set the SelectObjectInputSerialization struct using options passed in by client. If unspecified,
@@ -585,12 +1185,17 @@ func selectObjectInputOpts(selOpts SelectObjectOpts, object string) minio.Select
 
 // get client specified compression type or default compression type from file extension
 func selectCompressionType(selOpts SelectObjectOpts, object string) minio.SelectCompressionType {
-	ext := filepath.Ext(object)
-	contentType := mimedb.TypeByExtension(ext)
-
+	// An explicit compression type, NONE included, always wins over
+	// guesses from the object name as those can be wrong.
 	if selOpts.CompressionType != "" {
-		return selOpts.CompressionType
+		return minio.SelectCompressionType(strings.ToUpper(string(selOpts.CompressionType)))
+	}
+
+	ext := filepath.Ext(object)
+	if ext == ".zst" {
+		return selectCompressionZSTD
 	}
+	contentType := mimedb.TypeByExtension(ext)
 	if strings.Contains(ext, "parquet") || strings.Contains(object, ".parquet") {
 		return minio.SelectCompressionNONE
 	}
@@ -606,11 +1211,22 @@ func selectCompressionType(selOpts SelectObjectOpts, object string) minio.Select
 
 // Select - select object content wrapper.
 func (c *S3Client) Select(expression string, sse encrypt.ServerSide, selOpts SelectObjectOpts) (io.ReadCloser, *probe.Error) {
+	if err := validateSelectExpression(expression); err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	if _, ok := selOpts.OutputSerOpts["passthrough"]; ok {
+		// The object is returned as stored, without S3 Select,
+		// which can't filter it then.
+		if !selectsWholeObject(expression) {
+			return nil, errInvalidArgument().Trace("passthrough output needs SELECT * FROM S3Object", expression)
+		}
+		return c.Get(context.Background(), sse)
+	}
 	opts := minio.SelectObjectOptions{
 		Expression:     expression,
 		ExpressionType: minio.QueryExpressionTypeSQL,
 		// Set any encryption headers
-		ServerSideEncryption: sse,
+		ServerSideEncryption: c.readSSE(sse),
 	}
 
 	bucket, object := c.url2BucketAndObject()
@@ -659,6 +1275,10 @@ func (c *S3Client) watchOneBucket(bucket, prefix, suffix string, events []string
 						Host:         record.Source.Host,
 						Port:         record.Source.Port,
 						UserAgent:    record.Source.UserAgent,
+						Region:       record.AwsRegion,
+						Sequencer:    record.S3.Object.Sequencer,
+						ETag:         record.S3.Object.ETag,
+						VersionID:    record.S3.Object.VersionID,
 					}
 				} else if strings.HasPrefix(record.EventName, "s3:ObjectCreated:PutRetention") {
 					eventChan <- EventInfo{
@@ -670,6 +1290,10 @@ func (c *S3Client) watchOneBucket(bucket, prefix, suffix string, events []string
 						Host:         record.Source.Host,
 						Port:         record.Source.Port,
 						UserAgent:    record.Source.UserAgent,
+						Region:       record.AwsRegion,
+						Sequencer:    record.S3.Object.Sequencer,
+						ETag:         record.S3.Object.ETag,
+						VersionID:    record.S3.Object.VersionID,
 					}
 				} else {
 					eventChan <- EventInfo{
@@ -681,6 +1305,10 @@ func (c *S3Client) watchOneBucket(bucket, prefix, suffix string, events []string
 						Host:         record.Source.Host,
 						Port:         record.Source.Port,
 						UserAgent:    record.Source.UserAgent,
+						Region:       record.AwsRegion,
+						Sequencer:    record.S3.Object.Sequencer,
+						ETag:         record.S3.Object.ETag,
+						VersionID:    record.S3.Object.VersionID,
 					}
 				}
 			} else if strings.HasPrefix(record.EventName, "s3:ObjectRemoved:") {
@@ -691,6 +1319,9 @@ func (c *S3Client) watchOneBucket(bucket, prefix, suffix string, events []string
 					Host:      record.Source.Host,
 					Port:      record.Source.Port,
 					UserAgent: record.Source.UserAgent,
+					Region:    record.AwsRegion,
+					Sequencer: record.S3.Object.Sequencer,
+					VersionID: record.S3.Object.VersionID,
 				}
 			} else if record.EventName == minio.ObjectAccessedGet {
 				eventChan <- EventInfo{
@@ -702,6 +1333,10 @@ func (c *S3Client) watchOneBucket(bucket, prefix, suffix string, events []string
 					Host:         record.Source.Host,
 					Port:         record.Source.Port,
 					UserAgent:    record.Source.UserAgent,
+					Region:       record.AwsRegion,
+					Sequencer:    record.S3.Object.Sequencer,
+					ETag:         record.S3.Object.ETag,
+					VersionID:    record.S3.Object.VersionID,
 				}
 			} else if record.EventName == minio.ObjectAccessedHead {
 				eventChan <- EventInfo{
@@ -713,6 +1348,10 @@ func (c *S3Client) watchOneBucket(bucket, prefix, suffix string, events []string
 					Host:         record.Source.Host,
 					Port:         record.Source.Port,
 					UserAgent:    record.Source.UserAgent,
+					Region:       record.AwsRegion,
+					Sequencer:    record.S3.Object.Sequencer,
+					ETag:         record.S3.Object.ETag,
+					VersionID:    record.S3.Object.VersionID,
 				}
 			}
 		}
@@ -798,12 +1437,63 @@ func (c *S3Client) Watch(params watchParams) (*WatchObject, *probe.Error) {
 }
 
 // Get - get object with metadata.
-func (c *S3Client) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+func (c *S3Client) Get(ctx context.Context, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	return c.get(ctx, bucket, object, c.targetURL.String(), sse)
+}
+
+// get - get object of bucket like Get, name being the object as
+// reported by errors met while reading it.
+func (c *S3Client) get(ctx context.Context, bucket, object, name string, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	ctx, cancel := c.withRequestTimeout(ctx)
+	reader, err := c.getBucketObject(ctx, bucket, object, sse)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return regionErrorReader{
+		Object:     reader,
+		bucket:     bucket,
+		object:     name,
+		conditions: requestHeaders(ctx),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// withRequestTimeout - ctx bounded by the request timeout of the
+// client, if any, see Config.RequestTimeout.
+func (c *S3Client) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.requestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.requestTimeout)
+}
+
+// requestTimeoutError - context.DeadlineExceeded if e is due to the
+// request timeout of ctx, e otherwise.
+func requestTimeoutError(ctx context.Context, e error) error {
+	if ctx != nil && ctx.Err() == context.DeadlineExceeded {
+		return ctx.Err()
+	}
+	return e
+}
+
+// getObject - get object, requests are only made once it is read.
+func (c *S3Client) getObject(ctx context.Context, sse encrypt.ServerSide) (*minio.Object, *probe.Error) {
 	bucket, object := c.url2BucketAndObject()
+	return c.getBucketObject(ctx, bucket, object, sse)
+}
+
+// getBucketObject - get object of bucket like getObject.
+func (c *S3Client) getBucketObject(ctx context.Context, bucket, object string, sse encrypt.ServerSide) (*minio.Object, *probe.Error) {
 	opts := minio.GetObjectOptions{}
-	opts.ServerSideEncryption = sse
-	reader, e := c.api.GetObject(bucket, object, opts)
+	opts.ServerSideEncryption = c.readSSE(sse)
+	reader, e := c.api.GetObjectWithContext(ctx, bucket, object, opts)
 	if e != nil {
+		if err := c.conditionFailed(bucket, object, requestHeaders(ctx), e); err != nil {
+			return nil, err
+		}
 		errResponse := minio.ToErrorResponse(e)
 		if errResponse.Code == "NoSuchBucket" {
 			return nil, probe.NewError(BucketDoesNotExist{
@@ -818,44 +1508,379 @@ func (c *S3Client) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
 		if errResponse.Code == "NoSuchKey" {
 			return nil, probe.NewError(ObjectMissing{})
 		}
-		return nil, probe.NewError(e)
+		return nil, probe.NewError(regionError(bucket, e))
 	}
 	return reader, nil
 }
 
-// Copy - copy object, uses server side copy API. Also uses an abstracted API
-// such that large file sizes will be copied in multipart manner on server
-// side.
-func (c *S3Client) Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool) *probe.Error {
-	dstBucket, dstObject := c.url2BucketAndObject()
-	if dstBucket == "" {
-		return probe.NewError(BucketNameEmpty{})
-	}
+// Messages of AuthorizationHeaderMalformed errors, e.g. "the region
+// 'us-east-1' is wrong; expecting 'eu-west-1'".
+var (
+	wrongRegionRegexp     = regexp.MustCompile(`region '([^']+)' is wrong`)
+	expectingRegionRegexp = regexp.MustCompile(`expecting '([^']+)'`)
+)
 
-	tokens := splitStr(source, string(c.targetURL.Separator), 3)
+// regionError - returns BucketRegionMismatch if e reports that bucket
+// lives in another region than the one the request was made for, e
+// otherwise.
+func regionError(bucket string, e error) error {
+	errResponse := minio.ToErrorResponse(e)
+	mismatch := BucketRegionMismatch{Bucket: bucket, Actual: errResponse.Region}
+	if m := wrongRegionRegexp.FindStringSubmatch(errResponse.Message); m != nil {
+		mismatch.Expected = m[1]
+	}
+	if m := expectingRegionRegexp.FindStringSubmatch(errResponse.Message); m != nil && mismatch.Actual == "" {
+		mismatch.Actual = m[1]
+	}
+	switch {
+	case errResponse.Code == "PermanentRedirect", errResponse.StatusCode == http.StatusMovedPermanently:
+		return mismatch
+	case errResponse.Code == "AuthorizationHeaderMalformed" && mismatch.Actual != "":
+		// Also returned for reasons unrelated to the region.
+		return mismatch
+	}
+	return e
+}
 
-	// Source object
-	src := minio.NewSourceInfo(tokens[1], tokens[2], srcSSE)
+// regionErrorReader - reports region mismatches met while reading an
+// object as BucketRegionMismatch, failed conditions of the request as
+// ObjectNotModified or PreconditionFailed, and expired request
+// timeouts as context.DeadlineExceeded.
+type regionErrorReader struct {
+	*minio.Object
+	bucket     string
+	object     string
+	conditions http.Header
+	// Context of the request and its cancel function, if any.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
 
-	destOpts := minio.DestInfoOptions{
-		Encryption: tgtSSE,
+func (r regionErrorReader) Read(p []byte) (int, error) {
+	n, e := r.Object.Read(p)
+	if e != nil && e != io.EOF {
+		e = conditionError(r.object, r.conditions, regionError(r.bucket, requestTimeoutError(r.ctx, e)))
 	}
+	return n, e
+}
 
-	if lockModeStr, ok := metadata[AmzObjectLockMode]; ok {
-		destOpts.Mode = minio.RetentionMode(strings.ToUpper(lockModeStr))
-		delete(metadata, AmzObjectLockMode)
+// Close - close the object, releasing the context of the request.
+func (r regionErrorReader) Close() error {
+	e := r.Object.Close()
+	if r.cancel != nil {
+		r.cancel()
 	}
+	return e
+}
 
-	if retainUntilDateStr, ok := metadata[AmzObjectLockRetainUntilDate]; ok {
-		delete(metadata, AmzObjectLockRetainUntilDate)
-		if t, e := time.Parse(time.RFC3339, retainUntilDateStr); e == nil {
-			destOpts.RetainUntilDate = t.UTC()
+// conditionHeaders - headers making requests conditional, reported by
+// requestConditions in this order.
+var conditionHeaders = []string{
+	"If-Match",
+	"If-None-Match",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+	"X-Amz-Copy-Source-If-Match",
+	"X-Amz-Copy-Source-If-None-Match",
+	"X-Amz-Copy-Source-If-Modified-Since",
+	"X-Amz-Copy-Source-If-Unmodified-Since",
+}
+
+// requestConditions - conditional headers of header, e.g.
+// `If-Match: "etag"`, separated by commas.
+func requestConditions(header http.Header) string {
+	var conditions []string
+	for _, k := range conditionHeaders {
+		if v := header.Get(k); v != "" {
+			conditions = append(conditions, k+": "+v)
 		}
 	}
+	return strings.Join(conditions, ", ")
+}
 
-	if lh, ok := metadata[AmzObjectLockLegalHold]; ok {
-		destOpts.LegalHold = minio.LegalHoldStatus(lh)
-		delete(metadata, AmzObjectLockLegalHold)
+// conditionError - returns ObjectNotModified or PreconditionFailed if
+// e is a 304 or 412 response to a request on object with the given
+// conditional headers, e otherwise.
+func conditionError(object string, conditions http.Header, e error) error {
+	errResponse := minio.ToErrorResponse(e)
+	switch {
+	case errResponse.StatusCode == http.StatusNotModified, errResponse.Code == "NotModified":
+		return ObjectNotModified{Object: object, Condition: requestConditions(conditions)}
+	case errResponse.StatusCode == http.StatusPreconditionFailed, errResponse.Code == "PreconditionFailed":
+		return PreconditionFailed{Object: object, Condition: requestConditions(conditions)}
+	}
+	return e
+}
+
+// conditionFailed - typed error traced with the failed condition if e
+// is a 304 or 412 response to a request with the given conditional
+// headers, nil otherwise.
+func (c *S3Client) conditionFailed(bucket, object string, conditions http.Header, e error) *probe.Error {
+	switch err := conditionError(c.targetURL.String(), conditions, e).(type) {
+	case ObjectNotModified:
+		return probe.NewError(err).Trace(bucket, object, err.Condition)
+	case PreconditionFailed:
+		return probe.NewError(err).Trace(bucket, object, err.Condition)
+	}
+	return nil
+}
+
+// GetVerified - get object like Get, verifying the MD5 of the content
+// against the ETag of single part objects. Close on the returned reader
+// fails with ChecksumMismatch if the whole object was read and doesn't
+// match. Multipart objects and objects encrypted with SSE-C or SSE-KMS,
+// whose ETag isn't the MD5 of their content, are not verified.
+func (c *S3Client) GetVerified(ctx context.Context, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	object, err := c.getObject(ctx, sse)
+	if err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	st, err := c.statGotObject(ctx, object)
+	if err != nil {
+		object.Close()
+		return nil, err
+	}
+	etag, ok := c.md5ETag(st, sse)
+	if !ok {
+		return object, nil
+	}
+	_, name := c.url2BucketAndObject()
+	return &md5VerifyingReader{ReadCloser: object, object: name, etag: etag, hash: md5.New()}, nil
+}
+
+// statGotObject - info of an object being downloaded, the response to
+// its request.
+func (c *S3Client) statGotObject(ctx context.Context, object *minio.Object) (minio.ObjectInfo, *probe.Error) {
+	st, e := object.Stat()
+	if e != nil {
+		bucket, name := c.url2BucketAndObject()
+		if err := c.conditionFailed(bucket, name, requestHeaders(ctx), e); err != nil {
+			return st, err
+		}
+		if minio.ToErrorResponse(e).Code == "NoSuchKey" {
+			return st, probe.NewError(ObjectMissing{})
+		}
+		return st, probe.NewError(regionError(bucket, e))
+	}
+	return st, nil
+}
+
+// md5ETag - ETag of an object if it is the MD5 of its content, which
+// isn't the case for multipart uploads and encrypted objects.
+func (c *S3Client) md5ETag(st minio.ObjectInfo, sse encrypt.ServerSide) (string, bool) {
+	etag := strings.Trim(st.ETag, "\"")
+	switch {
+	case len(etag) != md5.Size*2 || strings.Contains(etag, "-"):
+		return "", false
+	case c.readSSE(sse) != nil:
+		return "", false
+	case st.Metadata.Get("X-Amz-Server-Side-Encryption") == "aws:kms",
+		st.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "":
+		return "", false
+	}
+	return etag, true
+}
+
+// VerifyIntegrity - download the object and check that the SHA256 of
+// its content is expectedSHA256, or that its MD5 is its ETag when
+// expectedSHA256 is empty. ETags are only MD5s for objects neither
+// uploaded in parts nor encrypted. Mismatches are reported as
+// IntegrityMismatch.
+func (c *S3Client) VerifyIntegrity(ctx context.Context, expectedSHA256 string) (bool, *probe.Error) {
+	bucket, name := c.url2BucketAndObject()
+	object, err := c.getObject(ctx, nil)
+	if err != nil {
+		return false, err.Trace(bucket, name)
+	}
+	defer object.Close()
+	st, err := c.statGotObject(ctx, object)
+	if err != nil {
+		return false, err.Trace(bucket, name)
+	}
+	algorithm, expected, hasher := "SHA256", strings.ToLower(expectedSHA256), sha256.New()
+	if expected == "" {
+		etag, ok := c.md5ETag(st, nil)
+		if !ok {
+			return false, errInvalidArgument().Trace("the ETag of `"+name+"` isn't its MD5, its SHA256 is needed", st.ETag)
+		}
+		algorithm, expected, hasher = "MD5", etag, md5.New()
+	}
+	if _, e := io.Copy(hasher, object); e != nil {
+		return false, probe.NewError(e).Trace(bucket, name)
+	}
+	if sum := hex.EncodeToString(hasher.Sum(nil)); sum != expected {
+		return false, probe.NewError(IntegrityMismatch{Object: name, Algorithm: algorithm, Expected: expected, Got: sum})
+	}
+	return true, nil
+}
+
+// md5VerifyingReader - computes the MD5 of the content read and checks
+// it against the expected ETag on Close, once EOF was reached.
+type md5VerifyingReader struct {
+	io.ReadCloser
+	object string
+	etag   string
+	hash   hash.Hash
+	eof    bool
+}
+
+func (r *md5VerifyingReader) Read(p []byte) (int, error) {
+	n, e := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if e == io.EOF {
+		r.eof = true
+	}
+	return n, e
+}
+
+func (r *md5VerifyingReader) Close() error {
+	if e := r.ReadCloser.Close(); e != nil {
+		return e
+	}
+	if !r.eof {
+		// Partial read, nothing to verify.
+		return nil
+	}
+	if sum := hex.EncodeToString(r.hash.Sum(nil)); sum != r.etag {
+		return ChecksumMismatch{Object: r.object, Expected: r.etag, Got: sum}
+	}
+	return nil
+}
+
+// ETagCache - last seen ETag and content of an object, used by
+// GetCached to avoid downloading unchanged objects again. It is
+// not safe for concurrent use.
+type ETagCache struct {
+	ETag string
+	Data []byte
+}
+
+// GetCached - get object unless it matches the cached ETag, in which
+// case the cached content is returned and cached is true. The cache
+// is updated whenever the object is downloaded.
+func (c *S3Client) GetCached(cache *ETagCache, sse encrypt.ServerSide) (reader io.ReadCloser, cached bool, err *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return nil, false, probe.NewError(BucketNameEmpty{})
+	}
+	if object == "" {
+		return nil, false, probe.NewError(ObjectNameEmpty{})
+	}
+	if cache == nil {
+		return nil, false, errInvalidArgument().Trace(bucket, object)
+	}
+
+	header := make(http.Header)
+	if sse = c.readSSE(sse); sse != nil && sse.Type() == encrypt.SSEC {
+		sse.Marshal(header)
+	}
+	if cache.ETag != "" {
+		header.Set("If-None-Match", "\""+strings.Trim(cache.ETag, "\"")+"\"")
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		object: object,
+		header: header,
+	})
+	if e != nil {
+		errResponse := minio.ToErrorResponse(e)
+		switch {
+		case errResponse.StatusCode == http.StatusNotModified:
+			return ioutil.NopCloser(bytes.NewReader(cache.Data)), true, nil
+		case errResponse.Code == "NoSuchBucket":
+			return nil, false, probe.NewError(BucketDoesNotExist{Bucket: bucket})
+		case errResponse.Code == "NoSuchKey":
+			return nil, false, probe.NewError(ObjectMissing{})
+		}
+		if err := c.conditionFailed(bucket, object, header, e); err != nil {
+			return nil, false, err
+		}
+		return nil, false, probe.NewError(e)
+	}
+	defer resp.Body.Close()
+
+	data, e := ioutil.ReadAll(resp.Body)
+	if e != nil {
+		return nil, false, probe.NewError(e)
+	}
+	cache.ETag = strings.Trim(resp.Header.Get("ETag"), "\"")
+	cache.Data = data
+	return ioutil.NopCloser(bytes.NewReader(data)), false, nil
+}
+
+// Copy - copy object, uses server side copy API. Also uses an abstracted API
+// such that large file sizes will be copied in multipart manner on server
+// side. A non-empty cannedACL is applied to the destination object.
+// Canceling ctx aborts the copy, including any in-flight multipart copy.
+// Its progress is reported to the TransferProgress of
+// WithTransferProgress, if any.
+func (c *S3Client) Copy(ctx context.Context, source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool, cannedACL string) *probe.Error {
+	dstBucket, dstObject := c.url2BucketAndObject()
+	if dstBucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if !isValidCannedACL(cannedACL) {
+		return errInvalidArgument().Trace(cannedACL)
+	}
+	// The metadata of the source is kept unless replaced by the caller.
+	replace := len(metadata) > 0
+	// Metadata is usually taken from Stat, whose keys may be cased differently.
+	metadata = canonicalizeMetadata(metadata)
+	if storageClass, ok := metadata["X-Amz-Storage-Class"]; ok {
+		storageClass, err := normalizeStorageClass(storageClass)
+		if err != nil {
+			return err.Trace(dstBucket, dstObject)
+		}
+		metadata["X-Amz-Storage-Class"] = storageClass
+	} else if c.defaultStorageClass != "" {
+		metadata["X-Amz-Storage-Class"] = c.defaultStorageClass
+	}
+
+	tokens := splitStr(source, string(c.targetURL.Separator), 3)
+
+	// Source object
+	src := minio.NewSourceInfo(tokens[1], tokens[2], c.readSSE(srcSSE))
+
+	destOpts := minio.DestInfoOptions{
+		Encryption: c.writeSSE(tgtSSE),
+	}
+
+	if lockModeStr, ok := metadata[AmzObjectLockMode]; ok {
+		destOpts.Mode = minio.RetentionMode(strings.ToUpper(lockModeStr))
+		delete(metadata, AmzObjectLockMode)
+	}
+
+	if retainUntilDateStr, ok := metadata[AmzObjectLockRetainUntilDate]; ok {
+		delete(metadata, AmzObjectLockRetainUntilDate)
+		if t, e := time.Parse(time.RFC3339, retainUntilDateStr); e == nil {
+			destOpts.RetainUntilDate = t.UTC()
+		}
+	}
+
+	if lh, ok := metadata[AmzObjectLockLegalHold]; ok {
+		destOpts.LegalHold = minio.LegalHoldStatus(lh)
+		delete(metadata, AmzObjectLockLegalHold)
+	}
+
+	if cannedACL != "" {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[amzACL] = cannedACL
+	}
+
+	if !replace && len(metadata) > 0 {
+		// Setting the storage class or the ACL replaces the metadata
+		// of the source, copy it along.
+		opts := minio.StatObjectOptions{}
+		opts.ServerSideEncryption = encrypt.SSE(c.readSSE(srcSSE))
+		source, e := c.api.StatObjectWithContext(ctx, tokens[1], tokens[2], opts)
+		if e != nil {
+			return c.copyError(tokens[1], tokens[2], nil, e)
+		}
+		for k, v := range sourceMetadata(source) {
+			metadata[k] = v
+		}
 	}
 
 	// Assign metadata after irrelevant parts are delete above
@@ -867,51 +1892,469 @@ func (c *S3Client) Copy(source string, size int64, progress io.Reader, srcSSE, t
 		return probe.NewError(e)
 	}
 
+	// minio-go copy APIs don't accept a context. Sources in other
+	// buckets may be in other regions, minio-go looks them up.
+	regionBucket := dstBucket
+	if tokens[1] != dstBucket {
+		regionBucket = ""
+	}
+	api, release := c.contextAPI(ctx, regionBucket)
+	defer release()
+	transfer := transferProgress(ctx)
 	if disableMultipart {
-		e = c.api.CopyObjectWithProgress(dst, src, progress)
+		var size int64
+		if transfer != nil {
+			opts := minio.StatObjectOptions{}
+			opts.ServerSideEncryption = encrypt.SSE(c.readSSE(srcSSE))
+			var source minio.ObjectInfo
+			if source, e = api.StatObject(tokens[1], tokens[2], opts); e == nil {
+				size = source.Size
+				transfer.Progress(0, size, 0)
+			}
+		}
+		if e == nil {
+			e = api.CopyObjectWithProgress(dst, src, progress)
+		}
+		if e == nil && transfer != nil {
+			transfer.Progress(size, size, 0)
+		}
 	} else {
-		e = c.api.ComposeObjectWithProgress(dst, []minio.SourceInfo{src}, progress)
+		e = c.multipartCopy(api, dst, src, serverCopy{
+			srcBucket: tokens[1],
+			srcObject: tokens[2],
+			dstBucket: dstBucket,
+			dstObject: dstObject,
+			srcSSE:    c.readSSE(srcSSE),
+			dstSSE:    destOpts.Encryption,
+			metadata:  metadata,
+			transfer:  transfer,
+		}, progress)
+	}
+
+	if e != nil && ctx.Err() != nil {
+		return probe.NewError(ctx.Err())
 	}
 
 	if e != nil {
-		errResponse := minio.ToErrorResponse(e)
-		if errResponse.Code == "AccessDenied" {
-			return probe.NewError(PathInsufficientPermission{
-				Path: c.targetURL.String(),
-			})
+		return c.copyError(dstBucket, dstObject, requestHeaders(ctx), e)
+	}
+	return nil
+}
+
+// copyError - typed error of a failed copy to bucket, made with the
+// given conditional headers.
+func (c *S3Client) copyError(bucket, object string, conditions http.Header, e error) *probe.Error {
+	if err := c.conditionFailed(bucket, object, conditions, e); err != nil {
+		return err
+	}
+	errResponse := minio.ToErrorResponse(e)
+	if errResponse.Code == "AccessDenied" {
+		return probe.NewError(PathInsufficientPermission{
+			Path: c.targetURL.String(),
+		})
+	}
+	if errResponse.Code == "NoSuchBucket" {
+		return probe.NewError(BucketDoesNotExist{
+			Bucket: bucket,
+		})
+	}
+	if errResponse.Code == "InvalidBucketName" {
+		return probe.NewError(BucketInvalid{
+			Bucket: bucket,
+		})
+	}
+	if errResponse.Code == "NoSuchKey" {
+		return probe.NewError(ObjectMissing{})
+	}
+	return probe.NewError(e)
+}
+
+// minCopyPartSize - minimum size of all but the last source of a
+// multipart copy.
+const minCopyPartSize = 5 * 1024 * 1024
+
+// SourceInfo - byte range of an object to copy with MultipartCopy.
+type SourceInfo struct {
+	Bucket string
+	Object string
+	// Start is the offset of the range in the object.
+	Start int64
+	// Length is the size of the range, the rest of the object from
+	// Start when 0.
+	Length int64
+	// SSE is the SSE-C key of the object, if encrypted with one.
+	SSE encrypt.ServerSide
+}
+
+// MultipartCopy - compose the target object of the byte ranges of
+// sources, in order, with a server side multipart copy. Unlike Copy,
+// the target can be larger than any source object. All ranges but
+// the last need at least 5MiB, ranges without length are computed
+// from the size of their object. Canceling ctx aborts the copy.
+func (c *S3Client) MultipartCopy(ctx context.Context, sources []SourceInfo, progress io.Reader) *probe.Error {
+	dstBucket, dstObject := c.url2BucketAndObject()
+	if dstBucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if len(sources) == 0 {
+		return errInvalidArgument().Trace("multipart copy without sources")
+	}
+
+	srcs := make([]minio.SourceInfo, 0, len(sources))
+	for i, source := range sources {
+		if source.Bucket == "" {
+			return probe.NewError(BucketNameEmpty{})
 		}
-		if errResponse.Code == "NoSuchBucket" {
-			return probe.NewError(BucketDoesNotExist{
-				Bucket: dstBucket,
-			})
+		if source.Object == "" {
+			return probe.NewError(ObjectNameEmpty{}).Trace(source.Bucket)
 		}
-		if errResponse.Code == "InvalidBucketName" {
-			return probe.NewError(BucketInvalid{
-				Bucket: dstBucket,
-			})
+		if source.Start < 0 || source.Length < 0 {
+			return errInvalidArgument().Trace("invalid byte range", source.Bucket, source.Object)
 		}
-		if errResponse.Code == "NoSuchKey" {
-			return probe.NewError(ObjectMissing{})
+		sse := c.readSSE(source.SSE)
+		length := source.Length
+		if length == 0 {
+			opts := minio.StatObjectOptions{}
+			opts.ServerSideEncryption = sse
+			objectStat, e := c.api.StatObjectWithContext(ctx, source.Bucket, source.Object, opts)
+			if e != nil {
+				return c.copyError(source.Bucket, source.Object, nil, e).Trace(source.Bucket, source.Object)
+			}
+			length = objectStat.Size - source.Start
+			if length <= 0 {
+				return errInvalidArgument().Trace("byte range starts after the end of the object", source.Bucket, source.Object)
+			}
+		}
+		if length < minCopyPartSize && i < len(sources)-1 {
+			return errInvalidArgument().Trace("all sources but the last need at least 5MiB", source.Bucket, source.Object)
 		}
+		src := minio.NewSourceInfo(source.Bucket, source.Object, sse)
+		if e := src.SetRange(source.Start, source.Start+length-1); e != nil {
+			return probe.NewError(e).Trace(source.Bucket, source.Object)
+		}
+		srcs = append(srcs, src)
+	}
+
+	var metadata map[string]string
+	if c.defaultStorageClass != "" {
+		metadata = map[string]string{"X-Amz-Storage-Class": c.defaultStorageClass}
+	}
+	dst, e := minio.NewDestinationInfo(dstBucket, dstObject, c.writeSSE(nil), metadata)
+	if e != nil {
 		return probe.NewError(e)
 	}
+	api, release := c.contextAPI(ctx, dstBucket)
+	defer release()
+	if e = api.ComposeObjectWithProgress(dst, srcs, progress); e != nil {
+		return c.copyError(dstBucket, dstObject, nil, e).Trace(dstBucket, dstObject)
+	}
 	return nil
 }
 
-// Put - upload an object with custom metadata.
-func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool) (int64, *probe.Error) {
+// StreamCopy - copy the object to the target of dst, which may be on
+// another endpoint or account where a server side copy isn't possible.
+// The object is streamed from a Get into a Put on dst, without a local
+// temporary file, reporting progress as it is uploaded. When metadata
+// is nil the content type and user metadata of the object are copied.
+// Canceling ctx aborts both the download and the upload.
+func (c *S3Client) StreamCopy(ctx context.Context, dst *S3Client, metadata map[string]string, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide) (int64, *probe.Error) {
+	reader, err := c.getObject(ctx, srcSSE)
+	if err != nil {
+		return 0, err.Trace(c.targetURL.String())
+	}
+	defer reader.Close()
+
+	bucket, object := c.url2BucketAndObject()
+	st, e := reader.Stat()
+	if e != nil {
+		if err := c.conditionFailed(bucket, object, requestHeaders(ctx), e); err != nil {
+			return 0, err
+		}
+		if minio.ToErrorResponse(e).Code == "NoSuchKey" {
+			return 0, probe.NewError(ObjectMissing{})
+		}
+		return 0, probe.NewError(regionError(bucket, e)).Trace(c.targetURL.String())
+	}
+	if metadata == nil {
+		metadata = userMetadata(st.Metadata)
+		if st.ContentType != "" {
+			metadata["Content-Type"] = st.ContentType
+		}
+	}
+
+	// Hide ReadAt and Seek of the object, so that it is downloaded with
+	// a single request, in order.
+	source := struct{ io.Reader }{regionErrorReader{Object: reader, bucket: bucket, object: c.targetURL.String()}}
+	n, err := dst.Put(ctx, source, st.Size, metadata, progress, tgtSSE, false, false, "")
+	if err != nil {
+		if ctx.Err() != nil {
+			return n, probe.NewError(ctx.Err())
+		}
+		return n, err.Trace(c.targetURL.String(), dst.targetURL.String())
+	}
+	return n, nil
+}
+
+// serverCopy - whole object copied on the server side by multipartCopy.
+type serverCopy struct {
+	srcBucket, srcObject string
+	dstBucket, dstObject string
+	srcSSE, dstSSE       encrypt.ServerSide
+	// Metadata of the destination, the one of the source when empty.
+	metadata map[string]string
+	// transfer, if not nil, receives the progress of the copy, made
+	// part by part if larger than one.
+	transfer TransferProgress
+}
+
+// maxSingleCopySize - objects larger than this can't be copied with a
+// single CopyObject request.
+const maxSingleCopySize = 5 * 1024 * 1024 * 1024
+
+// Parts of multipart copies are large enough to copy objects of up
+// to 5TiB with 10000 parts.
+const copyPartSize = 5 * 1024 * 1024 * 1024 * 1024 / 9999
+
+// multipartCopy - copy an object like minio-go ComposeObject, using the
+// requests of api: at once up to 5GiB, part by part beyond, or beyond
+// a part when the progress of the copy is reported. Unlike
+// ComposeObject, the multipart upload of a failed or canceled copy is
+// aborted by its upload ID, leaving other uploads of the object alone.
+func (c *S3Client) multipartCopy(api *minio.Client, dst minio.DestinationInfo, src minio.SourceInfo, cp serverCopy, progress io.Reader) error {
+	opts := minio.StatObjectOptions{}
+	opts.ServerSideEncryption = encrypt.SSE(cp.srcSSE)
+	source, e := api.StatObject(cp.srcBucket, cp.srcObject, opts)
+	if e != nil {
+		return e
+	}
+	if cp.transfer != nil {
+		cp.transfer.Progress(0, source.Size, 0)
+	}
+	if source.Size <= maxSingleCopySize && (cp.transfer == nil || source.Size <= copyPartSize) {
+		e = api.CopyObjectWithProgress(dst, src, progress)
+		if e == nil && cp.transfer != nil {
+			cp.transfer.Progress(source.Size, source.Size, 0)
+		}
+		return e
+	}
+
+	metadata := cp.metadata
+	if len(metadata) == 0 {
+		metadata = sourceMetadata(source)
+	}
+	core := minio.Core{Client: api}
+	uploadID, e := core.NewMultipartUpload(cp.dstBucket, cp.dstObject, minio.PutObjectOptions{
+		UserMetadata:         metadata,
+		ServerSideEncryption: cp.dstSSE,
+	})
+	if e != nil {
+		return e
+	}
+
+	// Fail if the source changes while it is copied.
+	header := http.Header{"X-Amz-Copy-Source-If-Match": []string{source.ETag}}
+	if cp.srcSSE != nil {
+		encrypt.SSECopy(cp.srcSSE).Marshal(header)
+	}
+	if cp.dstSSE != nil && cp.dstSSE.Type() == encrypt.SSEC {
+		cp.dstSSE.Marshal(header)
+	}
+	partHeaders := make(map[string]string, len(header))
+	for k := range header {
+		partHeaders[k] = header.Get(k)
+	}
+
+	// Split evenly, the last part can't be much smaller than the others.
+	count := (source.Size + copyPartSize - 1) / copyPartSize
+	parts := make([]minio.CompletePart, 0, count)
+	var offset int64
+	for i := int64(0); i < count && e == nil; i++ {
+		length := source.Size / count
+		if i < source.Size%count {
+			length++
+		}
+		var part minio.CompletePart
+		part, e = core.CopyObjectPart(cp.srcBucket, cp.srcObject, cp.dstBucket, cp.dstObject,
+			uploadID, int(i+1), offset, length, partHeaders)
+		if e == nil {
+			parts = append(parts, part)
+			offset += length
+			if progress != nil {
+				io.CopyN(ioutil.Discard, progress, length)
+			}
+			if cp.transfer != nil {
+				cp.transfer.Progress(offset, source.Size, int(i+1))
+			}
+		}
+	}
+	if e == nil {
+		_, e = core.CompleteMultipartUpload(cp.dstBucket, cp.dstObject, uploadID, parts)
+	}
+	if e != nil {
+		// The requests of api are canceled along with the copy, abort
+		// with the client ones. The copy error is the one reported, an
+		// upload failing to abort is left for removal as incomplete.
+		(minio.Core{Client: c.api}).AbortMultipartUpload(cp.dstBucket, cp.dstObject, uploadID)
+	}
+	return e
+}
+
+// sourceMetadata - metadata of a copy source which a copy replacing
+// it has to send again to keep it: its content headers and user
+// metadata.
+func sourceMetadata(source minio.ObjectInfo) map[string]string {
+	metadata := map[string]string{"Content-Type": source.ContentType}
+	for k, v := range source.Metadata {
+		if len(v) == 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(k, "X-Amz-Meta-"),
+			k == "Cache-Control", k == "Content-Disposition",
+			k == "Content-Encoding", k == "Content-Language":
+			metadata[k] = v[0]
+		}
+	}
+	return metadata
+}
+
+// canonicalizeMetadata - returns metadata with canonical header keys, so
+// that standard headers are recognized whatever their case. When keys
+// only differ in case, the value of the canonical key wins, otherwise
+// the value of the first key in sort order.
+func canonicalizeMetadata(metadata map[string]string) map[string]string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	canonical := make(map[string]string, len(metadata))
+	for _, k := range keys {
+		ck := http.CanonicalHeaderKey(k)
+		if _, ok := canonical[ck]; ok && k != ck {
+			continue
+		}
+		canonical[ck] = metadata[k]
+	}
+	return canonical
+}
+
+// sniffContentType - detect the content type from the first 512 bytes
+// of reader. The returned reader must be used in place of reader, it
+// still provides the peeked bytes. Seekable readers, such as files, are
+// seeked back to where they were and returned as is.
+func sniffContentType(reader io.Reader) (string, io.Reader, error) {
+	const contentTypeOctetStream = "application/octet-stream"
+	if reader == nil {
+		return contentTypeOctetStream, reader, nil
+	}
+	seeker, seekable := reader.(io.ReadSeeker)
+	var offset int64
+	if seekable {
+		var e error
+		// Pipes, such as stdin, are files which can't seek.
+		offset, e = seeker.Seek(0, io.SeekCurrent)
+		seekable = e == nil
+	}
+	buf := make([]byte, 512)
+	n, e := io.ReadFull(reader, buf)
+	if e == io.EOF || e == io.ErrUnexpectedEOF {
+		e = nil
+	}
+	if e != nil {
+		return "", nil, e
+	}
+	if seekable {
+		if _, e = seeker.Seek(offset, io.SeekStart); e != nil {
+			return "", nil, e
+		}
+	} else {
+		reader = io.MultiReader(bytes.NewReader(buf[:n]), reader)
+	}
+	if n == 0 {
+		return contentTypeOctetStream, reader, nil
+	}
+	return http.DetectContentType(buf[:n]), reader, nil
+}
+
+// parseExpires - parse an Expires value in RFC1123 or RFC3339 format.
+func parseExpires(value string) (time.Time, *probe.Error) {
+	for _, layout := range []string{http.TimeFormat, time.RFC1123, time.RFC1123Z, time.RFC3339} {
+		if t, e := time.Parse(layout, value); e == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, errInvalidArgument()
+}
+
+// multipartThreadsKey - context key of the threads set by WithMultipartThreads.
+type multipartThreadsKey struct{}
+
+// WithMultipartThreads - returns a context making Put calls upload
+// parts with threads goroutines, overriding Config.MultipartThreads.
+func WithMultipartThreads(ctx context.Context, threads int) context.Context {
+	return context.WithValue(ctx, multipartThreadsKey{}, threads)
+}
+
+// putThreads - number of goroutines uploading parts for a Put call.
+func (c *S3Client) putThreads(ctx context.Context) (uint, *probe.Error) {
+	threads, ok := ctx.Value(multipartThreadsKey{}).(int)
+	if !ok {
+		return uint(c.multipartThreads), nil
+	}
+	if threads < 1 {
+		return 0, errInvalidArgument().Trace("multipart threads must be at least 1", strconv.Itoa(threads))
+	}
+	return uint(threads), nil
+}
+
+// Put - upload an object with custom metadata. Standard headers
+// such as Expires are recognized in metadata, raw request headers
+// can be set on ctx with withRequestHeaders and the number of parts
+// uploaded in parallel with WithMultipartThreads, its progress
+// reported with WithTransferProgress. A non-empty
+// cannedACL is applied to the uploaded object. A size of -1
+// streams the reader until EOF using multipart, buffering at
+// most one part in memory; it cannot be combined with
+// disableMultipart.
+func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool, cannedACL string) (int64, *probe.Error) {
+	ctx, cancel := c.withRequestTimeout(ctx)
+	defer cancel()
 	bucket, object := c.url2BucketAndObject()
+	n, err := c.putObject(ctx, bucket, object, reader, size, metadata, progress, sse, md5, disableMultipart, cannedACL)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return n, probe.NewError(ctx.Err()).Trace(bucket, object)
+	}
+	return n, err
+}
+
+// putObject - upload to object in bucket as Put does, for uploads to
+// other objects than the one of the client URL.
+func (c *S3Client) putObject(ctx context.Context, bucket, object string, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool, cannedACL string) (int64, *probe.Error) {
 	if bucket == "" {
 		return 0, probe.NewError(BucketNameEmpty{})
 	}
+	if !isValidCannedACL(cannedACL) {
+		return 0, errInvalidArgument().Trace(cannedACL)
+	}
+	metadata = canonicalizeMetadata(metadata)
+	threads, err := c.putThreads(ctx)
+	if err != nil {
+		return 0, err.Trace(bucket, object)
+	}
+	if size < 0 && disableMultipart {
+		return 0, probe.NewError(UnknownSizeWithoutMultipart{Object: object})
+	}
 
 	contentType, ok := metadata["Content-Type"]
 	if ok {
 		delete(metadata, "Content-Type")
 	} else {
-		// Set content-type if not specified.
-		contentType = "application/octet-stream"
+		// Guess content-type from the object extension if not specified,
+		// then from the content itself once the rest is validated.
+		contentType = mimedb.TypeByExtension(filepath.Ext(object))
 	}
+	sniff := !ok && (contentType == "" || contentType == "application/octet-stream")
 
 	cacheControl, ok := metadata["Cache-Control"]
 	if ok {
@@ -936,6 +2379,30 @@ func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 	storageClass, ok := metadata["X-Amz-Storage-Class"]
 	if ok {
 		delete(metadata, "X-Amz-Storage-Class")
+	} else {
+		storageClass = c.defaultStorageClass
+	}
+	storageClass, err = normalizeStorageClass(storageClass)
+	if err != nil {
+		return 0, err.Trace(bucket, object)
+	}
+
+	websiteRedirect, ok := metadata[amzWebsiteRedirectLocation]
+	if ok {
+		delete(metadata, amzWebsiteRedirectLocation)
+	}
+
+	// minio-go rejects Expires as metadata, send it as the standard
+	// header of the request creating the object instead.
+	if expiresStr, ok := metadata["Expires"]; ok {
+		delete(metadata, "Expires")
+		expires, err := parseExpires(expiresStr)
+		if err != nil {
+			return 0, err.Trace(expiresStr)
+		}
+		ctx = withObjectHeaders(ctx, http.Header{
+			"Expires": []string{expires.Format(http.TimeFormat)},
+		})
 	}
 
 	lockModeStr, ok := metadata[AmzObjectLockMode]
@@ -953,92 +2420,396 @@ func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 			retainUntilDate = t.UTC()
 		}
 	}
+
+	if cannedACL != "" {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[amzACL] = cannedACL
+	}
+
+	if sniff {
+		var e error
+		if contentType, reader, e = sniffContentType(reader); e != nil {
+			return 0, probe.NewError(e)
+		}
+	}
+
 	opts := minio.PutObjectOptions{
-		UserMetadata:         metadata,
-		Progress:             progress,
-		NumThreads:           defaultMultipartThreadsNum,
-		ContentType:          contentType,
-		CacheControl:         cacheControl,
-		ContentDisposition:   contentDisposition,
-		ContentEncoding:      contentEncoding,
-		ContentLanguage:      contentLanguage,
-		StorageClass:         strings.ToUpper(storageClass),
-		ServerSideEncryption: sse,
-		SendContentMd5:       md5,
-		DisableMultipart:     disableMultipart,
+		UserMetadata:            metadata,
+		Progress:                progress,
+		NumThreads:              threads,
+		ContentType:             contentType,
+		CacheControl:            cacheControl,
+		ContentDisposition:      contentDisposition,
+		ContentEncoding:         contentEncoding,
+		ContentLanguage:         contentLanguage,
+		StorageClass:            storageClass,
+		WebsiteRedirectLocation: websiteRedirect,
+		ServerSideEncryption:    c.writeSSE(sse),
+		SendContentMd5:          md5,
+		DisableMultipart:        disableMultipart,
+	}
+
+	if !retainUntilDate.IsZero() && !retainUntilDate.Equal(timeSentinel) {
+		opts.RetainUntilDate = &retainUntilDate
+	}
+
+	if lockModeStr != "" {
+		opts.Mode = &lockMode
+	}
+
+	if lh, ok := metadata[AmzObjectLockLegalHold]; ok {
+		delete(metadata, AmzObjectLockLegalHold)
+		opts.LegalHold = minio.LegalHoldStatus(strings.ToUpper(lh))
+	}
+
+	if size < 0 {
+		// Stream of unknown size, upload it part by part until EOF
+		// instead of letting minio-go pick a part size for the
+		// largest possible object. Only one part is buffered in
+		// memory at a time and progress is reported as the stream
+		// is consumed.
+		opts.PartSize = uint64(c.streamPartSize)
+		if opts.PartSize == 0 {
+			opts.PartSize = defaultStreamPartSize
+		}
+	}
+
+	if p := transferProgress(ctx); p != nil {
+		var partSize int64
+		switch {
+		case size < 0:
+			partSize = int64(opts.PartSize)
+		case !disableMultipart && size >= putMinPartSize:
+			partSize = putPartSize(size)
+		}
+		opts.Progress = newProgressReader(progress, p, size, partSize)
+	}
+
+	n, e := c.api.PutObjectWithContext(ctx, bucket, object, reader, size, opts)
+	if e != nil {
+		errResponse := minio.ToErrorResponse(e)
+		if errResponse.Code == "UnexpectedEOF" || e == io.EOF {
+			return n, probe.NewError(UnexpectedEOF{
+				TotalSize:    size,
+				TotalWritten: n,
+			})
+		}
+		if err := c.conditionFailed(bucket, object, requestHeaders(ctx), e); err != nil {
+			return n, err
+		}
+		if errResponse.Code == "AccessDenied" {
+			return n, probe.NewError(PathInsufficientPermission{
+				Path: c.targetURL.String(),
+			})
+		}
+		if errResponse.Code == "MethodNotAllowed" {
+			return n, probe.NewError(ObjectAlreadyExists{
+				Object: object,
+			})
+		}
+		if errResponse.Code == "XMinioObjectExistsAsDirectory" {
+			return n, probe.NewError(ObjectAlreadyExistsAsDirectory{
+				Object: object,
+			})
+		}
+		if errResponse.Code == "NoSuchBucket" {
+			return n, probe.NewError(BucketDoesNotExist{
+				Bucket: bucket,
+			})
+		}
+		if errResponse.Code == "InvalidBucketName" {
+			return n, probe.NewError(BucketInvalid{
+				Bucket: bucket,
+			})
+		}
+		if errResponse.Code == "NoSuchKey" {
+			return n, probe.NewError(ObjectMissing{})
+		}
+		return n, probe.NewError(regionError(bucket, e))
+	}
+	return n, nil
+}
+
+// PutFile - upload the local file at localPath with Put, its size
+// taken from the file and its content type, unless set in metadata,
+// guessed from the extension of localPath. The file is closed once
+// uploaded or on error.
+func (c *S3Client) PutFile(ctx context.Context, localPath string, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide) (int64, *probe.Error) {
+	file, e := os.Open(localPath)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return 0, probe.NewError(PathNotFound{Path: localPath})
+		}
+		if os.IsPermission(e) {
+			return 0, probe.NewError(PathInsufficientPermission{Path: localPath})
+		}
+		return 0, probe.NewError(e).Trace(localPath)
+	}
+	defer file.Close()
+
+	st, e := file.Stat()
+	if e != nil {
+		return 0, probe.NewError(e).Trace(localPath)
+	}
+	if !st.Mode().IsRegular() {
+		return 0, probe.NewError(PathIsNotRegular{Path: localPath})
 	}
 
-	if !retainUntilDate.IsZero() && !retainUntilDate.Equal(timeSentinel) {
-		opts.RetainUntilDate = &retainUntilDate
+	// Don't modify the metadata of the caller.
+	meta := canonicalizeMetadata(metadata)
+	if _, ok := meta["Content-Type"]; !ok {
+		if contentType := mimedb.TypeByExtension(filepath.Ext(localPath)); contentType != "" && contentType != "application/octet-stream" {
+			meta["Content-Type"] = contentType
+		}
+	}
+	n, err := c.Put(ctx, file, st.Size(), meta, progress, sse, false, false, "")
+	if err != nil {
+		return n, err.Trace(localPath)
+	}
+	return n, nil
+}
+
+// GetToFile - download the object to localPath, streamed to a temporary
+// file in the same directory which is renamed to localPath once the
+// whole object was written, so that no partial file ever appears at
+// localPath. The temporary file is removed on error. The modification
+// time of objects uploaded with preserved attributes is restored.
+func (c *S3Client) GetToFile(ctx context.Context, localPath string, sse encrypt.ServerSide) (int64, *probe.Error) {
+	reader, err := c.getObject(ctx, sse)
+	if err != nil {
+		return 0, err.Trace(localPath)
+	}
+	defer reader.Close()
+
+	bucket, object := c.url2BucketAndObject()
+	st, e := reader.Stat()
+	if e != nil {
+		if err := c.conditionFailed(bucket, object, requestHeaders(ctx), e); err != nil {
+			return 0, err
+		}
+		if minio.ToErrorResponse(e).Code == "NoSuchKey" {
+			return 0, probe.NewError(ObjectMissing{})
+		}
+		return 0, probe.NewError(regionError(bucket, e))
+	}
+
+	tmp, e := ioutil.TempFile(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*.part")
+	if e != nil {
+		if os.IsPermission(e) {
+			return 0, probe.NewError(PathInsufficientPermission{Path: localPath})
+		}
+		return 0, probe.NewError(e).Trace(localPath)
+	}
+	n, e := io.Copy(tmp, reader)
+	if e == nil && n != st.Size {
+		e = io.ErrUnexpectedEOF
+	}
+	if e == nil {
+		// Flush the content before the rename makes it visible.
+		e = tmp.Sync()
+	}
+	if ce := tmp.Close(); e == nil {
+		e = ce
+	}
+	if e == nil {
+		// Temporary files are only readable by their owner.
+		e = os.Chmod(tmp.Name(), 0644)
+	}
+	if e == nil {
+		e = preserveTimestamp(tmp.Name(), st.Metadata.Get("X-Amz-Meta-Mc-Attrs"))
+	}
+	if e == nil {
+		e = os.Rename(tmp.Name(), localPath)
+	}
+	if e != nil {
+		os.Remove(tmp.Name())
+		return n, probe.NewError(regionError(bucket, e)).Trace(localPath)
+	}
+	return n, nil
+}
+
+// preserveTimestamp - set the access and modification times of path
+// saved in the mc-attrs metadata of an object, if any. The access time
+// defaults to the modification time.
+func preserveTimestamp(path, attrs string) error {
+	if attrs == "" {
+		return nil
+	}
+	attr, e := parseAttribute(attrs)
+	if e != nil || attr["mtime"] == "" {
+		return nil
+	}
+	mtime, e := strconv.ParseInt(attr["mtime"], 10, 64)
+	if e != nil {
+		return nil
+	}
+	atime := mtime
+	if v, e := strconv.ParseInt(attr["atime"], 10, 64); e == nil {
+		atime = v
+	}
+	return os.Chtimes(path, time.Unix(atime, 0), time.Unix(mtime, 0))
+}
+
+// PutExpiring - upload like Put an object which the backend removes
+// once expireAfter elapsed, with its expiry date sent in the header
+// set by Config.ObjectExpiryHeader. Fails with APINotImplemented if
+// the backend has no such header.
+func (c *S3Client) PutExpiring(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, expireAfter time.Duration) (int64, *probe.Error) {
+	if expireAfter <= 0 {
+		return 0, errInvalidArgument().Trace("object expiry must be positive", expireAfter.String())
+	}
+	if c.objectExpiryHeader == "" {
+		return 0, probe.NewError(APINotImplemented{
+			API:     "Object expiry",
+			APIType: c.targetURL.Scheme + "://" + c.targetURL.Host,
+		})
+	}
+	ctx = withObjectHeaders(ctx, http.Header{
+		c.objectExpiryHeader: []string{UTCNow().Add(expireAfter).Format(http.TimeFormat)},
+	})
+	return c.Put(ctx, reader, size, metadata, progress, sse, false, false, "")
+}
+
+// webhookNotification - payload posted to a webhook after an upload.
+type webhookNotification struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// PutCompressed - upload an object compressed with gzip at the given
+// level, streamed with multipart since the compressed size is unknown.
+// size bounds the data read from reader when not negative. The object
+// is stored with `Content-Encoding: gzip`, its content-type is guessed
+// from the uncompressed data if not specified.
+func (c *S3Client) PutCompressed(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, level int) (int64, *probe.Error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return 0, errInvalidArgument().Trace(strconv.Itoa(level))
+	}
+	// Put validates the rest, fail before sniffing the content.
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return 0, probe.NewError(BucketNameEmpty{})
+	}
+	if size >= 0 {
+		reader = io.LimitReader(reader, size)
+	}
+
+	metadata = canonicalizeMetadata(metadata)
+	if _, ok := metadata["Content-Type"]; !ok {
+		contentType := mimedb.TypeByExtension(filepath.Ext(object))
+		if contentType == "" || contentType == "application/octet-stream" {
+			var e error
+			if contentType, reader, e = sniffContentType(reader); e != nil {
+				return 0, probe.NewError(e)
+			}
+		}
+		metadata["Content-Type"] = contentType
+	}
+	metadata["Content-Encoding"] = "gzip"
+
+	pr, pw := io.Pipe()
+	zw, e := gzip.NewWriterLevel(pw, level)
+	if e != nil {
+		return 0, probe.NewError(e)
+	}
+	go func() {
+		_, e := io.Copy(zw, reader)
+		if e == nil {
+			e = zw.Close()
+		}
+		pw.CloseWithError(e)
+	}()
+
+	n, err := c.Put(ctx, pr, -1, metadata, progress, sse, false, false, "")
+	// Unblock the compressing goroutine if the upload stopped early.
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return n, err.Trace(c.targetURL.String())
 	}
+	return n, nil
+}
 
-	if lockModeStr != "" {
-		opts.Mode = &lockMode
+// PutWithWebhook - upload an object and notify webhookURL with a JSON
+// POST describing the uploaded object. A failure to notify the webhook
+// doesn't fail the upload: it is returned as a WebhookFailed webhookErr
+// while err is nil.
+func (c *S3Client) PutWithWebhook(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool, webhookURL string, webhookTimeout time.Duration) (n int64, webhookErr error, err *probe.Error) {
+	if _, e := url.ParseRequestURI(webhookURL); e != nil {
+		return 0, nil, probe.NewError(e).Trace(webhookURL)
+	}
+	n, err = c.Put(ctx, reader, size, metadata, progress, sse, md5, disableMultipart, "")
+	if err != nil {
+		return n, nil, err
 	}
 
-	if lh, ok := metadata[AmzObjectLockLegalHold]; ok {
-		delete(metadata, AmzObjectLockLegalHold)
-		opts.LegalHold = minio.LegalHoldStatus(strings.ToUpper(lh))
+	bucket, object := c.url2BucketAndObject()
+	notification := webhookNotification{
+		Bucket: bucket,
+		Key:    object,
+		Size:   n,
+	}
+	opts := minio.StatObjectOptions{}
+	opts.ServerSideEncryption = c.readSSE(sse)
+	if objectStat, e := c.api.StatObjectWithContext(ctx, bucket, object, opts); e == nil {
+		notification.ETag = objectStat.ETag
+	}
+	if e := postWebhook(ctx, webhookURL, webhookTimeout, notification); e != nil {
+		return n, WebhookFailed{URL: webhookURL, Err: e}, nil
 	}
+	return n, nil, nil
+}
 
-	n, e := c.api.PutObjectWithContext(ctx, bucket, object, reader, size, opts)
+// postWebhook - POST the JSON encoded payload to webhookURL.
+func postWebhook(ctx context.Context, webhookURL string, timeout time.Duration, payload interface{}) error {
+	body, e := json.Marshal(payload)
 	if e != nil {
-		errResponse := minio.ToErrorResponse(e)
-		if errResponse.Code == "UnexpectedEOF" || e == io.EOF {
-			return n, probe.NewError(UnexpectedEOF{
-				TotalSize:    size,
-				TotalWritten: n,
-			})
-		}
-		if errResponse.Code == "AccessDenied" {
-			return n, probe.NewError(PathInsufficientPermission{
-				Path: c.targetURL.String(),
-			})
-		}
-		if errResponse.Code == "MethodNotAllowed" {
-			return n, probe.NewError(ObjectAlreadyExists{
-				Object: object,
-			})
-		}
-		if errResponse.Code == "XMinioObjectExistsAsDirectory" {
-			return n, probe.NewError(ObjectAlreadyExistsAsDirectory{
-				Object: object,
-			})
-		}
-		if errResponse.Code == "NoSuchBucket" {
-			return n, probe.NewError(BucketDoesNotExist{
-				Bucket: bucket,
-			})
-		}
-		if errResponse.Code == "InvalidBucketName" {
-			return n, probe.NewError(BucketInvalid{
-				Bucket: bucket,
-			})
-		}
-		if errResponse.Code == "NoSuchKey" {
-			return n, probe.NewError(ObjectMissing{})
-		}
-		return n, probe.NewError(e)
+		return e
 	}
-	return n, nil
+	req, e := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if e != nil {
+		return e
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, e := (&http.Client{Timeout: timeout}).Do(req)
+	if e != nil {
+		return e
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("webhook responded with " + resp.Status)
+	}
+	return nil
 }
 
 // Remove incomplete uploads.
-func (c *S3Client) removeIncompleteObjects(bucket string, objectsCh <-chan string) <-chan minio.RemoveObjectError {
-	removeObjectErrorCh := make(chan minio.RemoveObjectError)
+func (c *S3Client) removeIncompleteObjects(ctx context.Context, bucket string, objectsCh <-chan removeObject) <-chan RemoveStatus {
+	removeStatusCh := make(chan RemoveStatus)
 
-	// Goroutine reads from objectsCh and sends error to removeObjectErrorCh if any.
+	// Goroutine reads from objectsCh and sends the status of every upload to removeStatusCh.
 	go func() {
-		defer close(removeObjectErrorCh)
+		defer close(removeStatusCh)
 
 		for object := range objectsCh {
-			if err := c.api.RemoveIncompleteUpload(bucket, object); err != nil {
-				removeObjectErrorCh <- minio.RemoveObjectError{ObjectName: object, Err: err}
+			status := RemoveStatus{Key: object.key}
+			if ctx.Err() != nil {
+				status.Err = probe.NewError(ctx.Err())
+			} else {
+				api, release := c.contextAPI(ctx, bucket)
+				if e := api.RemoveIncompleteUpload(bucket, object.key); e != nil {
+					status.Err = probe.NewError(e)
+				}
+				release()
 			}
+			removeStatusCh <- status
 		}
 	}()
 
-	return removeObjectErrorCh
+	return removeStatusCh
 }
 
 // AddUserAgent - add custom user agent.
@@ -1046,117 +2817,199 @@ func (c *S3Client) AddUserAgent(app string, version string) {
 	c.api.SetAppInfo(app, version)
 }
 
-// Remove - remove object or bucket(s).
-func (c *S3Client) Remove(isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent) <-chan *probe.Error {
+// Remove - remove object or bucket(s). Contents with a VersionID
+// remove only this version of their object, the others remove the
+// object, which creates a delete marker in versioned buckets.
+func (c *S3Client) Remove(ctx context.Context, isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent) <-chan *probe.Error {
 	errorCh := make(chan *probe.Error)
+	go func() {
+		defer close(errorCh)
+		c.remove(ctx, isIncomplete, isRemoveBucket, isBypass, contentCh, func(status RemoveStatus) {
+			if status.Err != nil {
+				errorCh <- status.Err
+			}
+		})
+	}()
+	return errorCh
+}
 
+// remove - remove the objects of contentCh as Remove does, calling send
+// with the status of every object and the errors of buckets.
+func (c *S3Client) remove(ctx context.Context, isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent, send func(RemoveStatus)) {
 	prevBucket := ""
 	// Maintain objectsCh, statusCh for each bucket
-	var objectsCh chan string
-	var statusCh <-chan minio.RemoveObjectError
-	opts := minio.RemoveObjectsOptions{
-		GovernanceBypass: isBypass,
+	var objectsCh chan removeObject
+	var statusCh <-chan RemoveStatus
+
+	if isRemoveBucket {
+		if _, object := c.url2BucketAndObject(); object != "" {
+			send(RemoveStatus{Err: probe.NewError(errors.New("cannot delete prefixes with `mc rb` command - Use `mc rm` instead"))})
+			return
+		}
 	}
+	for content := range contentCh {
+		// Convert content.URL.Path to objectName for objectsCh,
+		// the listed key is exact when known.
+		bucket, objectName := c.splitPath(content.URL.Path)
+		if content.Key != "" {
+			objectName = content.Key
+		}
 
-	go func() {
-		defer close(errorCh)
-		if isRemoveBucket {
-			if _, object := c.url2BucketAndObject(); object != "" {
-				errorCh <- probe.NewError(errors.New("cannot delete prefixes with `mc rb` command - Use `mc rm` instead"))
-				return
-			}
+		// We don't treat path when bucket is
+		// empty, just skip it when it happens.
+		if bucket == "" {
+			continue
 		}
-		for content := range contentCh {
-			// Convert content.URL.Path to objectName for objectsCh.
-			bucket, objectName := c.splitPath(content.URL.Path)
 
-			// We don't treat path when bucket is
-			// empty, just skip it when it happens.
-			if bucket == "" {
-				continue
+		// Init objectsCh the first time.
+		if prevBucket == "" {
+			objectsCh = make(chan removeObject)
+			prevBucket = bucket
+			if isIncomplete {
+				statusCh = c.removeIncompleteObjects(ctx, bucket, objectsCh)
+			} else {
+				statusCh = c.removeObjects(ctx, bucket, objectsCh, isBypass)
 			}
+		}
 
-			// Init objectsCh the first time.
-			if prevBucket == "" {
-				objectsCh = make(chan string)
-				prevBucket = bucket
-				if isIncomplete {
-					statusCh = c.removeIncompleteObjects(bucket, objectsCh)
-				} else {
-					statusCh = c.api.RemoveObjectsWithOptions(bucket, objectsCh, opts)
-				}
+		if prevBucket != bucket {
+			if objectsCh != nil {
+				close(objectsCh)
 			}
-
-			if prevBucket != bucket {
-				if objectsCh != nil {
-					close(objectsCh)
-				}
-				for removeStatus := range statusCh {
-					errorCh <- probe.NewError(removeStatus.Err)
-				}
-				// Remove bucket if it qualifies.
-				if isRemoveBucket && !isIncomplete {
-					if err := c.api.RemoveBucket(prevBucket); err != nil {
-						errorCh <- probe.NewError(err)
-					}
-				}
-				// Re-init objectsCh for next bucket
-				objectsCh = make(chan string)
-				if isIncomplete {
-					statusCh = c.removeIncompleteObjects(bucket, objectsCh)
-				} else {
-					statusCh = c.api.RemoveObjectsWithOptions(bucket, objectsCh, opts)
-				}
-				prevBucket = bucket
-			}
-
-			if objectName != "" {
-				// Send object name once but continuously checks for pending
-				// errors in parallel, the reason is that minio-go RemoveObjects
-				// can block if there is any pending error not received yet.
-				sent := false
-				for !sent {
-					select {
-					case objectsCh <- objectName:
-						sent = true
-					case removeStatus := <-statusCh:
-						errorCh <- probe.NewError(removeStatus.Err)
-					}
+			for removeStatus := range statusCh {
+				send(removeStatus)
+			}
+			// Remove bucket if it qualifies.
+			if isRemoveBucket && !isIncomplete {
+				if err := c.removeBucket(ctx, prevBucket); err != nil {
+					send(RemoveStatus{Err: err})
 				}
+			}
+			// Re-init objectsCh for next bucket
+			objectsCh = make(chan removeObject)
+			if isIncomplete {
+				statusCh = c.removeIncompleteObjects(ctx, bucket, objectsCh)
 			} else {
-				// end of bucket - close the objectsCh
-				if objectsCh != nil {
-					close(objectsCh)
+				statusCh = c.removeObjects(ctx, bucket, objectsCh, isBypass)
+			}
+			prevBucket = bucket
+		}
+
+		if objectName != "" {
+			// Send object name once but continuously checks for pending
+			// statuses in parallel, the reason is that removeObjects
+			// can block if there is any pending status not received yet.
+			sent := false
+			for !sent {
+				select {
+				case objectsCh <- removeObject{key: objectName, versionID: content.VersionID}:
+					sent = true
+				case removeStatus := <-statusCh:
+					send(removeStatus)
 				}
-				objectsCh = nil
 			}
+		} else {
+			// end of bucket - close the objectsCh
+			if objectsCh != nil {
+				close(objectsCh)
+			}
+			objectsCh = nil
 		}
-		// Close objectsCh at end of contentCh
-		if objectsCh != nil {
-			close(objectsCh)
+	}
+	// Close objectsCh at end of contentCh
+	if objectsCh != nil {
+		close(objectsCh)
+	}
+	// Send the remove objects status
+	if statusCh != nil {
+		for removeStatus := range statusCh {
+			send(removeStatus)
 		}
-		// Write remove objects status to errorCh
-		if statusCh != nil {
-			for removeStatus := range statusCh {
-				errorCh <- probe.NewError(removeStatus.Err)
-			}
+	}
+	// Remove last bucket if it qualifies.
+	if isRemoveBucket && prevBucket != "" && !isIncomplete {
+		if err := c.removeBucket(ctx, prevBucket); err != nil {
+			send(RemoveStatus{Err: err})
 		}
-		// Remove last bucket if it qualifies.
-		if isRemoveBucket && prevBucket != "" && !isIncomplete {
-			if err := c.api.RemoveBucket(prevBucket); err != nil {
-				errorCh <- probe.NewError(err)
-			}
+	}
+}
+
+// RemoveObject - remove the object of the target URL. When ifMatchETag
+// is not empty, the object is only removed if it still has this ETag:
+// it is compared on a stat first, then sent as an If-Match condition
+// on the delete. Servers ignoring the condition leave a window between
+// both where a newer object can still be removed. PreconditionFailed
+// is returned when the ETag doesn't match.
+func (c *S3Client) RemoveObject(ctx context.Context, ifMatchETag string) *probe.Error {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if object == "" {
+		return probe.NewError(ObjectNameEmpty{})
+	}
+	header := http.Header{}
+	etag := strings.Trim(ifMatchETag, "\"")
+	if etag != "" {
+		opts := minio.StatObjectOptions{}
+		opts.ServerSideEncryption = c.readSSE(nil)
+		header.Set("If-Match", "\""+etag+"\"")
+		objectStat, e := c.api.StatObjectWithContext(ctx, bucket, object, opts)
+		if e != nil {
+			return c.removeObjectError(bucket, object, header, e)
 		}
-	}()
-	return errorCh
+		if objectStat.ETag != etag {
+			condition := requestConditions(header)
+			return probe.NewError(PreconditionFailed{Object: c.targetURL.String(), Condition: condition}).Trace(bucket, object, condition)
+		}
+	}
+	resp, e := c.executeRequest(ctx, http.MethodDelete, s3RequestMetadata{
+		bucket: bucket,
+		object: object,
+		header: header,
+	})
+	if e != nil {
+		return c.removeObjectError(bucket, object, header, e)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// removeObjectError - typed error of a failed conditional removal.
+func (c *S3Client) removeObjectError(bucket, object string, conditions http.Header, e error) *probe.Error {
+	if err := c.conditionFailed(bucket, object, conditions, e); err != nil {
+		return err
+	}
+	switch minio.ToErrorResponse(e).Code {
+	case "NoSuchKey":
+		return probe.NewError(ObjectMissing{}).Trace(bucket, object)
+	case "NoSuchBucket":
+		return probe.NewError(BucketDoesNotExist{Bucket: bucket})
+	case "AccessDenied":
+		return probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
+	}
+	return probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+}
+
+// removeBucket - remove a bucket, canceled along with ctx.
+func (c *S3Client) removeBucket(ctx context.Context, bucket string) *probe.Error {
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
+	if e := api.RemoveBucket(bucket); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
 }
 
 // MakeBucket - make a new bucket.
-func (c *S3Client) MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error {
+func (c *S3Client) MakeBucket(ctx context.Context, region string, ignoreExisting, withLock bool) *probe.Error {
 	bucket, object := c.url2BucketAndObject()
 	if bucket == "" {
 		return probe.NewError(BucketNameEmpty{})
 	}
+	// The bucket may not exist yet, there is no region to look up.
+	api, release := c.contextAPI(ctx, "")
+	defer release()
 	if object != "" {
 		if !strings.HasSuffix(object, string(c.targetURL.Separator)) {
 			object = path.Dir(object)
@@ -1166,7 +3019,7 @@ func (c *S3Client) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 		}
 		var retried bool
 		for {
-			_, e := c.api.PutObject(bucket, object,
+			_, e := c.api.PutObjectWithContext(ctx, bucket, object,
 				bytes.NewReader([]byte("")), 0, minio.PutObjectOptions{})
 			if e == nil {
 				return nil
@@ -1177,9 +3030,9 @@ func (c *S3Client) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 			switch minio.ToErrorResponse(e).Code {
 			case "NoSuchBucket":
 				if withLock {
-					e = c.api.MakeBucketWithObjectLock(bucket, region)
+					e = api.MakeBucketWithObjectLock(bucket, region)
 				} else {
-					e = c.api.MakeBucket(bucket, region)
+					e = api.MakeBucket(bucket, region)
 				}
 				if e != nil {
 					return probe.NewError(e)
@@ -1193,9 +3046,9 @@ func (c *S3Client) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 
 	var e error
 	if withLock {
-		e = c.api.MakeBucketWithObjectLock(bucket, region)
+		e = api.MakeBucketWithObjectLock(bucket, region)
 	} else {
-		e = c.api.MakeBucket(bucket, region)
+		e = api.MakeBucket(bucket, region)
 	}
 	if e != nil {
 		// Ignore bucket already existing error when ignoreExisting flag is enabled
@@ -1274,6 +3127,68 @@ func (c *S3Client) SetAccess(bucketPolicy string, isJSON bool) *probe.Error {
 		}
 		return nil
 	}
+	return c.mergeAccessPolicy(bucket, object, policy.BucketPolicy(bucketPolicy))
+}
+
+// DeleteBucketPolicy - remove the bucket policy whatever its
+// statements are, without merging it like SetAccess does.
+func (c *S3Client) DeleteBucketPolicy() *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if e := c.api.SetBucketPolicy(bucket, ""); e != nil {
+		return probe.NewError(e).Trace(bucket)
+	}
+	return nil
+}
+
+// GrantPrefixRead - grant anonymous read access to all objects
+// under prefix, merged into the existing bucket policy.
+func (c *S3Client) GrantPrefixRead(prefix string) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if err := validatePolicyPrefix(bucket, prefix); err != nil {
+		return err.Trace(bucket, prefix)
+	}
+	return c.mergeAccessPolicy(bucket, prefix, policy.BucketPolicyReadOnly)
+}
+
+// RevokePrefixRead - remove anonymous access to objects under
+// prefix, other statements of the bucket policy are kept.
+func (c *S3Client) RevokePrefixRead(prefix string) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if err := validatePolicyPrefix(bucket, prefix); err != nil {
+		return err.Trace(bucket, prefix)
+	}
+	return c.mergeAccessPolicy(bucket, prefix, policy.BucketPolicyNone)
+}
+
+// validatePolicyPrefix - validate the bucket and prefix of the
+// `arn:aws:s3:::bucket/prefix*` resource of a policy statement.
+func validatePolicyPrefix(bucket, prefix string) *probe.Error {
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if e := s3utils.CheckValidBucketName(bucket); e != nil {
+		return probe.NewError(BucketInvalid{Bucket: bucket})
+	}
+	resource := "arn:aws:s3:::" + bucket + "/" + prefix + "*"
+	switch {
+	case strings.HasPrefix(prefix, "/"):
+		return errInvalidPolicyPrefix(prefix, "prefix cannot start with `/`")
+	case strings.ContainsAny(prefix, "*?"):
+		return errInvalidPolicyPrefix(prefix, "prefix cannot contain wildcards")
+	case !utf8.ValidString(prefix) || strings.IndexFunc(prefix, unicode.IsControl) >= 0:
+		return errInvalidPolicyPrefix(prefix, "prefix contains invalid characters")
+	case len(resource) > 2048:
+		return errInvalidPolicyPrefix(prefix, "resource `"+resource+"` is too long")
+	}
+	return nil
+}
+
+// mergeAccessPolicy - set the canned policy for prefix, merging it
+// with the statements of the current bucket policy.
+func (c *S3Client) mergeAccessPolicy(bucket, prefix string, bucketPolicy policy.BucketPolicy) *probe.Error {
 	policyStr, e := c.api.GetBucketPolicy(bucket)
 	if e != nil {
 		return probe.NewError(e)
@@ -1284,7 +3199,7 @@ func (c *S3Client) SetAccess(bucketPolicy string, isJSON bool) *probe.Error {
 			return probe.NewError(e)
 		}
 	}
-	p.Statements = policy.SetPolicy(p.Statements, policy.BucketPolicy(bucketPolicy), bucket, object)
+	p.Statements = policy.SetPolicy(p.Statements, bucketPolicy, bucket, prefix)
 	if len(p.Statements) == 0 {
 		if e = c.api.SetBucketPolicy(bucket, ""); e != nil {
 			return probe.NewError(e)
@@ -1298,14 +3213,344 @@ func (c *S3Client) SetAccess(bucketPolicy string, isJSON bool) *probe.Error {
 	if e = c.api.SetBucketPolicy(bucket, string(policyB)); e != nil {
 		return probe.NewError(e)
 	}
-	return nil
+	return nil
+}
+
+// Bucket request payment configuration values.
+const (
+	requestPayerBucketOwner = "BucketOwner"
+	requestPayerRequester   = "Requester"
+
+	// amzRequestPayer acknowledges charges of requester pays buckets.
+	amzRequestPayer = "X-Amz-Request-Payer"
+)
+
+// requestPaymentConfiguration - bucket request payment configuration.
+type requestPaymentConfiguration struct {
+	XMLName xml.Name `xml:"RequestPaymentConfiguration"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Payer   string   `xml:"Payer"`
+}
+
+// setRequestPayer - enable or disable the requester pays header on
+// all subsequent requests of the client made against the bucket.
+func (c *S3Client) setRequestPayer(bucket, payer string) *probe.Error {
+	value := ""
+	if payer == requestPayerRequester {
+		value = "requester"
+	}
+	return c.setBucketHeader(bucket, amzRequestPayer, value)
+}
+
+// setBucketHeader - send a header on all subsequent requests of the
+// client made against the bucket, stop sending it if value is empty.
+// Clients share their minio client with the other clients of the same
+// host, a client sending bucket headers switches to a minio client and
+// transport of its own. Not safe while other requests are in flight.
+func (c *S3Client) setBucketHeader(bucket, key, value string) *probe.Error {
+	c.Lock()
+	defer c.Unlock()
+	if c.transport.bucketHeaders[bucket].Get(key) == value {
+		return nil
+	}
+	bucketHeaders := make(map[string]http.Header, len(c.transport.bucketHeaders)+1)
+	for b, h := range c.transport.bucketHeaders {
+		bucketHeaders[b] = h.Clone()
+	}
+	h, ok := bucketHeaders[bucket]
+	if !ok {
+		h = make(http.Header)
+		bucketHeaders[bucket] = h
+	}
+	if value != "" {
+		h.Set(key, value)
+	} else {
+		h.Del(key)
+	}
+	if len(h) == 0 {
+		delete(bucketHeaders, bucket)
+	}
+
+	if len(bucketHeaders) == 0 {
+		c.api, c.transport = c.sharedAPI, c.sharedTransport
+		c.contextClients = c.sharedContextClients
+		return nil
+	}
+	transport := newHeaderTransport(c.sharedTransport.transport, c.creds, c.virtualStyle, bucketHeaders)
+	api, e := c.newAPI(transport, "")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	c.api, c.transport = api, transport
+	c.contextClients = &contextClientPool{}
+	return nil
+}
+
+// GetBucketRequestPayment - get who pays for requests made to the bucket,
+// either "BucketOwner" or "Requester".
+func (c *S3Client) GetBucketRequestPayment() (string, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return "", probe.NewError(BucketNameEmpty{})
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"requestPayment": []string{""}},
+	})
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	defer resp.Body.Close()
+
+	var config requestPaymentConfiguration
+	if e = xml.NewDecoder(resp.Body).Decode(&config); e != nil {
+		return "", probe.NewError(e)
+	}
+	payer := config.Payer
+	if payer == "" {
+		payer = requestPayerBucketOwner
+	}
+	if err := c.setRequestPayer(bucket, payer); err != nil {
+		return "", err.Trace(bucket)
+	}
+	return payer, nil
+}
+
+// SetBucketRequestPayment - set who pays for requests made to the bucket,
+// payer should be either "BucketOwner" or "Requester". Once "Requester" is
+// set, all subsequent requests against the bucket acknowledge the charges.
+func (c *S3Client) SetBucketRequestPayment(payer string) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	switch {
+	case strings.EqualFold(payer, requestPayerBucketOwner):
+		payer = requestPayerBucketOwner
+	case strings.EqualFold(payer, requestPayerRequester):
+		payer = requestPayerRequester
+	default:
+		return errInvalidArgument().Trace(payer)
+	}
+	configBytes, e := xml.Marshal(requestPaymentConfiguration{
+		Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/",
+		Payer: payer,
+	})
+	if e != nil {
+		return probe.NewError(e)
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodPut, s3RequestMetadata{
+		bucket:  bucket,
+		query:   url.Values{"requestPayment": []string{""}},
+		content: configBytes,
+	})
+	if e != nil {
+		return probe.NewError(e)
+	}
+	resp.Body.Close()
+	return c.setRequestPayer(bucket, payer).Trace(bucket)
+}
+
+// Canned ACLs of buckets, see GetBucketACL.
+var bucketCannedACLs = []string{
+	"private",
+	"public-read",
+	"public-read-write",
+	"authenticated-read",
+}
+
+// Grantee groups of the canned ACLs of buckets.
+const (
+	aclAllUsers           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	aclAuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// accessControlPolicy - ACL of a bucket.
+type accessControlPolicy struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	OwnerID string   `xml:"Owner>ID"`
+	Grants  []struct {
+		GranteeID  string `xml:"Grantee>ID"`
+		GranteeURI string `xml:"Grantee>URI"`
+		Permission string `xml:"Permission"`
+	} `xml:"AccessControlList>Grant"`
+}
+
+// GetBucketACL - get the canned ACL of the bucket matching its ACL,
+// one of "private", "public-read", "public-read-write" and
+// "authenticated-read". ACLs granting permissions no canned ACL does
+// fail with BucketACLNotCanned.
+func (c *S3Client) GetBucketACL() (string, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return "", probe.NewError(BucketNameEmpty{})
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodGet, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"acl": []string{""}},
+	})
+	if e != nil {
+		return "", probe.NewError(regionError(bucket, e)).Trace(bucket)
+	}
+	defer resp.Body.Close()
+
+	var policy accessControlPolicy
+	if e = xml.NewDecoder(resp.Body).Decode(&policy); e != nil {
+		return "", probe.NewError(e).Trace(bucket)
+	}
+	var allRead, allWrite, authenticatedRead bool
+	for _, grant := range policy.Grants {
+		switch {
+		case grant.GranteeURI == aclAllUsers && grant.Permission == "READ":
+			allRead = true
+		case grant.GranteeURI == aclAllUsers && grant.Permission == "WRITE":
+			allWrite = true
+		case grant.GranteeURI == aclAuthenticatedUsers && grant.Permission == "READ":
+			authenticatedRead = true
+		case grant.GranteeURI == "" && grant.GranteeID == policy.OwnerID && grant.Permission == "FULL_CONTROL":
+		default:
+			return "", probe.NewError(BucketACLNotCanned{Bucket: bucket})
+		}
+	}
+	switch {
+	case allRead && allWrite && !authenticatedRead:
+		return "public-read-write", nil
+	case allRead && !allWrite && !authenticatedRead:
+		return "public-read", nil
+	case authenticatedRead && !allRead && !allWrite:
+		return "authenticated-read", nil
+	case !allRead && !allWrite && !authenticatedRead:
+		return "private", nil
+	}
+	return "", probe.NewError(BucketACLNotCanned{Bucket: bucket})
+}
+
+// SetBucketACL - replace the ACL of the bucket with a canned ACL, one
+// of those GetBucketACL returns.
+func (c *S3Client) SetBucketACL(acl string) *probe.Error {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	valid := false
+	for _, cannedACL := range bucketCannedACLs {
+		if acl == cannedACL {
+			valid = true
+		}
+	}
+	if !valid {
+		return errInvalidArgument().Trace(acl)
+	}
+	resp, e := c.executeRequest(context.Background(), http.MethodPut, s3RequestMetadata{
+		bucket: bucket,
+		query:  url.Values{"acl": []string{""}},
+		header: http.Header{amzACL: []string{acl}},
+	})
+	if e != nil {
+		return probe.NewError(regionError(bucket, e)).Trace(bucket, acl)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listObjectsAfter - list objects like listObjectWrapper, only those
+// whose key sorts after startAfter when it is set.
+func (c *S3Client) listObjectsAfter(bucket, object string, isRecursive bool, doneCh <-chan struct{}, metadata bool, startAfter string) <-chan minio.ObjectInfo {
+	if startAfter == "" {
+		return c.listObjectWrapper(bucket, object, isRecursive, doneCh, metadata)
+	}
+	objectCh := make(chan minio.ObjectInfo, 1)
+	send := func(info minio.ObjectInfo) bool {
+		select {
+		case objectCh <- info:
+			return true
+		case <-doneCh:
+			return false
+		}
+	}
+	if metadata {
+		// minio-go only lists metadata from the first key on, skip
+		// the earlier ones.
+		go func() {
+			defer close(objectCh)
+			for info := range c.listObjectWrapper(bucket, object, isRecursive, doneCh, metadata) {
+				if info.Err == nil && info.Key <= startAfter {
+					continue
+				}
+				if !send(info) {
+					return
+				}
+			}
+		}()
+		return objectCh
+	}
+
+	delimiter := "/"
+	if isRecursive {
+		delimiter = ""
+	}
+	go func() {
+		defer close(objectCh)
+		core := minio.Core{Client: c.api}
+		marker, continuationToken := startAfter, ""
+		for {
+			var contents []minio.ObjectInfo
+			var prefixes []minio.CommonPrefix
+			var truncated bool
+			if c.listV1() {
+				// Markers of ListObjects V1 work like StartAfter.
+				result, e := core.ListObjects(bucket, object, marker, delimiter, 0)
+				if e != nil {
+					send(minio.ObjectInfo{Err: e})
+					return
+				}
+				contents, prefixes, truncated = result.Contents, result.CommonPrefixes, result.IsTruncated
+				marker = result.NextMarker
+			} else {
+				result, e := core.ListObjectsV2(bucket, object, continuationToken, false, delimiter, 0, startAfter)
+				if e != nil {
+					send(minio.ObjectInfo{Err: e})
+					return
+				}
+				contents, prefixes, truncated = result.Contents, result.CommonPrefixes, result.IsTruncated
+				continuationToken = result.NextContinuationToken
+			}
+			for _, info := range contents {
+				if marker == "" || info.Key > marker {
+					marker = info.Key
+				}
+				info.ETag = strings.Trim(info.ETag, "\"")
+				if !send(info) {
+					return
+				}
+			}
+			for _, prefix := range prefixes {
+				if marker == "" || prefix.Prefix > marker {
+					marker = prefix.Prefix
+				}
+				if !send(minio.ObjectInfo{Key: prefix.Prefix}) {
+					return
+				}
+			}
+			if !truncated {
+				return
+			}
+		}
+	}()
+	return objectCh
+}
+
+// listV1 - whether objects are listed with ListObjects V1 rather than
+// V2, which Google Cloud S3 layer doesn't implement
+// (https://github.com/minio/mc/issues/3073) and some servers get wrong,
+// see Config.ForceListV1. V1 listings have no metadata.
+func (c *S3Client) listV1() bool {
+	return c.forceListV1 || isGoogle(c.targetURL.Host)
 }
 
 // listObjectWrapper - select ObjectList version depending on the target hostname
-func (c *S3Client) listObjectWrapper(bucket, object string, isRecursive bool, doneCh chan struct{}, metadata bool) <-chan minio.ObjectInfo {
-	if isGoogle(c.targetURL.Host) {
-		// Google Cloud S3 layer doesn't implement ListObjectsV2 implementation
-		// https://github.com/minio/mc/issues/3073
+func (c *S3Client) listObjectWrapper(bucket, object string, isRecursive bool, doneCh <-chan struct{}, metadata bool) <-chan minio.ObjectInfo {
+	if c.listV1() {
 		return c.api.ListObjects(bucket, object, isRecursive, doneCh)
 	}
 	if metadata {
@@ -1314,13 +3559,20 @@ func (c *S3Client) listObjectWrapper(bucket, object string, isRecursive bool, do
 	return c.api.ListObjectsV2(bucket, object, isRecursive, doneCh)
 }
 
-func (c *S3Client) statIncompleteUpload(bucket, object string) (*ClientContent, *probe.Error) {
+func (c *S3Client) statIncompleteUpload(ctx context.Context, bucket, object string) (*ClientContent, *probe.Error) {
 	nonRecursive := false
 	objectMetadata := &ClientContent{}
 	// Prefix to pass to minio-go listing in order to fetch a given object/directory
 	prefix := strings.TrimRight(object, string(c.targetURL.Separator))
 
-	for objectMultipartInfo := range c.api.ListIncompleteUploads(bucket, prefix, nonRecursive, nil) {
+	// Stop the listing when returning early, before the client is
+	// given back.
+	ctx, cancel := context.WithCancel(ctx)
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
+	defer cancel()
+
+	for objectMultipartInfo := range api.ListIncompleteUploads(bucket, prefix, nonRecursive, ctx.Done()) {
 		if objectMultipartInfo.Err != nil {
 			return nil, probe.NewError(objectMultipartInfo.Err)
 		}
@@ -1330,7 +3582,16 @@ func (c *S3Client) statIncompleteUpload(bucket, object string) (*ClientContent,
 			objectMetadata.Time = objectMultipartInfo.Initiated
 			objectMetadata.Size = objectMultipartInfo.Size
 			objectMetadata.Type = os.FileMode(0664)
-			objectMetadata.Metadata = map[string]string{}
+			objectMetadata.Metadata = map[string]string{
+				"Upload-Id":        objectMultipartInfo.UploadID,
+				"Upload-Initiated": objectMultipartInfo.Initiated.UTC().Format(time.RFC3339),
+			}
+			// Most backends don't report the size of uploads, sum
+			// the size of their parts instead when possible.
+			if size, parts, e := c.uploadedPartsSize(bucket, object, objectMultipartInfo.UploadID); e == nil {
+				objectMetadata.Size = size
+				objectMetadata.Metadata["Upload-Parts"] = strconv.Itoa(parts)
+			}
 			return objectMetadata, nil
 		}
 
@@ -1344,9 +3605,30 @@ func (c *S3Client) statIncompleteUpload(bucket, object string) (*ClientContent,
 	return nil, probe.NewError(ObjectMissing{})
 }
 
+// uploadedPartsSize - total size and number of the parts uploaded so
+// far for an incomplete upload.
+func (c *S3Client) uploadedPartsSize(bucket, object, uploadID string) (size int64, parts int, err error) {
+	core := minio.Core{Client: c.api}
+	partNumberMarker := 0
+	for {
+		result, e := core.ListObjectParts(bucket, object, uploadID, partNumberMarker, 1000)
+		if e != nil {
+			return 0, 0, e
+		}
+		for _, part := range result.ObjectParts {
+			size += part.Size
+			parts++
+		}
+		if !result.IsTruncated || result.NextPartNumberMarker <= partNumberMarker {
+			return size, parts, nil
+		}
+		partNumberMarker = result.NextPartNumberMarker
+	}
+}
+
 // Stat - send a 'HEAD' on a bucket or object to fetch its metadata. It also returns
 // a DIR type content if a prefix does exist in the server.
-func (c *S3Client) Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (*ClientContent, *probe.Error) {
+func (c *S3Client) Stat(ctx context.Context, isIncomplete, isPreserve bool, sse encrypt.ServerSide) (*ClientContent, *probe.Error) {
 	c.Lock()
 	defer c.Unlock()
 	bucket, object := c.url2BucketAndObject()
@@ -1356,8 +3638,13 @@ func (c *S3Client) Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (
 		return nil, probe.NewError(BucketNameEmpty{})
 	}
 
+	// Bucket level requests, such as bucket stat and listing,
+	// don't accept a context in minio-go.
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
+
 	if object == "" {
-		content, err := c.bucketStat(bucket)
+		content, err := c.bucketStat(api, bucket)
 		if err != nil {
 			return nil, err.Trace(bucket)
 		}
@@ -1366,7 +3653,7 @@ func (c *S3Client) Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (
 
 	// If the request is for incomplete upload stat, handle it here.
 	if isIncomplete {
-		return c.statIncompleteUpload(bucket, object)
+		return c.statIncompleteUpload(ctx, bucket, object)
 	}
 
 	// The following code tries to calculate if a given prefix/object does really exist
@@ -1378,14 +3665,19 @@ func (c *S3Client) Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (
 	//     - /path/to/empty_directory/
 
 	opts := minio.StatObjectOptions{}
-	opts.ServerSideEncryption = sse
+	opts.ServerSideEncryption = c.readSSE(sse)
 
 	if !strings.HasSuffix(object, string(c.targetURL.Separator)) {
 		// Issue HEAD request first but ignore no such key error
 		// so we can check if there is such prefix which exists
-		ctnt, err := c.getObjectStat(bucket, object, opts)
+		ctnt, err := c.getObjectStat(ctx, bucket, object, opts)
 		if err == nil {
-			return ctnt, err
+			if verifyParts(ctx) {
+				if err = c.verifyObjectParts(ctx, bucket, object, opts, ctnt); err != nil {
+					return nil, err
+				}
+			}
+			return ctnt, nil
 		}
 		// Ignore object missing error but return for other errors
 		if !errors.As(err.ToGoError(), &ObjectMissing{}) {
@@ -1393,31 +3685,117 @@ func (c *S3Client) Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (
 		}
 	}
 
-	nonRecursive := false
+	// Any key under the prefix proves that it exists as a directory,
+	// so a single key page is enough however many children it has.
+	separator := string(c.targetURL.Separator)
+	prefix := strings.TrimRight(object, separator) + separator
+	found, e := c.hasKeys(api, bucket, prefix, separator)
+	if e != nil {
+		return nil, probe.NewError(regionError(bucket, e))
+	}
+	if !found {
+		return nil, probe.NewError(ObjectMissing{})
+	}
 	objectMetadata := &ClientContent{}
+	objectMetadata.URL = *c.targetURL
+	objectMetadata.Type = os.ModeDir
+	return objectMetadata, nil
+}
 
-	// Prefix to pass to minio-go listing in order to fetch if a prefix exists
-	prefix := strings.TrimRight(object, string(c.targetURL.Separator))
-
-	for objectStat := range c.listObjectWrapper(bucket, prefix, nonRecursive, nil, false) {
-		if objectStat.Err != nil {
-			return nil, probe.NewError(objectStat.Err)
-		}
-		if strings.HasSuffix(objectStat.Key, string(c.targetURL.Separator)) {
-			objectMetadata.URL = *c.targetURL
-			objectMetadata.Type = os.ModeDir
-			return objectMetadata, nil
+// hasKeys - lists a single key page under prefix to tell whether
+// anything exists there.
+func (c *S3Client) hasKeys(api *minio.Client, bucket, prefix, delimiter string) (bool, error) {
+	core := minio.Core{Client: api}
+	if c.listV1() {
+		result, e := core.ListObjects(bucket, prefix, "", delimiter, 1)
+		if e != nil {
+			return false, e
 		}
+		return len(result.Contents) > 0 || len(result.CommonPrefixes) > 0, nil
 	}
+	result, e := core.ListObjectsV2(bucket, prefix, "", false, delimiter, 1, "")
+	if e != nil {
+		return false, e
+	}
+	return len(result.Contents) > 0 || len(result.CommonPrefixes) > 0, nil
+}
 
-	return nil, probe.NewError(ObjectMissing{})
+// HasObjects - tells whether any object exists under prefix, relative
+// to the client URL, without listing more than a single key.
+func (c *S3Client) HasObjects(prefix string) (bool, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return false, probe.NewError(BucketNameEmpty{})
+	}
+	found, e := c.hasKeys(c.api, bucket, object+prefix, "")
+	if e != nil {
+		return false, probe.NewError(regionError(bucket, e))
+	}
+	return found, nil
 }
 
 // getObjectStat returns the metadata of an object from a HEAD call.
-func (c *S3Client) getObjectStat(bucket, object string, opts minio.StatObjectOptions) (*ClientContent, *probe.Error) {
+type verifyPartsKey struct{}
+
+// WithVerifyParts - returns a context making Stat check that the parts
+// of multipart objects add up to the object size, failing with
+// InconsistentObject otherwise. This costs a HEAD request per part and
+// detects objects whose content is corrupt on the backend.
+func WithVerifyParts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verifyPartsKey{}, true)
+}
+
+// verifyParts - whether Stat calls made with ctx verify the parts.
+func verifyParts(ctx context.Context) bool {
+	verify, _ := ctx.Value(verifyPartsKey{}).(bool)
+	return verify
+}
+
+// verifyObjectParts - check that the sizes of the parts of a multipart
+// object, whose count is the suffix of its ETag, add up to its size.
+// Objects uploaded in a single part have nothing to verify.
+func (c *S3Client) verifyObjectParts(ctx context.Context, bucket, object string, opts minio.StatObjectOptions, content *ClientContent) *probe.Error {
+	etag := strings.Trim(content.ETag, "\"")
+	i := strings.LastIndex(etag, "-")
+	if i < 0 {
+		return nil
+	}
+	parts, e := strconv.Atoi(etag[i+1:])
+	if e != nil || parts < 1 {
+		return nil
+	}
+	var size int64
+	for part := 1; part <= parts; part++ {
+		resp, e := c.executeRequest(ctx, http.MethodHead, s3RequestMetadata{
+			bucket: bucket,
+			object: object,
+			query:  url.Values{"partNumber": []string{strconv.Itoa(part)}},
+			header: opts.Header(),
+		})
+		if e != nil {
+			// HEAD responses have no body, only the status tells
+			// InvalidPartNumber.
+			if errResp := minio.ToErrorResponse(e); errResp.Code == "InvalidPartNumber" || errResp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+				return probe.NewError(InconsistentObject{Object: object, Size: content.Size, Parts: parts, MissingPart: part})
+			}
+			return probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+		}
+		resp.Body.Close()
+		size += resp.ContentLength
+	}
+	if size != content.Size {
+		return probe.NewError(InconsistentObject{Object: object, Size: content.Size, Parts: parts, PartsSize: size})
+	}
+	return nil
+}
+
+func (c *S3Client) getObjectStat(ctx context.Context, bucket, object string, opts minio.StatObjectOptions) (*ClientContent, *probe.Error) {
 	objectMetadata := &ClientContent{}
-	objectStat, e := c.api.StatObject(bucket, object, opts)
+	objectStat, e := c.api.StatObjectWithContext(ctx, bucket, object, opts)
 	if e != nil {
+		if err := c.conditionFailed(bucket, object, requestHeaders(ctx), e); err != nil {
+			return nil, err
+		}
 		errResponse := minio.ToErrorResponse(e)
 		if errResponse.Code == "AccessDenied" {
 			return nil, probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
@@ -1447,7 +3825,16 @@ func (c *S3Client) getObjectStat(bucket, object string, opts minio.StatObjectOpt
 	for k := range objectStat.Metadata {
 		objectMetadata.Metadata[k] = objectStat.Metadata.Get(k)
 	}
+	objectMetadata.UserMetadata = userMetadata(objectStat.Metadata)
+	objectMetadata.MetadataFetched = true
 	objectMetadata.ETag = objectStat.ETag
+	objectMetadata.Encryption = objectEncryption(objectStat.Metadata)
+	objectMetadata.Restore = objectRestore(objectStat.Metadata)
+	// S3 leaves out the header for the standard storage class.
+	objectMetadata.StorageClass = objectStat.Metadata.Get("X-Amz-Storage-Class")
+	if objectMetadata.StorageClass == "" {
+		objectMetadata.StorageClass = s3StorageClassStandard
+	}
 	return objectMetadata, nil
 }
 
@@ -1478,99 +3865,595 @@ func isVirtualHostStyle(host string, lookup minio.BucketLookupType) bool {
 	if lookup == minio.BucketLookupDNS {
 		return true
 	}
-	if lookup == minio.BucketLookupPath {
-		return false
-	}
-	return isAmazon(host) && !isAmazonChina(host) || isGoogle(host) || isAmazonAccelerated(host)
-}
+	if lookup == minio.BucketLookupPath {
+		return false
+	}
+	return isAmazon(host) && !isAmazonChina(host) || isGoogle(host) || isAmazonAccelerated(host)
+}
+
+// newTLSConfig - TLS configuration of the connections to the host of
+// config, verifying the hostname of the URL.
+func newTLSConfig(config *Config) *tls.Config {
+	tlsConfig := &tls.Config{
+		RootCAs: globalRootCAs,
+		// Can't use SSLv3 because of POODLE and BEAST
+		// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
+		// Can't use TLSv1.1 because of RC4 cipher usage
+		MinVersion:         tls.VersionTLS12,
+		MaxVersion:         config.TLSMaxVersion,
+		CipherSuites:       config.TLSCipherSuites,
+		InsecureSkipVerify: config.Insecure,
+	}
+	if config.TLSMinVersion != 0 {
+		tlsConfig.MinVersion = config.TLSMinVersion
+	}
+	return tlsConfig
+}
+
+// bucketLookupProbeTransport - transport of the bucket lookup probes,
+// one configured like clients when nil.
+var bucketLookupProbeTransport http.RoundTripper
+
+// newBucketLookupProbeClient - HTTP client of the bucket lookup probes
+// of the host of config.
+func newBucketLookupProbeClient(config *Config) *http.Client {
+	transport := bucketLookupProbeTransport
+	if transport == nil {
+		transport = &http.Transport{
+			TLSClientConfig:     newTLSConfig(config),
+			TLSHandshakeTimeout: 5 * time.Second,
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: 5 * time.Second}
+}
+
+// probeBucketLookup - bucket lookup of host, found with a HEAD request
+// on bucket as a subdomain of host. Buckets are reachable as
+// subdomains, e.g. through a wildcard DNS record, if an S3 server
+// answers whatever the status, as told by its request ID. DNS and TLS
+// failures, or answers of other servers, mean path style. Other
+// failures such as timeouts tell nothing, they keep the auto lookup
+// and the result is not conclusive. Hosts reached through a proxy,
+// which answers even for names which don't resolve, are not probed
+// and keep the auto lookup.
+func probeBucketLookup(client *http.Client, proxy func(*http.Request) (*url.URL, error), scheme, host, bucket string) (lookup minio.BucketLookupType, conclusive bool) {
+	req, e := http.NewRequest(http.MethodHead, scheme+"://"+bucket+"."+host+"/", nil)
+	if e != nil {
+		return minio.BucketLookupPath, true
+	}
+	if u, e := proxy(req); e != nil || u != nil {
+		return minio.BucketLookupAuto, true
+	}
+	resp, e := client.Do(req)
+	if e != nil {
+		if isDNSOrTLSError(e) {
+			return minio.BucketLookupPath, true
+		}
+		return minio.BucketLookupAuto, false
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Amz-Request-Id") == "" {
+		return minio.BucketLookupPath, true
+	}
+	return minio.BucketLookupDNS, true
+}
+
+// isDNSOrTLSError - tells whether e is a failure to resolve a host or
+// to establish a TLS connection with it.
+func isDNSOrTLSError(e error) bool {
+	var dnsErr *net.DNSError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.As(e, &dnsErr), errors.As(e, &hostnameErr), errors.As(e, &authorityErr),
+		errors.As(e, &invalidErr), errors.As(e, &recordErr):
+		return true
+	}
+	// TLS alerts sent by the server have no exported type.
+	return strings.Contains(e.Error(), "tls: ")
+}
+
+// isDNSCompatibleBucket - tells whether bucket can be reached as a
+// subdomain, over TLS only without dots which wildcard certificates
+// don't cover.
+func isDNSCompatibleBucket(bucket string, secure bool) bool {
+	if s3utils.CheckValidBucketNameStrict(bucket) != nil {
+		return false
+	}
+	return !secure || !strings.Contains(bucket, ".")
+}
+
+// url2BucketAndObject gives bucketName and objectName from URL path.
+func (c *S3Client) url2BucketAndObject() (bucketName, objectName string) {
+	path := c.targetURL.Path
+	// Convert any virtual host styled requests.
+	//
+	// For the time being this check is introduced for S3,
+	// If you have custom virtual styled hosts please.
+	// List them below.
+	if c.virtualStyle {
+		var bucket string
+		hostIndex := strings.Index(c.targetURL.Host, "s3")
+		if hostIndex != -1 && !matchS3InHost(c.targetURL.Host) {
+			hostIndex = -1
+		}
+		if hostIndex == -1 {
+			hostIndex = strings.Index(c.targetURL.Host, "s3-accelerate")
+		}
+		if hostIndex == -1 {
+			hostIndex = strings.Index(c.targetURL.Host, "storage.googleapis")
+		}
+		if hostIndex > 0 {
+			bucket = c.targetURL.Host[:hostIndex-1]
+			path = string(c.targetURL.Separator) + bucket + c.targetURL.Path
+		}
+	}
+	tokens := splitStr(path, string(c.targetURL.Separator), 3)
+	return tokens[1], tokens[2]
+}
+
+// splitPath split path into bucket and object.
+func (c *S3Client) splitPath(path string) (bucketName, objectName string) {
+	path = strings.TrimPrefix(path, string(c.targetURL.Separator))
+
+	// Handle path if its virtual style.
+	if c.virtualStyle {
+		hostIndex := strings.Index(c.targetURL.Host, "s3")
+		if hostIndex == -1 {
+			hostIndex = strings.Index(c.targetURL.Host, "s3-accelerate")
+		}
+		if hostIndex == -1 {
+			hostIndex = strings.Index(c.targetURL.Host, "storage.googleapis")
+		}
+		if hostIndex > 0 {
+			bucketName = c.targetURL.Host[:hostIndex-1]
+			objectName = path
+			return bucketName, objectName
+		}
+	}
+
+	tokens := splitStr(path, string(c.targetURL.Separator), 2)
+	return tokens[0], tokens[1]
+}
+
+/// Bucket API operations.
+
+// List - list at delimited path, if not recursive.
+func (c *S3Client) List(isRecursive, isIncomplete, isMetadata bool, showDir DirOpt) <-chan *ClientContent {
+	return c.ListWithOptions(ListOptions{
+		Recursive:  isRecursive,
+		Incomplete: isIncomplete,
+		Metadata:   isMetadata,
+		ShowDir:    showDir,
+	})
+}
+
+// ListOptions - options of ListWithOptions, the first ones being the
+// parameters of List.
+type ListOptions struct {
+	Recursive  bool
+	Incomplete bool
+	Metadata   bool
+	ShowDir    DirOpt
+	// Only list objects whose key sorts after StartAfter, e.g. to
+	// resume a listing after the last key received. Not supported
+	// for incomplete uploads.
+	StartAfter string
+	// Only list the keys, relative to the listed directory, matching
+	// any of the include patterns if there are some, and none of the
+	// exclude ones. In globs * and ? match separators too, regular
+	// expressions match anywhere unless anchored with ^. Directories
+	// none of whose keys can be listed are skipped with their content.
+	IncludeGlobs   []string
+	ExcludeGlobs   []string
+	IncludeRegexps []string
+	ExcludeRegexps []string
+	// Only list the objects modified, or the uploads initiated, at or
+	// after ModifiedAfter and less than NewerThan ago, and before
+	// ModifiedBefore and more than OlderThan ago, the ones set.
+	// Directories are listed whatever their time and size.
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	NewerThan      time.Duration
+	OlderThan      time.Duration
+	// Only list the objects, or uploads, of at least MinSize bytes and
+	// at most MaxSize bytes, unless zero.
+	MinSize int64
+	MaxSize int64
+}
+
+// ListWithOptions - list like List, with options List doesn't take.
+func (c *S3Client) ListWithOptions(opts ListOptions) <-chan *ClientContent {
+	c.Lock()
+	defer c.Unlock()
+
+	contentCh := make(chan *ClientContent)
+	fail := func(err *probe.Error) <-chan *ClientContent {
+		go func() {
+			defer close(contentCh)
+			contentCh <- &ClientContent{Err: err}
+		}()
+		return contentCh
+	}
+	// Patterns match keys relative to the listed directory.
+	_, object := c.url2BucketAndObject()
+	base := object[:strings.LastIndex(object, string(c.targetURL.Separator))+1]
+	filter, err := newListFilter(opts, base, UTCNow())
+	if err != nil {
+		return fail(err.Trace(c.targetURL.String()))
+	}
+	if opts.Incomplete {
+		if opts.StartAfter != "" {
+			return fail(errInvalidArgument().Trace("incomplete uploads can't be listed after a key"))
+		}
+		if opts.Recursive {
+			if opts.ShowDir == DirNone {
+				go c.listIncompleteRecursiveInRoutine(contentCh, filter)
+			} else {
+				go c.listIncompleteRecursiveInRoutineDirOpt(contentCh, opts.ShowDir, filter)
+			}
+		} else {
+			go c.listIncompleteInRoutine(contentCh, filter)
+		}
+	} else {
+		if opts.Recursive {
+			if opts.ShowDir == DirNone {
+				go c.listRecursiveInRoutine(contentCh, opts, filter)
+			} else {
+				go c.listRecursiveInRoutineDirOpt(contentCh, opts, filter)
+			}
+		} else {
+			go c.listInRoutine(contentCh, opts, filter)
+		}
+	}
+
+	return contentCh
+}
+
+// ListStorageClass - list objects of the given storage class only,
+// compared client side against the storage class returned by the
+// listing, objects listed without one are STANDARD. Directories are
+// never emitted. Callers can use it to skip or restore archived
+// objects, e.g. of the GLACIER storage class, before copying.
+func (c *S3Client) ListStorageClass(isRecursive, isIncomplete, isMetadata bool, storageClass string) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		for content := range c.List(isRecursive, isIncomplete, isMetadata, DirNone) {
+			if content.Err == nil {
+				if content.Type.IsDir() || !strings.EqualFold(content.StorageClass, storageClass) {
+					continue
+				}
+			}
+			contentCh <- content
+		}
+	}()
+	return contentCh
+}
+
+// SortBy - order of the entries listed by ListSorted.
+type SortBy int
+
+// Sort orders of ListSorted, all ascending.
+const (
+	// SortByKey is the order of S3 listings.
+	SortByKey SortBy = iota
+	SortBySize
+	SortByLastModified
+	SortByETag
+)
+
+// ListSorted - list like List, in the given order. Apart from
+// SortByKey, which streams entries as S3 returns them, S3 can't sort
+// listings so all entries are buffered in memory and sorted once the
+// listing is over, which can take a lot of memory for large listings.
+// Entries with equal size, time or ETag are kept in key order. Errors
+// are sent as soon as they are received.
+func (c *S3Client) ListSorted(isRecursive, isIncomplete, isMetadata bool, sortBy SortBy) <-chan *ClientContent {
+	if sortBy == SortByKey {
+		return c.List(isRecursive, isIncomplete, isMetadata, DirNone)
+	}
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		var contents []*ClientContent
+		var less func(i, j int) bool
+		switch sortBy {
+		case SortBySize:
+			less = func(i, j int) bool { return contents[i].Size < contents[j].Size }
+		case SortByLastModified:
+			less = func(i, j int) bool { return contents[i].Time.Before(contents[j].Time) }
+		case SortByETag:
+			less = func(i, j int) bool { return contents[i].ETag < contents[j].ETag }
+		default:
+			contentCh <- &ClientContent{Err: errInvalidArgument().Trace("unknown sort order", strconv.Itoa(int(sortBy)))}
+			return
+		}
+		for content := range c.List(isRecursive, isIncomplete, isMetadata, DirNone) {
+			if content.Err != nil {
+				contentCh <- content
+				continue
+			}
+			contents = append(contents, content)
+		}
+		sort.SliceStable(contents, less)
+		for _, content := range contents {
+			contentCh <- content
+		}
+	}()
+	return contentCh
+}
+
+// ListOrdered - list like List, guaranteeing that entries are sent in
+// lexicographic order of their key, and buckets in order of their name
+// when listing all buckets. S3 lists keys in order but minio-go sends
+// the directories of every page after its objects, so non recursive
+// listings are buffered and sorted one directory level at a time.
+// Recursive listings stream keys in server order and derive the
+// directories sent for showDir from them: with DirFirst a directory
+// comes right before its first entry, as its key sorts before all the
+// keys it prefixes, with DirLast right after its last entry, as if its
+// key sorted after all of them. Directory marker objects are then only
+// sent as directories. The listed bucket or prefix itself is not sent.
+func (c *S3Client) ListOrdered(isRecursive, isMetadata bool, showDir DirOpt) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		bucket, object := c.url2BucketAndObject()
+		if bucket != "" {
+			c.listOrdered(contentCh, bucket, object, isRecursive, isMetadata, showDir)
+			return
+		}
+		buckets, e := c.api.ListBuckets()
+		if e != nil {
+			contentCh <- &ClientContent{Err: probe.NewError(e)}
+			return
+		}
+		sort.Slice(buckets, func(i, j int) bool {
+			return buckets[i].Name < buckets[j].Name
+		})
+		for _, bucket := range buckets {
+			url := *c.targetURL
+			url.Path = c.joinPath(bucket.Name)
+			dir := &ClientContent{URL: url, Time: bucket.CreationDate, Type: os.ModeDir}
+			if !isRecursive {
+				contentCh <- dir
+				continue
+			}
+			if showDir == DirFirst {
+				contentCh <- dir
+			}
+			if !c.listOrdered(contentCh, bucket.Name, "", true, isMetadata, showDir) {
+				return
+			}
+			if showDir == DirLast {
+				contentCh <- dir
+			}
+		}
+	}()
+	return contentCh
+}
+
+// listOrdered - send the entries of bucket under prefix in key order
+// for ListOrdered, returns false if the listing failed.
+func (c *S3Client) listOrdered(contentCh chan<- *ClientContent, bucket, prefix string, isRecursive, isMetadata bool, showDir DirOpt) bool {
+	separator := string(c.targetURL.Separator)
+	if !isRecursive {
+		var entries []minio.ObjectInfo
+		for entry := range c.listObjectWrapper(bucket, prefix, false, nil, isMetadata) {
+			if entry.Err != nil {
+				contentCh <- &ClientContent{Err: probe.NewError(regionError(bucket, entry.Err)).Trace(bucket, prefix)}
+				return false
+			}
+			// Like List, skip the marker of the listed directory.
+			if strings.HasSuffix(entry.Key, separator) && entry.Key == prefix {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Key < entries[j].Key
+		})
+		for _, entry := range entries {
+			contentCh <- c.objectInfo2ClientContent(bucket, entry, isMetadata)
+		}
+		return true
+	}
 
-// url2BucketAndObject gives bucketName and objectName from URL path.
-func (c *S3Client) url2BucketAndObject() (bucketName, objectName string) {
-	path := c.targetURL.Path
-	// Convert any virtual host styled requests.
-	//
-	// For the time being this check is introduced for S3,
-	// If you have custom virtual styled hosts please.
-	// List them below.
-	if c.virtualStyle {
-		var bucket string
-		hostIndex := strings.Index(c.targetURL.Host, "s3")
-		if hostIndex != -1 && !matchS3InHost(c.targetURL.Host) {
-			hostIndex = -1
+	// Directories are relative to the directory of the prefix.
+	base := prefix[:strings.LastIndex(prefix, separator)+1]
+	// Directories containing the last key, outermost first.
+	var dirs []*ClientContent
+	for entry := range c.listObjectWrapper(bucket, prefix, true, nil, isMetadata) {
+		if entry.Err != nil {
+			contentCh <- &ClientContent{Err: probe.NewError(regionError(bucket, entry.Err)).Trace(bucket, prefix)}
+			return false
 		}
-		if hostIndex == -1 {
-			hostIndex = strings.Index(c.targetURL.Host, "s3-accelerate")
+		if showDir == DirNone {
+			contentCh <- c.objectInfo2ClientContent(bucket, entry, isMetadata)
+			continue
 		}
-		if hostIndex == -1 {
-			hostIndex = strings.Index(c.targetURL.Host, "storage.googleapis")
+		for len(dirs) > 0 && !strings.HasPrefix(entry.Key, dirs[len(dirs)-1].Key) {
+			if showDir == DirLast {
+				contentCh <- dirs[len(dirs)-1]
+			}
+			dirs = dirs[:len(dirs)-1]
 		}
-		if hostIndex > 0 {
-			bucket = c.targetURL.Host[:hostIndex-1]
-			path = string(c.targetURL.Separator) + bucket + c.targetURL.Path
+		opened := len(base)
+		if len(dirs) > 0 {
+			opened = len(dirs[len(dirs)-1].Key)
 		}
-	}
-	tokens := splitStr(path, string(c.targetURL.Separator), 3)
-	return tokens[1], tokens[2]
-}
-
-// splitPath split path into bucket and object.
-func (c *S3Client) splitPath(path string) (bucketName, objectName string) {
-	path = strings.TrimPrefix(path, string(c.targetURL.Separator))
-
-	// Handle path if its virtual style.
-	if c.virtualStyle {
-		hostIndex := strings.Index(c.targetURL.Host, "s3")
-		if hostIndex == -1 {
-			hostIndex = strings.Index(c.targetURL.Host, "s3-accelerate")
+		for i := opened; i < len(entry.Key); i++ {
+			if entry.Key[i] != separator[0] {
+				continue
+			}
+			dir := c.objectInfo2ClientContent(bucket, minio.ObjectInfo{Key: entry.Key[:i+1]}, false)
+			if showDir == DirFirst {
+				contentCh <- dir
+			}
+			dirs = append(dirs, dir)
 		}
-		if hostIndex == -1 {
-			hostIndex = strings.Index(c.targetURL.Host, "storage.googleapis")
+		if strings.HasSuffix(entry.Key, separator) {
+			// Directory marker.
+			continue
 		}
-		if hostIndex > 0 {
-			bucketName = c.targetURL.Host[:hostIndex-1]
-			objectName = path
-			return bucketName, objectName
+		contentCh <- c.objectInfo2ClientContent(bucket, entry, isMetadata)
+	}
+	if showDir == DirLast {
+		for i := len(dirs) - 1; i >= 0; i-- {
+			contentCh <- dirs[i]
 		}
 	}
-
-	tokens := splitStr(path, string(c.targetURL.Separator), 2)
-	return tokens[0], tokens[1]
+	return true
 }
 
-/// Bucket API operations.
-
-// List - list at delimited path, if not recursive.
-func (c *S3Client) List(isRecursive, isIncomplete, isMetadata bool, showDir DirOpt) <-chan *ClientContent {
-	c.Lock()
-	defer c.Unlock()
-
+// ListResumable - list objects of the current bucket and prefix in key
+// order, starting after startAfter. onPage, if not nil, is called with
+// the last key of every listing page once all its entries have been
+// received from the returned channel, so that callers can persist it
+// and resume a listing interrupted midway by passing it back as
+// startAfter. Resuming is only exact for recursive listings, a non
+// recursive listing may emit a directory again.
+func (c *S3Client) ListResumable(ctx context.Context, isRecursive bool, startAfter string, onPage func(lastKey string)) <-chan *ClientContent {
 	contentCh := make(chan *ClientContent)
-	if isIncomplete {
+	go func() {
+		defer close(contentCh)
+		bucket, prefix := c.url2BucketAndObject()
+		if bucket == "" {
+			contentCh <- &ClientContent{Err: probe.NewError(BucketNameEmpty{})}
+			return
+		}
+		delimiter := string(c.targetURL.Separator)
 		if isRecursive {
-			if showDir == DirNone {
-				go c.listIncompleteRecursiveInRoutine(contentCh)
+			delimiter = ""
+		}
+		core := minio.Core{Client: c.api}
+		marker, continuationToken := startAfter, ""
+		for {
+			if ctx.Err() != nil {
+				contentCh <- &ClientContent{Err: probe.NewError(ctx.Err())}
+				return
+			}
+			var entries []minio.ObjectInfo
+			var prefixes []minio.CommonPrefix
+			var truncated bool
+			var e error
+			if c.listV1() {
+				var result minio.ListBucketResult
+				result, e = core.ListObjects(bucket, prefix, marker, delimiter, 0)
+				entries, prefixes, truncated = result.Contents, result.CommonPrefixes, result.IsTruncated
 			} else {
-				go c.listIncompleteRecursiveInRoutineDirOpt(contentCh, showDir)
+				var result minio.ListBucketV2Result
+				result, e = core.ListObjectsV2(bucket, prefix, continuationToken, false, delimiter, 0, startAfter)
+				entries, prefixes, truncated = result.Contents, result.CommonPrefixes, result.IsTruncated
+				continuationToken = result.NextContinuationToken
+			}
+			if e != nil {
+				contentCh <- &ClientContent{Err: probe.NewError(regionError(bucket, e)).Trace(bucket, prefix)}
+				return
+			}
+			for _, p := range prefixes {
+				entries = append(entries, minio.ObjectInfo{Key: p.Prefix})
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].Key < entries[j].Key
+			})
+			for _, entry := range entries {
+				entry.ETag = strings.Trim(entry.ETag, "\"")
+				select {
+				case contentCh <- c.objectInfo2ClientContent(bucket, entry, false):
+				case <-ctx.Done():
+					contentCh <- &ClientContent{Err: probe.NewError(ctx.Err())}
+					return
+				}
+			}
+			if len(entries) > 0 {
+				// Markers of ListObjects V1 work like StartAfter.
+				marker = entries[len(entries)-1].Key
+				if onPage != nil {
+					onPage(marker)
+				}
+			}
+			if !truncated {
+				return
 			}
-		} else {
-			go c.listIncompleteInRoutine(contentCh)
 		}
-	} else {
-		if isRecursive {
-			if showDir == DirNone {
-				go c.listRecursiveInRoutine(contentCh, isMetadata)
-			} else {
-				go c.listRecursiveInRoutineDirOpt(contentCh, showDir, isMetadata)
+	}()
+	return contentCh
+}
+
+// ListCaseInsensitive - list objects whose key starts with the object
+// prefix of the target URL ignoring case, a convenience for users used
+// to case insensitive filesystems as S3 prefixes are case sensitive.
+// All objects under the longest leading part of the prefix which has
+// no cased letters are listed recursively and filtered client side,
+// which can be much slower than List. Incomplete uploads are not
+// listed.
+func (c *S3Client) ListCaseInsensitive(isRecursive, isMetadata bool) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent)
+	go func() {
+		defer close(contentCh)
+		bucket, prefix := c.url2BucketAndObject()
+		if bucket == "" {
+			contentCh <- &ClientContent{Err: probe.NewError(BucketNameEmpty{})}
+			return
+		}
+		separator := string(c.targetURL.Separator)
+		dirs := make(map[string]bool)
+		for object := range c.listObjectWrapper(bucket, caseStablePrefix(prefix), true, nil, isMetadata) {
+			if object.Err != nil {
+				contentCh <- &ClientContent{Err: probe.NewError(regionError(bucket, object.Err)).Trace(bucket, prefix)}
+				return
 			}
-		} else {
-			go c.listInRoutine(contentCh, isMetadata)
+			matched, ok := hasPrefixFold(object.Key, prefix)
+			if !ok {
+				continue
+			}
+			if !isRecursive {
+				if i := strings.Index(object.Key[len(matched):], separator); i >= 0 {
+					dir := object.Key[:len(matched)+i+len(separator)]
+					if !dirs[dir] {
+						dirs[dir] = true
+						contentCh <- c.objectInfo2ClientContent(bucket, minio.ObjectInfo{Key: dir}, isMetadata)
+					}
+					continue
+				}
+			}
+			contentCh <- c.objectInfo2ClientContent(bucket, object, isMetadata)
+		}
+	}()
+	return contentCh
+}
+
+// caseStablePrefix - longest leading part of prefix which doesn't
+// depend on case, i.e. without any cased letter.
+func caseStablePrefix(prefix string) string {
+	for i, r := range prefix {
+		if unicode.ToUpper(r) != unicode.ToLower(r) || unicode.IsTitle(r) {
+			return prefix[:i]
 		}
 	}
+	return prefix
+}
 
-	return contentCh
+// hasPrefixFold - reports whether s starts with prefix ignoring case,
+// along with the matching leading part of s.
+func hasPrefixFold(s, prefix string) (string, bool) {
+	n := utf8.RuneCountInString(prefix)
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	if n > 0 || !strings.EqualFold(s[:i], prefix) {
+		return "", false
+	}
+	return s[:i], true
 }
 
-func (c *S3Client) listIncompleteInRoutine(contentCh chan *ClientContent) {
+func (c *S3Client) listIncompleteInRoutine(contentCh chan *ClientContent, filter *listFilter) {
 	defer close(contentCh)
 	// get bucket and object from URL.
 	b, o := c.url2BucketAndObject()
@@ -1588,7 +4471,7 @@ func (c *S3Client) listIncompleteInRoutine(contentCh chan *ClientContent) {
 			for object := range c.api.ListIncompleteUploads(bucket.Name, o, isRecursive, nil) {
 				if object.Err != nil {
 					contentCh <- &ClientContent{
-						Err: probe.NewError(object.Err),
+						Err: probe.NewError(regionError(bucket.Name, object.Err)),
 					}
 					return
 				}
@@ -1600,15 +4483,17 @@ func (c *S3Client) listIncompleteInRoutine(contentCh chan *ClientContent) {
 				case strings.HasSuffix(object.Key, string(c.targetURL.Separator)):
 					// We need to keep the trailing Separator, do not use filepath.Join().
 					content.URL = url
+					content.Key = object.Key
 					content.Time = time.Now()
 					content.Type = os.ModeDir
 				default:
 					content.URL = url
+					content.Key = object.Key
 					content.Size = object.Size
 					content.Time = object.Initiated
 					content.Type = os.ModeTemporary
 				}
-				contentCh <- content
+				filter.send(contentCh, content)
 			}
 		}
 	default:
@@ -1616,7 +4501,7 @@ func (c *S3Client) listIncompleteInRoutine(contentCh chan *ClientContent) {
 		for object := range c.api.ListIncompleteUploads(b, o, isRecursive, nil) {
 			if object.Err != nil {
 				contentCh <- &ClientContent{
-					Err: probe.NewError(object.Err),
+					Err: probe.NewError(regionError(b, object.Err)),
 				}
 				return
 			}
@@ -1628,20 +4513,22 @@ func (c *S3Client) listIncompleteInRoutine(contentCh chan *ClientContent) {
 			case strings.HasSuffix(object.Key, string(c.targetURL.Separator)):
 				// We need to keep the trailing Separator, do not use filepath.Join().
 				content.URL = url
+				content.Key = object.Key
 				content.Time = time.Now()
 				content.Type = os.ModeDir
 			default:
 				content.URL = url
+				content.Key = object.Key
 				content.Size = object.Size
 				content.Time = object.Initiated
 				content.Type = os.ModeTemporary
 			}
-			contentCh <- content
+			filter.send(contentCh, content)
 		}
 	}
 }
 
-func (c *S3Client) listIncompleteRecursiveInRoutine(contentCh chan *ClientContent) {
+func (c *S3Client) listIncompleteRecursiveInRoutine(contentCh chan *ClientContent, filter *listFilter) {
 	defer close(contentCh)
 	// get bucket and object from URL.
 	b, o := c.url2BucketAndObject()
@@ -1659,7 +4546,7 @@ func (c *S3Client) listIncompleteRecursiveInRoutine(contentCh chan *ClientConten
 			for object := range c.api.ListIncompleteUploads(bucket.Name, o, isRecursive, nil) {
 				if object.Err != nil {
 					contentCh <- &ClientContent{
-						Err: probe.NewError(object.Err),
+						Err: probe.NewError(regionError(bucket.Name, object.Err)),
 					}
 					return
 				}
@@ -1667,10 +4554,11 @@ func (c *S3Client) listIncompleteRecursiveInRoutine(contentCh chan *ClientConten
 				url.Path = c.joinPath(bucket.Name, object.Key)
 				content := &ClientContent{}
 				content.URL = url
+				content.Key = object.Key
 				content.Size = object.Size
 				content.Time = object.Initiated
 				content.Type = os.ModeTemporary
-				contentCh <- content
+				filter.send(contentCh, content)
 			}
 		}
 	default:
@@ -1678,7 +4566,7 @@ func (c *S3Client) listIncompleteRecursiveInRoutine(contentCh chan *ClientConten
 		for object := range c.api.ListIncompleteUploads(b, o, isRecursive, nil) {
 			if object.Err != nil {
 				contentCh <- &ClientContent{
-					Err: probe.NewError(object.Err),
+					Err: probe.NewError(regionError(b, object.Err)),
 				}
 				return
 			}
@@ -1687,10 +4575,11 @@ func (c *S3Client) listIncompleteRecursiveInRoutine(contentCh chan *ClientConten
 			url.Path = c.joinPath(b, object.Key)
 			content := &ClientContent{}
 			content.URL = url
+			content.Key = object.Key
 			content.Size = object.Size
 			content.Time = object.Initiated
 			content.Type = os.ModeTemporary
-			contentCh <- content
+			filter.send(contentCh, content)
 		}
 	}
 }
@@ -1703,6 +4592,7 @@ func (c *S3Client) objectMultipartInfo2ClientContent(bucket string, entry minio.
 	// Join bucket and incoming object key.
 	url.Path = c.joinPath(bucket, entry.Key)
 	content.URL = url
+	content.Key = entry.Key
 	content.Size = entry.Size
 	content.Time = entry.Initiated
 
@@ -1716,7 +4606,7 @@ func (c *S3Client) objectMultipartInfo2ClientContent(bucket string, entry minio.
 }
 
 // Recursively lists incomplete uploads.
-func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *ClientContent, dirOpt DirOpt) {
+func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *ClientContent, dirOpt DirOpt, filter *listFilter) {
 	defer close(contentCh)
 
 	// Closure function reads list of incomplete uploads and sends to contentCh. If a directory is found, it lists
@@ -1728,7 +4618,7 @@ func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *Client
 			if entry.Err != nil {
 				url := *c.targetURL
 				url.Path = c.joinPath(bucket, object)
-				contentCh <- &ClientContent{URL: url, Err: probe.NewError(entry.Err)}
+				contentCh <- &ClientContent{URL: url, Err: probe.NewError(regionError(bucket, entry.Err))}
 
 				errResponse := minio.ToErrorResponse(entry.Err)
 				if errResponse.Code == "AccessDenied" {
@@ -1742,17 +4632,20 @@ func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *Client
 
 			// Handle if object.Key is a directory.
 			if strings.HasSuffix(entry.Key, string(c.targetURL.Separator)) {
+				if !filter.descend(entry.Key) {
+					continue
+				}
 				if dirOpt == DirFirst {
-					contentCh <- &content
+					filter.send(contentCh, &content)
 				}
 				if listDir(bucket, entry.Key) {
 					return true
 				}
 				if dirOpt == DirLast {
-					contentCh <- &content
+					filter.send(contentCh, &content)
 				}
 			} else {
-				contentCh <- &content
+				filter.send(contentCh, &content)
 			}
 		}
 
@@ -1774,7 +4667,7 @@ func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *Client
 		}
 	} else if object == "" {
 		// Get bucket stat if object is empty.
-		content, err := c.bucketStat(bucket)
+		content, err := c.bucketStat(c.api, bucket)
 		if err != nil {
 			contentCh <- &ClientContent{Err: err.Trace(bucket)}
 			return
@@ -1783,7 +4676,7 @@ func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *Client
 	} else if strings.HasSuffix(object, string(c.targetURL.Separator)) {
 		// Get stat of given object is a directory.
 		isIncomplete := true
-		content, perr := c.Stat(isIncomplete, false, nil)
+		content, perr := c.Stat(context.Background(), isIncomplete, false, nil)
 		cContent = content
 		if perr != nil {
 			contentCh <- &ClientContent{Err: perr.Trace(bucket)}
@@ -1814,68 +4707,213 @@ func (c *S3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *Client
 }
 
 // Returns new path by joining path segments with URL path separator.
+// Keys starting with or containing consecutive separators are
+// collapsed, so that the path splits back the same way, the exact
+// key of listed objects is kept in ClientContent.Key instead.
 func (c *S3Client) joinPath(bucket string, objects ...string) string {
-	p := string(c.targetURL.Separator) + bucket
+	separator := string(c.targetURL.Separator)
+	p := separator + bucket
 	for _, o := range objects {
-		p += string(c.targetURL.Separator) + o
+		p += separator + o
+	}
+	for strings.Contains(p, separator+separator) {
+		p = strings.Replace(p, separator+separator, separator, -1)
 	}
 	return p
 }
 
 // Convert objectInfo to ClientContent
-func (c *S3Client) objectInfo2ClientContent(bucket string, entry minio.ObjectInfo) *ClientContent {
+func (c *S3Client) objectInfo2ClientContent(bucket string, entry minio.ObjectInfo, metadata bool) *ClientContent {
 	content := &ClientContent{}
 	url := *c.targetURL
 	// Join bucket and incoming object key.
 	url.Path = c.joinPath(bucket, entry.Key)
 	content.URL = url
+	content.Key = entry.Key
 	content.Size = entry.Size
 	content.ETag = entry.ETag
 	content.Time = entry.LastModified
 	content.Expires = entry.Expires
-	content.Metadata = map[string]string{}
-	content.UserMetadata = map[string]string{}
-	for k, v := range entry.UserMetadata {
-		content.UserMetadata[k] = v
-	}
-	for k := range entry.Metadata {
-		content.Metadata[k] = entry.Metadata.Get(k)
-	}
+	c.setListedMetadata(content, entry, metadata)
+	content.Encryption = objectEncryption(entry.Metadata)
+	content.Restore = objectRestore(entry.Metadata)
 	if strings.HasSuffix(entry.Key, string(c.targetURL.Separator)) && entry.Size == 0 && entry.LastModified.IsZero() {
 		content.Type = os.ModeDir
 		content.Time = time.Now()
 	} else {
 		content.Type = os.FileMode(0664)
+		// Like on stat, objects without storage class are standard.
+		content.StorageClass = entry.StorageClass
+		if content.StorageClass == "" {
+			content.StorageClass = s3StorageClassStandard
+		}
 	}
 
 	return content
 }
 
+// setListedMetadata - sets the metadata of a listed object on content
+// if it was requested and the listing returns it, which Google Cloud
+// Storage listings don't. See ClientContent.MetadataFetched.
+func (c *S3Client) setListedMetadata(content *ClientContent, entry minio.ObjectInfo, metadata bool) {
+	if !metadata || c.listV1() {
+		return
+	}
+	content.MetadataFetched = true
+	content.Metadata = make(map[string]string, len(entry.Metadata))
+	for k := range entry.Metadata {
+		content.Metadata[k] = entry.Metadata.Get(k)
+	}
+	content.UserMetadata = make(map[string]string, len(entry.UserMetadata))
+	for k, v := range entry.UserMetadata {
+		content.UserMetadata[k] = v
+	}
+}
+
+// userMetadata - the X-Amz-Meta-* headers of metadata.
+func userMetadata(metadata http.Header) map[string]string {
+	user := map[string]string{}
+	for k := range metadata {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), "X-Amz-Meta-") {
+			user[k] = metadata.Get(k)
+		}
+	}
+	return user
+}
+
+// LoadMetadata - fetches the metadata of a listed object with a stat
+// unless the listing already did, e.g. for Google Cloud Storage which
+// ignores metadata listing requests. Directories have no metadata.
+func (c *S3Client) LoadMetadata(ctx context.Context, content *ClientContent) *probe.Error {
+	if content.MetadataFetched || content.Type.IsDir() || content.Err != nil {
+		return nil
+	}
+	bucket, object := c.splitPath(content.URL.Path)
+	if content.Key != "" {
+		object = content.Key
+	}
+	opts := minio.StatObjectOptions{}
+	opts.ServerSideEncryption = c.readSSE(nil)
+	objectStat, e := c.api.StatObjectWithContext(ctx, bucket, object, opts)
+	if e != nil {
+		if minio.ToErrorResponse(e).Code == "NoSuchKey" {
+			return probe.NewError(ObjectMissing{}).Trace(bucket, object)
+		}
+		return probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+	}
+	content.Metadata = make(map[string]string, len(objectStat.Metadata))
+	for k := range objectStat.Metadata {
+		content.Metadata[k] = objectStat.Metadata.Get(k)
+	}
+	content.UserMetadata = userMetadata(objectStat.Metadata)
+	content.Encryption = objectEncryption(objectStat.Metadata)
+	content.Restore = objectRestore(objectStat.Metadata)
+	content.MetadataFetched = true
+	return nil
+}
+
+// objectEncryption - server side encryption of an object from its
+// metadata, empty if it isn't encrypted.
+func objectEncryption(metadata http.Header) string {
+	if metadata.Get(serverEncryptionKeyPrefix+"-customer-algorithm") != "" {
+		return "SSE-C"
+	}
+	return metadata.Get(serverEncryptionKeyPrefix)
+}
+
+var (
+	restoreOngoingRegex = regexp.MustCompile(`ongoing-request="(true|false)"`)
+	restoreExpiryRegex  = regexp.MustCompile(`expiry-date="([^"]*)"`)
+)
+
+// objectRestore - restore status of an object from the x-amz-restore
+// header of its metadata, nil if no restore was requested.
+func objectRestore(metadata http.Header) *RestoreStatus {
+	restore := metadata.Get("X-Amz-Restore")
+	if restore == "" {
+		return nil
+	}
+	status := &RestoreStatus{}
+	if matches := restoreOngoingRegex.FindStringSubmatch(restore); matches != nil {
+		status.Ongoing = matches[1] == "true"
+	}
+	if matches := restoreExpiryRegex.FindStringSubmatch(restore); matches != nil {
+		if expiry, e := http.ParseTime(matches[1]); e == nil {
+			status.Expiry = expiry.UTC()
+		}
+	}
+	return status
+}
+
 // Returns bucket stat info of current bucket.
-func (c *S3Client) bucketStat(bucket string) (*ClientContent, *probe.Error) {
-	exists, e := c.api.BucketExists(bucket)
+func (c *S3Client) bucketStat(api *minio.Client, bucket string) (*ClientContent, *probe.Error) {
+	exists, e := api.BucketExists(bucket)
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
 	if !exists {
 		return nil, probe.NewError(BucketDoesNotExist{Bucket: bucket})
 	}
-	return &ClientContent{URL: *c.targetURL, Time: time.Unix(0, 0), Type: os.ModeDir}, nil
+	content := &ClientContent{URL: *c.targetURL, Time: time.Unix(0, 0), Type: os.ModeDir}
+	if region, err := c.bucketRegion(bucket); err == nil {
+		content.Metadata = map[string]string{"Region": region}
+	}
+	return content, nil
+}
+
+// GetBucketRegion - region of the bucket, looked up once per client.
+func (c *S3Client) GetBucketRegion() (string, *probe.Error) {
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return "", probe.NewError(BucketNameEmpty{})
+	}
+	region, err := c.bucketRegion(bucket)
+	if err != nil {
+		return "", err.Trace(bucket)
+	}
+	return region, nil
+}
+
+// bucketRegion - region of bucket, cached on the client.
+func (c *S3Client) bucketRegion(bucket string) (string, *probe.Error) {
+	c.regionsMutex.Lock()
+	defer c.regionsMutex.Unlock()
+	if region, ok := c.regions[bucket]; ok {
+		return region, nil
+	}
+	region, e := c.api.GetBucketLocation(bucket)
+	if e != nil {
+		switch minio.ToErrorResponse(e).Code {
+		case "NoSuchBucket":
+			return "", probe.NewError(BucketDoesNotExist{Bucket: bucket})
+		case "InvalidBucketName":
+			return "", probe.NewError(BucketInvalid{Bucket: bucket})
+		case "AccessDenied":
+			return "", probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
+		}
+		return "", probe.NewError(e)
+	}
+	if c.regions == nil {
+		c.regions = make(map[string]string)
+	}
+	c.regions[bucket] = region
+	return region, nil
 }
 
 // Recursively lists objects.
-func (c *S3Client) listRecursiveInRoutineDirOpt(contentCh chan *ClientContent, dirOpt DirOpt, metadata bool) {
+func (c *S3Client) listRecursiveInRoutineDirOpt(contentCh chan *ClientContent, opts ListOptions, filter *listFilter) {
 	defer close(contentCh)
+	dirOpt, metadata := opts.ShowDir, opts.Metadata
 	// Closure function reads list objects and sends to contentCh. If a directory is found, it lists
 	// objects of the directory content recursively.
 	var listDir func(bucket, object string) bool
 	listDir = func(bucket, object string) (isStop bool) {
 		isRecursive := false
-		for entry := range c.listObjectWrapper(bucket, object, isRecursive, nil, metadata) {
+		for entry := range c.listObjectsAfter(bucket, object, isRecursive, nil, metadata, opts.StartAfter) {
 			if entry.Err != nil {
 				url := *c.targetURL
 				url.Path = c.joinPath(bucket, object)
-				contentCh <- &ClientContent{URL: url, Err: probe.NewError(entry.Err)}
+				contentCh <- &ClientContent{URL: url, Err: probe.NewError(regionError(bucket, entry.Err))}
 
 				errResponse := minio.ToErrorResponse(entry.Err)
 				if errResponse.Code == "AccessDenied" {
@@ -1884,21 +4922,24 @@ func (c *S3Client) listRecursiveInRoutineDirOpt(contentCh chan *ClientContent, d
 				return true
 			}
 
-			content := c.objectInfo2ClientContent(bucket, entry)
+			content := c.objectInfo2ClientContent(bucket, entry, metadata)
 
 			// Handle if object.Key is a directory.
 			if content.Type.IsDir() {
+				if !filter.descend(entry.Key) {
+					continue
+				}
 				if dirOpt == DirFirst {
-					contentCh <- content
+					filter.send(contentCh, content)
 				}
 				if listDir(bucket, entry.Key) {
 					return true
 				}
 				if dirOpt == DirLast {
-					contentCh <- content
+					filter.send(contentCh, content)
 				}
 			} else {
-				contentCh <- content
+				filter.send(contentCh, content)
 			}
 		}
 		return false
@@ -1920,7 +4961,7 @@ func (c *S3Client) listRecursiveInRoutineDirOpt(contentCh chan *ClientContent, d
 		}
 	} else if object == "" {
 		// Get bucket stat if object is empty.
-		content, err := c.bucketStat(bucket)
+		content, err := c.bucketStat(c.api, bucket)
 		if err != nil {
 			contentCh <- &ClientContent{Err: err.Trace(bucket)}
 			return
@@ -1929,7 +4970,7 @@ func (c *S3Client) listRecursiveInRoutineDirOpt(contentCh chan *ClientContent, d
 	} else {
 		// Get stat of given object is a directory.
 		isIncomplete := false
-		content, perr := c.Stat(isIncomplete, false, nil)
+		content, perr := c.Stat(context.Background(), isIncomplete, false, nil)
 		cContent = content
 		if perr != nil {
 			contentCh <- &ClientContent{Err: perr.Trace(bucket)}
@@ -1960,8 +5001,9 @@ func (c *S3Client) listRecursiveInRoutineDirOpt(contentCh chan *ClientContent, d
 	}
 }
 
-func (c *S3Client) listInRoutine(contentCh chan *ClientContent, metadata bool) {
+func (c *S3Client) listInRoutine(contentCh chan *ClientContent, opts ListOptions, filter *listFilter) {
 	defer close(contentCh)
+	metadata := opts.Metadata
 	// get bucket and object from URL.
 	b, o := c.url2BucketAndObject()
 	switch {
@@ -1981,21 +5023,21 @@ func (c *S3Client) listInRoutine(contentCh chan *ClientContent, metadata bool) {
 			content.Size = 0
 			content.Time = bucket.CreationDate
 			content.Type = os.ModeDir
-			contentCh <- content
+			filter.send(contentCh, content)
 		}
 	case b != "" && !strings.HasSuffix(c.targetURL.Path, string(c.targetURL.Separator)) && o == "":
-		content, err := c.bucketStat(b)
+		content, err := c.bucketStat(c.api, b)
 		if err != nil {
 			contentCh <- &ClientContent{Err: err.Trace(b)}
 			return
 		}
-		contentCh <- content
+		filter.send(contentCh, content)
 	default:
 		isRecursive := false
-		for object := range c.listObjectWrapper(b, o, isRecursive, nil, metadata) {
+		for object := range c.listObjectsAfter(b, o, isRecursive, nil, metadata, opts.StartAfter) {
 			if object.Err != nil {
 				contentCh <- &ClientContent{
-					Err: probe.NewError(object.Err),
+					Err: probe.NewError(regionError(b, object.Err)),
 				}
 				return
 			}
@@ -2005,7 +5047,7 @@ func (c *S3Client) listInRoutine(contentCh chan *ClientContent, metadata bool) {
 				continue
 			}
 
-			contentCh <- c.objectInfo2ClientContent(b, object)
+			filter.send(contentCh, c.objectInfo2ClientContent(b, object, metadata))
 		}
 	}
 }
@@ -2014,17 +5056,72 @@ func (c *S3Client) listInRoutine(contentCh chan *ClientContent, metadata bool) {
 // different use cases, following list captures these.
 const (
 	// General purpose.
-	// s3StorageClassStandard = "STANDARD"
+	s3StorageClassStandard = "STANDARD"
 	// Infrequent access.
-	// s3StorageClassInfrequent = "STANDARD_IA"
+	s3StorageClassInfrequent = "STANDARD_IA"
+	// Infrequent access, single availability zone.
+	s3StorageClassOneZoneInfrequent = "ONEZONE_IA"
+	// Automatic tiering.
+	s3StorageClassIntelligentTiering = "INTELLIGENT_TIERING"
 	// Reduced redundancy access.
-	// s3StorageClassRedundancy = "REDUCED_REDUNDANCY"
+	s3StorageClassRedundancy = "REDUCED_REDUNDANCY"
 	// Archive access.
 	s3StorageClassGlacier = "GLACIER"
+	// Long term archive access.
+	s3StorageClassDeepArchive = "DEEP_ARCHIVE"
+
+	// Storage classes with this prefix are sent as is, without
+	// validation, e.g. `passthrough:CUSTOM_CLASS`.
+	storageClassPassthroughPrefix = "passthrough:"
 )
 
-func (c *S3Client) listRecursiveInRoutine(contentCh chan *ClientContent, metadata bool) {
+// validStorageClasses - storage classes accepted on upload.
+var validStorageClasses = []string{
+	s3StorageClassStandard,
+	s3StorageClassRedundancy,
+	s3StorageClassInfrequent,
+	s3StorageClassOneZoneInfrequent,
+	s3StorageClassIntelligentTiering,
+	s3StorageClassGlacier,
+	s3StorageClassDeepArchive,
+}
+
+// registerStorageClass - accept an additional storage class on
+// upload, such as MinIO specific ones. Must be called during init.
+func registerStorageClass(storageClass string) {
+	storageClass = strings.ToUpper(storageClass)
+	for _, class := range validStorageClasses {
+		if class == storageClass {
+			return
+		}
+	}
+	validStorageClasses = append(validStorageClasses, storageClass)
+}
+
+// normalizeStorageClass - validate a storage class and return the
+// value to send, upper cased unless it is a passthrough class.
+func normalizeStorageClass(storageClass string) (string, *probe.Error) {
+	if storageClass == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(storageClass, storageClassPassthroughPrefix) {
+		return strings.TrimPrefix(storageClass, storageClassPassthroughPrefix), nil
+	}
+	upper := strings.ToUpper(storageClass)
+	for _, class := range validStorageClasses {
+		if class == upper {
+			return upper, nil
+		}
+	}
+	return "", probe.NewError(InvalidStorageClass{
+		StorageClass: storageClass,
+		Valid:        validStorageClasses,
+	})
+}
+
+func (c *S3Client) listRecursiveInRoutine(contentCh chan *ClientContent, opts ListOptions, filter *listFilter) {
 	defer close(contentCh)
+	metadata := opts.Metadata
 	// get bucket and object from URL.
 	b, o := c.url2BucketAndObject()
 	switch {
@@ -2038,10 +5135,10 @@ func (c *S3Client) listRecursiveInRoutine(contentCh chan *ClientContent, metadat
 		}
 		for _, bucket := range buckets {
 			isRecursive := true
-			for object := range c.listObjectWrapper(bucket.Name, o, isRecursive, nil, metadata) {
+			for object := range c.listObjectsAfter(bucket.Name, o, isRecursive, nil, metadata, opts.StartAfter) {
 				if object.Err != nil {
 					contentCh <- &ClientContent{
-						Err: probe.NewError(object.Err),
+						Err: probe.NewError(regionError(bucket.Name, object.Err)),
 					}
 					return
 				}
@@ -2049,25 +5146,23 @@ func (c *S3Client) listRecursiveInRoutine(contentCh chan *ClientContent, metadat
 				objectURL := *c.targetURL
 				objectURL.Path = c.joinPath(bucket.Name, object.Key)
 				content.URL = objectURL
+				content.Key = object.Key
 				content.StorageClass = object.StorageClass
 				content.Size = object.Size
 				content.ETag = object.ETag
 				content.Time = object.LastModified
 				content.Type = os.FileMode(0664)
 				content.Expires = object.Expires
-				content.UserMetadata = map[string]string{}
-				for k, v := range object.UserMetadata {
-					content.UserMetadata[k] = v
-				}
-				contentCh <- content
+				c.setListedMetadata(content, object, metadata)
+				filter.send(contentCh, content)
 			}
 		}
 	default:
 		isRecursive := true
-		for object := range c.listObjectWrapper(b, o, isRecursive, nil, metadata) {
+		for object := range c.listObjectsAfter(b, o, isRecursive, nil, metadata, opts.StartAfter) {
 			if object.Err != nil {
 				contentCh <- &ClientContent{
-					Err: probe.NewError(object.Err),
+					Err: probe.NewError(regionError(b, object.Err)),
 				}
 				return
 			}
@@ -2076,16 +5171,15 @@ func (c *S3Client) listRecursiveInRoutine(contentCh chan *ClientContent, metadat
 			// Join bucket and incoming object key.
 			url.Path = c.joinPath(b, object.Key)
 			content.URL = url
+			content.Key = object.Key
+			content.StorageClass = object.StorageClass
 			content.Size = object.Size
 			content.ETag = object.ETag
 			content.Time = object.LastModified
 			content.Type = os.FileMode(0664)
 			content.Expires = object.Expires
-			content.UserMetadata = map[string]string{}
-			for k, v := range object.UserMetadata {
-				content.UserMetadata[k] = v
-			}
-			contentCh <- content
+			c.setListedMetadata(content, object, metadata)
+			filter.send(contentCh, content)
 		}
 	}
 }
@@ -2099,6 +5193,49 @@ func (c *S3Client) ShareDownload(expires time.Duration) (string, *probe.Error) {
 	if e != nil {
 		return "", probe.NewError(e)
 	}
+	c.tracePresigned(http.MethodGet, presignedURL, nil)
+	return presignedURL.String(), nil
+}
+
+// Presign - get a presigned object url for any of GET, PUT, HEAD,
+// DELETE and POST requests, e.g. to share short lived delete links.
+func (c *S3Client) Presign(method string, expires time.Duration, reqParams url.Values) (string, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return "", probe.NewError(BucketNameEmpty{})
+	}
+	method = strings.ToUpper(method)
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodHead, http.MethodDelete, http.MethodPost:
+	default:
+		return "", errInvalidArgument().Trace(method)
+	}
+	if reqParams == nil {
+		reqParams = make(url.Values)
+	}
+	presignedURL, e := c.api.Presign(method, bucket, object, expires, reqParams)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	c.tracePresigned(method, presignedURL, nil)
+	return presignedURL.String(), nil
+}
+
+// PresignedHeadObject - get a presigned object url for a HEAD request,
+// allows checking object existence and headers without downloading it.
+func (c *S3Client) PresignedHeadObject(expires time.Duration) (string, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return "", probe.NewError(BucketNameEmpty{})
+	}
+	if object == "" {
+		return "", probe.NewError(ObjectNameEmpty{})
+	}
+	presignedURL, e := c.api.PresignedHeadObject(bucket, object, expires, make(url.Values))
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	c.tracePresigned(http.MethodHead, presignedURL, nil)
 	return presignedURL.String(), nil
 }
 
@@ -2129,14 +5266,63 @@ func (c *S3Client) ShareUpload(isRecursive bool, expires time.Duration, contentT
 	if e != nil {
 		return "", nil, probe.NewError(e)
 	}
+	c.tracePresigned(http.MethodPost, u, m)
 	return u.String(), m, nil
 }
 
+// tracePresigned - print a presigned URL and the form data of presigned
+// POST policies when debugging, with signatures and access keys redacted.
+func (c *S3Client) tracePresigned(method string, presignedURL *url.URL, formData map[string]string) {
+	if !c.debug {
+		return
+	}
+	trace := method + " " + redactPresignedURL(presignedURL) + "\n"
+	var fields []string
+	for field := range formData {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		value := formData[field]
+		if isPresignSecretField(field) {
+			value = "**REDACTED**"
+		}
+		trace += field + ": " + value + "\n"
+	}
+	console.Debug(trace)
+}
+
+// isPresignSecretField - whether a query parameter or form field of a
+// presigned request holds a signature or an access key.
+func isPresignSecretField(field string) bool {
+	switch strings.ToLower(field) {
+	case "x-amz-signature", "signature", "x-amz-credential", "awsaccesskeyid", "x-amz-security-token":
+		return true
+	}
+	return false
+}
+
+// redactPresignedURL - presigned URL with the signature and the access
+// key redacted.
+func redactPresignedURL(presignedURL *url.URL) string {
+	redacted := *presignedURL
+	query := redacted.Query()
+	for field := range query {
+		if isPresignSecretField(field) {
+			query.Set(field, "**REDACTED**")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
 // SetObjectLockConfig - Set object lock configurataion of bucket.
-func (c *S3Client) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
+func (c *S3Client) SetObjectLockConfig(ctx context.Context, mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
 	bucket, _ := c.url2BucketAndObject()
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
 
-	err := c.api.SetBucketObjectLockConfig(bucket, mode, validity, unit)
+	err := api.SetBucketObjectLockConfig(bucket, mode, validity, unit)
 	if err != nil {
 		return probe.NewError(err)
 	}
@@ -2145,15 +5331,17 @@ func (c *S3Client) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint
 }
 
 // PutObjectRetention - Set object retention for a given object.
-func (c *S3Client) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
+func (c *S3Client) PutObjectRetention(ctx context.Context, mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
 	bucket, object := c.url2BucketAndObject()
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
 
 	opts := minio.PutObjectRetentionOptions{
 		RetainUntilDate:  retainUntilDate,
 		Mode:             mode,
 		GovernanceBypass: bypassGovernance,
 	}
-	err := c.api.PutObjectRetention(bucket, object, opts)
+	err := api.PutObjectRetention(bucket, object, opts)
 	if err != nil {
 		return probe.NewError(err)
 	}
@@ -2162,12 +5350,14 @@ func (c *S3Client) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate
 }
 
 // PutObjectLegalHold - Set object legal hold for a given object.
-func (c *S3Client) PutObjectLegalHold(lhold *minio.LegalHoldStatus) *probe.Error {
+func (c *S3Client) PutObjectLegalHold(ctx context.Context, lhold *minio.LegalHoldStatus) *probe.Error {
 	bucket, object := c.url2BucketAndObject()
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
 	opts := minio.PutObjectLegalHoldOptions{
 		Status: lhold,
 	}
-	err := c.api.PutObjectLegalHold(bucket, object, opts)
+	err := api.PutObjectLegalHold(bucket, object, opts)
 	if err != nil {
 		return probe.NewError(err)
 	}
@@ -2175,10 +5365,12 @@ func (c *S3Client) PutObjectLegalHold(lhold *minio.LegalHoldStatus) *probe.Error
 }
 
 // GetObjectLockConfig - Get object lock configuration of bucket.
-func (c *S3Client) GetObjectLockConfig() (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error) {
+func (c *S3Client) GetObjectLockConfig(ctx context.Context) (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error) {
 	bucket, _ := c.url2BucketAndObject()
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
 
-	mode, validity, unit, err := c.api.GetBucketObjectLockConfig(bucket)
+	mode, validity, unit, err := api.GetBucketObjectLockConfig(bucket)
 	if err != nil {
 		return nil, nil, nil, probe.NewError(err)
 	}
@@ -2186,8 +5378,58 @@ func (c *S3Client) GetObjectLockConfig() (mode *minio.RetentionMode, validity *u
 	return mode, validity, unit, nil
 }
 
+// ObjectLockState - object lock state of an object, fields are empty
+// when unset.
+type ObjectLockState struct {
+	LegalHold       minio.LegalHoldStatus
+	Mode            minio.RetentionMode
+	RetainUntilDate time.Time
+}
+
+// GetObjectLockState - legal hold and retention of the object, with
+// one call each. Unset legal hold or retention are left empty rather
+// than being errors.
+func (c *S3Client) GetObjectLockState(ctx context.Context) (ObjectLockState, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	if bucket == "" {
+		return ObjectLockState{}, probe.NewError(BucketNameEmpty{})
+	}
+	if object == "" {
+		return ObjectLockState{}, probe.NewError(ObjectNameEmpty{})
+	}
+	api, release := c.contextAPI(ctx, bucket)
+	defer release()
+
+	// Both are reported as missing object lock configurations.
+	isUnset := func(e error) bool {
+		code := minio.ToErrorResponse(e).Code
+		return code == "NoSuchObjectLockConfiguration" || code == "ObjectLockConfigurationNotFoundError"
+	}
+	var state ObjectLockState
+	status, e := api.GetObjectLegalHold(bucket, object, minio.GetObjectLegalHoldOptions{})
+	if e != nil && !isUnset(e) {
+		return ObjectLockState{}, probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+	}
+	if e == nil && status != nil {
+		state.LegalHold = *status
+	}
+	mode, retainUntilDate, e := api.GetObjectRetention(bucket, object, "")
+	if e != nil && !isUnset(e) {
+		return ObjectLockState{}, probe.NewError(regionError(bucket, e)).Trace(bucket, object)
+	}
+	if e == nil {
+		if mode != nil {
+			state.Mode = *mode
+		}
+		if retainUntilDate != nil {
+			state.RetainUntilDate = retainUntilDate.UTC()
+		}
+	}
+	return state, nil
+}
+
 // GetObjectTagging - Get Object Tags
-func (c *S3Client) GetObjectTagging() (tagging.Tagging, *probe.Error) {
+func (c *S3Client) GetObjectTagging(ctx context.Context) (tagging.Tagging, *probe.Error) {
 	var err error
 	bucketName, objectName := c.url2BucketAndObject()
 	if bucketName == "" {
@@ -2196,7 +5438,9 @@ func (c *S3Client) GetObjectTagging() (tagging.Tagging, *probe.Error) {
 	if objectName == "" {
 		return tagging.Tagging{}, probe.NewError(ObjectNameEmpty{})
 	}
-	tagXML, err := c.api.GetObjectTagging(bucketName, objectName)
+	api, release := c.contextAPI(ctx, bucketName)
+	defer release()
+	tagXML, err := api.GetObjectTagging(bucketName, objectName)
 	if err != nil {
 		return tagging.Tagging{}, probe.NewError(err)
 	}
@@ -2208,7 +5452,7 @@ func (c *S3Client) GetObjectTagging() (tagging.Tagging, *probe.Error) {
 }
 
 // SetObjectTagging - Set Object tags
-func (c *S3Client) SetObjectTagging(tagMap map[string]string) *probe.Error {
+func (c *S3Client) SetObjectTagging(ctx context.Context, tagMap map[string]string) *probe.Error {
 	var err error
 	bucketName, objectName := c.url2BucketAndObject()
 	if bucketName == "" {
@@ -2217,14 +5461,16 @@ func (c *S3Client) SetObjectTagging(tagMap map[string]string) *probe.Error {
 	if objectName == "" {
 		return probe.NewError(ObjectNameEmpty{})
 	}
-	if err = c.api.PutObjectTagging(bucketName, objectName, tagMap); err != nil {
+	api, release := c.contextAPI(ctx, bucketName)
+	defer release()
+	if err = api.PutObjectTagging(bucketName, objectName, tagMap); err != nil {
 		return probe.NewError(err)
 	}
 	return nil
 }
 
 // DeleteObjectTagging - Delete object tags
-func (c *S3Client) DeleteObjectTagging() *probe.Error {
+func (c *S3Client) DeleteObjectTagging(ctx context.Context) *probe.Error {
 	bucketName, objectName := c.url2BucketAndObject()
 	if bucketName == "" {
 		return probe.NewError(BucketNameEmpty{})
@@ -2232,7 +5478,9 @@ func (c *S3Client) DeleteObjectTagging() *probe.Error {
 	if objectName == "" {
 		return probe.NewError(ObjectNameEmpty{})
 	}
-	if err := c.api.RemoveObjectTagging(bucketName, objectName); err != nil {
+	api, release := c.contextAPI(ctx, bucketName)
+	defer release()
+	if err := api.RemoveObjectTagging(bucketName, objectName); err != nil {
 		return probe.NewError(err)
 	}
 	return nil
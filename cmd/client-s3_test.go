@@ -18,17 +18,56 @@ package cmd
 
 // bucketHandler is an http.Handler that verifies bucket responses and validates incoming requests
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
 	. "gopkg.in/check.v1"
 )
 
+// testConfig - configuration of a client for hostURL, usually the URL of
+// an httptest server, with the test credentials.
+func testConfig(hostURL, signature string) *Config {
+	conf := new(Config)
+	conf.HostURL = hostURL
+	conf.AccessKey = "WLGDGYAQYIGI833EV05A"
+	conf.SecretKey = "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF"
+	conf.Signature = signature
+	return conf
+}
+
+// newTestS3Client - client for hostURL with the test credentials.
+func newTestS3Client(c *C, hostURL, signature string) *S3Client {
+	clnt, err := S3New(testConfig(hostURL, signature))
+	c.Assert(err, IsNil)
+	return clnt.(*S3Client)
+}
+
 type bucketHandler struct {
 	resource string
 }
@@ -171,7 +210,7 @@ func (s *TestSuite) TestBucketOperations(c *C) {
 	s3c, err := S3New(conf)
 	c.Assert(err, IsNil)
 
-	err = s3c.MakeBucket("us-east-1", true, false)
+	err = s3c.MakeBucket(context.Background(), "us-east-1", true, false)
 	c.Assert(err, IsNil)
 
 	conf.HostURL = server.URL + string(s3c.GetURL().Separator)
@@ -223,11 +262,11 @@ func (s *TestSuite) TestObjectOperations(c *C) {
 	reader = bytes.NewReader(object.data)
 	n, err := s3c.Put(context.Background(), reader, int64(len(object.data)), map[string]string{
 		"Content-Type": "application/octet-stream",
-	}, nil, nil, false, false)
+	}, nil, nil, false, false, "")
 	c.Assert(err, IsNil)
 	c.Assert(n, Equals, int64(len(object.data)))
 
-	reader, err = s3c.Get(nil)
+	reader, err = s3c.Get(context.Background(), nil)
 	c.Assert(err, IsNil)
 	var buffer bytes.Buffer
 	{
@@ -244,6 +283,11 @@ var testSelectCompressionTypeCases = []struct {
 }{
 	{SelectObjectOpts{CompressionType: minio.SelectCompressionNONE}, "a.gzip", minio.SelectCompressionNONE},
 	{SelectObjectOpts{CompressionType: minio.SelectCompressionBZIP}, "a.gz", minio.SelectCompressionBZIP},
+	{SelectObjectOpts{CompressionType: minio.SelectCompressionNONE}, "x.csv.gz", minio.SelectCompressionNONE},
+	{SelectObjectOpts{CompressionType: minio.SelectCompressionNONE}, "k.bz2", minio.SelectCompressionNONE},
+	{SelectObjectOpts{CompressionType: "none"}, "b.gz", minio.SelectCompressionNONE},
+	{SelectObjectOpts{CompressionType: "gzip"}, "a.txt", minio.SelectCompressionGZIP},
+	{SelectObjectOpts{CompressionType: minio.SelectCompressionGZIP}, "t.parquet", minio.SelectCompressionGZIP},
 	{SelectObjectOpts{}, "t.parquet", minio.SelectCompressionNONE},
 	{SelectObjectOpts{}, "x.csv.gz", minio.SelectCompressionGZIP},
 	{SelectObjectOpts{}, "x.json.bz2", minio.SelectCompressionBZIP},
@@ -251,6 +295,9 @@ var testSelectCompressionTypeCases = []struct {
 	{SelectObjectOpts{}, "k.bz2", minio.SelectCompressionBZIP},
 	{SelectObjectOpts{}, "a.csv", minio.SelectCompressionNONE},
 	{SelectObjectOpts{}, "a.json", minio.SelectCompressionNONE},
+	{SelectObjectOpts{}, "x.csv.tar.gz", minio.SelectCompressionGZIP},
+	{SelectObjectOpts{}, "t.parquet.zst", selectCompressionZSTD},
+	{SelectObjectOpts{}, "x.csv.zst", selectCompressionZSTD},
 }
 
 // TestSelectCompressionType - tests compression type returned
@@ -259,5 +306,4050 @@ func (s *TestSuite) TestSelectCompressionType(c *C) {
 	for _, test := range testSelectCompressionTypeCases {
 		cType := selectCompressionType(test.opts, test.object)
 		c.Assert(cType, DeepEquals, test.compressionType)
+		// The input serialization must keep the same type.
+		c.Assert(selectObjectInputOpts(test.opts, test.object).CompressionType, DeepEquals, test.compressionType)
+	}
+}
+
+// TestTrimCompressionFileExts - tests the format of compressed objects
+// is guessed from their name.
+func (s *TestSuite) TestTrimCompressionFileExts(c *C) {
+	testCases := []struct {
+		object string
+		name   string
+		input  string
+	}{
+		{"x.csv.gz", "x.csv", "csv"},
+		{"x.json.bz2", "x.json", "json"},
+		{"x.csv.tar.gz", "x.csv", "csv"},
+		{"x.json.tar.bz2", "x.json", "json"},
+		{"t.parquet.zst", "t.parquet", "parquet"},
+		{"x.tar", "x.tar", ""},
+		{"x.csv", "x.csv", "csv"},
+	}
+	for i, testCase := range testCases {
+		c.Assert(trimCompressionFileExts(testCase.object), Equals, testCase.name, Commentf("test %d", i+1))
+		opts := selectObjectInputOpts(SelectObjectOpts{}, testCase.object)
+		input := ""
+		switch {
+		case opts.CSV != nil:
+			input = "csv"
+		case opts.JSON != nil:
+			input = "json"
+		case opts.Parquet != nil:
+			input = "parquet"
+		}
+		c.Assert(input, Equals, testCase.input, Commentf("object %s", testCase.object))
+	}
+}
+
+// requestPaymentHandler is an http.Handler that records the request payer
+// header of incoming requests, and whether it is signed, and serves bucket
+// request payment APIs.
+type requestPaymentHandler struct {
+	payer      *string
+	lastPaid   *string
+	lastSigned *bool
+}
+
+func (h requestPaymentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	*h.lastPaid = r.Header.Get("X-Amz-Request-Payer")
+	*h.lastSigned = strings.Contains(r.Header.Get("Authorization"), "x-amz-request-payer")
+	switch {
+	case r.Method == "GET":
+		if _, ok := r.URL.Query()["location"]; ok {
+			response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.Write(response)
+			return
+		}
+		if _, ok := r.URL.Query()["requestPayment"]; ok {
+			response := []byte("<RequestPaymentConfiguration xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Payer>" + *h.payer + "</Payer></RequestPaymentConfiguration>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.Write(response)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == "PUT":
+		if _, ok := r.URL.Query()["requestPayment"]; ok {
+			var config requestPaymentConfiguration
+			if e := xml.NewDecoder(r.Body).Decode(&config); e != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			*h.payer = config.Payer
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	case r.Method == "HEAD":
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Test bucket request payment configuration.
+func (s *TestSuite) TestBucketRequestPayment(c *C) {
+	payer, lastPaid, lastSigned := "BucketOwner", "", false
+	server := httptest.NewServer(requestPaymentHandler{payer: &payer, lastPaid: &lastPaid, lastSigned: &lastSigned})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	_, err = s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(lastPaid, Equals, "")
+
+	c.Assert(s3c.SetBucketRequestPayment("invalid"), NotNil)
+
+	err = s3c.SetBucketRequestPayment("Requester")
+	c.Assert(err, IsNil)
+	c.Assert(payer, Equals, "Requester")
+
+	got, err := s3c.GetBucketRequestPayment()
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, "Requester")
+
+	_, err = s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(lastPaid, Equals, "requester")
+	c.Assert(lastSigned, Equals, true)
+
+	// Other clients of the host don't send it.
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(lastPaid, Equals, "")
+
+	err = s3c.SetBucketRequestPayment("BucketOwner")
+	c.Assert(err, IsNil)
+	_, err = s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(lastPaid, Equals, "")
+}
+
+// aclHandler stores the canned ACL of a bucket, serving the grants it
+// stands for, and extra grants when set.
+type aclHandler struct {
+	acl    *string
+	extra  string
+	puts   *int
+	bucket string
+}
+
+func (h aclHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if _, ok := query["acl"]; !ok || r.URL.Path != "/"+h.bucket+"/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		*h.acl = r.Header.Get("X-Amz-Acl")
+		*h.puts++
+		w.WriteHeader(http.StatusOK)
+	case "GET":
+		group := func(uri, permission string) string {
+			return "<Grant><Grantee xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xsi:type=\"Group\"><URI>" + uri + "</URI></Grantee><Permission>" + permission + "</Permission></Grant>"
+		}
+		grants := "<Grant><Grantee xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xsi:type=\"CanonicalUser\"><ID>owner</ID></Grantee><Permission>FULL_CONTROL</Permission></Grant>"
+		switch *h.acl {
+		case "public-read":
+			grants += group(aclAllUsers, "READ")
+		case "public-read-write":
+			grants += group(aclAllUsers, "READ") + group(aclAllUsers, "WRITE")
+		case "authenticated-read":
+			grants += group(aclAuthenticatedUsers, "READ")
+		}
+		response := []byte("<AccessControlPolicy xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Owner><ID>owner</ID></Owner><AccessControlList>" +
+			grants + h.extra + "</AccessControlList></AccessControlPolicy>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Test setting and getting the canned ACL of a bucket.
+func (s *TestSuite) TestBucketACL(c *C) {
+	acl, puts := "private", 0
+	server := httptest.NewServer(aclHandler{acl: &acl, puts: &puts, bucket: "bucket"})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	got, err := s3c.GetBucketACL()
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, "private")
+
+	for _, cannedACL := range []string{"public-read", "public-read-write", "authenticated-read", "private"} {
+		c.Assert(s3c.SetBucketACL(cannedACL), IsNil)
+		c.Assert(acl, Equals, cannedACL)
+		got, err = s3c.GetBucketACL()
+		c.Assert(err, IsNil)
+		c.Assert(got, Equals, cannedACL)
+	}
+
+	// Object only canned ACLs are invalid and not sent.
+	for _, cannedACL := range []string{"", "bucket-owner-read", "Private"} {
+		c.Assert(s3c.SetBucketACL(cannedACL), NotNil)
+	}
+	c.Assert(puts, Equals, 4)
+
+	// ACLs granting other users aren't canned.
+	server = httptest.NewServer(aclHandler{acl: &acl, puts: &puts, bucket: "bucket",
+		extra: "<Grant><Grantee xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xsi:type=\"CanonicalUser\"><ID>other</ID></Grantee><Permission>READ</Permission></Grant>"})
+	defer server.Close()
+	s3c = newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+	_, err = s3c.GetBucketACL()
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(BucketACLNotCanned)
+	c.Assert(ok, Equals, true)
+}
+
+var testValidateNotificationARNCases = []struct {
+	arn   string
+	valid bool
+}{
+	{"arn:minio:sqs::1:webhook", true},
+	{"arn:aws:sqs:us-east-1:123456789012:queue", true},
+	{"arn:aws-cn:sns:cn-north-1:123456789012:topic", true},
+	{"arn:aws:lambda:us-west-2:123456789012:function", true},
+	{"arn:minio:sqs::1", false},
+	{"urn:minio:sqs::1:webhook", false},
+	{"arn:azure:sqs::1:webhook", false},
+	{"arn:minio:s3::1:webhook", false},
+	{"arn:aws:sqs::123456789012:queue", false},
+	{"arn:minio:sqs:::webhook", false},
+	{"arn:minio:sqs::1:", false},
+}
+
+// TestValidateNotificationARN - tests notification target ARN validation.
+func (s *TestSuite) TestValidateNotificationARN(c *C) {
+	for _, test := range testValidateNotificationARNCases {
+		err := validateNotificationARN(test.arn)
+		c.Assert(err == nil, Equals, test.valid, Commentf("%s", test.arn))
+	}
+}
+
+// recordHandler is an http.Handler that records the headers of
+// incoming requests before passing them on to the wrapped handler.
+type recordHandler struct {
+	handler http.Handler
+	headers *[]http.Header
+}
+
+func (h recordHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	*h.headers = append(*h.headers, r.Header.Clone())
+	h.handler.ServeHTTP(w, r)
+}
+
+// Test canned ACL on upload.
+func (s *TestSuite) TestPutCannedACL(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	s3c, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	_, err = s3c.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "invalid-acl")
+	c.Assert(err, NotNil)
+
+	headers = nil
+	_, err = s3c.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "bucket-owner-full-control")
+	c.Assert(err, IsNil)
+	c.Assert(len(headers) > 0, Equals, true)
+	c.Assert(headers[len(headers)-1].Get("X-Amz-Acl"), Equals, "bucket-owner-full-control")
+}
+
+// Test the default storage class of uploads.
+func (s *TestSuite) TestDefaultStorageClass(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	var storageClass string
+	server := httptest.NewServer(recordHandler{
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "HEAD" && storageClass != "" {
+				w.Header().Set("X-Amz-Storage-Class", storageClass)
+			}
+			object.ServeHTTP(w, r)
+		}),
+		headers: &headers,
+	})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	conf.DefaultStorageClass = "FASTEST"
+	_, err := S3New(conf)
+	c.Assert(err, NotNil)
+
+	conf.DefaultStorageClass = "standard_ia"
+	s3c, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	testCases := []struct {
+		metadata     map[string]string
+		storageClass string
+	}{
+		{map[string]string{}, "STANDARD_IA"},
+		{map[string]string{"X-Amz-Storage-Class": "GLACIER"}, "GLACIER"},
+		{map[string]string{"X-Amz-Storage-Class": "STANDARD"}, "STANDARD"},
+	}
+	for i, testCase := range testCases {
+		headers = nil
+		_, err = s3c.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), testCase.metadata, nil, nil, false, false, "")
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(len(headers) > 0, Equals, true)
+		c.Assert(headers[len(headers)-1].Get("X-Amz-Storage-Class"), Equals, testCase.storageClass, Commentf("test %d", i+1))
+	}
+
+	// Stat reports the storage class, S3 omits STANDARD.
+	content, err := s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.StorageClass, Equals, "STANDARD")
+	storageClass = "STANDARD_IA"
+	content, err = s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.StorageClass, Equals, "STANDARD_IA")
+
+	// Copies keep the metadata of the source along with the default.
+	copyServer := httptest.NewServer(recordHandler{
+		handler: copyHandler{resource: "/bucket/source", header: http.Header{
+			"Content-Type":     []string{"text/plain"},
+			"Cache-Control":    []string{"no-cache"},
+			"X-Amz-Meta-Owner": []string{"alice"},
+		}},
+		headers: &headers,
+	})
+	defer copyServer.Close()
+	conf.HostURL = copyServer.URL + "/bucket/target"
+	s3c, err = S3New(conf)
+	c.Assert(err, IsNil)
+	for _, disableMultipart := range []bool{true, false} {
+		headers = nil
+		err = s3c.Copy(context.Background(), "/bucket/source", 12, nil, nil, nil, nil, disableMultipart, "")
+		c.Assert(err, IsNil)
+		last := headers[len(headers)-1]
+		c.Assert(last.Get("X-Amz-Copy-Source"), Not(Equals), "")
+		c.Assert(last.Get("X-Amz-Storage-Class"), Equals, "STANDARD_IA")
+		c.Assert(last.Get("X-Amz-Metadata-Directive"), Equals, "REPLACE")
+		c.Assert(last.Get("X-Amz-Meta-Owner"), Equals, "alice")
+		c.Assert(last.Get("Content-Type"), Equals, "text/plain")
+		c.Assert(last.Get("Cache-Control"), Equals, "no-cache")
+	}
+}
+
+// Test configuring the number of parts uploaded in parallel.
+func (s *TestSuite) TestMultipartThreads(c *C) {
+	conf := testConfig("http://localhost:9000/bucket/object", "S3v4")
+	conf.MultipartThreads = -1
+	_, err := S3New(conf)
+	c.Assert(err, NotNil)
+
+	testCases := []struct {
+		config  int
+		call    int
+		threads uint
+		invalid bool
+	}{
+		{0, 0, defaultMultipartThreadsNum, false},
+		{1, 0, 1, false},
+		{16, 0, 16, false},
+		{16, 2, 2, false},
+		{0, 1, 1, false},
+		{4, -1, 0, true},
+	}
+	for i, testCase := range testCases {
+		conf.MultipartThreads = testCase.config
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		ctx := context.Background()
+		if testCase.call != 0 {
+			ctx = WithMultipartThreads(ctx, testCase.call)
+		}
+		threads, err := clnt.(*S3Client).putThreads(ctx)
+		if testCase.invalid {
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			_, err = clnt.Put(ctx, strings.NewReader("data"), 4, nil, nil, nil, false, false, "")
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(threads, Equals, testCase.threads, Commentf("test %d", i+1))
+	}
+}
+
+// Test webhook notification after upload.
+func (s *TestSuite) TestPutWithWebhook(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	server := httptest.NewServer(object)
+	defer server.Close()
+
+	var notification webhookNotification
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&notification)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	n, webhookErr, err := s3c.PutWithWebhook(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, webhook.URL, 5*time.Second)
+	c.Assert(err, IsNil)
+	c.Assert(webhookErr, IsNil)
+	c.Assert(n, Equals, int64(len(object.data)))
+	c.Assert(notification.Bucket, Equals, "bucket")
+	c.Assert(notification.Key, Equals, "object")
+	c.Assert(notification.ETag, Equals, "9af2f8218b150c351ad802c6f3d66abe")
+	c.Assert(notification.Size, Equals, int64(len(object.data)))
+
+	// A failing webhook must not hide the successful upload.
+	webhook.Close()
+	n, webhookErr, err = s3c.PutWithWebhook(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, webhook.URL, time.Second)
+	c.Assert(err, IsNil)
+	c.Assert(errors.As(webhookErr, &WebhookFailed{}), Equals, true)
+	c.Assert(n, Equals, int64(len(object.data)))
+}
+
+// TestSortNotificationConfigs - tests notification configs are sorted
+// by ARN and then by ID.
+func (s *TestSuite) TestSortNotificationConfigs(c *C) {
+	configs := []NotificationConfig{
+		{ID: "2", Arn: "arn:minio:sqs::1:webhook", Type: notificationTypeQueue},
+		{ID: "1", Arn: "arn:minio:sqs::1:webhook", Type: notificationTypeQueue},
+		{ID: "3", Arn: "arn:minio:sns::1:topic", Type: notificationTypeTopic},
+		{ID: "0", Arn: "arn:minio:lambda::1:function", Type: notificationTypeLambda},
+	}
+	sortNotificationConfigs(configs)
+	var ids []string
+	for _, config := range configs {
+		ids = append(ids, config.ID)
+	}
+	c.Assert(ids, DeepEquals, []string{"0", "3", "1", "2"})
+}
+
+// Test presigned HEAD object url.
+func (s *TestSuite) TestPresignedHeadObject(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	server := httptest.NewServer(object)
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	presignedURL, err := s3c.PresignedHeadObject(time.Hour)
+	c.Assert(err, IsNil)
+	u, e := url.Parse(presignedURL)
+	c.Assert(e, IsNil)
+	c.Assert(u.Scheme, Equals, "http")
+	c.Assert(u.Path, Equals, object.resource)
+	c.Assert(u.Query().Get("X-Amz-Signature"), Not(Equals), "")
+
+	resp, e := http.Head(presignedURL)
+	c.Assert(e, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.ContentLength, Equals, int64(len(object.data)))
+}
+
+// stallHandler never answers until the request is canceled or released.
+type stallHandler struct {
+	release chan struct{}
+}
+
+func (h stallHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+	case <-h.release:
+	}
+}
+
+// Test canceling a context aborts in-flight operations.
+func (s *TestSuite) TestContextCancel(c *C) {
+	stall := stallHandler{release: make(chan struct{})}
+	server := httptest.NewServer(stall)
+	defer server.Close()
+	defer close(stall.release)
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = clnt.Stat(ctx, false, false, nil)
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = clnt.MakeBucket(ctx, "us-east-1", false, false)
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+}
+
+// Test the context clients of a host are reused across calls and
+// clients, looking up the region of a bucket once.
+func (s *TestSuite) TestContextAPIReuse(c *C) {
+	var locations int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			atomic.AddInt32(&locations, 1)
+			w.Write([]byte("<LocationConstraint xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\">us-west-2</LocationConstraint>"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/object", "S3v4")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, release := s3c.contextAPI(ctx, "bucket")
+	c.Assert(api, Not(Equals), s3c.api)
+	release()
+
+	// Clients in use aren't handed out twice.
+	again, releaseAgain := s3c.contextAPI(ctx, "bucket")
+	c.Assert(again, Equals, api)
+	other, releaseOther := newTestS3Client(c, server.URL+"/bucket/other", "S3v4").contextAPI(ctx, "bucket")
+	c.Assert(other, Not(Equals), api)
+	releaseOther()
+	releaseAgain()
+	c.Assert(atomic.LoadInt32(&locations), Equals, int32(1))
+
+	// Nothing to cancel, the client itself is used.
+	api, release = s3c.contextAPI(context.Background(), "bucket")
+	c.Assert(api, Equals, s3c.api)
+	release()
+}
+
+// Configs whose fields only differ in where they are split get their
+// own clients.
+func (s *TestSuite) TestClientCacheKey(c *C) {
+	conf1 := testConfig("http://localhost:9000/bucket", "S3v4")
+	conf1.AccessKey, conf1.SecretKey = "access", "keysecret"
+	conf2 := testConfig("http://localhost:9000/bucket", "S3v4")
+	conf2.AccessKey, conf2.SecretKey = "accesskey", "secret"
+
+	clnt1, err := S3New(conf1)
+	c.Assert(err, IsNil)
+	clnt2, err := S3New(conf2)
+	c.Assert(err, IsNil)
+	c.Assert(clnt1.(*S3Client).api == clnt2.(*S3Client).api, Equals, false)
+
+	clnt3, err := S3New(conf1)
+	c.Assert(err, IsNil)
+	c.Assert(clnt1.(*S3Client).api == clnt3.(*S3Client).api, Equals, true)
+}
+
+// Test uploading a stream of unknown size.
+func (s *TestSuite) TestPutUnknownSize(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RawQuery)
+		object.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	// Unknown size requires multipart.
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), -1, map[string]string{}, nil, nil, false, true, "")
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(UnknownSizeWithoutMultipart)
+	c.Assert(ok, Equals, true)
+	c.Assert(requests, HasLen, 0)
+
+	n, err := clnt.Put(context.Background(), bytes.NewReader(object.data), -1, map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(object.data)))
+
+	var initiated bool
+	for _, request := range requests {
+		if request == "POST uploads=" {
+			initiated = true
+		}
+	}
+	c.Assert(initiated, Equals, true)
+}
+
+// policyHandler serves and stores a bucket policy.
+type policyHandler struct {
+	policy *string
+}
+
+func (h policyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if _, ok := query["policy"]; !ok || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		if *h.policy == "" {
+			response := []byte("<Error><Code>NoSuchBucketPolicy</Code><Message>The bucket policy does not exist</Message></Error>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(response)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(*h.policy)))
+		w.Write([]byte(*h.policy))
+	case "PUT":
+		var buffer bytes.Buffer
+		if _, e := io.Copy(&buffer, r.Body); e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		*h.policy = buffer.String()
+		w.WriteHeader(http.StatusNoContent)
+	case "DELETE":
+		*h.policy = ""
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Test granting and revoking anonymous read on a prefix.
+func (s *TestSuite) TestPrefixReadPolicy(c *C) {
+	var bucketPolicy string
+	server := httptest.NewServer(policyHandler{policy: &bucketPolicy})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket", "S3v4")
+
+	for _, prefix := range []string{"/public", "public/*", "pub?ic", "public\n"} {
+		c.Assert(s3c.GrantPrefixRead(prefix), NotNil)
 	}
+	c.Assert(bucketPolicy, Equals, "")
+
+	c.Assert(s3c.GrantPrefixRead("public/"), IsNil)
+	c.Assert(s3c.GrantPrefixRead("shared/"), IsNil)
+	c.Assert(strings.Contains(bucketPolicy, "arn:aws:s3:::bucket/public/*"), Equals, true)
+	c.Assert(strings.Contains(bucketPolicy, "arn:aws:s3:::bucket/shared/*"), Equals, true)
+	c.Assert(strings.Contains(bucketPolicy, "s3:GetObject"), Equals, true)
+
+	c.Assert(s3c.RevokePrefixRead("public/"), IsNil)
+	c.Assert(strings.Contains(bucketPolicy, "arn:aws:s3:::bucket/public/*"), Equals, false)
+	c.Assert(strings.Contains(bucketPolicy, "arn:aws:s3:::bucket/shared/*"), Equals, true)
+
+	c.Assert(s3c.RevokePrefixRead("shared/"), IsNil)
+	c.Assert(strings.Contains(bucketPolicy, "arn:aws:s3:::bucket/shared/*"), Equals, false)
+}
+
+// Test removing a custom bucket policy.
+func (s *TestSuite) TestDeleteBucketPolicy(c *C) {
+	bucketPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789012:root"]},` +
+		`"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::bucket/*"],"Condition":{"IpAddress":{"aws:SourceIp":"192.0.2.0/24"}}}]}`
+	server := httptest.NewServer(policyHandler{policy: &bucketPolicy})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	c.Assert(clnt.(*S3Client).DeleteBucketPolicy(), IsNil)
+	c.Assert(bucketPolicy, Equals, "")
+}
+
+// headerStoreHandler stores the headers of an uploaded object and
+// returns them on stat.
+type headerStoreHandler struct {
+	resource string
+	headers  http.Header
+}
+
+func (h *headerStoreHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, location := r.URL.Query()["location"]
+	switch {
+	case r.Method == "GET" && location:
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	case r.Method == "PUT" && r.URL.Path == h.resource:
+		h.headers = r.Header.Clone()
+		io.Copy(ioutil.Discard, r.Body)
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "HEAD" && r.URL.Path == h.resource && h.headers != nil:
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.Header().Set("Expires", h.headers.Get("Expires"))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Test Put with standard and raw request headers.
+func (s *TestSuite) TestPutHeaders(c *C) {
+	handler := &headerStoreHandler{resource: "/bucket/object"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := testConfig(server.URL+handler.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	data := []byte("Hello, World")
+	_, err = clnt.Put(context.Background(), bytes.NewReader(data), int64(len(data)), map[string]string{
+		"Expires": "not a date",
+	}, nil, nil, false, false, "")
+	c.Assert(err, NotNil)
+
+	ctx := withRequestHeaders(context.Background(), http.Header{
+		"X-Custom-Header": []string{"custom"},
+	})
+	_, err = clnt.Put(ctx, bytes.NewReader(data), int64(len(data)), map[string]string{
+		"Expires":                         "2030-01-02T03:04:05Z",
+		"X-Amz-Website-Redirect-Location": "/index.html",
+	}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(handler.headers.Get("Expires"), Equals, "Wed, 02 Jan 2030 03:04:05 GMT")
+	c.Assert(handler.headers.Get("X-Amz-Meta-Expires"), Equals, "")
+	c.Assert(handler.headers.Get("X-Amz-Website-Redirect-Location"), Equals, "/index.html")
+	c.Assert(handler.headers.Get("X-Amz-Meta-X-Amz-Website-Redirect-Location"), Equals, "")
+	c.Assert(handler.headers.Get("X-Custom-Header"), Equals, "custom")
+
+	content, err := clnt.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Expires.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)), Equals, true)
+}
+
+// Test overriding the TLS server name.
+func (s *TestSuite) TestTLSServerName(c *C) {
+	testCases := []struct {
+		serverName string
+	}{
+		{""},
+		{"backend.example.com"},
+	}
+	for _, testCase := range testCases {
+		conf := testConfig("https://192.168.1.10:9000/bucket", "S3v4")
+		conf.TLSServerName = testCase.serverName
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		tr, ok := clnt.(*S3Client).requestIDs.Transport.(*http.Transport)
+		c.Assert(ok, Equals, true)
+		c.Assert(tr.TLSClientConfig, NotNil)
+		c.Assert(tr.TLSClientConfig.ServerName, Equals, testCase.serverName)
+	}
+}
+
+// Test bounding the TLS versions and cipher suites of connections.
+func (s *TestSuite) TestTLSVersions(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	server := httptest.NewUnstartedServer(object)
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	testCases := []struct {
+		min, max uint16
+		suites   []uint16
+		success  bool
+	}{
+		{0, 0, nil, true},
+		{tls.VersionTLS12, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, true},
+		// The server doesn't support TLS 1.3.
+		{tls.VersionTLS13, 0, nil, false},
+		// Nor the suite, the certificate of the server is RSA.
+		{0, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, false},
+	}
+	for i, testCase := range testCases {
+		conf := testConfig(server.URL+object.resource, "S3v4")
+		conf.Insecure = true
+		conf.TLSMinVersion, conf.TLSMaxVersion, conf.TLSCipherSuites = testCase.min, testCase.max, testCase.suites
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+
+		tr, ok := clnt.(*S3Client).requestIDs.Transport.(*http.Transport)
+		c.Assert(ok, Equals, true)
+		min := testCase.min
+		if min == 0 {
+			min = tls.VersionTLS12
+		}
+		c.Assert(tr.TLSClientConfig.MinVersion, Equals, min, Commentf("test %d", i+1))
+		c.Assert(tr.TLSClientConfig.MaxVersion, Equals, testCase.max, Commentf("test %d", i+1))
+		c.Assert(tr.TLSClientConfig.CipherSuites, DeepEquals, testCase.suites, Commentf("test %d", i+1))
+
+		// Handshake with the configuration of the transport, minio-go
+		// would retry failed requests.
+		conn, e := tls.Dial("tcp", server.Listener.Addr().String(), tr.TLSClientConfig)
+		c.Assert(e == nil, Equals, testCase.success, Commentf("test %d: %v", i+1, e))
+		if e == nil {
+			conn.Close()
+		}
+	}
+}
+
+// Test connecting to a server listening on a Unix socket.
+func (s *TestSuite) TestUnixSocket(c *C) {
+	dir, e := ioutil.TempDir("", "mc-socket-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "minio.sock")
+	listener, e := net.Listen("unix", socket)
+	c.Assert(e, IsNil)
+
+	var stored []byte
+	var hosts []string
+	handler := memoryObjectHandler{resource: "/bucket/object", data: &stored}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		handler.ServeHTTP(w, r)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	conf := testConfig("http://localhost/bucket/object", "S3v2")
+	conf.UnixSocket = socket
+	conf.ProxyByHost = map[string]string{"localhost": "http://127.0.0.1:1"}
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	data := "Hello, World"
+	_, err = clnt.Put(context.Background(), strings.NewReader(data), int64(len(data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	reader, err := clnt.Get(context.Background(), nil)
+	c.Assert(err, IsNil)
+	got, e := ioutil.ReadAll(reader)
+	c.Assert(e, IsNil)
+	reader.Close()
+	c.Assert(string(got), Equals, data)
+	c.Assert(len(hosts) > 0, Equals, true)
+	for _, host := range hosts {
+		c.Assert(host, Equals, "localhost")
+	}
+}
+
+// storageClassHandler lists objects of different storage classes.
+type storageClassHandler struct{}
+
+func (h storageClassHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method != "GET" || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	response := []byte("<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">" +
+		"<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>hot</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>22061</Size><StorageClass>STANDARD</StorageClass></Contents>" +
+		"<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>cold</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>22061</Size><StorageClass>GLACIER</StorageClass></Contents>" +
+		"<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>")
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write(response)
+}
+
+// Test listing objects of a given storage class.
+func (s *TestSuite) TestListStorageClass(c *C) {
+	server := httptest.NewServer(storageClassHandler{})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	testCases := []struct {
+		storageClass string
+		keys         []string
+	}{
+		{"GLACIER", []string{"/bucket/cold"}},
+		{"standard", []string{"/bucket/hot"}},
+		{"DEEP_ARCHIVE", nil},
+	}
+	for _, testCase := range testCases {
+		for _, isRecursive := range []bool{true, false} {
+			var keys []string
+			for content := range s3c.ListStorageClass(isRecursive, false, false, testCase.storageClass) {
+				c.Assert(content.Err, IsNil)
+				keys = append(keys, content.URL.Path)
+			}
+			c.Assert(keys, DeepEquals, testCase.keys)
+		}
+	}
+}
+
+// etagHandler serves an object honoring If-None-Match and counts
+// how many times the body was sent.
+type etagHandler struct {
+	resource string
+	etag     string
+	data     []byte
+	served   *int
+}
+
+func (h etagHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method != "GET" || r.URL.Path != h.resource {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("ETag", "\""+h.etag+"\"")
+	if r.Header.Get("If-None-Match") == "\""+h.etag+"\"" {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	*h.served++
+	w.Header().Set("Content-Length", strconv.Itoa(len(h.data)))
+	w.Write(h.data)
+}
+
+// Test conditional get with an ETag cache.
+func (s *TestSuite) TestGetCached(c *C) {
+	var served int
+	handler := etagHandler{
+		resource: "/bucket/object",
+		etag:     "9af2f8218b150c351ad802c6f3d66abe",
+		data:     []byte("Hello, World"),
+		served:   &served,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+handler.resource, "S3v4")
+
+	_, _, err := s3c.GetCached(nil, nil)
+	c.Assert(err, NotNil)
+
+	cache := &ETagCache{}
+	for i, expectCached := range []bool{false, true, true} {
+		reader, cached, err := s3c.GetCached(cache, nil)
+		c.Assert(err, IsNil)
+		c.Assert(cached, Equals, expectCached)
+		data, e := ioutil.ReadAll(reader)
+		c.Assert(e, IsNil)
+		reader.Close()
+		c.Assert(data, DeepEquals, handler.data, Commentf("call %d", i))
+		c.Assert(cache.ETag, Equals, handler.etag)
+	}
+	c.Assert(served, Equals, 1)
+}
+
+// Test storage class validation.
+func (s *TestSuite) TestNormalizeStorageClass(c *C) {
+	defer func(classes []string) { validStorageClasses = classes }(validStorageClasses)
+	registerStorageClass("minio_custom")
+
+	testCases := []struct {
+		storageClass string
+		expected     string
+		valid        bool
+	}{
+		{"", "", true},
+		{"STANDARD", "STANDARD", true},
+		{"standard_ia", "STANDARD_IA", true},
+		{"DEEP_ARCHIVE", "DEEP_ARCHIVE", true},
+		{"MINIO_CUSTOM", "MINIO_CUSTOM", true},
+		{"STANDARD-IA", "", false},
+		{"GLACIERS", "", false},
+		{"passthrough:GLACIER_IR", "GLACIER_IR", true},
+	}
+	for _, testCase := range testCases {
+		storageClass, err := normalizeStorageClass(testCase.storageClass)
+		if !testCase.valid {
+			c.Assert(err, NotNil, Commentf("%s", testCase.storageClass))
+			_, ok := err.ToGoError().(InvalidStorageClass)
+			c.Assert(ok, Equals, true)
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("%s", testCase.storageClass))
+		c.Assert(storageClass, Equals, testCase.expected)
+	}
+}
+
+// Test Put fails fast on an invalid storage class.
+func (s *TestSuite) TestPutInvalidStorageClass(c *C) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	data := []byte("Hello, World")
+	_, err = clnt.Put(context.Background(), bytes.NewReader(data), int64(len(data)), map[string]string{
+		"X-Amz-Storage-Class": "STANDARD-IA",
+	}, nil, nil, false, false, "")
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(InvalidStorageClass)
+	c.Assert(ok, Equals, true)
+	c.Assert(requests, Equals, 0)
+}
+
+// Test metadata keys are handled case insensitively.
+func (s *TestSuite) TestCanonicalizeMetadata(c *C) {
+	testCases := []struct {
+		metadata map[string]string
+		expected map[string]string
+	}{
+		{nil, map[string]string{}},
+		{
+			map[string]string{"content-type": "text/plain", "Content-Type": "text/html"},
+			map[string]string{"Content-Type": "text/html"},
+		},
+		{
+			map[string]string{"x-amz-meta-foo": "a", "X-AMZ-META-FOO": "b"},
+			map[string]string{"X-Amz-Meta-Foo": "b"},
+		},
+		{
+			map[string]string{"cache-control": "no-cache", "x-amz-storage-class": "STANDARD"},
+			map[string]string{"Cache-Control": "no-cache", "X-Amz-Storage-Class": "STANDARD"},
+		},
+	}
+	for _, testCase := range testCases {
+		c.Assert(canonicalizeMetadata(testCase.metadata), DeepEquals, testCase.expected)
+	}
+}
+
+// copyHandler answers stat and server side copy requests, the source
+// having the given headers.
+type copyHandler struct {
+	resource string
+	header   http.Header
+}
+
+func (h copyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET":
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	case r.Method == "HEAD" && r.URL.Path == h.resource:
+		for k, v := range h.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", "12")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PUT" && r.Header.Get("X-Amz-Copy-Source") != "":
+		response := []byte("<CopyObjectResult><LastModified>2015-05-21T18:24:21.097Z</LastModified><ETag>\"9af2f8218b150c351ad802c6f3d66abe\"</ETag></CopyObjectResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Test mixed case metadata on upload and copy.
+func (s *TestSuite) TestMixedCaseMetadata(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{
+		"content-type":  "text/plain",
+		"Content-Type":  "text/html",
+		"cache-control": "no-cache",
+	}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	last := headers[len(headers)-1]
+	c.Assert(last.Get("Content-Type"), Equals, "text/html")
+	c.Assert(last.Get("Cache-Control"), Equals, "no-cache")
+	c.Assert(last.Get("X-Amz-Meta-Content-Type"), Equals, "")
+	c.Assert(last.Get("X-Amz-Meta-Cache-Control"), Equals, "")
+
+	// Copy reuses metadata from Stat, keys may be lower cased.
+	copyServer := httptest.NewServer(recordHandler{handler: copyHandler{resource: "/bucket/source"}, headers: &headers})
+	defer copyServer.Close()
+	conf.HostURL = copyServer.URL + "/bucket/target"
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+
+	headers = nil
+	err = clnt.Copy(context.Background(), "/bucket/source", int64(len(object.data)), nil, nil, nil, map[string]string{
+		"content-type":        "text/plain",
+		"x-amz-storage-class": "standard",
+	}, true, "")
+	c.Assert(err, IsNil)
+	last = headers[len(headers)-1]
+	c.Assert(last.Get("X-Amz-Copy-Source"), Not(Equals), "")
+	c.Assert(last.Get("Content-Type"), Equals, "text/plain")
+	c.Assert(last.Get("X-Amz-Storage-Class"), Equals, "STANDARD")
+	c.Assert(last.Get("X-Amz-Meta-Content-Type"), Equals, "")
+}
+
+// Test content-type is guessed from the object extension.
+func (s *TestSuite) TestPutContentType(c *C) {
+	testCases := []struct {
+		object      string
+		metadata    map[string]string
+		contentType string
+	}{
+		{"/bucket/index.html", nil, "text/html"},
+		{"/bucket/style.css", nil, "text/css"},
+		{"/bucket/data.json", nil, "application/json"},
+		// No extension, detected from the content.
+		{"/bucket/object", nil, "text/plain"},
+		{"/bucket/index.html", map[string]string{"Content-Type": "text/plain"}, "text/plain"},
+	}
+	for _, testCase := range testCases {
+		object := objectHandler(objectHandler{
+			resource: testCase.object,
+			data:     []byte("Hello, World"),
+		})
+		var headers []http.Header
+		server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+
+		conf := testConfig(server.URL+object.resource, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+
+		_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), testCase.metadata, nil, nil, false, false, "")
+		c.Assert(err, IsNil)
+		contentType := headers[len(headers)-1].Get("Content-Type")
+		c.Assert(strings.HasPrefix(contentType, testCase.contentType), Equals, true, Commentf("%s: %s", testCase.object, contentType))
+		server.Close()
+	}
+}
+
+// Test presigning arbitrary methods.
+func (s *TestSuite) TestPresign(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	server := httptest.NewServer(object)
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+object.resource, "S3v4")
+
+	_, err := s3c.Presign("PATCH", time.Hour, nil)
+	c.Assert(err, NotNil)
+
+	for _, method := range []string{"GET", "PUT", "HEAD", "delete", "POST"} {
+		presignedURL, err := s3c.Presign(method, time.Hour, url.Values{"versionId": []string{"1"}})
+		c.Assert(err, IsNil)
+		u, e := url.Parse(presignedURL)
+		c.Assert(e, IsNil)
+		c.Assert(u.Path, Equals, "/bucket/object")
+		c.Assert(u.Query().Get("versionId"), Equals, "1")
+		c.Assert(u.Query().Get("X-Amz-Expires"), Equals, "3600")
+		c.Assert(u.Query().Get("X-Amz-Signature"), Not(Equals), "")
+	}
+}
+
+// Test traced presigned URLs don't show signatures or access keys.
+func (s *TestSuite) TestRedactPresignedURL(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	server := httptest.NewServer(object)
+	defer server.Close()
+
+	for _, signature := range []string{"S3v4", "S3v2"} {
+		s3c := newTestS3Client(c, server.URL+object.resource, signature)
+		presignedURL, err := s3c.Presign("GET", time.Hour, url.Values{"versionId": []string{"1"}})
+		c.Assert(err, IsNil)
+		u, e := url.Parse(presignedURL)
+		c.Assert(e, IsNil)
+
+		redacted, e := url.Parse(redactPresignedURL(u))
+		c.Assert(e, IsNil)
+		c.Assert(redacted.Path, Equals, "/bucket/object")
+		c.Assert(redacted.Query().Get("versionId"), Equals, "1")
+		c.Assert(strings.Contains(redacted.String(), "WLGDGYAQYIGI833EV05A"), Equals, false)
+		for field, values := range u.Query() {
+			if isPresignSecretField(field) {
+				c.Assert(redacted.Query().Get(field), Equals, "**REDACTED**")
+			} else {
+				c.Assert(redacted.Query()[field], DeepEquals, values)
+			}
+		}
+	}
+}
+
+// Test default encryption of an alias.
+func (s *TestSuite) TestDefaultSSE(c *C) {
+	sseCKey := "32byteslongsecretkeymustbegiven1"
+	testCases := []struct {
+		sse, kmsKeyID, sseCKey string
+		sseType                encrypt.Type
+		valid                  bool
+	}{
+		{"", "", "", "", true},
+		{"SSE-S3", "", "", encrypt.S3, true},
+		{"sse-kms", "my-key", "", encrypt.KMS, true},
+		{"SSE-KMS", "", "", "", false},
+		{"SSE-C", "", sseCKey, encrypt.SSEC, true},
+		{"SSE-C", "", "short", "", false},
+		{"AES", "", "", "", false},
+	}
+	for _, testCase := range testCases {
+		conf := &Config{
+			DefaultSSE:         testCase.sse,
+			DefaultSSEKMSKeyID: testCase.kmsKeyID,
+			DefaultSSECKey:     testCase.sseCKey,
+		}
+		sse, err := newDefaultSSE(conf)
+		if !testCase.valid {
+			c.Assert(err, NotNil, Commentf("%s", testCase.sse))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("%s", testCase.sse))
+		if testCase.sseType == "" {
+			c.Assert(sse, IsNil)
+			continue
+		}
+		c.Assert(sse.Type(), Equals, testCase.sseType)
+	}
+
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	conf.DefaultSSE = "SSE-KMS"
+	conf.DefaultSSEKMSKeyID = "my-key"
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	// SSE-KMS is applied on upload but never sent on stat.
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), nil, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	last := headers[len(headers)-1]
+	c.Assert(last.Get("X-Amz-Server-Side-Encryption"), Equals, "aws:kms")
+	c.Assert(last.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"), Equals, "my-key")
+	_, err = clnt.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(headers[len(headers)-1].Get("X-Amz-Server-Side-Encryption"), Equals, "")
+
+	// Explicit encryption overrides the default.
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), nil, nil, encrypt.NewSSE(), false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(headers[len(headers)-1].Get("X-Amz-Server-Side-Encryption"), Equals, "AES256")
+
+	// SSE-C keys are supplied on stat.
+	conf.DefaultSSE = "SSE-C"
+	conf.DefaultSSECKey = sseCKey
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	last = headers[len(headers)-1]
+	c.Assert(last.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm"), Equals, "AES256")
+	c.Assert(last.Get("X-Amz-Server-Side-Encryption-Customer-Key"), Not(Equals), "")
+}
+
+// Test content-type is detected from the content of extensionless objects.
+func (s *TestSuite) TestPutDetectContentType(c *C) {
+	var stored []byte
+	object := memoryObjectHandler{resource: "/bucket/image", data: &stored}
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v2")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	// 1x1 transparent PNG.
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89" +
+		"\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+	n, err := clnt.Put(context.Background(), bytes.NewReader(png), int64(len(png)), nil, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(png)))
+	c.Assert(headers[len(headers)-1].Get("Content-Type"), Equals, "image/png")
+	c.Assert(stored, DeepEquals, png)
+
+	// Files are sniffed in place.
+	f, e := ioutil.TempFile("", "mc-sniff")
+	c.Assert(e, IsNil)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, e = f.Write(png)
+	c.Assert(e, IsNil)
+	_, e = f.Seek(0, io.SeekStart)
+	c.Assert(e, IsNil)
+	contentType, reader, e := sniffContentType(f)
+	c.Assert(e, IsNil)
+	c.Assert(contentType, Equals, "image/png")
+	c.Assert(reader, Equals, io.Reader(f))
+	data, e := ioutil.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(data, DeepEquals, png)
+
+	// So are other seekable readers, from where they were.
+	seekable := bytes.NewReader(png)
+	_, e = seekable.Seek(8, io.SeekStart)
+	c.Assert(e, IsNil)
+	_, reader, e = sniffContentType(seekable)
+	c.Assert(e, IsNil)
+	c.Assert(reader, Equals, io.Reader(seekable))
+	c.Assert(seekable.Len(), Equals, len(png)-8)
+
+	// Invalid uploads fail before the content is read.
+	unread := bytes.NewReader(png)
+	_, err = clnt.Put(context.Background(), struct{ io.Reader }{unread}, int64(len(png)), map[string]string{
+		"X-Amz-Storage-Class": "UNKNOWN",
+	}, nil, nil, false, false, "")
+	c.Assert(err, NotNil)
+	c.Assert(unread.Len(), Equals, len(png))
+}
+
+// multipartCopyHandler stalls server side multipart copies until
+// they are canceled and records the IDs of aborted uploads.
+type multipartCopyHandler struct {
+	aborted chan string
+}
+
+func (h multipartCopyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	_, uploadID := query["uploadId"]
+	var response []byte
+	switch {
+	case r.Method == "GET" && location:
+		response = []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+	case r.Method == "HEAD" && r.URL.Path == "/bucket/source":
+		// Large enough to be copied using multipart.
+		w.Header().Set("Content-Length", strconv.FormatInt(6*1024*1024*1024, 10))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+		return
+	case r.Method == "POST" && uploads:
+		response = []byte("<InitiateMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>target</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == "PUT" && uploadID:
+		// Part copy, never completes.
+		<-r.Context().Done()
+		return
+	case r.Method == "GET" && uploads:
+		// Another upload of the same object is in progress.
+		response = []byte("<ListMultipartUploadsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><KeyMarker/><UploadIdMarker/><NextKeyMarker/><NextUploadIdMarker/><MaxUploads>1000</MaxUploads><IsTruncated>false</IsTruncated>" +
+			"<Upload><Key>target</Key><UploadId>other</UploadId><Initiated>2015-05-21T18:24:21.097Z</Initiated></Upload>" +
+			"<Upload><Key>target</Key><UploadId>upload</UploadId><Initiated>2015-05-21T18:24:21.097Z</Initiated></Upload><Prefix>target</Prefix></ListMultipartUploadsResult>")
+	case r.Method == "DELETE" && uploadID:
+		h.aborted <- query.Get("uploadId")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write(response)
+}
+
+// Test canceling a multipart copy aborts its upload only.
+func (s *TestSuite) TestCopyCancel(c *C) {
+	aborted := make(chan string, 10)
+	server := httptest.NewServer(multipartCopyHandler{aborted: aborted})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/target", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = clnt.Copy(ctx, "/bucket/source", 6*1024*1024*1024, nil, nil, nil, map[string]string{}, false, "")
+	c.Assert(err, NotNil)
+	c.Assert(err.ToGoError(), Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+	close(aborted)
+	var ids []string
+	for id := range aborted {
+		ids = append(ids, id)
+	}
+	c.Assert(ids, DeepEquals, []string{"upload"})
+}
+
+// composeHandler serves objects of the given sizes and records the
+// source ranges of part copies into bucket/target.
+type composeHandler struct {
+	sizes     map[string]int64
+	ranges    *[]string
+	completed *bool
+}
+
+func (h composeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	_, uploadID := query["uploadId"]
+	var response []byte
+	switch {
+	case r.Method == "GET" && location:
+		response = []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+	case r.Method == "HEAD":
+		size, ok := h.sizes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+		return
+	case r.Method == "POST" && uploads && r.URL.Path == "/bucket/target":
+		response = []byte("<InitiateMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>target</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == "PUT" && uploadID:
+		*h.ranges = append(*h.ranges, r.Header.Get("X-Amz-Copy-Source")+" "+r.Header.Get("X-Amz-Copy-Source-Range"))
+		response = []byte("<CopyPartResult><LastModified>2015-05-21T18:24:21.097Z</LastModified><ETag>\"9af2f8218b150c351ad802c6f3d66abe\"</ETag></CopyPartResult>")
+	case r.Method == "POST" && uploadID:
+		*h.completed = true
+		response = []byte("<CompleteMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>target</Key><ETag>\"3858f62230ac3c915f300c664312c11f-2\"</ETag></CompleteMultipartUploadResult>")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write(response)
+}
+
+// Test composing an object larger than 5GiB with a multipart copy.
+func (s *TestSuite) TestMultipartCopy(c *C) {
+	const gib = 1024 * 1024 * 1024
+	var ranges []string
+	var completed bool
+	server := httptest.NewServer(composeHandler{
+		sizes: map[string]int64{
+			"/bucket/large": 10 * gib,
+			"/bucket/part1": 5 * gib,
+			"/bucket/part2": 5 * gib,
+		},
+		ranges:    &ranges,
+		completed: &completed,
+	})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/target", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	testCases := []struct {
+		sources []SourceInfo
+		ranges  []string
+		success bool
+	}{
+		// Explicit ranges of a single object.
+		{
+			[]SourceInfo{
+				{Bucket: "bucket", Object: "large", Length: 5 * gib},
+				{Bucket: "bucket", Object: "large", Start: 5 * gib, Length: 5 * gib},
+			},
+			[]string{"/bucket/large bytes=0-5368709119", "/bucket/large bytes=5368709120-10737418239"},
+			true,
+		},
+		// Ranges computed from the size of the objects.
+		{
+			[]SourceInfo{
+				{Bucket: "bucket", Object: "part1"},
+				{Bucket: "bucket", Object: "part2"},
+			},
+			[]string{"/bucket/part1 bytes=0-5368709119", "/bucket/part2 bytes=0-5368709119"},
+			true,
+		},
+		// Parts but the last need 5MiB.
+		{
+			[]SourceInfo{
+				{Bucket: "bucket", Object: "part1", Length: 1024 * 1024},
+				{Bucket: "bucket", Object: "part2"},
+			},
+			nil,
+			false,
+		},
+		// Range after the end of the object.
+		{
+			[]SourceInfo{{Bucket: "bucket", Object: "part1", Start: 6 * gib}},
+			nil,
+			false,
+		},
+		// Missing object.
+		{
+			[]SourceInfo{{Bucket: "bucket", Object: "missing"}},
+			nil,
+			false,
+		},
+		{nil, nil, false},
+	}
+	for i, testCase := range testCases {
+		ranges = nil
+		completed = false
+		err = s3c.MultipartCopy(context.Background(), testCase.sources, nil)
+		if !testCase.success {
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			c.Assert(ranges, HasLen, 0, Commentf("test %d", i+1))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(ranges, DeepEquals, testCase.ranges, Commentf("test %d", i+1))
+		c.Assert(completed, Equals, true, Commentf("test %d", i+1))
+	}
+
+	// Sources need an object name.
+	ranges = nil
+	err = s3c.MultipartCopy(context.Background(), []SourceInfo{{Bucket: "bucket", Length: 5 * gib}}, nil)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ObjectNameEmpty)
+	c.Assert(ok, Equals, true)
+
+	// Canceled copies stop before any part is copied.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = s3c.MultipartCopy(ctx, []SourceInfo{{Bucket: "bucket", Object: "part1"}}, nil)
+	c.Assert(err, NotNil)
+	c.Assert(ranges, HasLen, 0)
+}
+
+// Test uploading gzip compressed objects.
+func (s *TestSuite) TestPutCompressed(c *C) {
+	var stored []byte
+	object := memoryObjectHandler{resource: "/bucket/data.json", data: &stored}
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+object.resource, "S3v4")
+
+	data := bytes.Repeat([]byte(`{"hello": "world"}`+"\n"), 4096)
+	_, err := s3c.PutCompressed(context.Background(), bytes.NewReader(data), int64(len(data)), nil, nil, nil, 42)
+	c.Assert(err, NotNil)
+
+	n, err := s3c.PutCompressed(context.Background(), bytes.NewReader(data), int64(len(data)), nil, nil, nil, gzip.BestCompression)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(stored)))
+	c.Assert(len(stored) < len(data), Equals, true)
+
+	var encoded bool
+	for _, header := range headers {
+		if header.Get("Content-Encoding") == "gzip" {
+			encoded = true
+			c.Assert(header.Get("Content-Type"), Equals, "application/json")
+		}
+	}
+	c.Assert(encoded, Equals, true)
+
+	reader, err := s3c.Get(context.Background(), nil)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	zr, e := gzip.NewReader(reader)
+	c.Assert(e, IsNil)
+	got, e := ioutil.ReadAll(zr)
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, data)
+}
+
+// pagedListHandler lists keys two at a time with ListObjectsV2.
+type pagedListHandler struct {
+	keys []string
+}
+
+func (h pagedListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method != "GET" || r.URL.Path != "/bucket/" || query.Get("list-type") != "2" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	start := sort.SearchStrings(h.keys, query.Get("start-after"))
+	if start < len(h.keys) && h.keys[start] == query.Get("start-after") {
+		start++
+	}
+	if token := query.Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	end := start + 2
+	if end > len(h.keys) {
+		end = len(h.keys)
+	}
+	response := "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+	for _, key := range h.keys[start:end] {
+		response += "<Contents><ETag>\"259d04a13802ae09c7e41be50ccc6baa\"</ETag><Key>" + key +
+			"</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>"
+	}
+	if end < len(h.keys) {
+		response += "<IsTruncated>true</IsTruncated><NextContinuationToken>" + strconv.Itoa(end) + "</NextContinuationToken>"
+	} else {
+		response += "<IsTruncated>false</IsTruncated>"
+	}
+	response += "<KeyCount>" + strconv.Itoa(end-start) + "</KeyCount><MaxKeys>2</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>"
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test resuming a listing from the cursor reported after each page.
+func (s *TestSuite) TestListResumable(c *C) {
+	server := httptest.NewServer(pagedListHandler{keys: []string{"a", "b", "c", "d", "e"}})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	testCases := []struct {
+		startAfter string
+		keys       []string
+		cursors    []string
+	}{
+		{"", []string{"a", "b", "c", "d", "e"}, []string{"b", "d", "e"}},
+		{"b", []string{"c", "d", "e"}, []string{"d", "e"}},
+		{"e", nil, nil},
+	}
+	for _, testCase := range testCases {
+		var keys, cursors []string
+		onPage := func(lastKey string) {
+			cursors = append(cursors, lastKey)
+		}
+		for content := range s3c.ListResumable(context.Background(), true, testCase.startAfter, onPage) {
+			c.Assert(content.Err, IsNil)
+			c.Assert(content.ETag, Equals, "259d04a13802ae09c7e41be50ccc6baa")
+			keys = append(keys, strings.TrimPrefix(content.URL.Path, "/bucket/"))
+		}
+		c.Assert(keys, DeepEquals, testCase.keys)
+		c.Assert(cursors, DeepEquals, testCase.cursors)
+	}
+}
+
+// partsHandler serves HEAD requests of a multipart object, with or
+// without a part number.
+type partsHandler struct {
+	size  int64
+	parts []int64
+}
+
+func (h partsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method != "HEAD" || r.URL.Path != "/bucket/object" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("ETag", "\"3858f62230ac3c915f300c664312c11f-"+strconv.Itoa(len(h.parts))+"\"")
+	w.Header().Set("Last-Modified", "Thu, 21 May 2015 18:24:21 GMT")
+	size := h.size
+	if part := query.Get("partNumber"); part != "" {
+		n, _ := strconv.Atoi(part)
+		if n < 1 || n > len(h.parts) || h.parts[n-1] < 0 {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		size = h.parts[n-1]
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Test Stat verifying that the parts of multipart objects add up to the
+// object size.
+func (s *TestSuite) TestStatVerifyParts(c *C) {
+	testCases := []struct {
+		parts        []int64
+		inconsistent bool
+	}{
+		{[]int64{5, 5, 2}, false},
+		{[]int64{5, 5, 1}, true},
+		{[]int64{5, -1, 2}, true},
+	}
+	for _, testCase := range testCases {
+		server := httptest.NewServer(partsHandler{size: 12, parts: testCase.parts})
+		s3c := newTestS3Client(c, server.URL+"/bucket/object", "S3v4")
+
+		// Plain Stat doesn't look at the parts.
+		content, err := s3c.Stat(context.Background(), false, false, nil)
+		c.Assert(err, IsNil)
+		c.Assert(content.Size, Equals, int64(12))
+
+		content, err = s3c.Stat(WithVerifyParts(context.Background()), false, false, nil)
+		if testCase.inconsistent {
+			c.Assert(err, NotNil)
+			c.Assert(errors.As(err.ToGoError(), &InconsistentObject{}), Equals, true)
+		} else {
+			c.Assert(err, IsNil)
+			c.Assert(content.Size, Equals, int64(12))
+		}
+		server.Close()
+	}
+}
+
+// Test listing only the objects after a key.
+func (s *TestSuite) TestListStartAfter(c *C) {
+	server := httptest.NewServer(pagedListHandler{keys: []string{"a", "b", "c", "d", "e"}})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	testCases := []struct {
+		recursive  bool
+		startAfter string
+		keys       []string
+	}{
+		{true, "", []string{"a", "b", "c", "d", "e"}},
+		{true, "b", []string{"c", "d", "e"}},
+		{false, "c", []string{"d", "e"}},
+		{true, "e", nil},
+	}
+	for _, testCase := range testCases {
+		var keys []string
+		opts := ListOptions{Recursive: testCase.recursive, StartAfter: testCase.startAfter}
+		for content := range s3c.ListWithOptions(opts) {
+			c.Assert(content.Err, IsNil)
+			c.Assert(content.ETag, Equals, "259d04a13802ae09c7e41be50ccc6baa")
+			keys = append(keys, strings.TrimPrefix(content.URL.Path, "/bucket/"))
+		}
+		c.Assert(keys, DeepEquals, testCase.keys)
+	}
+
+	for content := range s3c.ListWithOptions(ListOptions{Incomplete: true, StartAfter: "a"}) {
+		c.Assert(content.Err, NotNil)
+	}
+}
+
+// discardPartsHandler accepts multipart uploads, discarding the parts
+// and recording their size.
+type discardPartsHandler struct {
+	received *int64
+	mutex    *sync.Mutex
+	parts    *[]int64
+}
+
+func (h discardPartsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	_, uploads := query["uploads"]
+	_, uploadID := query["uploadId"]
+	switch {
+	case r.Method == "POST" && uploads:
+		response := []byte("<InitiateMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	case r.Method == "PUT" && uploadID:
+		n, e := io.Copy(ioutil.Discard, r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt64(h.received, n)
+		h.mutex.Lock()
+		*h.parts = append(*h.parts, n)
+		h.mutex.Unlock()
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "POST" && uploadID:
+		response := []byte("<CompleteMultipartUploadResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>object</Key><ETag>\"3858f62230ac3c915f300c664312c11f-7\"</ETag></CompleteMultipartUploadResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// boundedReader is an endless stream failing reads once more than
+// limit bytes were read ahead of the bytes received by the server.
+type boundedReader struct {
+	read     int64
+	received *int64
+	limit    int64
+}
+
+func (r *boundedReader) Read(p []byte) (int, error) {
+	if r.read+int64(len(p))-atomic.LoadInt64(r.received) > r.limit {
+		return 0, errors.New("stream buffered beyond limit")
+	}
+	for i := range p {
+		p[i] = byte(r.read + int64(i))
+	}
+	r.read += int64(len(p))
+	return len(p), nil
+}
+
+// progressCounter counts the bytes reported as progress.
+type progressCounter struct {
+	n int64
+}
+
+func (p *progressCounter) Read(b []byte) (int, error) {
+	atomic.AddInt64(&p.n, int64(len(b)))
+	return len(b), nil
+}
+
+// Test piping a stream of unknown size with a bounded part buffer.
+func (s *TestSuite) TestPutStreamPartSize(c *C) {
+	const partSize = 16 * 1024 * 1024
+	const size = 100 * 1024 * 1024
+
+	var received int64
+	var parts []int64
+	server := httptest.NewServer(discardPartsHandler{received: &received, mutex: &sync.Mutex{}, parts: &parts})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v2")
+
+	// Out of range part sizes.
+	for _, invalid := range []int64{1024, 6 * 1024 * 1024 * 1024} {
+		conf.StreamPartSize = invalid
+		_, err := S3New(conf)
+		c.Assert(err, NotNil)
+	}
+
+	conf.StreamPartSize = partSize
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	reader := io.LimitReader(&boundedReader{received: &received, limit: partSize}, size)
+	progress := &progressCounter{}
+	n, err := clnt.Put(context.Background(), reader, -1, map[string]string{}, progress, nil, false, false, "")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(size))
+	c.Assert(atomic.LoadInt64(&progress.n), Equals, int64(size))
+	c.Assert(received, Equals, int64(size))
+	c.Assert(parts, HasLen, (size+partSize-1)/partSize)
+	for _, part := range parts {
+		c.Assert(part <= partSize, Equals, true)
+	}
+}
+
+// Test MD5 verification of downloaded objects.
+func (s *TestSuite) TestGetVerified(c *C) {
+	data := []byte("Hello, World")
+	sum := md5.Sum(data)
+	var etag string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.Write(response)
+			return
+		}
+		if r.Method != "GET" || r.URL.Path != "/bucket/object" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", "\""+etag+"\"")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	testCases := []struct {
+		etag     string
+		mismatch bool
+	}{
+		{hex.EncodeToString(sum[:]), false},
+		{"9af2f8218b150c351ad802c6f3d66abe", true},
+		// Multipart ETags are not verified.
+		{"9af2f8218b150c351ad802c6f3d66abe-2", false},
+	}
+	for _, testCase := range testCases {
+		etag = testCase.etag
+		reader, err := s3c.GetVerified(context.Background(), nil)
+		c.Assert(err, IsNil)
+		got, e := ioutil.ReadAll(reader)
+		c.Assert(e, IsNil)
+		c.Assert(got, DeepEquals, data)
+		e = reader.Close()
+		if testCase.mismatch {
+			_, ok := e.(ChecksumMismatch)
+			c.Assert(ok, Equals, true, Commentf("etag %s", testCase.etag))
+		} else {
+			c.Assert(e, IsNil, Commentf("etag %s", testCase.etag))
+		}
+	}
+
+	// Partial reads are not verified.
+	etag = "9af2f8218b150c351ad802c6f3d66abe"
+	reader, err := s3c.GetVerified(context.Background(), nil)
+	c.Assert(err, IsNil)
+	_, e := reader.Read(make([]byte, 1))
+	c.Assert(e, IsNil)
+	c.Assert(reader.Close(), IsNil)
+}
+
+// Test signing requests without sending them.
+func (s *TestSuite) TestSignRequest(c *C) {
+	var lookups int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			atomic.AddInt32(&lookups, 1)
+		}
+		storageClassHandler{}.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	authRegexp := regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=WLGDGYAQYIGI833EV05A/[0-9]{8}/us-east-1/s3/aws4_request, ` +
+		`SignedHeaders=([a-z0-9-]+;)*host(;[a-z0-9-]+)*, Signature=[0-9a-f]{64}$`)
+
+	req, e := http.NewRequest("GET", server.URL+"/bucket/object", nil)
+	c.Assert(e, IsNil)
+	signed, err := s3c.SignRequest(req)
+	c.Assert(err, IsNil)
+	c.Assert(req.Header.Get("Authorization"), Equals, "")
+	c.Assert(authRegexp.MatchString(signed.Header.Get("Authorization")), Equals, true, Commentf("%s", signed.Header.Get("Authorization")))
+	c.Assert(signed.Header.Get("X-Amz-Date"), Not(Equals), "")
+	c.Assert(signed.Header.Get("X-Amz-Content-Sha256"), Equals, "UNSIGNED-PAYLOAD")
+
+	header, err := s3c.SignedHeaders("PUT", "bucket", "object", 10*time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(authRegexp.MatchString(header.Get("Authorization")), Equals, true, Commentf("%s", header.Get("Authorization")))
+	c.Assert(strings.Contains(header.Get("Authorization"), "x-amz-expires"), Equals, true)
+	c.Assert(header.Get("X-Amz-Expires"), Equals, "600")
+	c.Assert(header.Get("Host"), Equals, "bucket."+strings.TrimPrefix(server.URL, "http://"))
+
+	for _, expires := range []time.Duration{0, time.Hour} {
+		_, err = s3c.SignedHeaders("PUT", "bucket", "object", expires)
+		c.Assert(err, NotNil)
+	}
+
+	// The region of the bucket is only looked up once.
+	c.Assert(atomic.LoadInt32(&lookups), Equals, int32(1))
+}
+
+// oddKeysHandler lists keys with leading and consecutive separators
+// and records the keys removed.
+type oddKeysHandler struct {
+	keys    []string
+	removed *[]string
+}
+
+func (h oddKeysHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if _, ok := query["delete"]; ok && r.Method == "POST" {
+		var request struct {
+			Objects []struct {
+				Key string
+			} `xml:"Object"`
+		}
+		if e := xml.NewDecoder(r.Body).Decode(&request); e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, object := range request.Objects {
+			*h.removed = append(*h.removed, object.Key)
+		}
+		response := []byte("<DeleteResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"></DeleteResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	response := "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+	for _, key := range h.keys {
+		response += "<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>" + key +
+			"</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>"
+	}
+	response += "<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>"
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test listing and removing keys with leading and consecutive separators.
+func (s *TestSuite) TestOddKeys(c *C) {
+	var removed []string
+	keys := []string{"/leading", "a//b", "plain"}
+	server := httptest.NewServer(oddKeysHandler{keys: keys, removed: &removed})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	c.Assert(s3c.joinPath("bucket", "/leading"), Equals, "/bucket/leading")
+	c.Assert(s3c.joinPath("bucket", "a//b/"), Equals, "/bucket/a/b/")
+
+	var contents []*ClientContent
+	var paths, listed []string
+	for content := range s3c.List(true, false, false, DirNone) {
+		c.Assert(content.Err, IsNil)
+		contents = append(contents, content)
+		paths = append(paths, content.URL.Path)
+		listed = append(listed, content.Key)
+	}
+	c.Assert(paths, DeepEquals, []string{"/bucket/leading", "/bucket/a/b", "/bucket/plain"})
+	c.Assert(listed, DeepEquals, keys)
+
+	contentCh := make(chan *ClientContent, len(contents))
+	for _, content := range contents {
+		contentCh <- content
+	}
+	close(contentCh)
+	for err := range s3c.Remove(context.Background(), false, false, false, contentCh) {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(removed, DeepEquals, keys)
+}
+
+// prefixListHandler lists the keys matching the requested prefix and
+// records the prefixes requested.
+type prefixListHandler struct {
+	keys     []string
+	prefixes *[]string
+}
+
+func (h prefixListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method != "GET" || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	prefix := query.Get("prefix")
+	*h.prefixes = append(*h.prefixes, prefix)
+	response := "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+	for _, key := range h.keys {
+		if strings.HasPrefix(key, prefix) {
+			response += "<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>" + key +
+				"</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>"
+		}
+	}
+	response += "<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix>" + prefix + "</Prefix></ListBucketResult>"
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test listing with a case insensitive prefix.
+func (s *TestSuite) TestListCaseInsensitive(c *C) {
+	c.Assert(caseStablePrefix("2020/Photos"), Equals, "2020/")
+	c.Assert(caseStablePrefix("photos"), Equals, "")
+	c.Assert(caseStablePrefix("2020/"), Equals, "2020/")
+	matched, ok := hasPrefixFold("PhOtOs/a", "photos/")
+	c.Assert(ok, Equals, true)
+	c.Assert(matched, Equals, "PhOtOs/")
+	_, ok = hasPrefixFold("pho", "photos")
+	c.Assert(ok, Equals, false)
+
+	var prefixes []string
+	keys := []string{"2020/PHOTOS.txt", "2020/Photos/a.jpg", "2020/Photos/b/c.jpg", "2020/other", "2020/photos/d.jpg", "2021/photos/e.jpg"}
+	server := httptest.NewServer(prefixListHandler{keys: keys, prefixes: &prefixes})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/2020/photos", "S3v4")
+
+	testCases := []struct {
+		isRecursive bool
+		keys        []string
+	}{
+		{true, []string{"2020/PHOTOS.txt", "2020/Photos/a.jpg", "2020/Photos/b/c.jpg", "2020/photos/d.jpg"}},
+		{false, []string{"2020/PHOTOS.txt", "2020/Photos/", "2020/photos/"}},
+	}
+	for _, testCase := range testCases {
+		prefixes = nil
+		var listed []string
+		for content := range s3c.ListCaseInsensitive(testCase.isRecursive, false) {
+			c.Assert(content.Err, IsNil)
+			listed = append(listed, content.Key)
+		}
+		c.Assert(listed, DeepEquals, testCase.keys)
+		c.Assert(prefixes, DeepEquals, []string{"2020/"})
+	}
+}
+
+// wrongRegionHandler rejects all bucket requests as if the bucket
+// lived in eu-west-1.
+type wrongRegionHandler struct{}
+
+func (h wrongRegionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	var response []byte
+	if r.Method == "PUT" {
+		response = []byte("<Error><Code>AuthorizationHeaderMalformed</Code><Message>The authorization header is malformed; " +
+			"the region 'us-east-1' is wrong; expecting 'eu-west-1'</Message></Error>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		response = []byte("<Error><Code>PermanentRedirect</Code><Message>The bucket you are attempting to access must be addressed " +
+			"using the specified endpoint.</Message><Endpoint>bucket.s3.eu-west-1.amazonaws.com</Endpoint></Error>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Header().Set("X-Amz-Bucket-Region", "eu-west-1")
+		w.WriteHeader(http.StatusMovedPermanently)
+	}
+	w.Write(response)
+}
+
+// Test reporting requests made to a bucket in another region.
+func (s *TestSuite) TestBucketRegionMismatch(c *C) {
+	server := httptest.NewServer(wrongRegionHandler{})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	reader, err := clnt.Get(context.Background(), nil)
+	c.Assert(err, IsNil)
+	_, e := ioutil.ReadAll(reader)
+	mismatch, ok := e.(BucketRegionMismatch)
+	c.Assert(ok, Equals, true, Commentf("%v", e))
+	c.Assert(mismatch, DeepEquals, BucketRegionMismatch{Bucket: "bucket", Actual: "eu-west-1"})
+
+	_, err = clnt.Put(context.Background(), bytes.NewReader([]byte("Hello, World")), 12, map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, NotNil)
+	mismatch, ok = err.ToGoError().(BucketRegionMismatch)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+	c.Assert(mismatch, DeepEquals, BucketRegionMismatch{Bucket: "bucket", Expected: "us-east-1", Actual: "eu-west-1"})
+
+	for content := range clnt.List(true, false, false, DirNone) {
+		c.Assert(content.Err, NotNil)
+		mismatch, ok = content.Err.ToGoError().(BucketRegionMismatch)
+		c.Assert(ok, Equals, true, Commentf("%v", content.Err))
+		c.Assert(mismatch.Actual, Equals, "eu-west-1")
+	}
+}
+
+// statListHandler serves HEAD on keys and delimited listings, recording
+// the max-keys of each listing.
+type statListHandler struct {
+	keys    []string
+	maxKeys *[]string
+	// Listed prefixes, recorded when not nil.
+	prefixes *[]string
+}
+
+func (h statListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method == "HEAD" {
+		for _, key := range h.keys {
+			if r.URL.Path == "/bucket/"+key {
+				w.Header().Set("Content-Length", "0")
+				w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+				w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != "GET" || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	*h.maxKeys = append(*h.maxKeys, query.Get("max-keys"))
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	if h.prefixes != nil {
+		*h.prefixes = append(*h.prefixes, prefix)
+	}
+	maxKeys, _ := strconv.Atoi(query.Get("max-keys"))
+	var contents, prefixes []string
+	for _, key := range h.keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if maxKeys > 0 && len(contents)+len(prefixes) >= maxKeys {
+			break
+		}
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			commonPrefix := key[:len(prefix)+i+len(delimiter)]
+			if len(prefixes) == 0 || prefixes[len(prefixes)-1] != commonPrefix {
+				prefixes = append(prefixes, commonPrefix)
+			}
+			continue
+		}
+		contents = append(contents, key)
+	}
+	response := "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">"
+	for _, key := range contents {
+		response += "<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>" + key +
+			"</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>"
+	}
+	for _, commonPrefix := range prefixes {
+		response += "<CommonPrefixes><Prefix>" + commonPrefix + "</Prefix></CommonPrefixes>"
+	}
+	response += "<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix>" + prefix + "</Prefix></ListBucketResult>"
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test stat of objects and prefixes.
+func (s *TestSuite) TestStatPrefix(c *C) {
+	var maxKeys []string
+	keys := []string{"dir/child1", "dir/child2", "dir/sub/object", "dirx", "empty/", "file"}
+	server := httptest.NewServer(statListHandler{keys: keys, maxKeys: &maxKeys})
+	defer server.Close()
+
+	testCases := []struct {
+		object  string
+		isDir   bool
+		missing bool
+		listed  bool
+	}{
+		{"file", false, false, false},
+		{"dir", true, false, true},
+		{"dir/", true, false, true},
+		{"dir/sub", true, false, true},
+		{"empty", true, false, true},
+		{"di", false, true, true},
+		{"missing/", false, true, true},
+	}
+	for _, testCase := range testCases {
+		conf := testConfig(server.URL+"/bucket/"+testCase.object, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+
+		maxKeys = nil
+		content, err := clnt.Stat(context.Background(), false, false, nil)
+		if testCase.missing {
+			c.Assert(err, NotNil, Commentf("object %s", testCase.object))
+			c.Assert(errors.As(err.ToGoError(), &ObjectMissing{}), Equals, true)
+		} else {
+			c.Assert(err, IsNil, Commentf("object %s", testCase.object))
+			c.Assert(content.Type.IsDir(), Equals, testCase.isDir, Commentf("object %s", testCase.object))
+		}
+		if testCase.listed {
+			c.Assert(maxKeys, DeepEquals, []string{"1"}, Commentf("object %s", testCase.object))
+		} else {
+			c.Assert(maxKeys, IsNil, Commentf("object %s", testCase.object))
+		}
+	}
+}
+
+// Test probing whether objects exist under a prefix.
+func (s *TestSuite) TestHasObjects(c *C) {
+	var maxKeys []string
+	keys := []string{"dir/child1", "dir/child2", "dir/sub/object", "file"}
+	server := httptest.NewServer(statListHandler{keys: keys, maxKeys: &maxKeys})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	testCases := []struct {
+		prefix string
+		found  bool
+	}{
+		{"", true},
+		{"dir/", true},
+		{"dir/sub", true},
+		{"fi", true},
+		{"missing/", false},
+	}
+	for _, testCase := range testCases {
+		maxKeys = nil
+		found, err := s3c.HasObjects(testCase.prefix)
+		c.Assert(err, IsNil, Commentf("prefix %s", testCase.prefix))
+		c.Assert(found, Equals, testCase.found, Commentf("prefix %s", testCase.prefix))
+		c.Assert(maxKeys, DeepEquals, []string{"1"}, Commentf("prefix %s", testCase.prefix))
+	}
+}
+
+// queryRecorder records the query of every request to handler.
+type queryRecorder struct {
+	handler http.Handler
+	queries *[]url.Values
+}
+
+func (h queryRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	*h.queries = append(*h.queries, r.URL.Query())
+	h.handler.ServeHTTP(w, r)
+}
+
+// Test Config.ForceListV1 lists objects with ListObjects V1 only.
+func (s *TestSuite) TestForceListV1(c *C) {
+	var maxKeys []string
+	var queries []url.Values
+	keys := []string{"dir/child1", "dir/sub/object", "file"}
+	server := httptest.NewServer(queryRecorder{handler: statListHandler{keys: keys, maxKeys: &maxKeys}, queries: &queries})
+	defer server.Close()
+
+	for _, forceListV1 := range []bool{false, true} {
+		queries = nil
+		conf := testConfig(server.URL+"/bucket/", "S3v4")
+		conf.ForceListV1 = forceListV1
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		s3c := clnt.(*S3Client)
+
+		for _, isRecursive := range []bool{false, true} {
+			for content := range s3c.List(isRecursive, false, false, DirNone) {
+				c.Assert(content.Err, IsNil)
+			}
+		}
+		for content := range s3c.ListWithOptions(ListOptions{Recursive: true, StartAfter: "dir/"}) {
+			c.Assert(content.Err, IsNil)
+		}
+		for content := range s3c.ListResumable(context.Background(), true, "", nil) {
+			c.Assert(content.Err, IsNil)
+		}
+		found, err := s3c.HasObjects("dir/")
+		c.Assert(err, IsNil)
+		c.Assert(found, Equals, true)
+
+		var listings int
+		for _, query := range queries {
+			if _, ok := query["location"]; ok {
+				continue
+			}
+			listings++
+			c.Assert(query.Get("list-type") == "2", Equals, !forceListV1, Commentf("query %v", query))
+		}
+		c.Assert(listings, Equals, 5)
+	}
+}
+
+// Test capturing the request IDs of responses.
+func (s *TestSuite) TestLastRequestID(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("X-Amz-Request-Id", "REQUEST"+strconv.Itoa(count))
+		w.Header().Set("X-Amz-Id-2", "HOST"+strconv.Itoa(count))
+		object.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+object.resource, "S3v4")
+
+	_, err := s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	requestID, hostID := s3c.LastRequestID(), s3c.LastHostID()
+	c.Assert(requestID, Not(Equals), "")
+	c.Assert(hostID, Not(Equals), "")
+	c.Assert(strings.TrimPrefix(requestID, "REQUEST"), Equals, strings.TrimPrefix(hostID, "HOST"))
+
+	// Calls made with other contexts don't change the IDs of a call.
+	ctx, ids := WithRequestIDs(context.Background())
+	_, err = s3c.Stat(ctx, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(ids.RequestID(), Equals, s3c.LastRequestID())
+	c.Assert(ids.RequestID(), Not(Equals), requestID)
+	requestID = ids.RequestID()
+	_, err = s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s3c.LastRequestID(), Not(Equals), requestID)
+	c.Assert(ids.RequestID(), Equals, requestID)
+	c.Assert(strings.TrimPrefix(ids.RequestID(), "REQUEST"), Equals, strings.TrimPrefix(ids.HostID(), "HOST"))
+}
+
+// Test per host proxies.
+func (s *TestSuite) TestProxyByHost(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var hostsA, hostsB []string
+	proxyA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostsA = append(hostsA, r.Host)
+		object.ServeHTTP(w, r)
+	}))
+	defer proxyA.Close()
+	proxyB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostsB = append(hostsB, r.Host)
+		object.ServeHTTP(w, r)
+	}))
+	defer proxyB.Close()
+
+	proxyByHost := map[string]string{
+		"s3-a.example.com": proxyA.URL,
+		"s3-b.example.com": proxyB.URL,
+		"onprem.example":   "",
+	}
+	for _, host := range []string{"s3-a.example.com", "s3-b.example.com"} {
+		conf := testConfig("http://"+host+object.resource, "S3v4")
+		conf.ProxyByHost = proxyByHost
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		_, err = clnt.Stat(context.Background(), false, false, nil)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(len(hostsA) > 0, Equals, true)
+	c.Assert(len(hostsB) > 0, Equals, true)
+	for _, host := range hostsA {
+		c.Assert(host, Equals, "s3-a.example.com")
+	}
+	for _, host := range hostsB {
+		c.Assert(host, Equals, "s3-b.example.com")
+	}
+
+	proxy, err := newProxyFunc(proxyByHost)
+	c.Assert(err, IsNil)
+	for host, expected := range map[string]string{
+		"bucket.s3-a.example.com": proxyA.URL,
+		"onprem.example:9000":     "",
+	} {
+		req, e := http.NewRequest("GET", "http://"+host+"/object", nil)
+		c.Assert(e, IsNil)
+		u, e := proxy(req)
+		c.Assert(e, IsNil)
+		if expected == "" {
+			c.Assert(u, IsNil)
+		} else {
+			c.Assert(u.String(), Equals, expected)
+		}
+	}
+
+	_, err = newProxyFunc(map[string]string{"s3.example.com": "not a url"})
+	c.Assert(err, NotNil)
+}
+
+// incompleteUploadHandler serves a single incomplete upload whose
+// parts are listed two per page.
+type incompleteUploadHandler struct {
+	parts []int
+}
+
+func (h incompleteUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	var response string
+	switch {
+	case r.Method == "GET" && location:
+		response = "<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>"
+	case r.Method == "GET" && uploads:
+		response = "<ListMultipartUploadsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><KeyMarker/><UploadIdMarker/><NextKeyMarker/><NextUploadIdMarker/><MaxUploads>1000</MaxUploads><IsTruncated>false</IsTruncated><Upload><Key>object</Key><UploadId>upload</UploadId><Initiated>2015-05-21T18:24:21.097Z</Initiated></Upload><Prefix>object</Prefix></ListMultipartUploadsResult>"
+	case r.Method == "GET" && query.Get("uploadId") == "upload":
+		marker, _ := strconv.Atoi(query.Get("part-number-marker"))
+		end := marker + 2
+		if end > len(h.parts) {
+			end = len(h.parts)
+		}
+		response = "<ListPartsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload</UploadId>"
+		for i := marker; i < end; i++ {
+			response += "<Part><PartNumber>" + strconv.Itoa(i+1) + "</PartNumber><LastModified>2015-05-21T18:24:21.097Z</LastModified><ETag>\"9af2f8218b150c351ad802c6f3d66abe\"</ETag><Size>" + strconv.Itoa(h.parts[i]) + "</Size></Part>"
+		}
+		response += "<PartNumberMarker>" + strconv.Itoa(marker) + "</PartNumberMarker><NextPartNumberMarker>" + strconv.Itoa(end) + "</NextPartNumberMarker><MaxParts>1000</MaxParts><IsTruncated>" + strconv.FormatBool(end < len(h.parts)) + "</IsTruncated></ListPartsResult>"
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test stating an incomplete upload sums the size of its parts.
+func (s *TestSuite) TestStatIncompleteUpload(c *C) {
+	server := httptest.NewServer(incompleteUploadHandler{parts: []int{5 * 1024 * 1024, 5 * 1024 * 1024, 1024}})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	content, err := clnt.Stat(context.Background(), true, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Size, Equals, int64(10*1024*1024+1024))
+	c.Assert(content.Metadata["Upload-Parts"], Equals, "3")
+	c.Assert(content.Metadata["Upload-Id"], Equals, "upload")
+	c.Assert(content.Metadata["Upload-Initiated"], Equals, "2015-05-21T18:24:21Z")
+}
+
+// statusHandler answers like a MinIO server, denying bucket listings
+// when denied is set.
+type statusHandler struct {
+	denied bool
+}
+
+func (h statusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", "MinIO/RELEASE.2020-05-16T01-33-21Z")
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/" && h.denied:
+		w.Header().Set("X-Amz-Bucket-Region", "eu-west-1")
+		w.WriteHeader(http.StatusForbidden)
+	case r.Method == "GET" && r.URL.Path == "/":
+		response := []byte("<ListAllMyBucketsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Owner><ID>minio</ID><DisplayName>minio</DisplayName></Owner><Buckets><Bucket><Name>bucket</Name><CreationDate>2015-05-21T18:24:21.097Z</CreationDate></Bucket></Buckets></ListAllMyBucketsResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	case r.Method == "HEAD" && r.URL.Path == "/bucket/object":
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "9af2f8218b150c351ad802c6f3d66abe")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Test checking the health of an endpoint.
+func (s *TestSuite) TestGetServiceStatus(c *C) {
+	for _, denied := range []bool{false, true} {
+		server := httptest.NewServer(statusHandler{denied: denied})
+
+		conf := testConfig(server.URL, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		s3c := clnt.(*S3Client)
+
+		status, err := s3c.GetServiceStatus(context.Background())
+		c.Assert(err, IsNil)
+		c.Assert(status.Reachable, Equals, true)
+		c.Assert(status.Latency > 0, Equals, true)
+		c.Assert(status.ServerVersion, Equals, "MinIO/RELEASE.2020-05-16T01-33-21Z")
+		if denied {
+			c.Assert(status.RegionHint, Equals, "eu-west-1")
+		} else {
+			c.Assert(status.RegionHint, Equals, "")
+		}
+
+		latency, err := s3c.PingObject(context.Background(), "bucket", "object")
+		c.Assert(err, IsNil)
+		c.Assert(latency >= time.Millisecond, Equals, true)
+		_, err = s3c.PingObject(context.Background(), "bucket", "missing")
+		c.Assert(err, NotNil)
+		server.Close()
+
+		// Nothing listens anymore.
+		status, err = s3c.GetServiceStatus(context.Background())
+		c.Assert(err, NotNil)
+		c.Assert(status.Reachable, Equals, false)
+	}
+}
+
+// regionHandler serves the location of bucket, other buckets don't exist.
+type regionHandler struct {
+	locations *int32
+}
+
+func (h regionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bucket/" {
+		response := []byte("<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.WriteHeader(http.StatusNotFound)
+		if r.Method != "HEAD" {
+			w.Write(response)
+		}
+		return
+	}
+	if _, ok := r.URL.Query()["location"]; ok {
+		atomic.AddInt32(h.locations, 1)
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">eu-west-1</LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Test looking up bucket regions.
+func (s *TestSuite) TestGetBucketRegion(c *C) {
+	var locations int32
+	server := httptest.NewServer(regionHandler{locations: &locations})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket", "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	for i := 0; i < 2; i++ {
+		region, err := s3c.GetBucketRegion()
+		c.Assert(err, IsNil)
+		c.Assert(region, Equals, "eu-west-1")
+	}
+	c.Assert(atomic.LoadInt32(&locations), Equals, int32(1))
+
+	content, err := s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Metadata["Region"], Equals, "eu-west-1")
+
+	conf.HostURL = server.URL + "/missing"
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	_, err = clnt.(*S3Client).GetBucketRegion()
+	c.Assert(err, NotNil)
+	c.Assert(errors.As(err.ToGoError(), &BucketDoesNotExist{}), Equals, true)
+}
+
+// Test reporting the server side encryption of objects.
+func (s *TestSuite) TestObjectEncryption(c *C) {
+	testCases := []struct {
+		headers    map[string]string
+		encryption string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"X-Amz-Server-Side-Encryption": "AES256"}, "AES256"},
+		{map[string]string{"X-Amz-Server-Side-Encryption": "aws:kms", "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": "key"}, "aws:kms"},
+		{map[string]string{"X-Amz-Server-Side-Encryption-Customer-Algorithm": "AES256", "X-Amz-Server-Side-Encryption-Customer-Key-Md5": "md5"}, "SSE-C"},
+	}
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+		}
+		object.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	for i, testCase := range testCases {
+		header := http.Header{}
+		for k, v := range testCase.headers {
+			header.Set(k, v)
+		}
+		c.Assert(objectEncryption(header), Equals, testCase.encryption, Commentf("test %d", i+1))
+
+		headers = testCase.headers
+		content, err := clnt.Stat(context.Background(), false, false, nil)
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(content.Encryption, Equals, testCase.encryption, Commentf("test %d", i+1))
+		c.Assert(parseStat(content).Encryption, Equals, testCase.encryption, Commentf("test %d", i+1))
+	}
+}
+
+// Test parsing the restore status of archived objects on stat.
+func (s *TestSuite) TestObjectRestore(c *C) {
+	testCases := []struct {
+		restore  string
+		expected *RestoreStatus
+	}{
+		{"", nil},
+		{`ongoing-request="true"`, &RestoreStatus{Ongoing: true}},
+		{`ongoing-request="false", expiry-date="Fri, 23 Dec 2012 00:00:00 GMT"`, &RestoreStatus{Expiry: time.Date(2012, 12, 23, 0, 0, 0, 0, time.UTC)}},
+	}
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var restore string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.Header().Set("X-Amz-Storage-Class", "GLACIER")
+			if restore != "" {
+				w.Header().Set("X-Amz-Restore", restore)
+			}
+		}
+		object.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	for i, testCase := range testCases {
+		restore = testCase.restore
+		content, err := clnt.Stat(context.Background(), false, false, nil)
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(content.StorageClass, Equals, "GLACIER")
+		c.Assert(content.Restore, DeepEquals, testCase.expected, Commentf("test %d", i+1))
+	}
+}
+
+// conditionalDeleteHandler serves an object with a stat ETag and a
+// current ETag, which differ when it was overwritten after the stat.
+type conditionalDeleteHandler struct {
+	statETag string
+	etag     string
+	deleted  *bool
+}
+
+func (h conditionalDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.URL.Path != "/bucket/object" || *h.deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "HEAD":
+		w.Header().Set("Content-Length", "12")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "\""+h.statETag+"\"")
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "\""+h.etag+"\"" {
+			response := []byte("<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write(response)
+			return
+		}
+		*h.deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Test removing an object only if it has a given ETag.
+func (s *TestSuite) TestRemoveObjectIfMatch(c *C) {
+	testCases := []struct {
+		statETag    string
+		etag        string
+		ifMatchETag string
+		deleted     bool
+	}{
+		{"etag1", "etag1", "", true},
+		{"etag1", "etag1", "etag1", true},
+		{"etag1", "etag1", "\"etag1\"", true},
+		// Overwritten before the stat.
+		{"etag2", "etag2", "etag1", false},
+		// Overwritten between the stat and the delete.
+		{"etag1", "etag2", "etag1", false},
+	}
+	for i, testCase := range testCases {
+		var deleted bool
+		server := httptest.NewServer(conditionalDeleteHandler{statETag: testCase.statETag, etag: testCase.etag, deleted: &deleted})
+
+		conf := testConfig(server.URL+"/bucket/object", "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+
+		err = clnt.(*S3Client).RemoveObject(context.Background(), testCase.ifMatchETag)
+		c.Assert(deleted, Equals, testCase.deleted, Commentf("test %d", i+1))
+		if testCase.deleted {
+			c.Assert(err, IsNil, Commentf("test %d", i+1))
+		} else {
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			_, ok := err.ToGoError().(PreconditionFailed)
+			c.Assert(ok, Equals, true, Commentf("test %d", i+1))
+		}
+
+		// Fails again, the object is gone or has another ETag.
+		err = clnt.(*S3Client).RemoveObject(context.Background(), "etag1")
+		c.Assert(err, NotNil, Commentf("test %d", i+1))
+		server.Close()
+	}
+}
+
+// sortListHandler lists 50 objects whose sizes, times and ETags are
+// in different orders than their keys.
+type sortListHandler struct{}
+
+func (h sortListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if r.Method != "GET" || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	buf.WriteString("<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">")
+	for i := 0; i < 50; i++ {
+		sum := md5.Sum([]byte(strconv.Itoa(i)))
+		modTime := base.Add(time.Duration((i*23)%50) * time.Minute)
+		buf.WriteString("<Contents><Key>object-" + strconv.Itoa(100 + i)[1:] + "</Key>" +
+			"<ETag>" + hex.EncodeToString(sum[:]) + "</ETag>" +
+			"<LastModified>" + modTime.Format("2006-01-02T15:04:05.000Z") + "</LastModified>" +
+			// Sizes repeat to check ties are kept in key order.
+			"<Size>" + strconv.Itoa((i*17)%25) + "</Size><StorageClass>STANDARD</StorageClass></Contents>")
+	}
+	buf.WriteString("<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
+
+// Test listing objects sorted by key, size, time or ETag.
+func (s *TestSuite) TestListSorted(c *C) {
+	server := httptest.NewServer(sortListHandler{})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	testCases := []struct {
+		sortBy SortBy
+		less   func(a, b *ClientContent) bool
+	}{
+		{SortByKey, func(a, b *ClientContent) bool { return a.Key < b.Key }},
+		{SortBySize, func(a, b *ClientContent) bool {
+			return a.Size < b.Size || (a.Size == b.Size && a.Key < b.Key)
+		}},
+		{SortByLastModified, func(a, b *ClientContent) bool { return a.Time.Before(b.Time) }},
+		{SortByETag, func(a, b *ClientContent) bool { return a.ETag < b.ETag }},
+	}
+	for i, testCase := range testCases {
+		var contents []*ClientContent
+		for content := range s3c.ListSorted(true, false, false, testCase.sortBy) {
+			c.Assert(content.Err, IsNil, Commentf("test %d", i+1))
+			contents = append(contents, content)
+		}
+		c.Assert(contents, HasLen, 50, Commentf("test %d", i+1))
+		for j := 1; j < len(contents); j++ {
+			c.Assert(testCase.less(contents[j-1], contents[j]), Equals, true, Commentf("test %d: %s before %s", i+1, contents[j-1].Key, contents[j].Key))
+		}
+	}
+
+	// Unknown sort order.
+	for content := range s3c.ListSorted(true, false, false, SortBy(42)) {
+		c.Assert(content.Err, NotNil)
+	}
+}
+
+// objectLockHandler serves the legal hold and retention of an object,
+// NoSuchObjectLockConfiguration for the unset ones.
+type objectLockHandler struct {
+	legalHold string
+	retention string
+}
+
+func (h objectLockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var response string
+	switch {
+	case len(query["location"]) > 0:
+		response = "<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>"
+	case r.URL.Path != "/bucket/object" || r.Method != "GET":
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case len(query["legal-hold"]) > 0:
+		response = h.legalHold
+	case len(query["retention"]) > 0:
+		response = h.retention
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if response == "" {
+		response = "<Error><Code>NoSuchObjectLockConfiguration</Code><Message>The specified object does not have a ObjectLock configuration</Message></Error>"
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(response))
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test getting the legal hold and retention of an object at once.
+func (s *TestSuite) TestGetObjectLockState(c *C) {
+	retainUntilDate := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		legalHold string
+		retention string
+		state     ObjectLockState
+	}{
+		{"", "", ObjectLockState{}},
+		{"<LegalHold><Status>ON</Status></LegalHold>", "", ObjectLockState{LegalHold: minio.LegalHoldEnabled}},
+		{"", "<Retention><Mode>GOVERNANCE</Mode><RetainUntilDate>2030-01-01T00:00:00Z</RetainUntilDate></Retention>",
+			ObjectLockState{Mode: minio.Governance, RetainUntilDate: retainUntilDate}},
+		{"<LegalHold><Status>OFF</Status></LegalHold>", "<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>2030-01-01T00:00:00Z</RetainUntilDate></Retention>",
+			ObjectLockState{LegalHold: minio.LegalHoldDisabled, Mode: minio.Compliance, RetainUntilDate: retainUntilDate}},
+	}
+	for i, testCase := range testCases {
+		server := httptest.NewServer(objectLockHandler{legalHold: testCase.legalHold, retention: testCase.retention})
+
+		conf := testConfig(server.URL+"/bucket/object", "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+
+		state, err := clnt.(*S3Client).GetObjectLockState(context.Background())
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(state, DeepEquals, testCase.state, Commentf("test %d", i+1))
+		server.Close()
+	}
+}
+
+// conditionalHandler serves an object honoring If-Match and
+// If-None-Match on reads and uploads.
+type conditionalHandler struct {
+	etag string
+}
+
+func (h conditionalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	quoted := "\"" + h.etag + "\""
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch == "*" || ifNoneMatch == quoted {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		response := []byte("<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.WriteHeader(http.StatusPreconditionFailed)
+		w.Write(response)
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != quoted {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	data := []byte("Hello, World")
+	switch r.Method {
+	case "HEAD", "GET":
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", quoted)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == "GET" {
+			w.Write(data)
+		}
+	case "PUT":
+		ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			if ifMatch := r.Header.Get("X-Amz-Copy-Source-If-Match"); ifMatch != "" && ifMatch != quoted {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			response := []byte("<CopyObjectResult><LastModified>2015-05-21T18:24:21.097Z</LastModified><ETag>" + quoted + "</ETag></CopyObjectResult>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(response)
+			return
+		}
+		w.Header().Set("ETag", quoted)
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Test failed conditions are reported as typed errors.
+func (s *TestSuite) TestConditionErrors(c *C) {
+	server := httptest.NewServer(conditionalHandler{etag: "9af2f8218b150c351ad802c6f3d66abe"})
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v2")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	unchanged := withRequestHeaders(context.Background(), http.Header{"If-None-Match": []string{"\"9af2f8218b150c351ad802c6f3d66abe\""}})
+	changed := withRequestHeaders(context.Background(), http.Header{"If-Match": []string{"\"3858f62230ac3c915f300c664312c11f\""}})
+	absent := withRequestHeaders(context.Background(), http.Header{"If-None-Match": []string{"*"}})
+
+	// Reads.
+	_, err = clnt.Stat(unchanged, false, false, nil)
+	c.Assert(err, NotNil)
+	notModified, ok := err.ToGoError().(ObjectNotModified)
+	c.Assert(ok, Equals, true)
+	c.Assert(notModified.Condition, Equals, "If-None-Match: \"9af2f8218b150c351ad802c6f3d66abe\"")
+	var traced bool
+	for _, tp := range err.CallTrace {
+		traced = traced || strings.Contains(strings.Join(tp.Env["Tags"], " "), notModified.Condition)
+	}
+	c.Assert(traced, Equals, true)
+
+	_, err = clnt.Stat(changed, false, false, nil)
+	c.Assert(err, NotNil)
+	preconditionFailed, ok := err.ToGoError().(PreconditionFailed)
+	c.Assert(ok, Equals, true)
+	c.Assert(preconditionFailed.Condition, Equals, "If-Match: \"3858f62230ac3c915f300c664312c11f\"")
+
+	reader, err := clnt.Get(unchanged, nil)
+	c.Assert(err, IsNil)
+	_, e := ioutil.ReadAll(reader)
+	reader.Close()
+	_, ok = e.(ObjectNotModified)
+	c.Assert(ok, Equals, true)
+
+	reader, err = clnt.Get(context.Background(), nil)
+	c.Assert(err, IsNil)
+	data, e := ioutil.ReadAll(reader)
+	reader.Close()
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "Hello, World")
+
+	// Uploads.
+	data = []byte("Hello, World")
+	_, err = clnt.Put(absent, bytes.NewReader(data), int64(len(data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, NotNil)
+	preconditionFailed, ok = err.ToGoError().(PreconditionFailed)
+	c.Assert(ok, Equals, true)
+	c.Assert(preconditionFailed.Condition, Equals, "If-None-Match: *")
+
+	_, err = clnt.Put(context.Background(), bytes.NewReader(data), int64(len(data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+
+	// Copies, the condition is only sent by the call it was set on.
+	copyChanged := withRequestHeaders(context.Background(), http.Header{"X-Amz-Copy-Source-If-Match": []string{"\"3858f62230ac3c915f300c664312c11f\""}})
+	err = clnt.Copy(copyChanged, "/bucket/object", int64(len(data)), nil, nil, nil, nil, true, "")
+	c.Assert(err, NotNil)
+	preconditionFailed, ok = err.ToGoError().(PreconditionFailed)
+	c.Assert(ok, Equals, true)
+	c.Assert(preconditionFailed.Condition, Equals, "X-Amz-Copy-Source-If-Match: \"3858f62230ac3c915f300c664312c11f\"")
+
+	err = clnt.Copy(context.Background(), "/bucket/object", int64(len(data)), nil, nil, nil, nil, true, "")
+	c.Assert(err, IsNil)
+}
+
+// metadataListHandler lists an object with its metadata when asked
+// for, and serves it for stats.
+type metadataListHandler struct{}
+
+func (h metadataListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/bucket/":
+		var userMetadata string
+		if query.Get("metadata") == "true" {
+			userMetadata = "<UserMetadata><X-Amz-Meta-Owner>me</X-Amz-Meta-Owner><content-type>text/plain</content-type></UserMetadata>"
+		}
+		response := []byte("<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\">" +
+			"<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>object</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>12</Size><StorageClass>STANDARD</StorageClass>" + userMetadata + "</Contents>" +
+			"<IsTruncated>false</IsTruncated><MaxKeys>1000</MaxKeys><Name>bucket</Name><Prefix></Prefix></ListBucketResult>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+	case r.Method == "HEAD" && r.URL.Path == "/bucket/object":
+		w.Header().Set("Content-Length", "12")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+		w.Header().Set("ETag", "\"259d04a13802ae09c7e41be50ccc6baa\"")
+		w.Header().Set("X-Amz-Meta-Owner", "me")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Test listings tell whether they fetched the metadata of objects.
+func (s *TestSuite) TestListMetadataFetched(c *C) {
+	server := httptest.NewServer(metadataListHandler{})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	for _, isRecursive := range []bool{true, false} {
+		// Metadata not requested.
+		var contents []*ClientContent
+		for content := range s3c.List(isRecursive, false, false, DirNone) {
+			c.Assert(content.Err, IsNil)
+			contents = append(contents, content)
+		}
+		c.Assert(contents, HasLen, 1)
+		c.Assert(contents[0].MetadataFetched, Equals, false)
+		c.Assert(contents[0].Metadata, IsNil)
+		c.Assert(contents[0].UserMetadata, IsNil)
+
+		// Loaded on demand with a stat.
+		c.Assert(s3c.LoadMetadata(context.Background(), contents[0]), IsNil)
+		c.Assert(contents[0].MetadataFetched, Equals, true)
+		c.Assert(contents[0].UserMetadata, DeepEquals, map[string]string{"X-Amz-Meta-Owner": "me"})
+		c.Assert(contents[0].Metadata["Content-Type"], Equals, "text/plain")
+
+		// Metadata listed.
+		contents = nil
+		for content := range s3c.List(isRecursive, false, true, DirNone) {
+			c.Assert(content.Err, IsNil)
+			contents = append(contents, content)
+		}
+		c.Assert(contents, HasLen, 1)
+		c.Assert(contents[0].MetadataFetched, Equals, true)
+		c.Assert(contents[0].UserMetadata["X-Amz-Meta-Owner"], Equals, "me")
+	}
+
+	// Fetched metadata isn't fetched again.
+	content := &ClientContent{URL: *newClientURL(server.URL + "/bucket/object"), MetadataFetched: true}
+	c.Assert(s3c.LoadMetadata(context.Background(), content), IsNil)
+	c.Assert(content.UserMetadata, IsNil)
+}
+
+// Test uploading a local file.
+func (s *TestSuite) TestPutFile(c *C) {
+	dir, e := ioutil.TempDir("", "mc-putfile-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(dir)
+	data := []byte("name,city\nJohn,Ottawa\n")
+	localPath := filepath.Join(dir, "data.csv")
+	c.Assert(ioutil.WriteFile(localPath, data, 0600), IsNil)
+
+	var stored []byte
+	object := memoryObjectHandler{resource: "/bucket/data", data: &stored}
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v2")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	testCases := []struct {
+		metadata    map[string]string
+		contentType string
+	}{
+		// Guessed from the extension of the file, not of the object.
+		{nil, "text/csv"},
+		{map[string]string{"content-type": "text/plain"}, "text/plain"},
+	}
+	for i, testCase := range testCases {
+		headers = nil
+		n, err := s3c.PutFile(context.Background(), localPath, testCase.metadata, nil, nil)
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		c.Assert(n, Equals, int64(len(data)))
+		c.Assert(stored, DeepEquals, data)
+		c.Assert(len(headers) > 0, Equals, true)
+		c.Assert(headers[len(headers)-1].Get("Content-Type"), Equals, testCase.contentType, Commentf("test %d", i+1))
+	}
+
+	_, err = s3c.PutFile(context.Background(), filepath.Join(dir, "missing.csv"), nil, nil, nil)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(PathNotFound)
+	c.Assert(ok, Equals, true)
+
+	_, err = s3c.PutFile(context.Background(), dir, nil, nil, nil)
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(PathIsNotRegular)
+	c.Assert(ok, Equals, true)
+}
+
+// downloadHandler serves objects with mc-attrs metadata, the body of
+// /bucket/truncated is cut short.
+type downloadHandler struct {
+	data  []byte
+	attrs string
+}
+
+func (h downloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bucket/object" && r.URL.Path != "/bucket/truncated" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(h.data)))
+	w.Header().Set("Last-Modified", UTCNow().Format(http.TimeFormat))
+	w.Header().Set("ETag", "\"9af2f8218b150c351ad802c6f3d66abe\"")
+	if h.attrs != "" {
+		w.Header().Set("X-Amz-Meta-Mc-Attrs", h.attrs)
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "GET" {
+		data := h.data
+		if r.URL.Path == "/bucket/truncated" {
+			data = data[:len(data)/2]
+		}
+		w.Write(data)
+	}
+}
+
+// Test downloading objects to files.
+func (s *TestSuite) TestGetToFile(c *C) {
+	dir, e := ioutil.TempDir("", "mc-gettofile-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(dir)
+
+	data := []byte("name,city\nJohn,Ottawa\n")
+	server := httptest.NewServer(downloadHandler{data: data, attrs: "atime:1500000000/mtime:1600000000"})
+	defer server.Close()
+
+	newClient := func(object string) *S3Client {
+		conf := testConfig(server.URL+"/bucket/"+object, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		return clnt.(*S3Client)
+	}
+
+	localPath := filepath.Join(dir, "data.csv")
+	n, err := newClient("object").GetToFile(context.Background(), localPath, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	got, e := ioutil.ReadFile(localPath)
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, data)
+	st, e := os.Stat(localPath)
+	c.Assert(e, IsNil)
+	c.Assert(st.ModTime().Unix(), Equals, int64(1600000000))
+
+	// Failed downloads leave neither the file nor the temporary file.
+	_, err = newClient("truncated").GetToFile(context.Background(), filepath.Join(dir, "truncated.csv"), nil)
+	c.Assert(err, NotNil)
+	_, err = newClient("missing").GetToFile(context.Background(), filepath.Join(dir, "missing.csv"), nil)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ObjectMissing)
+	c.Assert(ok, Equals, true)
+
+	entries, e := ioutil.ReadDir(dir)
+	c.Assert(e, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].Name(), Equals, "data.csv")
+}
+
+// Test uploading objects which expire.
+func (s *TestSuite) TestPutExpiring(c *C) {
+	var stored []byte
+	object := memoryObjectHandler{resource: "/bucket/upload", data: &stored}
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v2")
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	data := "temporary upload"
+
+	// Not supported without expiry header.
+	_, err = clnt.(*S3Client).PutExpiring(context.Background(), strings.NewReader(data), int64(len(data)), nil, nil, nil, time.Hour)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(APINotImplemented)
+	c.Assert(ok, Equals, true)
+	c.Assert(headers, HasLen, 0)
+
+	conf.ObjectExpiryHeader = "x-delete-at"
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+	for _, expireAfter := range []time.Duration{0, -time.Minute} {
+		_, err = s3c.PutExpiring(context.Background(), strings.NewReader(data), int64(len(data)), nil, nil, nil, expireAfter)
+		c.Assert(err, NotNil)
+	}
+	c.Assert(headers, HasLen, 0)
+
+	before := UTCNow().Truncate(time.Second)
+	n, err := s3c.PutExpiring(context.Background(), strings.NewReader(data), int64(len(data)), nil, nil, nil, time.Hour)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(string(stored), Equals, data)
+	c.Assert(len(headers) > 0, Equals, true)
+	expiry, e := http.ParseTime(headers[len(headers)-1].Get("X-Delete-At"))
+	c.Assert(e, IsNil)
+	c.Assert(expiry.Before(before.Add(time.Hour)), Equals, false)
+	c.Assert(expiry.After(UTCNow().Add(time.Hour)), Equals, false)
+
+	// x-amz-* expiry headers are signed, including on the streaming
+	// signature uploads of V4 over plain HTTP, and multipart uploads
+	// only send them when initiated.
+	var v4Stored []byte
+	v4Object := memoryObjectHandler{resource: "/bucket/upload", data: &v4Stored}
+	requests := make(map[string]http.Header)
+	v4Server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" || r.Method == "POST" {
+			requests[r.Method+" "+r.URL.RawQuery] = r.Header.Clone()
+		}
+		v4Object.ServeHTTP(w, r)
+	}))
+	defer v4Server.Close()
+	conf.HostURL = v4Server.URL + v4Object.resource
+	conf.Signature = "S3v4"
+	conf.ObjectExpiryHeader = "x-amz-expiry-date"
+	clnt, err = S3New(conf)
+	c.Assert(err, IsNil)
+	s3c = clnt.(*S3Client)
+
+	_, err = s3c.PutExpiring(context.Background(), strings.NewReader(data), int64(len(data)), nil, nil, nil, time.Hour)
+	c.Assert(err, IsNil)
+	put := requests["PUT "]
+	c.Assert(put, NotNil)
+	c.Assert(put.Get("X-Amz-Content-Sha256"), Equals, streamingPayload)
+	c.Assert(put.Get("X-Amz-Expiry-Date"), Not(Equals), "")
+	c.Assert(strings.Contains(put.Get("Authorization"), "x-amz-expiry-date"), Equals, true)
+	decoded, e := ioutil.ReadAll(&awsChunkedReader{reader: bufio.NewReader(bytes.NewReader(v4Stored)), closer: ioutil.NopCloser(nil)})
+	c.Assert(e, IsNil)
+	c.Assert(string(decoded), Equals, data)
+
+	_, err = s3c.PutExpiring(context.Background(), strings.NewReader(data), -1, nil, nil, nil, time.Hour)
+	c.Assert(err, IsNil)
+	c.Assert(string(v4Stored), Equals, data)
+	initiate := requests["POST uploads="]
+	c.Assert(initiate, NotNil)
+	c.Assert(initiate.Get("X-Amz-Expiry-Date"), Not(Equals), "")
+	c.Assert(strings.Contains(initiate.Get("Authorization"), "x-amz-expiry-date"), Equals, true)
+	part := requests["PUT partNumber=1&uploadId=upload"]
+	c.Assert(part, NotNil)
+	c.Assert(part.Get("X-Amz-Expiry-Date"), Equals, "")
+}
+
+// orderedListHandler serves ListBuckets, with buckets out of order, and
+// ListObjectsV2 of the keys of every bucket in pages of three entries.
+type orderedListHandler struct {
+	buckets map[string][]string
+}
+
+func (h orderedListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, location := query["location"]
+	var response string
+	switch {
+	case r.Method != "GET":
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case location:
+		response = "<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>"
+	case r.URL.Path == "/":
+		response = "<ListAllMyBucketsResult><Owner><ID>owner</ID><DisplayName>owner</DisplayName></Owner><Buckets>"
+		// Not in order of name.
+		for _, name := range []string{"b1", "a0b"} {
+			response += "<Bucket><Name>" + name + "</Name><CreationDate>2015-05-21T18:24:21.097Z</CreationDate></Bucket>"
+		}
+		response += "</Buckets></ListAllMyBucketsResult>"
+	default:
+		bucket := strings.Trim(r.URL.Path, "/")
+		keys, ok := h.buckets[bucket]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		// Entries in key order, common prefixes ending with "/".
+		var entries []string
+		for _, key := range keys {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+				key = key[:len(prefix)+i+1]
+				if len(entries) > 0 && entries[len(entries)-1] == key {
+					continue
+				}
+			}
+			entries = append(entries, key)
+		}
+		start, _ := strconv.Atoi(query.Get("continuation-token"))
+		end := start + 3
+		truncated := end < len(entries)
+		if !truncated {
+			end = len(entries)
+		}
+		response = "<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"><Name>" + bucket + "</Name><Prefix>" + prefix + "</Prefix><MaxKeys>3</MaxKeys>"
+		var prefixes string
+		for _, entry := range entries[start:end] {
+			if delimiter != "" && strings.HasSuffix(entry, delimiter) {
+				prefixes += "<CommonPrefixes><Prefix>" + entry + "</Prefix></CommonPrefixes>"
+				continue
+			}
+			response += "<Contents><ETag>259d04a13802ae09c7e41be50ccc6baa</ETag><Key>" + entry +
+				"</Key><LastModified>2015-05-21T18:24:21.097Z</LastModified><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>"
+		}
+		response += prefixes
+		if truncated {
+			response += "<IsTruncated>true</IsTruncated><NextContinuationToken>" + strconv.Itoa(end) + "</NextContinuationToken>"
+		} else {
+			response += "<IsTruncated>false</IsTruncated>"
+		}
+		response += "<KeyCount>" + strconv.Itoa(end-start) + "</KeyCount></ListBucketResult>"
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write([]byte(response))
+}
+
+// Test listings in lexicographic order, directories included.
+func (s *TestSuite) TestListOrdered(c *C) {
+	server := httptest.NewServer(orderedListHandler{buckets: map[string][]string{
+		"a0b": {"k"},
+		// "a/" is a directory marker.
+		"b1": {"a.txt", "a/", "a/b", "a/b/c", "a/x", "a0", "z/y/w"},
+	}})
+	defer server.Close()
+
+	testCases := []struct {
+		path        string
+		isRecursive bool
+		showDir     DirOpt
+		golden      []string
+	}{
+		{"/b1/", false, DirNone, []string{"/b1/a.txt", "/b1/a/ dir", "/b1/a0", "/b1/z/ dir"}},
+		{"/b1/", true, DirNone, []string{"/b1/a.txt", "/b1/a/", "/b1/a/b", "/b1/a/b/c", "/b1/a/x", "/b1/a0", "/b1/z/y/w"}},
+		{"/b1/", true, DirFirst, []string{
+			"/b1/a.txt", "/b1/a/ dir", "/b1/a/b", "/b1/a/b/ dir", "/b1/a/b/c", "/b1/a/x",
+			"/b1/a0", "/b1/z/ dir", "/b1/z/y/ dir", "/b1/z/y/w",
+		}},
+		{"/b1/", true, DirLast, []string{
+			"/b1/a.txt", "/b1/a/b", "/b1/a/b/c", "/b1/a/b/ dir", "/b1/a/x", "/b1/a/ dir",
+			"/b1/a0", "/b1/z/y/w", "/b1/z/y/ dir", "/b1/z/ dir",
+		}},
+		{"/b1/a/", true, DirFirst, []string{"/b1/a/b", "/b1/a/b/ dir", "/b1/a/b/c", "/b1/a/x"}},
+		{"/b1/a/", true, DirLast, []string{"/b1/a/b", "/b1/a/b/c", "/b1/a/b/ dir", "/b1/a/x"}},
+		{"/", false, DirNone, []string{"/a0b dir", "/b1 dir"}},
+		{"/", true, DirNone, []string{"/a0b/k", "/b1/a.txt", "/b1/a/", "/b1/a/b", "/b1/a/b/c", "/b1/a/x", "/b1/a0", "/b1/z/y/w"}},
+		{"/", true, DirFirst, []string{
+			"/a0b dir", "/a0b/k",
+			"/b1 dir", "/b1/a.txt", "/b1/a/ dir", "/b1/a/b", "/b1/a/b/ dir", "/b1/a/b/c", "/b1/a/x",
+			"/b1/a0", "/b1/z/ dir", "/b1/z/y/ dir", "/b1/z/y/w",
+		}},
+		{"/", true, DirLast, []string{
+			"/a0b/k", "/a0b dir",
+			"/b1/a.txt", "/b1/a/b", "/b1/a/b/c", "/b1/a/b/ dir", "/b1/a/x", "/b1/a/ dir",
+			"/b1/a0", "/b1/z/y/w", "/b1/z/y/ dir", "/b1/z/ dir", "/b1 dir",
+		}},
+	}
+	for i, testCase := range testCases {
+		conf := testConfig(server.URL+testCase.path, "S3v4")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+
+		var got []string
+		for content := range clnt.(*S3Client).ListOrdered(testCase.isRecursive, false, testCase.showDir) {
+			c.Assert(content.Err, IsNil, Commentf("test %d", i+1))
+			entry := content.URL.Path
+			if content.Type.IsDir() {
+				entry += " dir"
+			}
+			got = append(got, entry)
+		}
+		c.Assert(got, DeepEquals, testCase.golden, Commentf("test %d", i+1))
+	}
+}
+
+// Test streaming a copy between two endpoints.
+func (s *TestSuite) TestStreamCopy(c *C) {
+	data := []byte("name,city\nJohn,Ottawa\n")
+	source := httptest.NewServer(downloadHandler{data: data, attrs: "mtime:1600000000"})
+	defer source.Close()
+	var stored []byte
+	object := memoryObjectHandler{resource: "/bucket/copy", data: &stored}
+	var headers []http.Header
+	target := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer target.Close()
+
+	newClient := func(url string) *S3Client {
+		conf := testConfig(url, "S3v2")
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		return clnt.(*S3Client)
+	}
+	src := newClient(source.URL + "/bucket/object")
+	dst := newClient(target.URL + object.resource)
+
+	n, err := src.StreamCopy(context.Background(), dst, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(stored, DeepEquals, data)
+	c.Assert(len(headers) > 0, Equals, true)
+	c.Assert(headers[len(headers)-1].Get("X-Amz-Meta-Mc-Attrs"), Equals, "mtime:1600000000")
+
+	// Metadata replaces the one of the object.
+	n, err = src.StreamCopy(context.Background(), dst, map[string]string{"Content-Type": "text/csv"}, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(headers[len(headers)-1].Get("X-Amz-Meta-Mc-Attrs"), Equals, "")
+	c.Assert(headers[len(headers)-1].Get("Content-Type"), Equals, "text/csv")
+
+	_, err = newClient(source.URL+"/bucket/missing").StreamCopy(context.Background(), dst, nil, nil, nil, nil)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ObjectMissing)
+	c.Assert(ok, Equals, true)
+
+	stored = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = src.StreamCopy(ctx, dst, nil, nil, nil, nil)
+	c.Assert(err, NotNil)
+	c.Assert(stored, IsNil)
+}
+
+// fakeResolverTransport answers requests to resolvable hosts, as an S3
+// server for s3 hosts, times out for slow hosts and fails like a DNS
+// lookup otherwise, recording the hosts requested.
+type fakeResolverTransport struct {
+	resolves map[string]bool
+	s3       map[string]bool
+	slow     map[string]bool
+	hosts    *[]string
+}
+
+func (t fakeResolverTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	*t.hosts = append(*t.hosts, r.URL.Host)
+	if t.slow[r.URL.Hostname()] {
+		return nil, errors.New("i/o timeout")
+	}
+	if !t.resolves[r.URL.Hostname()] {
+		return nil, &net.DNSError{Err: "no such host", Name: r.URL.Hostname()}
+	}
+	header := make(http.Header)
+	if t.s3[r.URL.Hostname()] {
+		header.Set("X-Amz-Request-Id", "REQUEST")
+	}
+	return &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    r,
+	}, nil
+}
+
+// Test probing whether buckets are reachable as subdomains.
+func (s *TestSuite) TestBucketLookupProbe(c *C) {
+	var hosts []string
+	bucketLookupProbeTransport = fakeResolverTransport{
+		resolves: map[string]bool{"bucket.wildcard.example.test": true, "bucket.web.example.test": true},
+		s3:       map[string]bool{"bucket.wildcard.example.test": true},
+		slow:     map[string]bool{"bucket.slow.example.test": true},
+		hosts:    &hosts,
+	}
+	defer func() { bucketLookupProbeTransport = nil }()
+
+	testCases := []struct {
+		hostURL string
+		lookup  minio.BucketLookupType
+		probed  []string
+		result  minio.BucketLookupType
+	}{
+		{"https://wildcard.example.test/bucket", minio.BucketLookupAuto, []string{"bucket.wildcard.example.test"}, minio.BucketLookupDNS},
+		// Remembered per host.
+		{"https://wildcard.example.test/bucket/object", minio.BucketLookupAuto, nil, minio.BucketLookupDNS},
+		{"https://nodns.example.test:9000/bucket", minio.BucketLookupAuto, []string{"bucket.nodns.example.test:9000"}, minio.BucketLookupPath},
+		// A web server answering any subdomain isn't an S3 server.
+		{"https://web.example.test/bucket", minio.BucketLookupAuto, []string{"bucket.web.example.test"}, minio.BucketLookupPath},
+		// Timeouts tell nothing, the next client probes again.
+		{"https://slow.example.test/bucket", minio.BucketLookupAuto, []string{"bucket.slow.example.test"}, minio.BucketLookupAuto},
+		{"https://slow.example.test/bucket", minio.BucketLookupAuto, []string{"bucket.slow.example.test"}, minio.BucketLookupAuto},
+		// Dotted buckets aren't covered by wildcard certificates.
+		{"https://wildcard.example.test/my.bucket", minio.BucketLookupAuto, nil, minio.BucketLookupPath},
+		{"https://other.example.test/my.bucket", minio.BucketLookupAuto, nil, minio.BucketLookupPath},
+		// Skipped when the lookup is set, or can't be probed.
+		{"https://explicit.example.test/bucket", minio.BucketLookupPath, nil, minio.BucketLookupPath},
+		{"https://explicit.example.test/bucket", minio.BucketLookupDNS, nil, minio.BucketLookupDNS},
+		{"https://nobucket.example.test", minio.BucketLookupAuto, nil, minio.BucketLookupAuto},
+		{"https://192.168.1.10:9000/bucket", minio.BucketLookupAuto, nil, minio.BucketLookupAuto},
+		{"https://s3.amazonaws.com/bucket", minio.BucketLookupAuto, nil, minio.BucketLookupAuto},
+	}
+	for i, testCase := range testCases {
+		hosts = nil
+		conf := testConfig(testCase.hostURL, "S3v4")
+		conf.Lookup = testCase.lookup
+		clnt, err := S3New(conf)
+		c.Assert(err, IsNil)
+		c.Assert(hosts, DeepEquals, testCase.probed, Commentf("test %d", i+1))
+		c.Assert(clnt.(*S3Client).bucketLookup, Equals, testCase.result, Commentf("test %d", i+1))
+	}
+}
+
+// Test configurations are validated before clients are made.
+func (s *TestSuite) TestConfigValidate(c *C) {
+	c.Assert(testConfig("http://localhost:9000/bucket/object", "S3v4").Validate(), IsNil)
+	c.Assert(testConfig("https://localhost:9000", "s3v2").Validate(), IsNil)
+	c.Assert(testConfig("https://localhost:9000", "").Validate(), IsNil)
+
+	testCases := []struct {
+		update func(*Config)
+		field  string
+	}{
+		{func(conf *Config) { conf.HostURL = "" }, "HostURL"},
+		{func(conf *Config) { conf.HostURL = "http://local host:9000/%zz" }, "HostURL"},
+		{func(conf *Config) { conf.HostURL = "ftp://localhost:9000" }, "HostURL"},
+		{func(conf *Config) { conf.HostURL = "localhost:9000/bucket" }, "HostURL"},
+		{func(conf *Config) { conf.HostURL = "http:///bucket" }, "HostURL"},
+		{func(conf *Config) { conf.Signature = "S3v3" }, "Signature"},
+		{func(conf *Config) { conf.SecretKey = "" }, "SecretKey"},
+		{func(conf *Config) { conf.Lookup = minio.BucketLookupType(42) }, "Lookup"},
+		{func(conf *Config) { conf.StreamPartSize = 1024 }, "StreamPartSize"},
+		{func(conf *Config) { conf.StreamPartSize = -minStreamPartSize }, "StreamPartSize"},
+		{func(conf *Config) { conf.MultipartThreads = -1 }, "MultipartThreads"},
+		{func(conf *Config) { conf.RequestTimeout = -time.Second }, "RequestTimeout"},
+		{func(conf *Config) { conf.DefaultSSE = "SSE-C"; conf.DefaultSSECKey = "short" }, "DefaultSSECKey"},
+		{func(conf *Config) { conf.DefaultSSE = "SSE-KMS" }, "DefaultSSEKMSKeyID"},
+		{func(conf *Config) { conf.DefaultSSE = "AES256" }, "DefaultSSE"},
+		{func(conf *Config) { conf.DefaultStorageClass = "COLD" }, "DefaultStorageClass"},
+		{func(conf *Config) { conf.ProxyByHost = map[string]string{"localhost": "proxy:3128"} }, "ProxyByHost"},
+		{func(conf *Config) { conf.TLSMinVersion = tls.VersionTLS11 }, "TLSMinVersion"},
+		{func(conf *Config) { conf.TLSMaxVersion = 0x0305 }, "TLSMaxVersion"},
+		{func(conf *Config) { conf.TLSMinVersion, conf.TLSMaxVersion = tls.VersionTLS13, tls.VersionTLS12 }, "TLSMaxVersion"},
+		{func(conf *Config) { conf.TLSCipherSuites = []uint16{tls.TLS_RSA_WITH_RC4_128_SHA} }, "TLSCipherSuites"},
+		{func(conf *Config) {
+			conf.TLSMinVersion = tls.VersionTLS13
+			conf.TLSCipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+		}, "TLSCipherSuites"},
+	}
+	for i, testCase := range testCases {
+		conf := testConfig("http://localhost:9000/bucket/object", "S3v4")
+		testCase.update(conf)
+		err := conf.Validate()
+		c.Assert(err, NotNil, Commentf("test %d", i+1))
+		var invalid InvalidConfig
+		c.Assert(errors.As(err.ToGoError(), &invalid), Equals, true, Commentf("test %d", i+1))
+		c.Assert(invalid.Field, Equals, testCase.field, Commentf("test %d", i+1))
+		c.Assert(invalid.Reason, Not(Equals), "", Commentf("test %d", i+1))
+
+		_, err = S3New(conf)
+		c.Assert(err, NotNil, Commentf("test %d", i+1))
+	}
+}
+
+// Test tracing requests as JSON objects.
+func (s *TestSuite) TestTraceJSON(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	server := httptest.NewServer(object)
+	defer server.Close()
+
+	var traces bytes.Buffer
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	conf.Debug = true
+	conf.TraceJSON = &traces
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	_, err = clnt.Put(context.Background(), bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	reader, err := clnt.Get(context.Background(), nil)
+	c.Assert(err, IsNil)
+	_, e := ioutil.ReadAll(reader)
+	c.Assert(e, IsNil)
+	reader.Close()
+
+	var puts, gets int
+	scanner := bufio.NewScanner(&traces)
+	for scanner.Scan() {
+		var trace jsonTrace
+		c.Assert(json.Unmarshal(scanner.Bytes(), &trace), IsNil)
+		c.Assert(trace.Status, Equals, http.StatusOK)
+		c.Assert(trace.RequestHeader.Get("Authorization"), Equals, "**REDACTED**")
+		c.Assert(strings.HasPrefix(trace.URL, server.URL), Equals, true)
+		switch trace.Method {
+		case "PUT":
+			puts++
+			// Streaming signatures add chunk headers to the content.
+			c.Assert(trace.RequestBytes >= int64(len(object.data)), Equals, true)
+		case "GET":
+			if strings.HasSuffix(trace.URL, object.resource) {
+				gets++
+				c.Assert(trace.ResponseBytes, Equals, int64(len(object.data)))
+			}
+		}
+	}
+	c.Assert(puts, Equals, 1)
+	c.Assert(gets, Equals, 1)
+}
+
+// warmupHandler answers ListBuckets requests, holding the first ones
+// until all of them arrived so that they need their own connections.
+type warmupHandler struct {
+	parallel int
+	mutex    *sync.Mutex
+	arrived  *int
+	release  chan struct{}
+}
+
+func (h warmupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	*h.arrived++
+	if *h.arrived == h.parallel {
+		close(h.release)
+	}
+	h.mutex.Unlock()
+	select {
+	case <-h.release:
+	case <-time.After(5 * time.Second):
+	}
+	response := []byte("<ListAllMyBucketsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Owner><ID>minio</ID></Owner><Buckets></Buckets></ListAllMyBucketsResult>")
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write(response)
+}
+
+// Test warming up connections, which later requests reuse.
+func (s *TestSuite) TestWarmup(c *C) {
+	const connections = 3
+	var arrived int
+	server := httptest.NewUnstartedServer(warmupHandler{
+		parallel: connections,
+		mutex:    &sync.Mutex{},
+		arrived:  &arrived,
+		release:  make(chan struct{}),
+	})
+	var connMutex sync.Mutex
+	var newConns int
+	connStates := make(map[net.Conn]http.ConnState)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		connMutex.Lock()
+		if state == http.StateNew {
+			newConns++
+		}
+		connStates[conn] = state
+		connMutex.Unlock()
+	}
+	// idleConns - number of connections kept open without requests,
+	// waiting a bit for the server to see responses through.
+	idleConns := func() int {
+		deadline := time.Now().Add(time.Second)
+		for {
+			connMutex.Lock()
+			var idle int
+			for _, state := range connStates {
+				if state == http.StateIdle {
+					idle++
+				}
+			}
+			connMutex.Unlock()
+			if idle == connections || time.Now().After(deadline) {
+				return idle
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	conf := testConfig(server.URL, "S3v4")
+	conf.MultipartThreads = connections
+	conf.WarmupOnCreate = true
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+	s3c := clnt.(*S3Client)
+
+	connMutex.Lock()
+	c.Assert(newConns, Equals, connections)
+	connMutex.Unlock()
+	// All of them are left open in the idle pool.
+	c.Assert(idleConns(), Equals, connections)
+
+	// The pool already has the connections.
+	c.Assert(s3c.Warmup(context.Background(), connections), IsNil)
+	connMutex.Lock()
+	c.Assert(newConns, Equals, connections)
+	connMutex.Unlock()
+	c.Assert(idleConns(), Equals, connections)
+
+	c.Assert(s3c.Warmup(context.Background(), 0), NotNil)
+}
+
+// Test appending a suffix to the User-Agent of some calls only.
+func (s *TestSuite) TestUserAgentSuffix(c *C) {
+	object := objectHandler(objectHandler{
+		resource: "/bucket/object",
+		data:     []byte("Hello, World"),
+	})
+	var headers []http.Header
+	server := httptest.NewServer(recordHandler{handler: object, headers: &headers})
+	defer server.Close()
+
+	conf := testConfig(server.URL+object.resource, "S3v4")
+	conf.AppName = "mc"
+	conf.AppVersion = "1.0"
+	s3c, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	ctx := WithUserAgentSuffix(WithUserAgentSuffix(context.Background(), "mirror"), "watch")
+	_, err = s3c.Put(ctx, bytes.NewReader(object.data), int64(len(object.data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+	_, err = s3c.Stat(ctx, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(len(headers) > 0, Equals, true)
+	for _, header := range headers {
+		userAgent := header.Get("User-Agent")
+		c.Assert(strings.Contains(userAgent, "mc/1.0"), Equals, true)
+		c.Assert(strings.HasSuffix(userAgent, " mirror watch"), Equals, true)
+	}
+
+	headers = nil
+	_, err = s3c.Stat(context.Background(), false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(len(headers) > 0, Equals, true)
+	for _, header := range headers {
+		c.Assert(strings.HasSuffix(header.Get("User-Agent"), "watch"), Equals, false)
+	}
+}
+
+// notificationHandler serves the notification config of a bucket,
+// recording the requests changing it.
+type notificationHandler struct {
+	config string
+	puts   *int
+}
+
+func (h notificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["location"]; ok {
+		response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+		w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		w.Write(response)
+		return
+	}
+	if _, ok := query["notification"]; !ok || r.URL.Path != "/bucket/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == "PUT" {
+		*h.puts++
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	response := []byte("<NotificationConfiguration xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\">" + h.config + "</NotificationConfiguration>")
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.Write(response)
+}
+
+// Test checking a notification config against the existing ones
+// without adding it.
+func (s *TestSuite) TestCheckNotificationConfig(c *C) {
+	config := "<QueueConfiguration><Id>1</Id><Queue>arn:minio:sqs::1:webhook</Queue>" +
+		"<Event>s3:ObjectCreated:*</Event><Event>s3:ObjectRemoved:*</Event>" +
+		"<Filter><S3Key><FilterRule><Name>prefix</Name><Value>photos/</Value></FilterRule>" +
+		"<FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule></S3Key></Filter></QueueConfiguration>" +
+		"<TopicConfiguration><Id>2</Id><Topic>arn:minio:sns::1:kafka</Topic>" +
+		"<Event>s3:ObjectAccessed:Get</Event></TopicConfiguration>"
+	var puts int
+	server := httptest.NewServer(notificationHandler{config: config, puts: &puts})
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/", "S3v4")
+
+	testCases := []struct {
+		arn       string
+		events    []string
+		prefix    string
+		suffix    string
+		conflicts map[string][]string
+		invalid   bool
+	}{
+		{"arn:minio:sqs::1:other", []string{"put"}, "", "", map[string][]string{"1": {"s3:ObjectCreated:*"}}, false},
+		{"arn:minio:sqs::1:other", []string{"put", "delete"}, "photos/2020/", "", map[string][]string{"1": {"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}}, false},
+		{"arn:minio:sqs::1:other", []string{"put"}, "docs/", "", map[string][]string{}, false},
+		{"arn:minio:sqs::1:other", []string{"put"}, "", ".png", map[string][]string{}, false},
+		{"arn:minio:sqs::1:other", []string{"put", "get"}, "photos/", "large.jpg", map[string][]string{"1": {"s3:ObjectCreated:*"}, "2": {"s3:ObjectAccessed:Get"}}, false},
+		{"arn:minio:sqs::1:other", []string{"post"}, "", "", nil, true},
+		{"arn:minio:queue::1:other", []string{"put"}, "", "", nil, true},
+	}
+	for i, testCase := range testCases {
+		conflicts, err := s3c.CheckNotificationConfig(context.Background(), testCase.arn, testCase.events, testCase.prefix, testCase.suffix)
+		if testCase.invalid {
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("test %d", i+1))
+		got := map[string][]string{}
+		for _, conflict := range conflicts {
+			got[conflict.Config.ID] = conflict.Events
+		}
+		c.Assert(got, DeepEquals, testCase.conflicts, Commentf("test %d", i+1))
+	}
+	c.Assert(puts, Equals, 0)
+}
+
+// etagWriter replaces the ETag of responses, e.g. to mock objects whose
+// content no longer matches their ETag.
+type etagWriter struct {
+	http.ResponseWriter
+	etag string
+}
+
+func (w etagWriter) WriteHeader(statusCode int) {
+	w.Header().Set("ETag", w.etag)
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w etagWriter) Write(b []byte) (int, error) {
+	w.Header().Set("ETag", w.etag)
+	return w.ResponseWriter.Write(b)
+}
+
+// Test verifying the content of objects against a SHA256 or their ETag.
+func (s *TestSuite) TestVerifyIntegrity(c *C) {
+	var stored []byte
+	var etag string
+	handler := memoryObjectHandler{resource: "/bucket/object", data: &stored}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && etag != "" {
+			w = etagWriter{ResponseWriter: w, etag: etag}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	s3c := newTestS3Client(c, server.URL+"/bucket/object", "S3v4")
+	data := []byte("Hello, World")
+	_, err := s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), map[string]string{}, nil, nil, false, false, "")
+	c.Assert(err, IsNil)
+
+	sha256Sum := sha256.Sum256(data)
+	md5Sum := md5.Sum(data)
+	testCases := []struct {
+		etag      string
+		sha256    string
+		algorithm string
+		invalid   bool
+	}{
+		{"", hex.EncodeToString(sha256Sum[:]), "", false},
+		{"", strings.ToUpper(hex.EncodeToString(sha256Sum[:])), "", false},
+		{"", "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", "SHA256", false},
+		{hex.EncodeToString(md5Sum[:]), "", "", false},
+		// Corrupt content, or ETag.
+		{"9af2f8218b150c351ad802c6f3d66abe", "", "MD5", false},
+		// ETags of multipart uploads aren't MD5s.
+		{"9af2f8218b150c351ad802c6f3d66abe-2", "", "", true},
+	}
+	for i, testCase := range testCases {
+		etag = testCase.etag
+		ok, err := s3c.VerifyIntegrity(context.Background(), testCase.sha256)
+		switch {
+		case testCase.invalid:
+			c.Assert(err, NotNil, Commentf("test %d", i+1))
+			c.Assert(errors.As(err.ToGoError(), &IntegrityMismatch{}), Equals, false, Commentf("test %d", i+1))
+		case testCase.algorithm != "":
+			c.Assert(ok, Equals, false, Commentf("test %d", i+1))
+			var mismatch IntegrityMismatch
+			c.Assert(errors.As(err.ToGoError(), &mismatch), Equals, true, Commentf("test %d", i+1))
+			c.Assert(mismatch.Algorithm, Equals, testCase.algorithm, Commentf("test %d", i+1))
+			c.Assert(mismatch.Got, Not(Equals), mismatch.Expected, Commentf("test %d", i+1))
+		default:
+			c.Assert(err, IsNil, Commentf("test %d", i+1))
+			c.Assert(ok, Equals, true, Commentf("test %d", i+1))
+		}
+	}
+}
+
+// Test Put and Get give up on slow servers after the request timeout.
+func (s *TestSuite) TestRequestTimeout(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			response := []byte("<LocationConstraint xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"></LocationConstraint>")
+			w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+			w.Write(response)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	conf := testConfig(server.URL+"/bucket/object", "S3v4")
+	conf.RequestTimeout = time.Second
+	clnt, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	start := time.Now()
+	data := []byte("hello")
+	_, err = clnt.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, nil, nil, false, false, "")
+	c.Assert(err, NotNil)
+	c.Assert(err.ToGoError(), Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) < 3*time.Second, Equals, true)
+
+	start = time.Now()
+	reader, err := clnt.Get(context.Background(), nil)
+	c.Assert(err, IsNil)
+	_, e := ioutil.ReadAll(reader)
+	c.Assert(e, Equals, context.DeadlineExceeded)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(time.Since(start) < 3*time.Second, Equals, true)
 }
@@ -18,8 +18,12 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/minio/mc/pkg/probe"
@@ -43,16 +47,26 @@ const (
 // Default number of multipart workers for a Put operation.
 const defaultMultipartThreadsNum = 4
 
+// Part size used by Put for streams of unknown size, each part is
+// buffered in memory before upload. An upload has at most 10000 parts,
+// which bounds such streams to 10000 times the part size, 640GiB by
+// default.
+const (
+	defaultStreamPartSize = 64 * 1024 * 1024
+	minStreamPartSize     = 5 * 1024 * 1024
+	maxStreamPartSize     = 5 * 1024 * 1024 * 1024
+)
+
 // Client - client interface
 type Client interface {
 	// Common operations
-	Stat(isIncomplete, isPreserve bool, sse encrypt.ServerSide) (content *ClientContent, err *probe.Error)
+	Stat(ctx context.Context, isIncomplete, isPreserve bool, sse encrypt.ServerSide) (content *ClientContent, err *probe.Error)
 	List(isRecursive, isIncomplete, isFetchMeta bool, showDir DirOpt) <-chan *ClientContent
 
 	// Bucket operations
-	MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error
-	SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error
-	GetObjectLockConfig() (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error)
+	MakeBucket(ctx context.Context, region string, ignoreExisting, withLock bool) *probe.Error
+	SetObjectLockConfig(ctx context.Context, mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error
+	GetObjectLockConfig(ctx context.Context) (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error)
 
 	// Access policy operations.
 	GetAccess() (access string, policyJSON string, error *probe.Error)
@@ -60,17 +74,17 @@ type Client interface {
 	SetAccess(access string, isJSON bool) *probe.Error
 
 	// I/O operations
-	Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool) *probe.Error
+	Copy(ctx context.Context, source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool, cannedACL string) *probe.Error
 
 	// Runs select expression on object storage on specific files.
 	Select(expression string, sse encrypt.ServerSide, opts SelectObjectOpts) (io.ReadCloser, *probe.Error)
 
 	// I/O operations with metadata.
-	Get(sse encrypt.ServerSide) (reader io.ReadCloser, err *probe.Error)
-	Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool) (n int64, err *probe.Error)
+	Get(ctx context.Context, sse encrypt.ServerSide) (reader io.ReadCloser, err *probe.Error)
+	Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide, md5, disableMultipart bool, cannedACL string) (n int64, err *probe.Error)
 	// Object Locking related API
-	PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error
-	PutObjectLegalHold(hold *minio.LegalHoldStatus) *probe.Error
+	PutObjectRetention(ctx context.Context, mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error
+	PutObjectLegalHold(ctx context.Context, hold *minio.LegalHoldStatus) *probe.Error
 
 	// I/O operations with expiration
 	ShareDownload(expires time.Duration) (string, *probe.Error)
@@ -80,21 +94,24 @@ type Client interface {
 	Watch(params watchParams) (*WatchObject, *probe.Error)
 
 	// Delete operations
-	Remove(isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent) (errorCh <-chan *probe.Error)
+	Remove(ctx context.Context, isIncomplete, isRemoveBucket, isBypass bool, contentCh <-chan *ClientContent) (errorCh <-chan *probe.Error)
 	// GetURL returns back internal url
 	GetURL() ClientURL
 
 	AddUserAgent(app, version string)
 
 	// Object Tag operations
-	GetObjectTagging() (tagging.Tagging, *probe.Error)
-	SetObjectTagging(tagMap map[string]string) *probe.Error
-	DeleteObjectTagging() *probe.Error
+	GetObjectTagging(ctx context.Context) (tagging.Tagging, *probe.Error)
+	SetObjectTagging(ctx context.Context, tagMap map[string]string) *probe.Error
+	DeleteObjectTagging(ctx context.Context) *probe.Error
 }
 
 // ClientContent - Content container for content metadata
 type ClientContent struct {
-	URL               ClientURL
+	URL ClientURL
+	// Key is the exact key of a listed object, which URL may not
+	// represent faithfully, e.g. when it contains "//".
+	Key               string
 	Time              time.Time
 	Size              int64
 	Type              os.FileMode
@@ -109,6 +126,34 @@ type ClientContent struct {
 	BypassGovernance  bool
 	LegalHold         string
 	Err               *probe.Error
+	// Encryption is the server side encryption of the object, one of
+	// AES256 (SSE-S3), aws:kms (SSE-KMS) or SSE-C, empty if none.
+	Encryption string
+	// Restore is the restore status of an archived object, nil if no
+	// restore was requested.
+	Restore *RestoreStatus
+	// MetadataFetched tells whether Metadata and UserMetadata hold the
+	// object metadata, empty maps then meaning the object has none.
+	// Listings set it only when metadata was requested, leaving the
+	// maps nil otherwise. Listings of backends which can't return
+	// metadata, such as Google Cloud Storage, don't set it even when
+	// requested, S3Client.LoadMetadata fetches it with a stat then.
+	MetadataFetched bool
+	// SelectFormat is the format S3 Select reads the object as, one of
+	// csv, json, parquet or orc, only set by ListSelectableObjects.
+	SelectFormat string
+	// VersionID is the version of the object, empty for its latest
+	// version or if unknown.
+	VersionID string
+}
+
+// RestoreStatus - status of the restore of an archived object, e.g.
+// of the GLACIER storage class.
+type RestoreStatus struct {
+	// Ongoing is true while the object is being restored.
+	Ongoing bool
+	// Expiry is when the restored copy is removed, zero while ongoing.
+	Expiry time.Time
 }
 
 // Config - see http://docs.amazonwebservices.com/AmazonS3/latest/dev/index.html?RESTAuthentication.html
@@ -123,6 +168,189 @@ type Config struct {
 	Debug       bool
 	Insecure    bool
 	Lookup      minio.BucketLookupType
+	// Region, if set, is the region requests are signed for, instead of
+	// the one looked up for each bucket.
+	Region string
+	// TLSServerName overrides the hostname used to verify the server
+	// certificate, e.g. when TLS terminates on a load balancer.
+	TLSServerName string
+	// TLSMinVersion and TLSMaxVersion bound the TLS version of
+	// connections, tls.VersionTLS12 or tls.VersionTLS13. Connections
+	// use TLS 1.2 at least and the latest version if unset.
+	TLSMinVersion uint16
+	TLSMaxVersion uint16
+	// TLSCipherSuites restricts the cipher suites of TLS 1.2
+	// connections to those of tlsCipherSuites listed, e.g. FIPS
+	// approved ones. TLS 1.3 suites can't be restricted.
+	TLSCipherSuites []uint16
+	// DefaultSSE is the encryption applied when a call doesn't set
+	// any, one of SSE-S3, SSE-KMS (with DefaultSSEKMSKeyID) or SSE-C
+	// (with the 32 bytes DefaultSSECKey).
+	DefaultSSE         string
+	DefaultSSEKMSKeyID string
+	DefaultSSECKey     string
+	// DefaultStorageClass is the storage class of uploads and copies
+	// whose metadata doesn't set any.
+	DefaultStorageClass string
+	// StreamPartSize is the memory buffered per part when uploading a
+	// stream of unknown size, between 5MiB and 5GiB, 64MiB if unset.
+	// Such streams can't exceed StreamPartSize * 10000 bytes.
+	StreamPartSize int64
+	// MultipartThreads is the number of parts Put uploads in parallel,
+	// defaultMultipartThreadsNum if unset.
+	MultipartThreads int
+	// RequestTimeout bounds the time Put takes to upload an object and
+	// Get to download one, until the reader it returns is closed. The
+	// calls fail with context.DeadlineExceeded once it is exceeded.
+	// Dialing connections has its own 10s timeout.
+	RequestTimeout time.Duration
+	// ObjectExpiryHeader is the request header the backend reads the
+	// expiry date of an object from, to remove it once expired. Objects
+	// can't expire on their own when empty, S3 itself has no such
+	// header.
+	ObjectExpiryHeader string
+	// ProxyByHost maps endpoint hostnames to the URL of the proxy to
+	// use for them, an empty URL connects directly. Other hosts use
+	// the proxy from the environment.
+	ProxyByHost map[string]string
+	// UseEC2Metadata gets credentials from the EC2 instance metadata
+	// service when AccessKey and SecretKey are empty.
+	UseEC2Metadata bool
+	// UseECSCredentials gets the task role credentials from the ECS
+	// endpoint of the container environment when AccessKey and
+	// SecretKey are empty.
+	UseECSCredentials bool
+	// CredentialProcess is a shell command printing credentials as
+	// JSON, like the credential_process setting of AWS SDKs. When set,
+	// AccessKey and SecretKey are ignored.
+	CredentialProcess string
+	// UnixSocket is the path of a Unix socket to connect to instead
+	// of the URL host, e.g. for a local MinIO server.
+	UnixSocket string
+	// TraceJSON, when set with Debug, receives the trace of every
+	// request instead of the console, as one JSON object per line with
+	// the method, URL, status, duration, headers and byte counts.
+	// Secrets are redacted.
+	TraceJSON io.Writer
+	// WarmupOnCreate opens as many connections to the host as parts
+	// are uploaded in parallel when the first client of the host is
+	// made, see S3Client.Warmup.
+	WarmupOnCreate bool
+	// ForceListV1 lists objects with ListObjects V1 only, for servers
+	// which claim to support V2 but get it wrong. Google Cloud Storage
+	// is always listed with V1.
+	ForceListV1 bool
+}
+
+// tlsCipherSuites - cipher suites of TLS 1.2 connections which can be
+// configured, those crypto/tls supports but RC4 ones.
+var tlsCipherSuites = []uint16{
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// Validate - check the configuration before a client is made with it,
+// reporting the first invalid field as InvalidConfig.
+func (c *Config) Validate() *probe.Error {
+	if c.HostURL == "" {
+		return probe.NewError(InvalidConfig{Field: "HostURL", Reason: "it is empty"})
+	}
+	u, e := url.Parse(c.HostURL)
+	if e != nil {
+		return probe.NewError(InvalidConfig{Field: "HostURL", Reason: e.Error()})
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return probe.NewError(InvalidConfig{Field: "HostURL", Reason: "the scheme must be http or https, not `" + u.Scheme + "`"})
+	}
+	if u.Host == "" {
+		return probe.NewError(InvalidConfig{Field: "HostURL", Reason: "it has no host"})
+	}
+	switch strings.ToUpper(c.Signature) {
+	case "", "S3V2", "S3V4":
+	default:
+		return probe.NewError(InvalidConfig{Field: "Signature", Reason: "it must be S3v2 or S3v4, not `" + c.Signature + "`"})
+	}
+	if (c.AccessKey == "") != (c.SecretKey == "") {
+		return probe.NewError(InvalidConfig{Field: "SecretKey", Reason: "AccessKey and SecretKey must be both set or both empty"})
+	}
+	switch c.Lookup {
+	case minio.BucketLookupAuto, minio.BucketLookupDNS, minio.BucketLookupPath:
+	default:
+		return probe.NewError(InvalidConfig{Field: "Lookup", Reason: "unknown bucket lookup " + strconv.Itoa(int(c.Lookup))})
+	}
+	if c.StreamPartSize != 0 && (c.StreamPartSize < minStreamPartSize || c.StreamPartSize > maxStreamPartSize) {
+		return probe.NewError(InvalidConfig{Field: "StreamPartSize", Reason: "it must be between 5MiB and 5GiB, not " + strconv.FormatInt(c.StreamPartSize, 10) + " bytes"})
+	}
+	if c.MultipartThreads < 0 {
+		return probe.NewError(InvalidConfig{Field: "MultipartThreads", Reason: "it is negative"})
+	}
+	if c.RequestTimeout < 0 {
+		return probe.NewError(InvalidConfig{Field: "RequestTimeout", Reason: "it is negative"})
+	}
+	switch strings.ToUpper(c.DefaultSSE) {
+	case "", defaultSSES3:
+	case defaultSSEKMS:
+		if c.DefaultSSEKMSKeyID == "" {
+			return probe.NewError(InvalidConfig{Field: "DefaultSSEKMSKeyID", Reason: "SSE-KMS needs a key id"})
+		}
+	case defaultSSEC:
+		if len(c.DefaultSSECKey) != 32 {
+			return probe.NewError(InvalidConfig{Field: "DefaultSSECKey", Reason: "SSE-C keys are 32 bytes long, not " + strconv.Itoa(len(c.DefaultSSECKey))})
+		}
+	default:
+		return probe.NewError(InvalidConfig{Field: "DefaultSSE", Reason: "it must be SSE-S3, SSE-KMS or SSE-C, not `" + c.DefaultSSE + "`"})
+	}
+	if _, err := normalizeStorageClass(c.DefaultStorageClass); err != nil {
+		return probe.NewError(InvalidConfig{Field: "DefaultStorageClass", Reason: err.ToGoError().Error()})
+	}
+	for field, version := range map[string]uint16{"TLSMinVersion": c.TLSMinVersion, "TLSMaxVersion": c.TLSMaxVersion} {
+		switch version {
+		case 0, tls.VersionTLS12, tls.VersionTLS13:
+		default:
+			return probe.NewError(InvalidConfig{Field: field, Reason: "it must be TLS 1.2 or 1.3, not 0x" + strconv.FormatUint(uint64(version), 16)})
+		}
+	}
+	if c.TLSMaxVersion != 0 && c.TLSMaxVersion < c.TLSMinVersion {
+		return probe.NewError(InvalidConfig{Field: "TLSMaxVersion", Reason: "it is lower than TLSMinVersion"})
+	}
+	if len(c.TLSCipherSuites) > 0 && c.TLSMinVersion == tls.VersionTLS13 {
+		return probe.NewError(InvalidConfig{Field: "TLSCipherSuites", Reason: "TLS 1.3 cipher suites can't be restricted"})
+	}
+	for _, suite := range c.TLSCipherSuites {
+		supported := false
+		for _, s := range tlsCipherSuites {
+			supported = supported || s == suite
+		}
+		if !supported {
+			return probe.NewError(InvalidConfig{Field: "TLSCipherSuites", Reason: "unsupported cipher suite 0x" + strconv.FormatUint(uint64(suite), 16)})
+		}
+	}
+	for host, proxyURL := range c.ProxyByHost {
+		if proxyURL == "" {
+			continue
+		}
+		if u, e := url.Parse(proxyURL); e != nil || u.Scheme == "" || u.Host == "" {
+			return probe.NewError(InvalidConfig{Field: "ProxyByHost", Reason: "the proxy of `" + host + "` isn't a URL: `" + proxyURL + "`"})
+		}
+	}
+	return nil
 }
 
 // SelectObjectOpts - opts entered for select API
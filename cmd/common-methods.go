@@ -148,7 +148,7 @@ func getSourceStreamMetadataFromURL(urlStr string, encKeyDB map[string][]prefixS
 		return nil, nil, err.Trace(urlStr)
 	}
 	sseKey := getSSE(urlStr, encKeyDB[alias])
-	return getSourceStream(alias, urlStrFull, true, sseKey, false)
+	return getSourceStream(globalContext, alias, urlStrFull, true, sseKey, false)
 }
 
 // getSourceStreamFromURL gets a reader from URL.
@@ -158,7 +158,7 @@ func getSourceStreamFromURL(urlStr string, encKeyDB map[string][]prefixSSEPair)
 		return nil, err.Trace(urlStr)
 	}
 	sse := getSSE(urlStr, encKeyDB[alias])
-	reader, _, err = getSourceStream(alias, urlStrFull, false, sse, false)
+	reader, _, err = getSourceStream(globalContext, alias, urlStrFull, false, sse, false)
 	return reader, err
 }
 
@@ -210,12 +210,12 @@ func isReadAt(reader io.Reader) (ok bool) {
 }
 
 // getSourceStream gets a reader from URL.
-func getSourceStream(alias string, urlStr string, fetchStat bool, sse encrypt.ServerSide, preserve bool) (reader io.ReadCloser, metadata map[string]string, err *probe.Error) {
+func getSourceStream(ctx context.Context, alias string, urlStr string, fetchStat bool, sse encrypt.ServerSide, preserve bool) (reader io.ReadCloser, metadata map[string]string, err *probe.Error) {
 	sourceClnt, err := newClientFromAlias(alias, urlStr)
 	if err != nil {
 		return nil, nil, err.Trace(alias, urlStr)
 	}
-	reader, err = sourceClnt.Get(sse)
+	reader, err = sourceClnt.Get(ctx, sse)
 	if err != nil {
 		return nil, nil, err.Trace(alias, urlStr)
 	}
@@ -223,7 +223,10 @@ func getSourceStream(alias string, urlStr string, fetchStat bool, sse encrypt.Se
 	metadata = make(map[string]string)
 	if fetchStat {
 		var st *ClientContent
-		mo, mok := reader.(*minio.Object)
+		// S3 objects carry their stat.
+		mo, mok := reader.(interface {
+			Stat() (minio.ObjectInfo, error)
+		})
 		if mok {
 			oinfo, e := mo.Stat()
 			if e != nil {
@@ -241,7 +244,7 @@ func getSourceStream(alias string, urlStr string, fetchStat bool, sse encrypt.Se
 			}
 			st.ETag = oinfo.ETag
 		} else {
-			st, err = sourceClnt.Stat(false, preserve, sse)
+			st, err = sourceClnt.Stat(ctx, false, preserve, sse)
 			if err != nil {
 				return nil, nil, err.Trace(alias, urlStr)
 			}
@@ -290,7 +293,7 @@ func putTargetRetention(ctx context.Context, alias string, urlStr string, metada
 			retainUntilDate = t.UTC()
 		}
 	}
-	if err := targetClnt.PutObjectRetention(&lockMode, &retainUntilDate, false); err != nil {
+	if err := targetClnt.PutObjectRetention(ctx, &lockMode, &retainUntilDate, false); err != nil {
 		return err.Trace(alias, urlStr)
 	}
 	return nil
@@ -312,7 +315,7 @@ func putTargetStream(ctx context.Context, alias, urlStr, mode, until, legalHold
 	if legalHold != "" {
 		metadata[AmzObjectLockLegalHold] = legalHold
 	}
-	n, err := targetClnt.Put(ctx, reader, size, metadata, progress, sse, md5, disableMultipart)
+	n, err := targetClnt.Put(ctx, reader, size, metadata, progress, sse, md5, disableMultipart, "")
 	if err != nil {
 		return n, err.Trace(alias, urlStr)
 	}
@@ -333,7 +336,7 @@ func putTargetStreamWithURL(urlStr string, reader io.Reader, size int64, sse enc
 }
 
 // copySourceToTargetURL copies to targetURL from source.
-func copySourceToTargetURL(alias, urlStr, source, mode, until, legalHold string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool) *probe.Error {
+func copySourceToTargetURL(ctx context.Context, alias, urlStr, source, mode, until, legalHold string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string, disableMultipart bool) *probe.Error {
 
 	targetClnt, err := newClientFromAlias(alias, urlStr)
 	if err != nil {
@@ -343,7 +346,7 @@ func copySourceToTargetURL(alias, urlStr, source, mode, until, legalHold string,
 	metadata[AmzObjectLockMode] = mode
 	metadata[AmzObjectLockRetainUntilDate] = until
 	metadata[AmzObjectLockLegalHold] = legalHold
-	err = targetClnt.Copy(source, size, progress, srcSSE, tgtSSE, metadata, disableMultipart)
+	err = targetClnt.Copy(ctx, source, size, progress, srcSSE, tgtSSE, metadata, disableMultipart, "")
 
 	if err != nil {
 		return err.Trace(alias, urlStr)
@@ -368,13 +371,13 @@ func filterMetadata(metadata map[string]string) map[string]string {
 
 // getAllMetadata - returns a map of user defined function
 // by combining the usermetadata of object and values passed by attr keyword
-func getAllMetadata(sourceAlias, sourceURLStr string, srcSSE encrypt.ServerSide, urls URLs, preserve bool) (map[string]string, *probe.Error) {
+func getAllMetadata(ctx context.Context, sourceAlias, sourceURLStr string, srcSSE encrypt.ServerSide, urls URLs, preserve bool) (map[string]string, *probe.Error) {
 	metadata := make(map[string]string)
 	sourceClnt, err := newClientFromAlias(sourceAlias, sourceURLStr)
 	if err != nil {
 		return nil, err.Trace(sourceAlias, sourceURLStr)
 	}
-	st, err := sourceClnt.Stat(false, preserve, srcSSE)
+	st, err := sourceClnt.Stat(ctx, false, preserve, srcSSE)
 	if err != nil {
 		return nil, err.Trace(sourceAlias, sourceURLStr)
 	}
@@ -435,7 +438,7 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 		// If no metadata populated already by the caller
 		// just do a Stat() to obtain the metadata.
 		if len(metadata) == 0 {
-			metadata, err = getAllMetadata(sourceAlias, sourceURL.String(), srcSSE, urls, preserve)
+			metadata, err = getAllMetadata(ctx, sourceAlias, sourceURL.String(), srcSSE, urls, preserve)
 			if err != nil {
 				return urls.WithError(err.Trace(sourceURL.String()))
 			}
@@ -446,14 +449,14 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 			err = putTargetRetention(ctx, targetAlias, targetURL.String(), metadata)
 			return urls.WithError(err.Trace(sourceURL.String()))
 		}
-		err = copySourceToTargetURL(targetAlias, targetURL.String(), sourcePath, mode, until, urls.TargetContent.LegalHold, length,
+		err = copySourceToTargetURL(ctx, targetAlias, targetURL.String(), sourcePath, mode, until, urls.TargetContent.LegalHold, length,
 			progress, srcSSE, tgtSSE, filterMetadata(metadata), urls.DisableMultipart)
 	} else {
 		if urls.SourceContent.Retention {
 			// If no metadata populated already by the caller
 			// just do a Stat() to obtain the metadata.
 			if len(metadata) == 0 {
-				metadata, err = getAllMetadata(sourceAlias, sourceURL.String(), srcSSE, urls, preserve)
+				metadata, err = getAllMetadata(ctx, sourceAlias, sourceURL.String(), srcSSE, urls, preserve)
 				if err != nil {
 					return urls.WithError(err.Trace(sourceURL.String()))
 				}
@@ -464,7 +467,7 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 		}
 		var reader io.ReadCloser
 		// Proceed with regular stream copy.
-		reader, metadata, err = getSourceStream(sourceAlias, sourceURL.String(), true, srcSSE, preserve)
+		reader, metadata, err = getSourceStream(ctx, sourceAlias, sourceURL.String(), true, srcSSE, preserve)
 		if err != nil {
 			return urls.WithError(err.Trace(sourceURL.String()))
 		}
@@ -128,7 +128,7 @@ func mainEventAdd(ctx *cli.Context) error {
 		fatalIf(errDummy().Trace(), "The provided url doesn't point to a S3 server.")
 	}
 
-	err = s3Client.AddNotificationConfig(arn, event, prefix, suffix, ignoreExisting)
+	err = s3Client.AddNotificationConfig(globalContext, arn, event, prefix, suffix, ignoreExisting)
 	fatalIf(err, "Cannot enable notification on the specified bucket.")
 	printMsg(eventAddMessage{
 		ARN:    arn,
@@ -63,12 +63,14 @@ func checkEventListSyntax(ctx *cli.Context) {
 
 // eventListMessage container
 type eventListMessage struct {
-	Status string   `json:"status"`
-	ID     string   `json:"id"`
-	Event  []string `json:"event"`
-	Prefix string   `json:"prefix"`
-	Suffix string   `json:"suffix"`
-	Arn    string   `json:"arn"`
+	Status      string                   `json:"status"`
+	ID          string                   `json:"id"`
+	Type        string                   `json:"type"`
+	Event       []string                 `json:"event"`
+	Prefix      string                   `json:"prefix"`
+	Suffix      string                   `json:"suffix"`
+	FilterRules []NotificationFilterRule `json:"filterRules,omitempty"`
+	Arn         string                   `json:"arn"`
 }
 
 func (u eventListMessage) JSON() string {
@@ -120,16 +122,18 @@ func mainEventList(ctx *cli.Context) error {
 		fatalIf(errDummy().Trace(), "The provided url doesn't point to a S3 server.")
 	}
 
-	configs, err := s3Client.ListNotificationConfigs(arn)
+	configs, err := s3Client.ListNotificationConfigs(globalContext, arn)
 	fatalIf(err, "Cannot list notifications on the specified bucket.")
 
 	for _, config := range configs {
 		printMsg(eventListMessage{
-			Event:  config.Events,
-			Prefix: config.Prefix,
-			Suffix: config.Suffix,
-			Arn:    config.Arn,
-			ID:     config.ID})
+			Type:        config.Type,
+			Event:       config.Events,
+			Prefix:      config.Prefix,
+			Suffix:      config.Suffix,
+			FilterRules: config.FilterRules,
+			Arn:         config.Arn,
+			ID:          config.ID})
 	}
 
 	return nil
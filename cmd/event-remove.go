@@ -130,7 +130,7 @@ func mainEventRemove(ctx *cli.Context) error {
 	prefix := ctx.String("prefix")
 	suffix := ctx.String("suffix")
 
-	err = s3Client.RemoveNotificationConfig(arn, event, prefix, suffix)
+	err = s3Client.RemoveNotificationConfig(globalContext, arn, event, prefix, suffix)
 	if err != nil {
 		if err.Cause == minio.ErrNoNotificationConfigMatch {
 			fatalIf(err, "Remove event failed.")
@@ -92,7 +92,7 @@ func setLegalHold(urlStr string, lhold *minio.LegalHoldStatus, isRecursive bool)
 		fatalIf(err.Trace(), "Cannot parse the provided url.")
 	}
 	if !isRecursive {
-		probeErr := clnt.PutObjectLegalHold(lhold)
+		probeErr := clnt.PutObjectLegalHold(globalContext, lhold)
 		if probeErr != nil {
 			errorIf(probeErr.Trace(urlStr), "Failed to set legal hold on `"+urlStr+"` successfully")
 		} else {
@@ -120,7 +120,7 @@ func setLegalHold(urlStr string, lhold *minio.LegalHoldStatus, isRecursive bool)
 			errorIf(content.Err.Trace(clnt.GetURL().String()), "Invalid URL")
 			continue
 		}
-		probeErr := newClnt.PutObjectLegalHold(lhold)
+		probeErr := newClnt.PutObjectLegalHold(globalContext, lhold)
 		if probeErr != nil {
 			errorsFound = true
 			errorIf(probeErr.Trace(content.URL.Path), "Failed to set legal hold on `"+content.URL.Path+"` successfully")
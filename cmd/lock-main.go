@@ -119,10 +119,10 @@ func lock(urlStr string, mode *minio.RetentionMode, validity *uint, unit *minio.
 	}
 
 	if clearLock || mode != nil {
-		err = s3Client.SetObjectLockConfig(mode, validity, unit)
+		err = s3Client.SetObjectLockConfig(globalContext, mode, validity, unit)
 		fatalIf(err, "Cannot enable object lock configuration on the specified bucket.")
 	} else {
-		mode, validity, unit, err = s3Client.GetObjectLockConfig()
+		mode, validity, unit, err = s3Client.GetObjectLockConfig(globalContext)
 		fatalIf(err, "Cannot get object lock configuration on the specified bucket.")
 	}
 
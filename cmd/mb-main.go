@@ -134,7 +134,7 @@ func mainMakeBucket(ctx *cli.Context) error {
 		}
 
 		// Make bucket.
-		err = clnt.MakeBucket(region, ignoreExisting, withLock)
+		err = clnt.MakeBucket(globalContext, region, ignoreExisting, withLock)
 		if err != nil {
 			switch err.ToGoError().(type) {
 			case BucketNameEmpty:
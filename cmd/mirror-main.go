@@ -261,7 +261,7 @@ func (mj *mirrorJob) doRemove(sURLs URLs) URLs {
 	contentCh <- &ClientContent{URL: *newClientURL(sURLs.TargetContent.URL.Path)}
 	close(contentCh)
 	isRemoveBucket := false
-	errorCh := clnt.Remove(false, isRemoveBucket, false, contentCh)
+	errorCh := clnt.Remove(globalContext, false, isRemoveBucket, false, contentCh)
 	for pErr := range errorCh {
 		if pErr != nil {
 			switch pErr.ToGoError().(type) {
@@ -461,7 +461,7 @@ func (mj *mirrorJob) watchMirror(ctx context.Context, cancelMirror context.Cance
 					}
 					shouldQueue := false
 					if !mj.isOverwrite {
-						_, err = targetClient.Stat(false, false, tgtSSE)
+						_, err = targetClient.Stat(globalContext, false, false, tgtSSE)
 						if err == nil {
 							continue
 						} // doesn't exist
@@ -491,7 +491,7 @@ func (mj *mirrorJob) watchMirror(ctx context.Context, cancelMirror context.Cance
 						}
 						return
 					}
-					_, err = targetClient.Stat(false, false, tgtSSE)
+					_, err = targetClient.Stat(globalContext, false, false, tgtSSE)
 					if err == nil {
 						if mirrorURL.SourceContent.Retention {
 							shouldQueue = true
@@ -837,18 +837,18 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 
 			if d.Diff == differInFirst {
 				withLock := false
-				mode, validity, unit, err := newSrcClt.GetObjectLockConfig()
+				mode, validity, unit, err := newSrcClt.GetObjectLockConfig(globalContext)
 				if err == nil {
 					withLock = true
 				}
 				// Bucket only exists in the source, create the same bucket in the destination
-				if err := newDstClt.MakeBucket(ctx.String("region"), false, withLock); err != nil {
+				if err := newDstClt.MakeBucket(globalContext, ctx.String("region"), false, withLock); err != nil {
 					errorIf(err, "Unable to create bucket at `"+newTgtURL+"`.")
 					continue
 				}
 				// object lock configuration set on bucket
 				if mode != nil {
-					errorIf(newDstClt.SetObjectLockConfig(mode, validity, unit),
+					errorIf(newDstClt.SetObjectLockConfig(globalContext, mode, validity, unit),
 						"Unable to set object lock config in `"+newTgtURL+"`.")
 				}
 				errorIf(copyBucketPolicies(newSrcClt, newDstClt, isOverwrite),
@@ -869,7 +869,7 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 		}
 	} else {
 		withLock := false
-		mode, validity, unit, err := srcClt.GetObjectLockConfig()
+		mode, validity, unit, err := srcClt.GetObjectLockConfig(globalContext)
 		if err == nil {
 			withLock = true
 		}
@@ -877,19 +877,19 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 		// Create bucket if it doesn't exist at destination.
 		// ignore if already exists.
 		if mj.multiMasterEnable {
-			err = dstClt.MakeBucket(ctx.String("region"), true, withLock)
+			err = dstClt.MakeBucket(globalContext, ctx.String("region"), true, withLock)
 			errorIf(err, "Unable to create bucket at `"+dstURL+"`.")
 			if err != nil {
 				return true
 			}
 		} else {
-			mj.status.fatalIf(dstClt.MakeBucket(ctx.String("region"), true, withLock),
+			mj.status.fatalIf(dstClt.MakeBucket(globalContext, ctx.String("region"), true, withLock),
 				"Unable to create bucket at `"+dstURL+"`.")
 		}
 
 		// object lock configuration set on bucket
 		if mode != nil {
-			err = dstClt.SetObjectLockConfig(mode, validity, unit)
+			err = dstClt.SetObjectLockConfig(globalContext, mode, validity, unit)
 			errorIf(err, "Unable to set object lock config in `"+dstURL+"`.")
 			if err != nil && mj.multiMasterEnable {
 				return true
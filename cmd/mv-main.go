@@ -200,7 +200,7 @@ func (rm *removeManager) add(targetAlias, targetURL string) {
 		}
 
 		contentCh := make(chan *ClientContent)
-		errorCh := client.Remove(false, false, false, contentCh)
+		errorCh := client.Remove(globalContext, false, false, false, contentCh)
 		rm.readErrors(errorCh, targetURL)
 
 		clientInfo = &removeClientInfo{
@@ -248,7 +248,7 @@ func bgRemove(url string) {
 		contentCh := make(chan *ClientContent, 1)
 		contentCh <- &ClientContent{URL: *newClientURL(targetURL)}
 		close(contentCh)
-		errorCh := clnt.Remove(false, false, false, contentCh)
+		errorCh := clnt.Remove(globalContext, false, false, false, contentCh)
 		for pErr := range errorCh {
 			if pErr != nil {
 				errorIf(pErr.Trace(url), "Failed to remove `"+url+"`.")
@@ -294,7 +294,7 @@ func mainMove(ctx *cli.Context) error {
 		}
 
 		if s3Client, ok := client.(*S3Client); ok {
-			if _, _, _, err = s3Client.GetObjectLockConfig(); err == nil {
+			if _, _, _, err = s3Client.GetObjectLockConfig(globalContext); err == nil {
 				fatalIf(err, fmt.Sprintf("object lock configuration is enabled on the specified bucket in alias %v.", urlStr))
 			}
 		}
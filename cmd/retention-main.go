@@ -165,7 +165,7 @@ func setRetention(urlStr string, mode *minio.RetentionMode, validity *uint, unit
 			errorIf(content.Err.Trace(clnt.GetURL().String()), "Invalid URL")
 			continue
 		}
-		probeErr := newClnt.PutObjectRetention(mode, &retainUntil, bypassGovernance)
+		probeErr := newClnt.PutObjectRetention(globalContext, mode, &retainUntil, bypassGovernance)
 		if probeErr != nil {
 			errorsFound = true
 			printMsg(retentionCmdMessage{
@@ -243,7 +243,7 @@ func removeSingle(url string, isIncomplete, isFake, isForce, isBypass bool, olde
 		contentCh <- &ClientContent{URL: *newClientURL(targetURL)}
 		close(contentCh)
 		isRemoveBucket := false
-		errorCh := clnt.Remove(isIncomplete, isRemoveBucket, isBypass, contentCh)
+		errorCh := clnt.Remove(globalContext, isIncomplete, isRemoveBucket, isBypass, contentCh)
 		for pErr := range errorCh {
 			if pErr != nil {
 				errorIf(pErr.Trace(url), "Failed to remove `"+url+"`.")
@@ -269,7 +269,7 @@ func removeRecursive(url string, isIncomplete, isFake, isBypass bool, olderThan,
 	contentCh := make(chan *ClientContent)
 	isRemoveBucket := false
 
-	errorCh := clnt.Remove(isIncomplete, isRemoveBucket, isBypass, contentCh)
+	errorCh := clnt.Remove(globalContext, isIncomplete, isRemoveBucket, isBypass, contentCh)
 
 	isRecursive := true
 	for content := range clnt.List(isRecursive, isIncomplete, false, DirNone) {
@@ -125,7 +125,7 @@ func doShareDownloadURL(targetURL string, isRecursive bool, expiry time.Duration
 	// Channel which will receive objects whose URLs need to be shared
 	objectsCh := make(chan *ClientContent)
 
-	content, err := clnt.Stat(isIncomplete, false, nil)
+	content, err := clnt.Stat(globalContext, isIncomplete, false, nil)
 	if err != nil {
 		return err.Trace(clnt.GetURL().String())
 	}
@@ -30,14 +30,15 @@ import (
 
 // contentMessage container for content message structure.
 type statMessage struct {
-	Status   string            `json:"status"`
-	Key      string            `json:"name"`
-	Date     time.Time         `json:"lastModified"`
-	Size     int64             `json:"size"`
-	ETag     string            `json:"etag"`
-	Type     string            `json:"type"`
-	Expires  time.Time         `json:"expires"`
-	Metadata map[string]string `json:"metadata"`
+	Status     string            `json:"status"`
+	Key        string            `json:"name"`
+	Date       time.Time         `json:"lastModified"`
+	Size       int64             `json:"size"`
+	ETag       string            `json:"etag"`
+	Type       string            `json:"type"`
+	Expires    time.Time         `json:"expires"`
+	Encryption string            `json:"encryption,omitempty"`
+	Metadata   map[string]string `json:"metadata"`
 }
 
 // String colorized string message.
@@ -54,6 +55,9 @@ func printStat(stat statMessage) {
 	if !stat.Expires.IsZero() {
 		console.Println(fmt.Sprintf("%-10s: %s ", "Expires", stat.Expires.Format(printDate)))
 	}
+	if stat.Encryption != "" {
+		console.Println(fmt.Sprintf("%-10s: %s ", "Encryption", stat.Encryption))
+	}
 	var maxKey = 0
 	for k := range stat.Metadata {
 		// Skip encryption headers, we print them later.
@@ -118,6 +122,7 @@ func parseStat(c *ClientContent) statMessage {
 	content.ETag = strings.TrimPrefix(c.ETag, "\"")
 	content.ETag = strings.TrimSuffix(content.ETag, "\"")
 	content.Expires = c.Expires
+	content.Encryption = c.Encryption
 	return content
 }
 
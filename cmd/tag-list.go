@@ -116,7 +116,7 @@ func getObjTagging(urlStr string) tagging.Tagging {
 	if pErr != nil {
 		fatalIf(pErr.Trace(urlStr), "Unable to initialize target "+urlStr+". "+pErr.ToGoError().Error())
 	}
-	tagObj, pErr := clnt.GetObjectTagging()
+	tagObj, pErr := clnt.GetObjectTagging(globalContext)
 	fatalIf(pErr, "Failed to get tags for "+urlStr)
 
 	return tagObj
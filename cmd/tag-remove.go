@@ -81,7 +81,7 @@ func mainRemoveTag(ctx *cli.Context) error {
 	objectURL := ctx.Args().Get(0)
 	clnt, pErr := newClient(objectURL)
 	fatalIf(pErr.Trace(objectURL), "Unable to initialize target "+objectURL+".")
-	pErr = clnt.DeleteObjectTagging()
+	pErr = clnt.DeleteObjectTagging(globalContext)
 	if pErr != nil {
 		errorIf(pErr.Trace(objectURL), "Failed to remove tags for "+objectURL)
 		return exitStatus(globalErrorExitStatus)
@@ -119,7 +119,7 @@ func mainSetTag(ctx *cli.Context) error {
 		fatalIf(pErr.Trace(objectURL), "Unable to initialize target "+objectURL+". "+pErr.ToGoError().Error())
 	}
 
-	pErr = clnt.SetObjectTagging(objTagMap)
+	pErr = clnt.SetObjectTagging(globalContext, objTagMap)
 	if pErr != nil {
 		errorIf(pErr.Trace(objectURL), "Failed to set tags for "+objectURL)
 		return exitStatus(globalErrorExitStatus)
@@ -125,3 +125,17 @@ var errConflictSSE = func(sseServer, sseKeys string) *probe.Error {
 	err := fmt.Errorf("SSE alias '%s' overlaps with SSE-C aliases '%s'", sseServer, sseKeys)
 	return probe.NewError(conflictSSEErr(err)).Untrace()
 }
+
+type invalidPolicyPrefixErr error
+
+var errInvalidPolicyPrefix = func(prefix, reason string) *probe.Error {
+	msg := "Invalid policy prefix `" + prefix + "`, " + reason + "."
+	return probe.NewError(invalidPolicyPrefixErr(errors.New(msg))).Untrace()
+}
+
+type invalidARNErr error
+
+var errInvalidARN = func(arn, reason string) *probe.Error {
+	msg := "Invalid ARN `" + arn + "`, " + reason + "."
+	return probe.NewError(invalidARNErr(errors.New(msg))).Untrace()
+}
@@ -96,18 +96,8 @@ func checkWatchSyntax(ctx *cli.Context) {
 
 // watchMessage container to hold one event notification
 type watchMessage struct {
-	Status string `json:"status"`
-	Event  struct {
-		Time string    `json:"time"`
-		Size int64     `json:"size"`
-		Path string    `json:"path"`
-		Type EventType `json:"type"`
-	} `json:"events"`
-	Source struct {
-		Host      string `json:"host,omitempty"`
-		Port      string `json:"port,omitempty"`
-		UserAgent string `json:"userAgent,omitempty"`
-	} `json:"source,omitempty"`
+	Status string    `json:"status"`
+	Event  EventInfo `json:"events"`
 }
 
 func (u watchMessage) JSON() string {
@@ -182,15 +172,7 @@ func mainWatch(ctx *cli.Context) error {
 				if !ok {
 					return
 				}
-				msg := watchMessage{}
-				msg.Event.Path = event.Path
-				msg.Event.Size = event.Size
-				msg.Event.Time = event.Time
-				msg.Event.Type = event.Type
-				msg.Source.Host = event.Host
-				msg.Source.Port = event.Port
-				msg.Source.UserAgent = event.UserAgent
-				printMsg(msg)
+				printMsg(watchMessage{Event: event})
 			case err, ok := <-wo.Errors():
 				if !ok {
 					return
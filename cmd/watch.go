@@ -17,6 +17,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -56,6 +57,76 @@ type EventInfo struct {
 	Host         string
 	Port         string
 	UserAgent    string
+	// Region is the region of the bucket, empty if unknown.
+	Region string
+	// Sequencer orders events of a same object, empty if unknown.
+	Sequencer string
+	// ETag is that of the object created, VersionID that of the
+	// version created or removed, both empty if unknown.
+	ETag      string
+	VersionID string
+}
+
+// eventInfoJSON is the stable JSON representation of EventInfo.
+type eventInfoJSON struct {
+	Time         string            `json:"time"`
+	Type         EventType         `json:"type"`
+	Path         string            `json:"path"`
+	Size         int64             `json:"size"`
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+	Region       string            `json:"region,omitempty"`
+	Sequencer    string            `json:"sequencer,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	VersionID    string            `json:"versionId,omitempty"`
+	Source       struct {
+		Host      string `json:"host,omitempty"`
+		Port      string `json:"port,omitempty"`
+		UserAgent string `json:"userAgent,omitempty"`
+	} `json:"source"`
+}
+
+// MarshalJSON - encodes the event with a stable schema shared by all
+// consumers of events, including structs embedding an EventInfo, e.g.
+// the messages of mc watch --json. UnmarshalJSON decodes it back.
+func (e EventInfo) MarshalJSON() ([]byte, error) {
+	event := eventInfoJSON{
+		Time:         e.Time,
+		Type:         e.Type,
+		Path:         e.Path,
+		Size:         e.Size,
+		UserMetadata: e.UserMetadata,
+		Region:       e.Region,
+		Sequencer:    e.Sequencer,
+		ETag:         e.ETag,
+		VersionID:    e.VersionID,
+	}
+	event.Source.Host = e.Host
+	event.Source.Port = e.Port
+	event.Source.UserAgent = e.UserAgent
+	return json.Marshal(event)
+}
+
+// UnmarshalJSON - decodes an event encoded by MarshalJSON.
+func (e *EventInfo) UnmarshalJSON(data []byte) error {
+	var event eventInfoJSON
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	*e = EventInfo{
+		Time:         event.Time,
+		Type:         event.Type,
+		Path:         event.Path,
+		Size:         event.Size,
+		UserMetadata: event.UserMetadata,
+		Host:         event.Source.Host,
+		Port:         event.Source.Port,
+		UserAgent:    event.Source.UserAgent,
+		Region:       event.Region,
+		Sequencer:    event.Sequencer,
+		ETag:         event.ETag,
+		VersionID:    event.VersionID,
+	}
+	return nil
 }
 
 type watchParams struct {
@@ -0,0 +1,74 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+// Test the JSON representation of events.
+func (s *TestSuite) TestEventInfoJSON(c *C) {
+	testCases := []struct {
+		event EventInfo
+		json  string
+	}{
+		{
+			EventInfo{
+				Time:         "2020-05-21T18:24:21.097Z",
+				Size:         42,
+				UserMetadata: map[string]string{"X-Amz-Meta-Owner": "me"},
+				Path:         "https://s3.amazonaws.com/bucket/object",
+				Type:         EventCreate,
+				Host:         "10.0.0.1",
+				Port:         "443",
+				UserAgent:    "mc",
+				Region:       "us-east-1",
+				Sequencer:    "0055AED6DCD90281E5",
+				ETag:         "9af2f8218b150c351ad802c6f3d66abe",
+				VersionID:    "v1",
+			},
+			`{"time":"2020-05-21T18:24:21.097Z","type":"ObjectCreated","path":"https://s3.amazonaws.com/bucket/object","size":42,` +
+				`"userMetadata":{"X-Amz-Meta-Owner":"me"},"region":"us-east-1","sequencer":"0055AED6DCD90281E5",` +
+				`"etag":"9af2f8218b150c351ad802c6f3d66abe","versionId":"v1",` +
+				`"source":{"host":"10.0.0.1","port":"443","userAgent":"mc"}}`,
+		},
+		{
+			EventInfo{
+				Time: "2020-05-21T18:24:21.097Z",
+				Path: "/tmp/object",
+				Type: EventRemove,
+			},
+			`{"time":"2020-05-21T18:24:21.097Z","type":"ObjectRemoved","path":"/tmp/object","size":0,"source":{}}`,
+		},
+	}
+	for i, testCase := range testCases {
+		data, e := json.Marshal(testCase.event)
+		c.Assert(e, IsNil)
+		c.Assert(string(data), Equals, testCase.json, Commentf("test %d", i+1))
+
+		var event EventInfo
+		c.Assert(json.Unmarshal(data, &event), IsNil)
+		c.Assert(event, DeepEquals, testCase.event, Commentf("test %d", i+1))
+
+		// mc watch --json messages carry the same encoding.
+		data, e = json.Marshal(watchMessage{Status: "success", Event: testCase.event})
+		c.Assert(e, IsNil)
+		c.Assert(string(data), Equals, `{"status":"success","events":`+testCase.json+`}`, Commentf("test %d", i+1))
+	}
+}